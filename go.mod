@@ -2,4 +2,7 @@ module github.com/getDragon-dev/dragon-registry
 
 go 1.26.5
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	github.com/BurntSushi/toml v1.6.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,221 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// BLAKE3, unkeyed with the default 32-byte output, following the
+// reference implementation in the specification
+// (https://github.com/BLAKE3-team/BLAKE3-specs). It is written for
+// clarity rather than speed: archives are a few MB at most.
+
+const (
+	blake3ChunkLen = 1024
+	blake3BlockLen = 64
+
+	blake3ChunkStart = 1 << 0
+	blake3ChunkEnd   = 1 << 1
+	blake3Parent     = 1 << 2
+	blake3Root       = 1 << 3
+)
+
+var blake3IV = [8]uint32{
+	0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a,
+	0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19,
+}
+
+var blake3Permutation = [16]int{2, 6, 3, 10, 7, 0, 4, 13, 1, 11, 12, 5, 9, 14, 15, 8}
+
+func blake3G(s *[16]uint32, a, b, c, d int, mx, my uint32) {
+	s[a] += s[b] + mx
+	s[d] = bits.RotateLeft32(s[d]^s[a], -16)
+	s[c] += s[d]
+	s[b] = bits.RotateLeft32(s[b]^s[c], -12)
+	s[a] += s[b] + my
+	s[d] = bits.RotateLeft32(s[d]^s[a], -8)
+	s[c] += s[d]
+	s[b] = bits.RotateLeft32(s[b]^s[c], -7)
+}
+
+func blake3Compress(cv [8]uint32, m [16]uint32, counter uint64, blockLen, flags uint32) [16]uint32 {
+	s := [16]uint32{
+		cv[0], cv[1], cv[2], cv[3], cv[4], cv[5], cv[6], cv[7],
+		blake3IV[0], blake3IV[1], blake3IV[2], blake3IV[3],
+		uint32(counter), uint32(counter >> 32), blockLen, flags,
+	}
+	for round := range 7 {
+		blake3G(&s, 0, 4, 8, 12, m[0], m[1])
+		blake3G(&s, 1, 5, 9, 13, m[2], m[3])
+		blake3G(&s, 2, 6, 10, 14, m[4], m[5])
+		blake3G(&s, 3, 7, 11, 15, m[6], m[7])
+		blake3G(&s, 0, 5, 10, 15, m[8], m[9])
+		blake3G(&s, 1, 6, 11, 12, m[10], m[11])
+		blake3G(&s, 2, 7, 8, 13, m[12], m[13])
+		blake3G(&s, 3, 4, 9, 14, m[14], m[15])
+		if round < 6 {
+			var p [16]uint32
+			for i, j := range blake3Permutation {
+				p[i] = m[j]
+			}
+			m = p
+		}
+	}
+	for i := range 8 {
+		s[i] ^= s[i+8]
+		s[i+8] ^= cv[i]
+	}
+	return s
+}
+
+func blake3Words(block []byte) [16]uint32 {
+	var buf [blake3BlockLen]byte
+	copy(buf[:], block)
+	var m [16]uint32
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint32(buf[4*i:])
+	}
+	return m
+}
+
+func blake3First8(s [16]uint32) [8]uint32 {
+	return [8]uint32(s[:8])
+}
+
+// blake3Output is a node of the tree that is yet to be compressed: as a
+// chaining value for its parent, or with the root flag for the hash.
+type blake3Output struct {
+	cv       [8]uint32
+	block    [16]uint32
+	counter  uint64
+	blockLen uint32
+	flags    uint32
+}
+
+func (o blake3Output) chainingValue() [8]uint32 {
+	return blake3First8(blake3Compress(o.cv, o.block, o.counter, o.blockLen, o.flags))
+}
+
+func (o blake3Output) root() []byte {
+	s := blake3Compress(o.cv, o.block, 0, o.blockLen, o.flags|blake3Root)
+	out := make([]byte, 0, 32)
+	for _, w := range s[:8] {
+		out = binary.LittleEndian.AppendUint32(out, w)
+	}
+	return out
+}
+
+func blake3ParentOutput(left, right [8]uint32) blake3Output {
+	var m [16]uint32
+	copy(m[:8], left[:])
+	copy(m[8:], right[:])
+	return blake3Output{cv: blake3IV, block: m, blockLen: blake3BlockLen, flags: blake3Parent}
+}
+
+// blake3Chunk hashes one 1 KiB chunk of the input.
+type blake3Chunk struct {
+	cv         [8]uint32
+	counter    uint64
+	block      [blake3BlockLen]byte
+	blockLen   int
+	compressed int
+}
+
+func newBLAKE3Chunk(counter uint64) blake3Chunk {
+	return blake3Chunk{cv: blake3IV, counter: counter}
+}
+
+func (c *blake3Chunk) len() int {
+	return blake3BlockLen*c.compressed + c.blockLen
+}
+
+func (c *blake3Chunk) startFlag() uint32 {
+	if c.compressed == 0 {
+		return blake3ChunkStart
+	}
+	return 0
+}
+
+func (c *blake3Chunk) update(p []byte) {
+	for len(p) > 0 {
+		if c.blockLen == blake3BlockLen {
+			c.cv = blake3First8(blake3Compress(c.cv, blake3Words(c.block[:]), c.counter, blake3BlockLen, c.startFlag()))
+			c.compressed++
+			c.block = [blake3BlockLen]byte{}
+			c.blockLen = 0
+		}
+		n := copy(c.block[c.blockLen:], p)
+		c.blockLen += n
+		p = p[n:]
+	}
+}
+
+func (c *blake3Chunk) output() blake3Output {
+	return blake3Output{
+		cv:       c.cv,
+		block:    blake3Words(c.block[:c.blockLen]),
+		counter:  c.counter,
+		blockLen: uint32(c.blockLen),
+		flags:    c.startFlag() | blake3ChunkEnd,
+	}
+}
+
+// blake3Hasher implements hash.Hash.
+type blake3Hasher struct {
+	chunk blake3Chunk
+	// stack holds the chaining values of complete subtrees, largest
+	// first
+	stack [][8]uint32
+}
+
+func newBLAKE3() hash.Hash {
+	return &blake3Hasher{chunk: newBLAKE3Chunk(0)}
+}
+
+func (h *blake3Hasher) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if h.chunk.len() == blake3ChunkLen {
+			cv := h.chunk.output().chainingValue()
+			total := h.chunk.counter + 1
+			// merge the subtrees this chunk completes
+			for total&1 == 0 {
+				cv = blake3ParentOutput(h.stack[len(h.stack)-1], cv).chainingValue()
+				h.stack = h.stack[:len(h.stack)-1]
+				total >>= 1
+			}
+			h.stack = append(h.stack, cv)
+			h.chunk = newBLAKE3Chunk(h.chunk.counter + 1)
+		}
+		take := min(blake3ChunkLen-h.chunk.len(), len(p))
+		h.chunk.update(p[:take])
+		p = p[take:]
+	}
+	return n, nil
+}
+
+func (h *blake3Hasher) Sum(b []byte) []byte {
+	out := h.chunk.output()
+	for i := len(h.stack) - 1; i >= 0; i-- {
+		out = blake3ParentOutput(h.stack[i], out.chainingValue())
+	}
+	return append(b, out.root()...)
+}
+
+func (h *blake3Hasher) Reset() {
+	*h = blake3Hasher{chunk: newBLAKE3Chunk(0), stack: h.stack[:0]}
+}
+
+func (h *blake3Hasher) Size() int      { return 32 }
+func (h *blake3Hasher) BlockSize() int { return blake3BlockLen }
@@ -0,0 +1,203 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testDatabase has every field of a registry set, entries and releases
+// out of order.
+func testDatabase() Database {
+	at := time.Date(2025, 3, 4, 5, 6, 7, 800, time.UTC)
+	return Database{
+		SchemaVersion: CurrentSchema,
+		Metadata: Metadata{
+			MinClientVersion: "0.1.0",
+			Tombstones: []Tombstone{
+				{Name: "core/old-b", Version: "0.2.0", Reason: "superseded", RemovedAt: at, SHA256: "ab", Visibility: "internal"},
+				{Name: "core/old-a", Version: "0.1.0", Reason: "broken", RemovedAt: at},
+			},
+			RequiredDigests: []string{"sha256", "blake3"},
+		},
+		Blueprints: []Blueprint{
+			{
+				Namespace: "core", Name: "web-api", Title: "Web API", Version: "2.0.0",
+				Repo: "github.com/getDragon-dev/dragon-blueprints", Path: "blueprints/web-api",
+				DownloadURL: "https://example.com/web-api-2.0.0.zip",
+				SHA256:      "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+				Digests:     Digests{"blake3": "fedc", "sha512": "ba98"},
+				Asset:       &AssetRef{ID: 42, Size: 2048},
+				SourceURL:   "https://github.com/getDragon-dev/dragon-blueprints/releases/download/v2.0.0/web-api.zip",
+				Signed:      true,
+				Provenance:  &Provenance{Builder: "https://example.com/builder@v1", SourceRepo: "github.com/getDragon-dev/dragon-blueprints", Commit: "abc123"},
+				Sources:     []string{"https://mirror.example.com/web-api.zip"},
+				Mirrors:     []string{"https://cdn.example.com/web-api.zip", "https://backup.example.com/web-api.zip"},
+				Description: "An HTTP API \u2014 with \"quotes\", <tags> & a tab\t.",
+				Tags:        []string{"api", "auto:go", "http"},
+				Features:    map[string]any{"docker": true, "db": "postgres"},
+				Dependencies: []Dependency{
+					{Name: "core/base", Version: "^1.0"},
+					{Name: "lint"},
+				},
+				Visibility:    "public",
+				License:       "Apache-2.0",
+				LicenseSource: "LICENSE",
+				Maintainers:   []string{"octocat", "hubot"},
+				Category:      "backend",
+				Icon:          "https://example.com/icon.png",
+				Screenshots:   []string{"https://example.com/1.png"},
+				Trust:         "verified",
+				Previous: []Release{
+					{Version: "1.0.0", DownloadURL: "https://example.com/web-api-1.0.0.zip", SHA256: "11", ReleasedAt: at.Add(-48 * time.Hour)},
+					{Version: "1.10.0", DownloadURL: "https://example.com/web-api-1.10.0.zip", SHA256: "22", Digests: Digests{"blake3": "33"}, Asset: &AssetRef{ID: 41, Size: 1024}, ReleasedAt: at.Add(-24 * time.Hour)},
+				},
+				CreatedAt:     at.Add(-72 * time.Hour),
+				UpdatedAt:     at,
+				Quality:       &Quality{Score: 80, Missing: []string{"icon", "screenshots"}},
+				Deprecation:   &Deprecation{Reason: "use web-api-v3", Replacement: "core/web-api-v3", Sunset: at.Add(720 * time.Hour)},
+				Compatibility: []Compatibility{{CLI: "1.4.0", Version: "2.0.0", Passed: true, TestedAt: at}, {CLI: "1.3.0", Version: "2.0.0"}},
+				Stale:         &Staleness{Since: at, LastRelease: at.Add(-24 * time.Hour)},
+			},
+			{Namespace: "acme", Name: "cli", Version: "0.1.0", Repo: "github.com/acme/blueprints", Path: "blueprints/cli", DownloadURL: "https://example.com/cli.zip", Description: "A CLI", Tags: []string{}},
+		},
+	}
+}
+
+// Appendix B of RFC 8785: IEEE 754 doubles and how they serialize.
+func TestFormatESNumber(t *testing.T) {
+	cases := []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	}
+	for _, c := range cases {
+		got, err := formatESNumber(math.Float64frombits(c.bits))
+		if err != nil {
+			t.Errorf("%016x: %v", c.bits, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%016x: %s, want %s", c.bits, got, c.want)
+		}
+	}
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := formatESNumber(f); err == nil {
+			t.Errorf("%v: no error", f)
+		}
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		// section 3.2.2 of RFC 8785
+		{
+			"rfc example",
+			`{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`,
+			`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"` + "\u20ac" + `$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		// section 3.2.3: keys in UTF-16 code unit order, where the
+		// emoji's surrogates come before U+FB33
+		{
+			"key order",
+			`{"\u20ac":"Euro Sign","\r":"Carriage Return","\ufb33":"Hebrew Letter Dalet With Dagesh","1":"One","\ud83d\ude00":"Emoji: Grinning Face","\u0080":"Control","\u00f6":"Latin Small Letter O With Diaeresis"}`,
+			`{"\r":"Carriage Return","1":"One","` + "\u0080" + `":"Control","` + "\u00f6" + `":"Latin Small Letter O With Diaeresis","` + "\u20ac" + `":"Euro Sign","` + "\U0001f600" + `":"Emoji: Grinning Face","` + "\ufb33" + `":"Hebrew Letter Dalet With Dagesh"}`,
+		},
+		{
+			"escapes",
+			`["\u0000\u0008\u0009\u000a\u000c\u000d\u001f\u007f", "<&>\u2028"]`,
+			`["\u0000\b\t\n\f\r\u001f` + "\u007f" + `","<&>` + "\u2028" + `"]`,
+		},
+		{"nesting", `{"b":[{"d":1,"c":[]}],"a":{}}`, `{"a":{},"b":[{"c":[],"d":1}]}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := CanonicalJSON(json.RawMessage(c.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != c.want {
+				t.Errorf("got  %s\nwant %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalRegistryRoundTrip(t *testing.T) {
+	db := testDatabase()
+	p := filepath.Join(t.TempDir(), "registry.json")
+	if err := Save(p, db, SaveOptions{Canonical: true}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := CanonicalJSON(Normalize(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the canonical bytes, then the newline ending a text file
+	if !bytes.Equal(b, append(want, '\n')) {
+		t.Fatalf("saved\n%s\nwant\n%s", b, want)
+	}
+	got, err := Load(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, Normalize(db)) {
+		t.Errorf("loaded\n%+v\nwant\n%+v", got, Normalize(db))
+	}
+	again, err := CanonicalJSON(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, want) {
+		t.Errorf("re-encoding what was loaded changed it:\n%s\nwant\n%s", again, want)
+	}
+}
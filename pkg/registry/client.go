@@ -0,0 +1,429 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMaxArchiveBytes caps archive downloads, as the updater caps the
+// archives it inspects.
+const DefaultMaxArchiveBytes = 100 << 20
+
+// maxRetryAfter is the longest Retry-After a rate-limited request waits
+// out before failing.
+const maxRetryAfter = time.Minute
+
+var (
+	// ErrOffline is returned in offline mode for what isn't cached.
+	ErrOffline = errors.New("not cached, and offline")
+	// ErrNoDigest is returned for archives of entries without a sha256,
+	// which can't be verified.
+	ErrNoDigest = errors.New("entry has no sha256")
+)
+
+// Client fetches a published registry and the archives it lists, for
+// tools such as the dragon CLI. With a CacheDir it keeps the index, to
+// revalidate rather than refetch it and to fall back on when the network
+// fails, and every archive it has verified, keyed by digest, so the same
+// archive is downloaded once.
+type Client struct {
+	// IndexURL is the registry to fetch, in the encoding its extension
+	// names.
+	IndexURL string
+	// CacheDir holds the cached index and archives; empty disables the
+	// cache.
+	CacheDir string
+	// Offline serves everything from the cache without touching the
+	// network.
+	Offline bool
+	// HTTP makes the requests; http.DefaultClient by default.
+	HTTP *http.Client
+	// MinInterval spaces requests at least this far apart, to stay
+	// under a public mirror's rate limit.
+	MinInterval time.Duration
+	// MaxArchiveBytes defaults to DefaultMaxArchiveBytes.
+	MaxArchiveBytes int64
+	// Now stamps Index.FetchedAt; time.Now by default. Tests set it to
+	// a fixed clock.
+	Now func() time.Time
+	// RequireDigests are algorithms, besides sha256, whose digests an
+	// archive must be verified with; entries without one can't be
+	// downloaded. The required_digests of the last index fetched are
+	// required too. Every other recorded digest in an algorithm the
+	// package supports is verified as well.
+	RequireDigests []string
+
+	mu   sync.Mutex
+	next time.Time
+	// required is the last index's required_digests
+	required []string
+}
+
+// Index is a registry as the Client got it.
+type Index struct {
+	DB        Database
+	FetchedAt time.Time
+	// Stale is set when the index came from the cache without being
+	// revalidated: offline, or because the registry couldn't be reached.
+	Stale bool
+}
+
+// indexMeta is what is cached next to the index to revalidate it.
+type indexMeta struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP == nil {
+		return http.DefaultClient
+	}
+	return c.HTTP
+}
+
+func (c *Client) now() time.Time {
+	if c.Now == nil {
+		return time.Now().UTC()
+	}
+	return c.Now().UTC()
+}
+
+// indexPath is where the index is cached, one file per URL.
+func (c *Client) indexPath() string {
+	sum := sha256.Sum256([]byte(c.IndexURL))
+	return filepath.Join(c.CacheDir, "index", hex.EncodeToString(sum[:8])+formatExt[c.format()])
+}
+
+func (c *Client) format() string {
+	u, err := url.Parse(c.IndexURL)
+	if err != nil {
+		return FormatJSON
+	}
+	return FormatOf(u.Path)
+}
+
+// ArchivePath is where the archive with the given sha256 is cached.
+func (c *Client) ArchivePath(digest string) string {
+	return filepath.Join(c.CacheDir, "archives", "sha256", digest)
+}
+
+// Index fetches the registry, revalidating the cached copy if there is
+// one. When the registry can't be reached, or answers with a server
+// error, the cached copy is returned marked Stale.
+func (c *Client) Index(ctx context.Context) (Index, error) {
+	meta, cached := c.cachedIndex()
+	if c.Offline {
+		if cached == nil {
+			return Index{}, fmt.Errorf("%s: %w", c.IndexURL, ErrOffline)
+		}
+		return c.decodeIndex(cached, meta.FetchedAt, true)
+	}
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", c.IndexURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if cached != nil {
+			if meta.ETag != "" {
+				req.Header.Set("If-None-Match", meta.ETag)
+			}
+			if meta.LastModified != "" {
+				req.Header.Set("If-Modified-Since", meta.LastModified)
+			}
+		}
+		return req, nil
+	})
+	if err != nil {
+		if cached != nil && ctx.Err() == nil && transient(err) {
+			return c.decodeIndex(cached, meta.FetchedAt, true)
+		}
+		return Index{}, err
+	}
+	defer resp.Body.Close()
+	now := c.now()
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		meta.FetchedAt = now
+		c.saveIndex(cached, meta)
+		return c.decodeIndex(cached, now, false)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxResponseBytes+1))
+	if err == nil && len(body) > MaxResponseBytes {
+		err = fmt.Errorf("GET %s: response exceeds %d bytes", c.IndexURL, MaxResponseBytes)
+	}
+	if err != nil {
+		return Index{}, err
+	}
+	idx, err := c.decodeIndex(body, now, false)
+	if err != nil {
+		return idx, err
+	}
+	// only a registry that decodes replaces the cached one
+	c.saveIndex(body, indexMeta{
+		URL:          c.IndexURL,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    now,
+	})
+	return idx, nil
+}
+
+func (c *Client) decodeIndex(b []byte, at time.Time, stale bool) (Index, error) {
+	db, err := Decode(b, c.format())
+	if err != nil {
+		return Index{}, fmt.Errorf("%s: %w", c.IndexURL, err)
+	}
+	c.mu.Lock()
+	c.required = db.Metadata.RequiredDigests
+	c.mu.Unlock()
+	return Index{DB: db, FetchedAt: at, Stale: stale}, nil
+}
+
+// cachedIndex returns the cached index, or nil.
+func (c *Client) cachedIndex() (indexMeta, []byte) {
+	var meta indexMeta
+	if c.CacheDir == "" {
+		return meta, nil
+	}
+	p := c.indexPath()
+	mb, err := os.ReadFile(p + ".meta.json")
+	if err != nil || json.Unmarshal(mb, &meta) != nil || meta.URL != c.IndexURL {
+		return indexMeta{}, nil
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return indexMeta{}, nil
+	}
+	return meta, b
+}
+
+// saveIndex caches the index. The cache is an optimization, so failing
+// to write it isn't an error.
+func (c *Client) saveIndex(b []byte, meta indexMeta) {
+	if c.CacheDir == "" {
+		return
+	}
+	p := c.indexPath()
+	mb, err := json.Marshal(meta)
+	if err != nil || os.MkdirAll(filepath.Dir(p), 0o755) != nil {
+		return
+	}
+	// the body first: meta pointing at an old body only costs a refetch
+	if WriteFileAtomic(p, b) == nil {
+		WriteFileAtomic(p+".meta.json", mb)
+	}
+}
+
+// Archive returns the path of bp's archive in the cache, downloading it
+// from DownloadURL or else each of its Mirrors if it isn't there yet. An
+// archive is only cached once its sha256, and every other digest recorded
+// for it that can be computed, matches the entry's. The entries
+// of private repos download through the GitHub API, which takes an HTTP
+// client that adds credentials.
+func (c *Client) Archive(ctx context.Context, bp Blueprint) (string, error) {
+	if c.CacheDir == "" {
+		return "", errors.New("archive cache needs a CacheDir")
+	}
+	if bp.SHA256 == "" {
+		return "", fmt.Errorf("%s: %w", bp.FullName(), ErrNoDigest)
+	}
+	want := bp.AllDigests()
+	required := c.requiredDigests()
+	if err := want.Verify(nil, required); err != nil {
+		return "", fmt.Errorf("%s %s: %w", bp.FullName(), bp.Version, err)
+	}
+	for _, alg := range required {
+		if !SupportedDigest(alg) {
+			return "", fmt.Errorf("%s %s: unsupported digest algorithm %q", bp.FullName(), bp.Version, alg)
+		}
+	}
+	p := c.ArchivePath(bp.SHA256)
+	if _, err := os.Stat(p); err == nil {
+		return p, nil
+	}
+	if c.Offline {
+		return "", fmt.Errorf("%s %s: %w", bp.FullName(), bp.Version, ErrOffline)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+	var errs []error
+	for _, u := range append([]string{bp.DownloadURL}, bp.Mirrors...) {
+		err := c.download(ctx, u, want, p)
+		if err == nil {
+			return p, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		errs = append(errs, err)
+	}
+	return "", fmt.Errorf("%s %s: %w", bp.FullName(), bp.Version, errors.Join(errs...))
+}
+
+// requiredDigests are the algorithms RequireDigests and the last index
+// require.
+func (c *Client) requiredDigests() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := slices.Clone(c.RequireDigests)
+	for _, alg := range c.required {
+		if !slices.Contains(out, alg) {
+			out = append(out, alg)
+		}
+	}
+	return out
+}
+
+// download fetches u into p if it hashes to the digests in want.
+func (c *Client) download(ctx context.Context, u string, want Digests, p string) error {
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+		if err == nil && IsAssetAPIURL(u) {
+			req.Header.Set("Accept", "application/octet-stream")
+		}
+		return req, err
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	limit := c.MaxArchiveBytes
+	if limit <= 0 {
+		limit = DefaultMaxArchiveBytes
+	}
+	if resp.ContentLength > limit {
+		return fmt.Errorf("GET %s: archive exceeds %d bytes", u, limit)
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), "."+path.Base(p)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	d, err := NewDigester(want.Computable()...)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(io.MultiWriter(f, d), io.LimitReader(resp.Body, limit+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	switch {
+	case err != nil:
+		return fmt.Errorf("GET %s: %w", u, err)
+	case n > limit:
+		return fmt.Errorf("GET %s: archive exceeds %d bytes", u, limit)
+	}
+	if err := want.Verify(d.Sum(), nil); err != nil {
+		return fmt.Errorf("GET %s: %w", u, err)
+	}
+	return ReplaceFile(f.Name(), p)
+}
+
+// do sends the request newReq builds, spaced by MinInterval. A 429 or
+// 503 with a short enough Retry-After is waited out and retried once.
+// Responses other than 2xx and 304 fail with a *StatusError.
+func (c *Client) do(ctx context.Context, newReq func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.wait(ctx); err != nil {
+			return nil, err
+		}
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode/100 == 2 || resp.StatusCode == http.StatusNotModified {
+			return resp, nil
+		}
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		resp.Body.Close()
+		if d, ok := retryAfter(resp); ok && attempt == 0 {
+			select {
+			case <-time.After(d):
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		return nil, &StatusError{URL: req.URL.String(), Code: resp.StatusCode, Body: string(b), RequestID: resp.Header.Get("X-Request-Id")}
+	}
+}
+
+// wait blocks until the next request may be sent.
+func (c *Client) wait(ctx context.Context) error {
+	if c.MinInterval <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	now := time.Now()
+	at := now
+	if c.next.After(now) {
+		at = c.next
+	}
+	c.next = at.Add(c.MinInterval)
+	c.mu.Unlock()
+	if at.Equal(now) {
+		return nil
+	}
+	t := time.NewTimer(at.Sub(now))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryAfter is how long a rate-limited response asks us to wait, if it
+// is worth waiting.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	s, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || s < 0 {
+		return 0, false
+	}
+	d := time.Duration(s) * time.Second
+	return d, d <= maxRetryAfter
+}
+
+// transient reports whether err is a failure to reach the registry, as
+// opposed to an answer that it has no such index.
+func transient(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code >= 500 || se.Code == http.StatusTooManyRequests
+	}
+	return true
+}
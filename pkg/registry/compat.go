@@ -0,0 +1,95 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ClientVersion is the version of the tooling reading registries, compared
+// against their min_client_version. Release builds set it with -ldflags
+// "-X github.com/getDragon-dev/dragon-registry/pkg/registry.ClientVersion=...".
+var ClientVersion = "0.1.0"
+
+// Metadata is the metadata block of registry.json.
+type Metadata struct {
+	// MinClientVersion is the oldest tooling that may read the registry.
+	// Raise it before publishing changes older readers would
+	// misinterpret.
+	MinClientVersion string `json:"min_client_version,omitempty"`
+	// Tombstones are the entries removed on purpose.
+	Tombstones []Tombstone `json:"tombstones,omitempty"`
+	// RequiredDigests are the algorithms whose digests clients must
+	// verify an archive with; an entry without one can't be installed.
+	RequiredDigests []string `json:"required_digests,omitempty"`
+}
+
+// ErrNewerTooling is matched by every error that means the registry was
+// written for newer tooling; errors.Is(err, ErrNewerTooling) tells users
+// to upgrade rather than that the registry is broken.
+var ErrNewerTooling = errors.New("registry requires newer tooling")
+
+// ClientTooOldError reports that the registry's min_client_version is
+// above the reader's version.
+type ClientTooOldError struct {
+	Required string // the registry's min_client_version
+	Have     string // the reader's version
+}
+
+func (e *ClientTooOldError) Error() string {
+	return fmt.Sprintf("%v: needs %s or later, this is %s", ErrNewerTooling, e.Required, e.Have)
+}
+
+func (e *ClientTooOldError) Is(target error) bool {
+	return target == ErrNewerTooling
+}
+
+// CheckClientVersion returns a *ClientTooOldError if have is older than
+// the registry allows. Registries whose minimum doesn't parse are read
+// anyway; validation reports them.
+func CheckClientVersion(meta Metadata, have string) error {
+	if meta.MinClientVersion == "" {
+		return nil
+	}
+	need, err := ParseSemver(meta.MinClientVersion)
+	if err != nil {
+		return nil
+	}
+	v, err := ParseSemver(have)
+	if err != nil {
+		return nil // development build
+	}
+	if v.Compare(need) < 0 {
+		return &ClientTooOldError{Required: meta.MinClientVersion, Have: have}
+	}
+	return nil
+}
+
+// CurrentSchema is the registry.json schema version this package writes.
+const CurrentSchema = 1
+
+// Migrate upgrades db in place to CurrentSchema.
+func Migrate(db *Database) error {
+	if db.SchemaVersion > CurrentSchema {
+		return fmt.Errorf("%w: schema %d is newer than this tool supports (%d)", ErrNewerTooling, db.SchemaVersion, CurrentSchema)
+	}
+	if db.SchemaVersion < 1 {
+		// v1: flat names move into the default namespace
+		for i := range db.Blueprints {
+			if db.Blueprints[i].Namespace == "" {
+				db.Blueprints[i].Namespace = DefaultNamespace
+			}
+		}
+	}
+	db.SchemaVersion = CurrentSchema
+	return nil
+}
@@ -0,0 +1,560 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conformancetest checks a registry server against the HTTP API
+// that `serve` documents, so alternative servers and mirrors can prove
+// that clients of the API will work with them. Serve Fixture, then call
+// Run from a test:
+//
+//	func TestConformance(t *testing.T) {
+//		srv := httptest.NewServer(newHandler(conformancetest.Fixture()))
+//		defer srv.Close()
+//		conformancetest.Run(t, conformancetest.Server{URL: srv.URL})
+//	}
+//
+// Run covers the entry list, search semantics, entry and release lookups,
+// ETag revalidation, pagination and the shape of errors. It only reads.
+package conformancetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// Server is the implementation under test.
+type Server struct {
+	// URL is where the API is served, e.g. an httptest.Server's URL;
+	// requests go to URL/v1/blueprints and so on.
+	URL string
+	// Client makes the requests; http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// epoch dates the fixture, so it is the same on every run.
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// hidden is the fixture's internal entry, which an anonymous client must
+// never see.
+const hidden = "core/secret-sauce"
+
+// pageSize is the limit pagination is checked with; the fixture has a
+// few more public entries than two pages hold.
+const pageSize = 5
+
+// Fixture returns the registry the server under test must serve, to
+// anonymous clients with the default audience: 12 public entries in two
+// namespaces and one internal entry.
+func Fixture() registry.Database {
+	entry := func(ns, name, desc string, tags ...string) registry.Blueprint {
+		return registry.Blueprint{
+			Namespace:   ns,
+			Name:        name,
+			Version:     "1.0.0",
+			Repo:        "github.com/" + ns + "/blueprints",
+			Path:        "blueprints/" + name,
+			DownloadURL: fmt.Sprintf("https://example.com/%s/%s-1.0.0.zip", ns, name),
+			SHA256:      fmt.Sprintf("%064x", len(ns)*1000+len(name)*10+len(tags)),
+			Description: desc,
+			Tags:        tags,
+			Visibility:  "public",
+			CreatedAt:   epoch,
+			UpdatedAt:   epoch,
+		}
+	}
+	web := entry("core", "web", "REST service backed by Postgres.", "api", "go", "http")
+	web.Version = "2.0.0"
+	web.DownloadURL = "https://example.com/core/web-2.0.0.zip"
+	web.Features = map[string]any{"db": "postgres"}
+	web.Previous = []registry.Release{{
+		Version:     "1.0.0",
+		DownloadURL: "https://example.com/core/web-1.0.0.zip",
+		SHA256:      strings.Repeat("ab", 32),
+		ReleasedAt:  epoch.Add(-24 * time.Hour),
+	}}
+	worker := entry("acme", "web-worker", "Background jobs for web apps.", "worker")
+	worker.Features = map[string]any{"db": "mysql"}
+	secret := entry("core", "secret-sauce", "Not for everyone.", "api")
+	secret.Visibility = "internal"
+	db := registry.Database{
+		SchemaVersion: registry.CurrentSchema,
+		Blueprints: []registry.Blueprint{
+			web,
+			worker,
+			entry("core", "grpc-gateway", "Gateway in front of a service.", "api", "grpc"),
+			entry("core", "kubernetes-operator", "Operator reconciling custom resources.", "k8s", "go"),
+			entry("core", "cli-tool", "Cobra CLI starter.", "cli", "go"),
+			entry("acme", "billing", "Invoices over grpc.", "api"),
+			secret,
+		},
+	}
+	for i := range 6 {
+		db.Blueprints = append(db.Blueprints, entry("core", fmt.Sprintf("filler-%02d", i), "Padding for pagination."))
+	}
+	return registry.Normalize(db)
+}
+
+// publicNames are the full names of the entries an anonymous client sees.
+func publicNames() []string {
+	var names []string
+	for _, bp := range Fixture().Blueprints {
+		if bp.FullName() != hidden {
+			names = append(names, bp.FullName())
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Run checks srv, which serves Fixture, as subtests of t.
+func Run(t *testing.T, srv Server) {
+	c := &client{base: strings.TrimSuffix(srv.URL, "/"), hc: srv.Client}
+	if c.hc == nil {
+		c.hc = http.DefaultClient
+	}
+	t.Run("List", c.testList)
+	t.Run("Pagination", c.testPagination)
+	t.Run("Search", c.testSearch)
+	t.Run("Entry", c.testEntry)
+	t.Run("ETag", c.testETag)
+	t.Run("Errors", c.testErrors)
+	t.Run("RegistryFile", c.testRegistryFile)
+}
+
+type client struct {
+	base string
+	hc   *http.Client
+}
+
+// response is what a request got back.
+type response struct {
+	url    *url.URL
+	code   int
+	header http.Header
+	body   []byte
+}
+
+// get requests path under the server's URL, or an absolute URL such as
+// the next page's.
+func (c *client) get(t *testing.T, path string, h http.Header) response {
+	t.Helper()
+	u := path
+	if strings.HasPrefix(path, "/") {
+		u = c.base + path
+	}
+	req, err := http.NewRequestWithContext(t.Context(), "GET", u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range h {
+		req.Header[k] = v
+	}
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	return response{url: req.URL, code: resp.StatusCode, header: resp.Header, body: b}
+}
+
+// getJSON requests path, which must answer 200 with JSON, into v.
+func (c *client) getJSON(t *testing.T, path string, v any) response {
+	t.Helper()
+	r := c.get(t, path, nil)
+	if r.code != http.StatusOK {
+		t.Fatalf("GET %s: status %d, want 200: %s", path, r.code, r.body)
+	}
+	r.wantJSON(t, v)
+	return r
+}
+
+func (r response) wantJSON(t *testing.T, v any) {
+	t.Helper()
+	if mt, _, _ := mime.ParseMediaType(r.header.Get("Content-Type")); mt != "application/json" {
+		t.Errorf("GET %s: Content-Type %q, want application/json", r.url.Path, r.header.Get("Content-Type"))
+	}
+	if err := json.Unmarshal(r.body, v); err != nil {
+		t.Fatalf("GET %s: %v: %s", r.url.Path, err, r.body)
+	}
+}
+
+// wantError checks that r is an error response with status code: a JSON
+// body {"error": "...", "request_id": "..."}, the ID as in X-Request-Id.
+func (r response) wantError(t *testing.T, code int) {
+	t.Helper()
+	if r.code != code {
+		t.Errorf("GET %s: status %d, want %d", r.url, r.code, code)
+		return
+	}
+	var e struct {
+		Error     *string `json:"error"`
+		RequestID string  `json:"request_id"`
+	}
+	r.wantJSON(t, &e)
+	if e.Error == nil || *e.Error == "" {
+		t.Errorf("GET %s: no error message in %s", r.url, r.body)
+	}
+	if id := r.header.Get("X-Request-Id"); id == "" || e.RequestID != id {
+		t.Errorf("GET %s: request_id %q, X-Request-Id %q; want the same ID in both", r.url, e.RequestID, id)
+	}
+}
+
+// next is the absolute URL of the next page, from the Link header, or "".
+func (r response) next(t *testing.T) string {
+	t.Helper()
+	for _, link := range r.header.Values("Link") {
+		for l := range strings.SplitSeq(link, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(l), ";")
+			if !ok || !strings.Contains(strings.ReplaceAll(params, " ", ""), `rel="next"`) {
+				continue
+			}
+			u, err := r.url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+			if err != nil {
+				t.Fatalf("GET %s: Link %q: %v", r.url, link, err)
+			}
+			return u.String()
+		}
+	}
+	return ""
+}
+
+type entryList struct {
+	Blueprints []registry.Blueprint `json:"blueprints"`
+	Total      *int                 `json:"total"`
+	Offset     *int                 `json:"offset"`
+	Limit      *int                 `json:"limit"`
+}
+
+type searchResult struct {
+	Entry registry.Blueprint `json:"entry"`
+	Score float64            `json:"score"`
+}
+
+type searchList struct {
+	Results []searchResult `json:"results"`
+	Total   *int           `json:"total"`
+	Offset  *int           `json:"offset"`
+	Limit   *int           `json:"limit"`
+}
+
+func names(bps []registry.Blueprint) []string {
+	out := make([]string, len(bps))
+	for i, bp := range bps {
+		out[i] = bp.FullName()
+	}
+	return out
+}
+
+func resultNames(res []searchResult) []string {
+	out := make([]string, len(res))
+	for i, r := range res {
+		out[i] = r.Entry.FullName()
+	}
+	return out
+}
+
+func sorted(s []string) []string {
+	s = slices.Clone(s)
+	slices.Sort(s)
+	return s
+}
+
+func (c *client) testList(t *testing.T) {
+	var l entryList
+	c.getJSON(t, "/v1/blueprints", &l)
+	if l.Blueprints == nil || l.Total == nil || l.Offset == nil || l.Limit == nil {
+		t.Fatalf("list has no blueprints, total, offset or limit: %+v", l)
+	}
+	if got, want := sorted(names(l.Blueprints)), publicNames(); !slices.Equal(got, want) {
+		t.Errorf("listed %v, want %v", got, want)
+	}
+	if *l.Total != len(publicNames()) || *l.Offset != 0 || *l.Limit != 100 {
+		t.Errorf("total %d, offset %d, limit %d; want %d, 0, 100", *l.Total, *l.Offset, *l.Limit, len(publicNames()))
+	}
+	want := map[string]registry.Blueprint{}
+	for _, bp := range Fixture().Blueprints {
+		want[bp.FullName()] = bp
+	}
+	for _, bp := range l.Blueprints {
+		w := want[bp.FullName()]
+		if bp.Version != w.Version || bp.DownloadURL != w.DownloadURL || bp.SHA256 != w.SHA256 || bp.Description != w.Description || !slices.Equal(bp.Tags, w.Tags) {
+			t.Errorf("%s listed as %+v, want %+v", bp.FullName(), bp, w)
+		}
+	}
+
+	for _, tc := range []struct{ query, want string }{
+		{"namespace=acme", "acme/billing acme/web-worker"},
+		{"tag=go", "core/cli-tool core/kubernetes-operator core/web"},
+		{"namespace=core&tag=api", "core/grpc-gateway core/web"},
+		{"namespace=nobody", ""},
+	} {
+		var l entryList
+		c.getJSON(t, "/v1/blueprints?"+tc.query, &l)
+		if got := strings.Join(sorted(names(l.Blueprints)), " "); got != tc.want {
+			t.Errorf("?%s: listed %q, want %q", tc.query, got, tc.want)
+		}
+		if l.Blueprints == nil {
+			t.Errorf("?%s: blueprints is null, want a list", tc.query)
+		}
+	}
+}
+
+func (c *client) testPagination(t *testing.T) {
+	for _, path := range []string{"/v1/blueprints", "/v1/search"} {
+		var all []string
+		next := fmt.Sprintf("%s?limit=%d", path, pageSize)
+		for pages := 0; next != ""; pages++ {
+			if pages > len(publicNames()) {
+				t.Fatalf("%s: the Link headers never end", path)
+			}
+			var got []string
+			var total, offset, limit *int
+			var r response
+			if path == "/v1/search" {
+				var l searchList
+				r = c.getJSON(t, next, &l)
+				got, total, offset, limit = resultNames(l.Results), l.Total, l.Offset, l.Limit
+			} else {
+				var l entryList
+				r = c.getJSON(t, next, &l)
+				got, total, offset, limit = names(l.Blueprints), l.Total, l.Offset, l.Limit
+			}
+			if total == nil || *total != len(publicNames()) || offset == nil || *offset != len(all) || limit == nil || *limit != pageSize {
+				t.Errorf("%s: page %d has total %v, offset %v, limit %v", next, pages, deref(total), deref(offset), deref(limit))
+			}
+			all = append(all, got...)
+			next = r.next(t)
+			if last := len(all) == len(publicNames()); last != (next == "") {
+				t.Errorf("%s: %d of %d entries seen, next page %q", path, len(all), len(publicNames()), next)
+			}
+			if len(got) > pageSize || (next != "" && len(got) != pageSize) {
+				t.Errorf("%s: a page of %d with limit %d", path, len(got), pageSize)
+			}
+		}
+		if got := sorted(all); !slices.Equal(got, publicNames()) {
+			t.Errorf("%s: paged through %v, want every entry once: %v", path, all, publicNames())
+		}
+
+		// a page past the end is empty, and the last
+		var l map[string]json.RawMessage
+		r := c.getJSON(t, fmt.Sprintf("%s?limit=%d&offset=%d", path, pageSize, 1000), &l)
+		list := l["blueprints"]
+		if path == "/v1/search" {
+			list = l["results"]
+		}
+		if string(bytes.TrimSpace(list)) != "[]" {
+			t.Errorf("%s: page past the end is %s, want []", path, list)
+		}
+		if n := r.next(t); n != "" {
+			t.Errorf("%s: page past the end links to %s", path, n)
+		}
+	}
+}
+
+func deref(p *int) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func (c *client) search(t *testing.T, query string) []searchResult {
+	t.Helper()
+	var l searchList
+	c.getJSON(t, "/v1/search?"+query, &l)
+	if l.Results == nil || l.Total == nil {
+		t.Fatalf("?%s: no results or total: %+v", query, l)
+	}
+	if *l.Total != len(l.Results) {
+		t.Errorf("?%s: total %d for %d results on one page", query, *l.Total, len(l.Results))
+	}
+	return l.Results
+}
+
+func (c *client) testSearch(t *testing.T) {
+	q := url.QueryEscape
+	for _, tc := range []struct {
+		name, query string
+		want        string // the full names matched, in any order
+	}{
+		{"everything without a query", "", strings.Join(publicNames(), " ")},
+		{"every word must match", "q=" + q("web postgres"), "core/web"},
+		{"prefix", "q=kube", "core/kubernetes-operator"},
+		{"substring", "q=gateway", "core/grpc-gateway"},
+		{"typo", "q=kuberentes", "core/kubernetes-operator"},
+		{"case", "q=COBRA", "core/cli-tool"},
+		{"nothing", "q=zzyzx", ""},
+		{"tags all match", "tag=api&tag=go", "core/web"},
+		{"feature", "feature=db", "acme/web-worker core/web"},
+		{"feature value", "feature=" + q("db=postgres"), "core/web"},
+		{"namespace", "q=web&namespace=acme", "acme/web-worker"},
+		{"internal hidden", "q=" + q("secret sauce"), ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res := c.search(t, tc.query)
+			if got := strings.Join(sorted(resultNames(res)), " "); got != tc.want {
+				t.Errorf("?%s: found %q, want %q", tc.query, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("ranking", func(t *testing.T) {
+		// a match in the name outranks one in the description
+		res := c.search(t, "q=grpc")
+		if got := resultNames(res); !slices.Equal(got, []string{"core/grpc-gateway", "acme/billing"}) {
+			t.Errorf("?q=grpc: %v, want core/grpc-gateway, then acme/billing", got)
+		}
+		// best first
+		res = c.search(t, "q=web")
+		for i := 1; i < len(res); i++ {
+			if res[i].Score > res[i-1].Score {
+				t.Errorf("?q=web: %s scores %v, more than %s before it", res[i].Entry.FullName(), res[i].Score, res[i-1].Entry.FullName())
+			}
+		}
+	})
+}
+
+func (c *client) testEntry(t *testing.T) {
+	db := Fixture()
+	web, _ := db.Lookup("core", "web")
+	// a bare name resolves when only one namespace has it
+	for _, path := range []string{"/v1/blueprints/core/web", "/v1/blueprints/web"} {
+		var bp registry.Blueprint
+		c.getJSON(t, path, &bp)
+		if bp.FullName() != web.FullName() || bp.Version != web.Version || bp.SHA256 != web.SHA256 || len(bp.Previous) != len(web.Previous) {
+			t.Errorf("%s: got %+v, want %+v", path, bp, web)
+		}
+	}
+
+	var rel struct {
+		Name string `json:"name"`
+		registry.Release
+	}
+	c.getJSON(t, "/v1/blueprints/core/web/1.0.0", &rel)
+	if rel.Name != "core/web" || rel.Version != "1.0.0" || rel.SHA256 != strings.Repeat("ab", 32) || rel.DownloadURL != "https://example.com/core/web-1.0.0.zip" {
+		t.Errorf("release 1.0.0: %+v", rel)
+	}
+	c.getJSON(t, "/v1/blueprints/core/web/2.0.0", &rel)
+	if rel.Version != "2.0.0" || rel.DownloadURL != "https://example.com/core/web-2.0.0.zip" {
+		t.Errorf("release 2.0.0, the current one: %+v", rel)
+	}
+
+	for _, path := range []string{
+		"/v1/blueprints/core/nonexistent",
+		"/v1/blueprints/core/web/9.9.9",
+		"/v1/blueprints/" + hidden,
+	} {
+		c.get(t, path, nil).wantError(t, http.StatusNotFound)
+	}
+}
+
+func (c *client) testETag(t *testing.T) {
+	for _, path := range []string{
+		"/v1/blueprints",
+		"/v1/blueprints?limit=2",
+		"/v1/search?q=web",
+		"/v1/blueprints/core/web",
+		"/registry.json",
+	} {
+		r := c.get(t, path, nil)
+		etag := r.header.Get("ETag")
+		if r.code != http.StatusOK || etag == "" {
+			t.Errorf("GET %s: status %d, ETag %q; want 200 with an ETag", path, r.code, etag)
+			continue
+		}
+		if again := c.get(t, path, nil); again.header.Get("ETag") != etag || !bytes.Equal(again.body, r.body) {
+			t.Errorf("GET %s: ETag %q, then %q for the same registry", path, etag, again.header.Get("ETag"))
+		}
+		// If-None-Match compares weakly (RFC 9110 §13.1.2)
+		weak := "W/" + strings.TrimPrefix(etag, "W/")
+		for _, inm := range []string{etag, weak, `"stale", ` + etag, "*"} {
+			nm := c.get(t, path, http.Header{"If-None-Match": {inm}})
+			if nm.code != http.StatusNotModified || len(nm.body) != 0 {
+				t.Errorf("GET %s with If-None-Match %s: status %d and %d bytes, want 304 and none", path, inm, nm.code, len(nm.body))
+			}
+			if nm.header.Get("ETag") != etag {
+				t.Errorf("GET %s: 304 with ETag %q, want %q", path, nm.header.Get("ETag"), etag)
+			}
+		}
+		if other := c.get(t, path, http.Header{"If-None-Match": {`"stale"`}}); other.code != http.StatusOK {
+			t.Errorf("GET %s with a stale If-None-Match: status %d, want 200", path, other.code)
+		}
+	}
+	// different pages are different representations
+	a, b := c.get(t, "/v1/blueprints?limit=2", nil), c.get(t, "/v1/blueprints?limit=2&offset=2", nil)
+	if a.header.Get("ETag") == b.header.Get("ETag") {
+		t.Errorf("two pages share the ETag %s", a.header.Get("ETag"))
+	}
+}
+
+func (c *client) testErrors(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		code int
+	}{
+		{"/v1/blueprints?limit=0", http.StatusBadRequest},
+		{"/v1/blueprints?limit=1001", http.StatusBadRequest},
+		{"/v1/blueprints?limit=ten", http.StatusBadRequest},
+		{"/v1/blueprints?offset=-1", http.StatusBadRequest},
+		{"/v1/blueprints?sort=name", http.StatusBadRequest},
+		{"/v1/search?q=web&limit=0", http.StatusBadRequest},
+		{"/v1/search?q=web&sort=name", http.StatusBadRequest},
+		{"/v1/search?stale=maybe", http.StatusBadRequest},
+		{"/v1/search?collection=nonexistent", http.StatusNotFound},
+		{"/v1/blueprints/core/nonexistent", http.StatusNotFound},
+		{"/v1/nonexistent", http.StatusNotFound},
+	} {
+		c.get(t, tc.path, nil).wantError(t, tc.code)
+	}
+
+	// a well-formed request ID is kept, and a bad one replaced
+	r := c.get(t, "/v1/nonexistent", http.Header{"X-Request-Id": {"conformance-1"}})
+	r.wantError(t, http.StatusNotFound)
+	if id := r.header.Get("X-Request-Id"); id != "conformance-1" {
+		t.Errorf("X-Request-Id conformance-1 answered with %q", id)
+	}
+	r = c.get(t, "/v1/nonexistent", http.Header{"X-Request-Id": {"bad id\twith spaces"}})
+	r.wantError(t, http.StatusNotFound)
+	if id := r.header.Get("X-Request-Id"); strings.ContainsAny(id, " \t") {
+		t.Errorf("a malformed X-Request-Id was echoed: %q", id)
+	}
+	// errors are not cached as if they were the resource
+	if cc := r.header.Get("Cache-Control"); strings.Contains(cc, "public") {
+		t.Errorf("404 is cacheable: Cache-Control %q", cc)
+	}
+}
+
+func (c *client) testRegistryFile(t *testing.T) {
+	r := c.get(t, "/registry.json", nil)
+	if r.code != http.StatusOK {
+		t.Fatalf("GET /registry.json: status %d", r.code)
+	}
+	db, err := registry.Decode(r.body, registry.FormatJSON)
+	if err != nil {
+		t.Fatalf("GET /registry.json: %v", err)
+	}
+	if got := sorted(names(db.Blueprints)); !slices.Equal(got, publicNames()) {
+		t.Errorf("registry.json has %v, want %v", got, publicNames())
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"fmt"
+	"time"
+)
+
+// Deprecation marks an entry users should move off: set by its author in
+// the manifest, or by the registry's maintainers.
+type Deprecation struct {
+	Reason string `json:"reason" yaml:"reason" toml:"reason"`
+	// Replacement is the entry to use instead, as namespace/name.
+	Replacement string `json:"replacement,omitempty" yaml:"replacement" toml:"replacement"`
+	// Sunset is when the entry is due to be removed; zero if it isn't.
+	Sunset time.Time `json:"sunset,omitzero" yaml:"sunset" toml:"sunset"`
+}
+
+// Warning codes.
+const (
+	WarningDeprecated = "deprecated"
+	// WarningSunset is a deprecated entry past its sunset date.
+	WarningSunset = "sunset"
+	// WarningStale is an entry whose source repo stopped releasing.
+	WarningStale = "stale"
+)
+
+// Warning is a machine-readable notice about an entry a client resolved,
+// for tools to show along with what to do about it.
+type Warning struct {
+	Code        string    `json:"code"`
+	Entry       string    `json:"entry"`
+	Message     string    `json:"message"`
+	Reason      string    `json:"reason,omitempty"`
+	Replacement string    `json:"replacement,omitempty"`
+	Sunset      time.Time `json:"sunset,omitzero"`
+}
+
+func (w Warning) String() string { return w.Message }
+
+// Warnings returns the notices a client resolving bp at now should pass
+// on to its user.
+func (b Blueprint) Warnings(now time.Time) []Warning {
+	var out []Warning
+	if d := b.Deprecation; d != nil {
+		out = append(out, b.deprecationWarning(d, now))
+	}
+	if st := b.Stale; st != nil {
+		w := Warning{Code: WarningStale, Entry: b.FullName()}
+		if st.LastRelease.IsZero() {
+			w.Message = fmt.Sprintf("%s may be unmaintained: its source has no releases", w.Entry)
+		} else {
+			w.Message = fmt.Sprintf("%s may be unmaintained: its source hasn't released since %s", w.Entry, st.LastRelease.Format(time.DateOnly))
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+func (b Blueprint) deprecationWarning(d *Deprecation, now time.Time) Warning {
+	w := Warning{
+		Code:        WarningDeprecated,
+		Entry:       b.FullName(),
+		Reason:      d.Reason,
+		Replacement: d.Replacement,
+		Sunset:      d.Sunset,
+	}
+	w.Message = fmt.Sprintf("%s is deprecated: %s", w.Entry, d.Reason)
+	if d.Replacement != "" {
+		w.Message += "; use " + d.Replacement + " instead"
+	}
+	switch {
+	case d.Sunset.IsZero():
+	case now.Before(d.Sunset):
+		w.Message += "; it will be removed on " + d.Sunset.Format(time.DateOnly)
+	default:
+		w.Code = WarningSunset
+		w.Message += "; it was due to be removed on " + d.Sunset.Format(time.DateOnly)
+	}
+	return w
+}
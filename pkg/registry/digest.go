@@ -0,0 +1,156 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Digest algorithms the package computes and verifies.
+const (
+	DigestSHA256 = "sha256"
+	DigestSHA512 = "sha512"
+	DigestBLAKE3 = "blake3"
+)
+
+var (
+	digestMu         sync.RWMutex
+	digestAlgorithms = map[string]func() hash.Hash{
+		DigestSHA256: sha256.New,
+		DigestSHA512: sha512.New,
+		DigestBLAKE3: newBLAKE3,
+	}
+)
+
+// RegisterDigest adds a digest algorithm, or replaces the implementation
+// of one, for Digester and Verify to use. name is lowercase, as in the
+// registry's digests.
+func RegisterDigest(name string, newHash func() hash.Hash) {
+	digestMu.Lock()
+	defer digestMu.Unlock()
+	digestAlgorithms[strings.ToLower(name)] = newHash
+}
+
+// DigestAlgorithms lists the algorithms that can be computed, sorted.
+func DigestAlgorithms() []string {
+	digestMu.RLock()
+	defer digestMu.RUnlock()
+	return slices.Sorted(maps.Keys(digestAlgorithms))
+}
+
+// SupportedDigest reports whether the algorithm can be computed.
+func SupportedDigest(name string) bool {
+	digestMu.RLock()
+	defer digestMu.RUnlock()
+	return digestAlgorithms[name] != nil
+}
+
+// Digests are an archive's hex-encoded digests by algorithm. An entry's
+// SHA-256 stays in its sha256 field, which every reader checks; its
+// Digests hold the others.
+type Digests map[string]string
+
+// Digester computes several digests of what is written to it at once.
+type Digester struct {
+	hashes map[string]hash.Hash
+}
+
+// NewDigester returns a Digester for the given algorithms. It fails for
+// one that isn't supported.
+func NewDigester(algs ...string) (*Digester, error) {
+	digestMu.RLock()
+	defer digestMu.RUnlock()
+	d := &Digester{hashes: map[string]hash.Hash{}}
+	for _, alg := range algs {
+		newHash := digestAlgorithms[alg]
+		if newHash == nil {
+			return nil, fmt.Errorf("unsupported digest algorithm %q", alg)
+		}
+		d.hashes[alg] = newHash()
+	}
+	return d, nil
+}
+
+func (d *Digester) Write(p []byte) (int, error) {
+	for _, h := range d.hashes {
+		h.Write(p)
+	}
+	return len(p), nil
+}
+
+// Sum returns the digests of what was written.
+func (d *Digester) Sum() Digests {
+	out := Digests{}
+	for alg, h := range d.hashes {
+		out[alg] = hex.EncodeToString(h.Sum(nil))
+	}
+	return out
+}
+
+// Verify checks the digests computed of an archive, got, against the ones
+// recorded for it: every algorithm both have must match, and each of
+// required must have been recorded.
+func (want Digests) Verify(got Digests, required []string) error {
+	for _, alg := range required {
+		if want[alg] == "" {
+			return fmt.Errorf("no %s digest recorded", alg)
+		}
+	}
+	for _, alg := range slices.Sorted(maps.Keys(want)) {
+		if g, ok := got[alg]; ok && !strings.EqualFold(g, want[alg]) {
+			return fmt.Errorf("%s is %s, want %s", alg, g, want[alg])
+		}
+	}
+	return nil
+}
+
+// Computable returns the algorithms of d that can be computed, sorted.
+func (d Digests) Computable() []string {
+	var algs []string
+	for alg, v := range d {
+		if v != "" && SupportedDigest(alg) {
+			algs = append(algs, alg)
+		}
+	}
+	slices.Sort(algs)
+	return algs
+}
+
+// AllDigests returns every digest recorded for the entry's current
+// release, its SHA-256 included.
+func (b Blueprint) AllDigests() Digests {
+	return withSHA256(b.Digests, b.SHA256)
+}
+
+// AllDigests returns every digest recorded for the release, its SHA-256
+// included.
+func (r Release) AllDigests() Digests {
+	return withSHA256(r.Digests, r.SHA256)
+}
+
+func withSHA256(d Digests, sum string) Digests {
+	out := maps.Clone(d)
+	if out == nil {
+		out = Digests{}
+	}
+	if sum != "" {
+		out[DigestSHA256] = sum
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Load reads the registry file at p, in the encoding its extension names.
+// A missing file is an empty registry.
+func Load(p string) (Database, error) {
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return Database{SchemaVersion: CurrentSchema, Blueprints: []Blueprint{}}, nil
+	}
+	if err != nil {
+		return Database{}, err
+	}
+	return Decode(b, FormatOf(p))
+}
+
+// Decode parses a registry in the given format, refusing ones written for
+// a newer ClientVersion, and migrates it to CurrentSchema.
+func Decode(b []byte, format string) (Database, error) {
+	db, err := Unmarshal(b, format)
+	if err != nil {
+		return db, err
+	}
+	if err := CheckClientVersion(db.Metadata, ClientVersion); err != nil {
+		return db, err
+	}
+	if err := Migrate(&db); err != nil {
+		return db, err
+	}
+	// ensure non-nil slice to avoid "null"
+	if db.Blueprints == nil {
+		db.Blueprints = []Blueprint{}
+	}
+	return db, nil
+}
+
+// Unmarshal only decodes a registry in the given format, as it is.
+func Unmarshal(b []byte, format string) (Database, error) {
+	switch format {
+	case FormatProto:
+		return decodeProto(b)
+	case FormatCBOR:
+		return decodeCBOR(b)
+	case FormatYAML:
+		return decodeYAML(b)
+	}
+	var db Database
+	err := json.Unmarshal(b, &db)
+	return db, err
+}
+
+// Encode encodes db in the given format. JSON is indented unless
+// canonical asks for RFC 8785 canonical JSON.
+func Encode(db Database, format string, canonical bool) ([]byte, error) {
+	switch format {
+	case FormatProto:
+		return encodeProto(db), nil
+	case FormatCBOR:
+		return encodeCBOR(db)
+	case FormatYAML:
+		return encodeYAML(db)
+	}
+	if canonical {
+		return CanonicalJSON(db)
+	}
+	// canonical JSON is exactly the signed bytes; indented JSON is a text
+	// file and ends like one
+	b, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// SaveOptions controls how Save writes a registry.
+type SaveOptions struct {
+	// Canonical writes RFC 8785 canonical JSON instead of indented JSON.
+	Canonical bool
+	// Formats lists extra encodings written next to the file, e.g.
+	// registry.pb beside registry.json.
+	Formats []string
+}
+
+// Save writes db to p, plus a copy next to it in each of opts.Formats.
+// Each file is replaced atomically.
+func Save(p string, db Database, opts SaveOptions) error {
+	db = Normalize(db)
+	b, err := Encode(db, FormatOf(p), opts.Canonical)
+	if err != nil {
+		return err
+	}
+	if FormatOf(p) == FormatJSON && !bytes.HasSuffix(b, []byte("\n")) {
+		// a text file ends with a newline, canonical JSON included,
+		// as it does on stdout
+		b = append(b, '\n')
+	}
+	if err := WriteFileAtomic(p, b); err != nil {
+		return err
+	}
+	for _, f := range opts.Formats {
+		b, err := Encode(db, f, opts.Canonical)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+		if err := WriteFileAtomic(SiblingPath(p, f), b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFileAtomic replaces p with b so readers see the old or the new
+// contents, never a partial file. The data is flushed to disk before the
+// rename and the rename itself after it, so a crash or power loss can't
+// leave p truncated either. An existing p keeps its mode; a new one is
+// created 0644.
+func WriteFileAtomic(p string, b []byte) error {
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(p); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := ReplaceFile(f.Name(), p); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return syncDir(filepath.Dir(p))
+}
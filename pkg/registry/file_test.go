@@ -0,0 +1,92 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSaveTrailingNewline(t *testing.T) {
+	db := Database{SchemaVersion: CurrentSchema, Blueprints: []Blueprint{{Namespace: DefaultNamespace, Name: "a", Version: "1.0.0"}}}
+	cases := []struct {
+		name string
+		file string
+		opts SaveOptions
+		nl   bool
+	}{
+		{"indented", "registry.json", SaveOptions{}, true},
+		{"canonical", "registry.json", SaveOptions{Canonical: true}, true},
+		{"cbor", "registry.cbor", SaveOptions{}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), c.file)
+			if err := Save(p, db, c.opts); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if nl := bytes.HasSuffix(b, []byte("\n")); nl != c.nl {
+				t.Errorf("ends with a newline: %t, want %t", nl, c.nl)
+			}
+			if _, err := Load(p); err != nil {
+				t.Errorf("load what was saved: %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomicMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no permission bits to keep")
+	}
+	dir := t.TempDir()
+	cases := []struct {
+		name     string
+		existing os.FileMode // 0: no file yet
+		want     os.FileMode
+	}{
+		{"new", 0, 0o644},
+		{"private", 0o600, 0o600},
+		{"group writable", 0o664, 0o664},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := filepath.Join(dir, c.name)
+			if c.existing != 0 {
+				if err := os.WriteFile(p, []byte("old"), c.existing); err != nil {
+					t.Fatal(err)
+				}
+				// WriteFile leaves the mode of an existing file and
+				// applies the umask to a new one
+				if err := os.Chmod(p, c.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := WriteFileAtomic(p, []byte("new")); err != nil {
+				t.Fatal(err)
+			}
+			fi, err := os.Stat(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fi.Mode().Perm(); got != c.want {
+				t.Errorf("mode %v, want %v", got, c.want)
+			}
+		})
+	}
+}
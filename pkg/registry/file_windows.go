@@ -0,0 +1,43 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package registry
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ReplaceFile moves src to dst, replacing dst if it exists. Virus scanners
+// and the search indexer briefly hold files open, which makes the rename
+// fail with a sharing violation, so retry for a short while.
+func ReplaceFile(src, dst string) error {
+	const (
+		errorAccessDenied     = syscall.Errno(5)
+		errorSharingViolation = syscall.Errno(32)
+	)
+	var err error
+	for i := 0; i < 10; i++ {
+		err = os.Rename(src, dst)
+		if err == nil || !(errors.Is(err, errorAccessDenied) || errors.Is(err, errorSharingViolation)) {
+			return err
+		}
+		time.Sleep(time.Duration(i+1) * 20 * time.Millisecond)
+	}
+	return err
+}
+
+// syncDir is a no-op: Windows can't open directories for syncing, and
+// NTFS journals the rename.
+func syncDir(string) error { return nil }
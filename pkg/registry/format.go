@@ -0,0 +1,938 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Registry encodings besides JSON, for clients that care about size.
+// The protobuf schema is proto/registry.proto.
+const (
+	FormatJSON  = "json"
+	FormatProto = "proto"
+	FormatCBOR  = "cbor"
+	FormatYAML  = "yaml"
+)
+
+// formatExt maps an encoding to the file extension it is written with.
+var formatExt = map[string]string{
+	FormatJSON:  ".json",
+	FormatProto: ".pb",
+	FormatCBOR:  ".cbor",
+	FormatYAML:  ".yaml",
+}
+
+// FormatOf picks the encoding of a registry file from its extension.
+func FormatOf(p string) string {
+	ext := filepath.Ext(p)
+	if ext == ".yml" {
+		return FormatYAML
+	}
+	for f, e := range formatExt {
+		if e == ext {
+			return f
+		}
+	}
+	return FormatJSON
+}
+
+// SiblingPath returns the path next to p for the given encoding, e.g.
+// registry.json -> registry.pb.
+func SiblingPath(p, format string) string {
+	return strings.TrimSuffix(p, filepath.Ext(p)) + formatExt[format]
+}
+
+var errMalformed = errors.New("malformed binary registry")
+
+// ---- protobuf ----
+
+// Wire types used by the schema.
+const (
+	pbVarint = 0
+	pbI64    = 1
+	pbLen    = 2
+	pbI32    = 5
+)
+
+type pbWriter struct{ b []byte }
+
+func (w *pbWriter) tag(field, wire int) {
+	w.b = binary.AppendUvarint(w.b, uint64(field)<<3|uint64(wire))
+}
+
+func (w *pbWriter) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	w.tag(field, pbVarint)
+	w.b = binary.AppendUvarint(w.b, v)
+}
+
+func (w *pbWriter) bytes(field int, b []byte) {
+	w.tag(field, pbLen)
+	w.b = binary.AppendUvarint(w.b, uint64(len(b)))
+	w.b = append(w.b, b...)
+}
+
+func (w *pbWriter) string(field int, s string) {
+	if s != "" {
+		w.bytes(field, []byte(s))
+	}
+}
+
+func (w *pbWriter) strings(field int, ss []string) {
+	for _, s := range ss {
+		w.bytes(field, []byte(s))
+	}
+}
+
+// timestamp writes a google.protobuf.Timestamp.
+func (w *pbWriter) timestamp(field int, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	var ts pbWriter
+	ts.varint(1, uint64(t.Unix()))
+	ts.varint(2, uint64(t.Nanosecond()))
+	w.bytes(field, ts.b)
+}
+
+// features writes a map<string, FeatureValue> in key order.
+func (w *pbWriter) features(field int, fs map[string]any) {
+	keys := make([]string, 0, len(fs))
+	for k := range fs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		var val pbWriter
+		switch v := fs[k].(type) {
+		case bool:
+			// a oneof member is written even when false
+			val.tag(1, pbVarint)
+			val.b = binary.AppendUvarint(val.b, map[bool]uint64{false: 0, true: 1}[v])
+		default:
+			val.bytes(2, []byte(fmt.Sprint(v)))
+		}
+		var entry pbWriter
+		entry.string(1, k)
+		entry.bytes(2, val.b)
+		w.bytes(field, entry.b)
+	}
+}
+
+// stringMap writes a map<string, string> in key order.
+func (w *pbWriter) stringMap(field int, m map[string]string) {
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		var entry pbWriter
+		entry.string(1, k)
+		entry.string(2, m[k])
+		w.bytes(field, entry.b)
+	}
+}
+
+// assetRef writes an AssetRef, if there is one.
+func (w *pbWriter) assetRef(field int, a *AssetRef) {
+	if a == nil {
+		return
+	}
+	var am pbWriter
+	am.varint(1, uint64(a.ID))
+	am.varint(2, uint64(a.Size))
+	w.bytes(field, am.b)
+}
+
+// encodeProto encodes db as a dragon.registry.v1.Registry message.
+func encodeProto(db Database) []byte {
+	var w pbWriter
+	w.varint(1, uint64(db.SchemaVersion))
+	if db.Metadata.MinClientVersion != "" || len(db.Metadata.Tombstones) > 0 || len(db.Metadata.RequiredDigests) > 0 {
+		var mm pbWriter
+		mm.string(1, db.Metadata.MinClientVersion)
+		for _, t := range db.Metadata.Tombstones {
+			var tm pbWriter
+			tm.string(1, t.Name)
+			tm.string(2, t.Version)
+			tm.string(3, t.Reason)
+			tm.timestamp(4, t.RemovedAt)
+			tm.string(5, t.SHA256)
+			tm.string(6, t.Visibility)
+			mm.bytes(2, tm.b)
+		}
+		mm.strings(3, db.Metadata.RequiredDigests)
+		w.bytes(3, mm.b)
+	}
+	for _, bp := range db.Blueprints {
+		var m pbWriter
+		m.string(1, bp.Namespace)
+		m.string(2, bp.Name)
+		m.string(3, bp.Version)
+		m.string(4, bp.Repo)
+		m.string(5, bp.Path)
+		m.string(6, bp.DownloadURL)
+		m.strings(7, bp.Mirrors)
+		m.string(8, bp.Description)
+		m.strings(9, bp.Tags)
+		m.string(10, bp.Visibility)
+		m.string(11, bp.License)
+		m.string(12, bp.LicenseSource)
+		m.timestamp(13, bp.CreatedAt)
+		m.timestamp(14, bp.UpdatedAt)
+		m.features(15, bp.Features)
+		m.string(16, bp.Trust)
+		m.string(18, bp.SHA256)
+		m.string(20, bp.SourceURL)
+		m.strings(21, bp.Sources)
+		m.string(22, bp.Title)
+		if bp.Signed {
+			m.varint(23, 1)
+		}
+		m.strings(24, bp.Maintainers)
+		m.string(25, bp.Category)
+		if p := bp.Provenance; p != nil {
+			var pm pbWriter
+			pm.string(1, p.Builder)
+			pm.string(2, p.SourceRepo)
+			pm.string(3, p.Commit)
+			m.bytes(26, pm.b)
+		}
+		m.string(27, bp.Icon)
+		if q := bp.Quality; q != nil {
+			var qm pbWriter
+			qm.varint(1, uint64(q.Score))
+			qm.strings(2, q.Missing)
+			m.bytes(28, qm.b)
+		}
+		if d := bp.Deprecation; d != nil {
+			var dm pbWriter
+			dm.string(1, d.Reason)
+			dm.string(2, d.Replacement)
+			dm.timestamp(3, d.Sunset)
+			m.bytes(29, dm.b)
+		}
+		for _, c := range bp.Compatibility {
+			var cm pbWriter
+			cm.string(1, c.CLI)
+			cm.string(2, c.Version)
+			if c.Passed {
+				cm.varint(3, 1)
+			}
+			cm.timestamp(4, c.TestedAt)
+			m.bytes(30, cm.b)
+		}
+		if st := bp.Stale; st != nil {
+			var sm pbWriter
+			sm.timestamp(1, st.Since)
+			sm.timestamp(2, st.LastRelease)
+			m.bytes(31, sm.b)
+		}
+		m.stringMap(32, bp.Digests)
+		m.strings(33, bp.Screenshots)
+		m.assetRef(34, bp.Asset)
+		for _, r := range bp.Previous {
+			var rm pbWriter
+			rm.string(1, r.Version)
+			rm.string(2, r.DownloadURL)
+			rm.string(3, r.SHA256)
+			rm.timestamp(4, r.ReleasedAt)
+			rm.stringMap(5, r.Digests)
+			rm.assetRef(6, r.Asset)
+			m.bytes(19, rm.b)
+		}
+		for _, d := range bp.Dependencies {
+			var dm pbWriter
+			dm.string(1, d.Name)
+			dm.string(2, d.Version)
+			m.bytes(17, dm.b)
+		}
+		w.bytes(2, m.b)
+	}
+	return w.b
+}
+
+// pbFields calls fn for each field of a message. Varint and fixed-width
+// values are passed in v, length-delimited ones in b.
+func pbFields(msg []byte, fn func(field, wire int, v uint64, b []byte) error) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errMalformed
+		}
+		msg = msg[n:]
+		field, wire := int(key>>3), int(key&7)
+		var v uint64
+		var b []byte
+		switch wire {
+		case pbVarint:
+			v, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return errMalformed
+			}
+			msg = msg[n:]
+		case pbI64:
+			if len(msg) < 8 {
+				return errMalformed
+			}
+			v, msg = binary.LittleEndian.Uint64(msg), msg[8:]
+		case pbI32:
+			if len(msg) < 4 {
+				return errMalformed
+			}
+			v, msg = uint64(binary.LittleEndian.Uint32(msg)), msg[4:]
+		case pbLen:
+			l, n := binary.Uvarint(msg)
+			if n <= 0 || l > uint64(len(msg)-n) {
+				return errMalformed
+			}
+			b, msg = msg[n:n+int(l)], msg[n+int(l):]
+		default:
+			return fmt.Errorf("%w: wire type %d", errMalformed, wire)
+		}
+		if err := fn(field, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pbTimestamp(b []byte) (time.Time, error) {
+	var sec, nsec uint64
+	err := pbFields(b, func(field, wire int, v uint64, _ []byte) error {
+		switch {
+		case field == 1 && wire == pbVarint:
+			sec = v
+		case field == 2 && wire == pbVarint:
+			nsec = v
+		}
+		return nil
+	})
+	if err != nil || nsec >= 1e9 {
+		return time.Time{}, errMalformed
+	}
+	return time.Unix(int64(sec), int64(nsec)).UTC(), nil
+}
+
+// decodeProto is the inverse of encodeProto. Unknown fields are skipped so
+// older readers accept newer files.
+func decodeProto(msg []byte) (Database, error) {
+	var db Database
+	err := pbFields(msg, func(field, wire int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == pbVarint:
+			db.SchemaVersion = int(int32(v))
+		case field == 2 && wire == pbLen:
+			bp, err := decodeProtoBlueprint(b)
+			if err != nil {
+				return err
+			}
+			db.Blueprints = append(db.Blueprints, bp)
+		case field == 3 && wire == pbLen:
+			return pbFields(b, func(field, wire int, v uint64, b []byte) error {
+				switch {
+				case field == 1 && wire == pbLen:
+					db.Metadata.MinClientVersion = string(b)
+				case field == 2 && wire == pbLen:
+					t, err := decodeProtoTombstone(b)
+					if err != nil {
+						return err
+					}
+					db.Metadata.Tombstones = append(db.Metadata.Tombstones, t)
+				case field == 3 && wire == pbLen:
+					db.Metadata.RequiredDigests = append(db.Metadata.RequiredDigests, string(b))
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return db, err
+}
+
+func decodeProtoTombstone(msg []byte) (Tombstone, error) {
+	var t Tombstone
+	err := pbFields(msg, func(field, wire int, _ uint64, b []byte) error {
+		if wire != pbLen {
+			return nil
+		}
+		switch field {
+		case 1:
+			t.Name = string(b)
+		case 2:
+			t.Version = string(b)
+		case 3:
+			t.Reason = string(b)
+		case 4:
+			ts, err := pbTimestamp(b)
+			if err != nil {
+				return err
+			}
+			t.RemovedAt = ts
+		case 5:
+			t.SHA256 = string(b)
+		case 6:
+			t.Visibility = string(b)
+		}
+		return nil
+	})
+	return t, err
+}
+
+func decodeProtoBlueprint(msg []byte) (Blueprint, error) {
+	var bp Blueprint
+	str := map[int]*string{
+		1: &bp.Namespace, 2: &bp.Name, 3: &bp.Version, 4: &bp.Repo,
+		5: &bp.Path, 6: &bp.DownloadURL, 8: &bp.Description,
+		10: &bp.Visibility, 11: &bp.License, 12: &bp.LicenseSource,
+		16: &bp.Trust, 18: &bp.SHA256, 20: &bp.SourceURL, 22: &bp.Title,
+		25: &bp.Category, 27: &bp.Icon,
+	}
+	list := map[int]*[]string{7: &bp.Mirrors, 9: &bp.Tags, 21: &bp.Sources, 24: &bp.Maintainers, 33: &bp.Screenshots}
+	ts := map[int]*time.Time{13: &bp.CreatedAt, 14: &bp.UpdatedAt}
+	err := pbFields(msg, func(field, wire int, v uint64, b []byte) error {
+		if field == 23 && wire == pbVarint {
+			bp.Signed = v != 0
+		}
+		if wire != pbLen {
+			return nil
+		}
+		if field == 19 {
+			var r Release
+			err := pbFields(b, func(field, wire int, _ uint64, b []byte) error {
+				if wire != pbLen {
+					return nil
+				}
+				switch field {
+				case 1:
+					r.Version = string(b)
+				case 2:
+					r.DownloadURL = string(b)
+				case 3:
+					r.SHA256 = string(b)
+				case 4:
+					t, err := pbTimestamp(b)
+					if err != nil {
+						return err
+					}
+					r.ReleasedAt = t
+				case 5:
+					k, v, err := pbStringEntry(b)
+					if err != nil {
+						return err
+					}
+					if r.Digests == nil {
+						r.Digests = Digests{}
+					}
+					r.Digests[k] = v
+				case 6:
+					a, err := pbAssetRef(b)
+					if err != nil {
+						return err
+					}
+					r.Asset = &a
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			bp.Previous = append(bp.Previous, r)
+		} else if field == 17 {
+			var d Dependency
+			err := pbFields(b, func(field, wire int, _ uint64, b []byte) error {
+				switch {
+				case field == 1 && wire == pbLen:
+					d.Name = string(b)
+				case field == 2 && wire == pbLen:
+					d.Version = string(b)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			bp.Dependencies = append(bp.Dependencies, d)
+		} else if field == 26 {
+			var p Provenance
+			err := pbFields(b, func(field, wire int, _ uint64, b []byte) error {
+				switch {
+				case field == 1 && wire == pbLen:
+					p.Builder = string(b)
+				case field == 2 && wire == pbLen:
+					p.SourceRepo = string(b)
+				case field == 3 && wire == pbLen:
+					p.Commit = string(b)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			bp.Provenance = &p
+		} else if field == 28 {
+			var q Quality
+			err := pbFields(b, func(field, wire int, v uint64, b []byte) error {
+				switch {
+				case field == 1 && wire == pbVarint:
+					q.Score = int(v)
+				case field == 2 && wire == pbLen:
+					q.Missing = append(q.Missing, string(b))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			bp.Quality = &q
+		} else if field == 29 {
+			var d Deprecation
+			err := pbFields(b, func(field, wire int, _ uint64, b []byte) error {
+				if wire != pbLen {
+					return nil
+				}
+				switch field {
+				case 1:
+					d.Reason = string(b)
+				case 2:
+					d.Replacement = string(b)
+				case 3:
+					t, err := pbTimestamp(b)
+					if err != nil {
+						return err
+					}
+					d.Sunset = t
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			bp.Deprecation = &d
+		} else if field == 30 {
+			var c Compatibility
+			err := pbFields(b, func(field, wire int, v uint64, b []byte) error {
+				switch {
+				case field == 1 && wire == pbLen:
+					c.CLI = string(b)
+				case field == 2 && wire == pbLen:
+					c.Version = string(b)
+				case field == 3 && wire == pbVarint:
+					c.Passed = v != 0
+				case field == 4 && wire == pbLen:
+					t, err := pbTimestamp(b)
+					if err != nil {
+						return err
+					}
+					c.TestedAt = t
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			bp.Compatibility = append(bp.Compatibility, c)
+		} else if field == 31 {
+			var st Staleness
+			err := pbFields(b, func(field, wire int, _ uint64, b []byte) error {
+				if wire != pbLen || (field != 1 && field != 2) {
+					return nil
+				}
+				t, err := pbTimestamp(b)
+				if err != nil {
+					return err
+				}
+				if field == 1 {
+					st.Since = t
+				} else {
+					st.LastRelease = t
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			bp.Stale = &st
+		} else if field == 32 {
+			k, v, err := pbStringEntry(b)
+			if err != nil {
+				return err
+			}
+			if bp.Digests == nil {
+				bp.Digests = Digests{}
+			}
+			bp.Digests[k] = v
+		} else if field == 34 {
+			a, err := pbAssetRef(b)
+			if err != nil {
+				return err
+			}
+			bp.Asset = &a
+		} else if field == 15 {
+			k, v, err := pbFeature(b)
+			if err != nil {
+				return err
+			}
+			if bp.Features == nil {
+				bp.Features = map[string]any{}
+			}
+			bp.Features[k] = v
+		} else if p, ok := str[field]; ok {
+			*p = string(b)
+		} else if p, ok := list[field]; ok {
+			*p = append(*p, string(b))
+		} else if p, ok := ts[field]; ok {
+			t, err := pbTimestamp(b)
+			if err != nil {
+				return err
+			}
+			*p = t
+		}
+		return nil
+	})
+	return bp, err
+}
+
+func pbAssetRef(msg []byte) (AssetRef, error) {
+	var a AssetRef
+	err := pbFields(msg, func(field, wire int, v uint64, _ []byte) error {
+		switch {
+		case field == 1 && wire == pbVarint:
+			a.ID = int64(v)
+		case field == 2 && wire == pbVarint:
+			a.Size = int64(v)
+		}
+		return nil
+	})
+	return a, err
+}
+
+// pbStringEntry decodes one entry of a map<string, string>.
+func pbStringEntry(entry []byte) (key, value string, err error) {
+	err = pbFields(entry, func(field, wire int, _ uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == pbLen:
+			key = string(b)
+		case field == 2 && wire == pbLen:
+			value = string(b)
+		}
+		return nil
+	})
+	return key, value, err
+}
+
+// pbFeature decodes one entry of the features map.
+func pbFeature(entry []byte) (key string, value any, err error) {
+	err = pbFields(entry, func(field, wire int, _ uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == pbLen:
+			key = string(b)
+		case field == 2 && wire == pbLen:
+			return pbFields(b, func(field, wire int, v uint64, b []byte) error {
+				switch {
+				case field == 1 && wire == pbVarint:
+					value = v != 0
+				case field == 2 && wire == pbLen:
+					value = string(b)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return key, value, err
+}
+
+// ---- CBOR ----
+
+// encodeCBOR encodes db as deterministic CBOR (RFC 8949 §4.2.1) with the
+// same structure and keys as registry.json, so it tracks the JSON schema
+// without a separate definition.
+func encodeCBOR(db Database) ([]byte, error) {
+	b, err := json.Marshal(db)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return appendCBOR(nil, doc)
+}
+
+func cborHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
+
+func appendCBOR(b []byte, v any) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return append(b, 0xf6), nil
+	case bool:
+		if x {
+			return append(b, 0xf5), nil
+		}
+		return append(b, 0xf4), nil
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			if i >= 0 {
+				return cborHead(b, 0, uint64(i)), nil
+			}
+			return cborHead(b, 1, uint64(-1-i)), nil
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(f)), nil
+	case string:
+		return append(cborHead(b, 3, uint64(len(x))), x...), nil
+	case []any:
+		b = cborHead(b, 4, uint64(len(x)))
+		for _, e := range x {
+			var err error
+			if b, err = appendCBOR(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		// deterministic order: bytewise on the encoded keys
+		type pair struct {
+			enc []byte
+			key string
+		}
+		keys := make([]pair, 0, len(x))
+		for k := range x {
+			keys = append(keys, pair{append(cborHead(nil, 3, uint64(len(k))), k...), k})
+		}
+		slices.SortFunc(keys, func(a, b pair) int { return bytes.Compare(a.enc, b.enc) })
+		b = cborHead(b, 5, uint64(len(x)))
+		for _, k := range keys {
+			b = append(b, k.enc...)
+			var err error
+			if b, err = appendCBOR(b, x[k.key]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("cbor: unexpected %T", v)
+}
+
+// maxCBORDepth bounds nesting when decoding untrusted input.
+const maxCBORDepth = 32
+
+type cborReader struct {
+	b     []byte
+	depth int
+}
+
+func (r *cborReader) head() (major byte, n uint64, err error) {
+	if len(r.b) == 0 {
+		return 0, 0, errMalformed
+	}
+	major, info := r.b[0]>>5, r.b[0]&0x1f
+	r.b = r.b[1:]
+	size := 0
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, fmt.Errorf("%w: indefinite or reserved length", errMalformed)
+	}
+	if len(r.b) < size {
+		return 0, 0, errMalformed
+	}
+	for _, c := range r.b[:size] {
+		n = n<<8 | uint64(c)
+	}
+	r.b = r.b[size:]
+	return major, n, nil
+}
+
+func (r *cborReader) value() (any, error) {
+	if r.depth++; r.depth > maxCBORDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", errMalformed)
+	}
+	defer func() { r.depth-- }()
+	start := r.b
+	major, n, err := r.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0:
+		return json.Number(fmt.Sprint(n)), nil
+	case 1:
+		if n > math.MaxInt64 {
+			return nil, errMalformed
+		}
+		return json.Number(fmt.Sprint(-1 - int64(n))), nil
+	case 3:
+		if n > uint64(len(r.b)) {
+			return nil, errMalformed
+		}
+		s := string(r.b[:n])
+		r.b = r.b[n:]
+		return s, nil
+	case 4:
+		// every element takes at least a byte
+		if n > uint64(len(r.b)) {
+			return nil, errMalformed
+		}
+		arr := make([]any, 0, n)
+		for range n {
+			e, err := r.value()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, e)
+		}
+		return arr, nil
+	case 5:
+		if n > uint64(len(r.b)) {
+			return nil, errMalformed
+		}
+		m := make(map[string]any, n)
+		for range n {
+			k, err := r.value()
+			if err != nil {
+				return nil, err
+			}
+			ks, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: non-string map key", errMalformed)
+			}
+			if m[ks], err = r.value(); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case 7:
+		switch start[0] & 0x1f {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			return float16(uint16(n)), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(n))), nil
+		case 27:
+			return math.Float64frombits(n), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unsupported major type %d", errMalformed, major)
+}
+
+func float16(h uint16) float64 {
+	exp, frac := int(h>>10&0x1f), float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(frac, -24)
+	case 31:
+		f = math.Inf(1)
+		if frac != 0 {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(frac+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+// decodeCBOR reads a registry written by encodeCBOR, or any CBOR document
+// with the same shape.
+func decodeCBOR(b []byte) (Database, error) {
+	var db Database
+	r := cborReader{b: b}
+	doc, err := r.value()
+	if err != nil {
+		return db, err
+	}
+	if len(r.b) != 0 {
+		return db, fmt.Errorf("%w: trailing data", errMalformed)
+	}
+	j, err := json.Marshal(doc)
+	if err != nil {
+		return db, err
+	}
+	err = json.Unmarshal(j, &db)
+	return db, err
+}
+
+// encodeYAML writes db as YAML with the same field names as the JSON
+// form, by way of a generic JSON value.
+func encodeYAML(db Database) ([]byte, error) {
+	b, err := json.Marshal(db)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// decodeYAML is the inverse of encodeYAML.
+func decodeYAML(b []byte) (Database, error) {
+	var db Database
+	var doc any
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return db, err
+	}
+	j, err := json.Marshal(doc)
+	if err != nil {
+		return db, err
+	}
+	err = json.Unmarshal(j, &db)
+	return db, err
+}
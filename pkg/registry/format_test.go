@@ -0,0 +1,44 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	db := testDatabase()
+	// a field added to Blueprint has to be added to the fixture, or this
+	// test says nothing about whether the encodings carry it
+	full := reflect.ValueOf(db.Blueprints[0])
+	for i := range full.NumField() {
+		if full.Field(i).IsZero() {
+			t.Errorf("testDatabase leaves Blueprint.%s unset", full.Type().Field(i).Name)
+		}
+	}
+	for _, format := range []string{FormatJSON, FormatProto, FormatCBOR, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			b, err := Encode(db, format, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Decode(b, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// protobuf has no empty list distinct from a missing one
+			if !reflect.DeepEqual(Normalize(got), Normalize(db)) {
+				t.Errorf("decoded\n%+v\nwant\n%+v", got, db)
+			}
+		})
+	}
+}
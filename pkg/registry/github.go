@@ -0,0 +1,349 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxResponseBytes caps API responses read into memory.
+const MaxResponseBytes = 16 << 20
+
+// StatusError is returned for non-2xx responses.
+type StatusError struct {
+	// Method defaults to GET.
+	Method string
+	URL    string
+	Code   int
+	Body   string
+	// RequestID identifies the request in the server's logs.
+	RequestID string
+}
+
+func (e *StatusError) Error() string {
+	method := e.Method
+	if method == "" {
+		method = "GET"
+	}
+	msg := fmt.Sprintf("%s %s: %d: %s", method, e.URL, e.Code, e.Body)
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// Fetcher gets the body of a URL, at most limit bytes of it. Responses
+// other than 2xx fail with a *StatusError.
+type Fetcher interface {
+	Get(ctx context.Context, url string, limit int64) ([]byte, error)
+}
+
+// httpFetcher is the Fetcher used when GitHub has none: plain,
+// unauthenticated requests.
+type httpFetcher struct{ hc *http.Client }
+
+func (f httpFetcher) Get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := f.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return nil, &StatusError{URL: url, Code: resp.StatusCode, Body: string(b), RequestID: resp.Header.Get("X-GitHub-Request-Id")}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err == nil && int64(len(body)) > limit {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", url, limit)
+	}
+	return body, err
+}
+
+// PageSize is as many items as List asks GitHub for per page; the most
+// it lists.
+const PageSize = 100
+
+// defaultPageSize is as many items as GitHub lists per page unless asked
+// for more, and the point from which a list in a response may have been
+// cut short.
+const defaultPageSize = 30
+
+// maxPages bounds List, should a server ignore the page parameter.
+const maxPages = 1000
+
+// List fetches every page of the GitHub API listing at u, an endpoint
+// that answers with a JSON array and takes the page and per_page
+// parameters, and returns the items of all of them. It stops at the first
+// page with fewer than PageSize items.
+func List[T any](ctx context.Context, f Fetcher, u string) ([]T, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	q := pu.Query()
+	q.Set("per_page", strconv.Itoa(PageSize))
+	var all []T
+	for page := 1; page <= maxPages; page++ {
+		q.Set("page", strconv.Itoa(page))
+		pu.RawQuery = q.Encode()
+		b, err := f.Get(ctx, pu.String(), MaxResponseBytes)
+		if err != nil {
+			return nil, err
+		}
+		var items []T
+		if err := json.Unmarshal(b, &items); err != nil {
+			return nil, fmt.Errorf("GET %s: decode: %w", pu, err)
+		}
+		all = append(all, items...)
+		if len(items) < PageSize {
+			return all, nil
+		}
+	}
+	return nil, fmt.Errorf("GET %s: more than %d pages", u, maxPages)
+}
+
+// GitHubRelease is a release as the GitHub API describes it.
+type GitHubRelease struct {
+	ID          int64         `json:"id"`
+	TagName     string        `json:"tag_name"`
+	Draft       bool          `json:"draft"`
+	PublishedAt time.Time     `json:"published_at"`
+	Assets      []GitHubAsset `json:"assets"`
+}
+
+// GitHubAsset is a file attached to a release.
+type GitHubAsset struct {
+	ID                 int64  `json:"id"`
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	// URL is the asset's API endpoint. It serves the file, to requests
+	// that Accept application/octet-stream, wherever the credentials can
+	// see the repo; browser_download_url only does for public repos.
+	URL           string `json:"url"`
+	DownloadCount int64  `json:"download_count"`
+	Size          int64  `json:"size"`
+	// Digest is "sha256:<hex>" on releases GitHub has hashed
+	Digest string `json:"digest"`
+
+	// private is set for the assets of a private repo's release
+	private bool
+}
+
+// DownloadURL is where to fetch the asset: its API endpoint for a private
+// repo, see IsAssetAPIURL, else its browser_download_url.
+func (a GitHubAsset) DownloadURL() string {
+	if a.private && a.URL != "" {
+		return a.URL
+	}
+	return a.BrowserDownloadURL
+}
+
+var assetAPIPath = regexp.MustCompile(`/repos/[^/]+/[^/]+/releases/assets/[0-9]+$`)
+
+// IsAssetAPIURL reports whether u is a release asset's API endpoint, as
+// recorded for the entries of private repos. Fetching the file from it
+// takes credentials that can see the repo and the Accept header
+// application/octet-stream; GitHub then redirects to a short-lived
+// download link.
+func IsAssetAPIURL(u string) bool {
+	pu, err := url.Parse(u)
+	return err == nil && assetAPIPath.MatchString(pu.Path)
+}
+
+// SHA256 returns the digest GitHub reports for the asset, if any.
+func (a GitHubAsset) SHA256() string {
+	if hex, ok := strings.CutPrefix(a.Digest, "sha256:"); ok && len(hex) == 64 {
+		return strings.ToLower(hex)
+	}
+	return ""
+}
+
+// GitHub fetches what the registry indexes from a source repo: its
+// releases, and the manifests and defaults file at a release's tag.
+type GitHub struct {
+	// Fetcher makes the requests, e.g. with credentials; plain HTTP by
+	// default.
+	Fetcher Fetcher
+	// APIURL and RawURL default to api.github.com and
+	// raw.githubusercontent.com.
+	APIURL string
+	RawURL string
+	// StrictManifests rejects manifests with fields Manifest doesn't
+	// have, as ParseManifestStrict does.
+	StrictManifests bool
+	// Private reads files through the contents API rather than
+	// RawURL, and has Release return assets that download through the
+	// API, so a Fetcher with credentials can index a private repo.
+	Private bool
+}
+
+func (g *GitHub) fetcher() Fetcher {
+	if g.Fetcher == nil {
+		return httpFetcher{http.DefaultClient}
+	}
+	return g.Fetcher
+}
+
+func (g *GitHub) apiURL() string {
+	if g.APIURL == "" {
+		return "https://api.github.com"
+	}
+	return strings.TrimSuffix(g.APIURL, "/")
+}
+
+func (g *GitHub) rawURL(repo, tag, file string) string {
+	base := g.RawURL
+	if base == "" {
+		base = "https://raw.githubusercontent.com"
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", strings.TrimSuffix(base, "/"), repo, tag, file)
+}
+
+// Release fetches the release of repo ("owner/repo") tagged tag.
+func (g *GitHub) Release(ctx context.Context, repo, tag string) (GitHubRelease, error) {
+	var rel GitHubRelease
+	b, err := g.fetcher().Get(ctx, fmt.Sprintf("%s/repos/%s/releases/tags/%s", g.apiURL(), repo, tag), MaxResponseBytes)
+	if err != nil {
+		return rel, err
+	}
+	if err := json.Unmarshal(b, &rel); err != nil {
+		return rel, fmt.Errorf("decode: %w", err)
+	}
+	if len(rel.Assets) >= defaultPageSize && rel.ID != 0 {
+		// the release may list only its first assets
+		if rel.Assets, err = g.Assets(ctx, repo, rel.ID); err != nil {
+			return rel, err
+		}
+	}
+	g.markPrivate(&rel)
+	return rel, nil
+}
+
+// Releases fetches every release of repo, newest first.
+func (g *GitHub) Releases(ctx context.Context, repo string) ([]GitHubRelease, error) {
+	rels, err := List[GitHubRelease](ctx, g.fetcher(), fmt.Sprintf("%s/repos/%s/releases", g.apiURL(), repo))
+	if err != nil {
+		return nil, err
+	}
+	for i := range rels {
+		if len(rels[i].Assets) >= defaultPageSize {
+			if rels[i].Assets, err = g.Assets(ctx, repo, rels[i].ID); err != nil {
+				return nil, err
+			}
+		}
+		g.markPrivate(&rels[i])
+	}
+	return rels, nil
+}
+
+// Assets fetches every asset of repo's release id.
+func (g *GitHub) Assets(ctx context.Context, repo string, id int64) ([]GitHubAsset, error) {
+	return List[GitHubAsset](ctx, g.fetcher(), fmt.Sprintf("%s/repos/%s/releases/%d/assets", g.apiURL(), repo, id))
+}
+
+func (g *GitHub) markPrivate(rel *GitHubRelease) {
+	for i := range rel.Assets {
+		rel.Assets[i].private = g.Private
+	}
+}
+
+// Manifest retrieves the manifest of the blueprint in dir from the repo at
+// tag, trying each of ManifestFiles. It returns ErrNoManifest when there
+// is none.
+func (g *GitHub) Manifest(ctx context.Context, repo, tag, dir string) (Manifest, error) {
+	for _, file := range ManifestFiles {
+		b, err := g.file(ctx, repo, tag, path.Join(dir, file))
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return Manifest{}, err
+		}
+		return parseManifest(file, b, g.StrictManifests)
+	}
+	return Manifest{}, ErrNoManifest
+}
+
+// Defaults retrieves the repo's DefaultsFile at tag. A repo without one
+// has no defaults.
+func (g *GitHub) Defaults(ctx context.Context, repo, tag string) (Defaults, error) {
+	b, err := g.file(ctx, repo, tag, DefaultsFile)
+	if isNotFound(err) {
+		return Defaults{}, nil
+	}
+	if err != nil {
+		return Defaults{}, err
+	}
+	return ParseDefaults(b)
+}
+
+// file fetches a manifest-sized file from the repo at tag.
+func (g *GitHub) file(ctx context.Context, repo, tag, p string) ([]byte, error) {
+	return g.File(ctx, repo, tag, p, MaxManifestBytes)
+}
+
+// File fetches the file at path p in repo at tag, of at most limit bytes.
+// A missing file fails with a *StatusError for a 404.
+func (g *GitHub) File(ctx context.Context, repo, tag, p string, limit int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if !g.Private {
+		return g.fetcher().Get(ctx, g.rawURL(repo, tag, p), limit)
+	}
+	// The contents API returns the file base64-encoded in JSON, a third
+	// larger than the file.
+	u := fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s", g.apiURL(), repo, p, url.QueryEscape(tag))
+	b, err := g.fetcher().Get(ctx, u, limit*2)
+	if err != nil {
+		return nil, err
+	}
+	var c struct {
+		Type     string `json:"type"`
+		Encoding string `json:"encoding"`
+		Content  string `json:"content"`
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("GET %s: decode: %w", u, err)
+	}
+	if c.Type != "file" || c.Encoding != "base64" {
+		return nil, fmt.Errorf("GET %s: not a file", u)
+	}
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(c.Content, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: decode: %w", u, err)
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("GET %s: file exceeds %d bytes", u, limit)
+	}
+	return content, nil
+}
+
+func isNotFound(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code == http.StatusNotFound
+}
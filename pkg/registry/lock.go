@@ -0,0 +1,60 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLocked is returned by Lock when another process holds the lock for
+// longer than the caller is willing to wait.
+var ErrLocked = errors.New("locked by another process")
+
+// Lock takes an exclusive advisory lock guarding the registry file at p,
+// waiting up to wait for another holder to let go. The lock lives in a
+// separate p.lock file: p itself is replaced on every save, so a lock on
+// it would not outlast the first write. Processes that read, modify and
+// save p should hold the lock throughout so none of them loses another's
+// changes. Call the returned function to release it.
+//
+// On platforms without file locking, Lock always succeeds at once.
+func Lock(p string, wait time.Duration) (unlock func() error, err error) {
+	f, err := os.OpenFile(p+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(wait)
+	for delay := 10 * time.Millisecond; ; delay = min(2*delay, 500*time.Millisecond) {
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("lock %s: %w", p, err)
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", p, ErrLocked)
+		}
+		time.Sleep(delay)
+	}
+	return func() error {
+		err := unlockFile(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
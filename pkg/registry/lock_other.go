@@ -0,0 +1,21 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows && !(unix && !solaris && !aix)
+
+package registry
+
+import "os"
+
+// tryLock always succeeds: this platform has no advisory file locks
+// the syscall package exposes.
+func tryLock(*os.File) (bool, error) { return true, nil }
+
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,323 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifests come from release authors we don't control, so decoding is
+// bounded in size and nesting before anything is mapped onto Manifest.
+const (
+	MaxManifestBytes = 64 << 10
+	maxManifestDepth = 32
+	maxManifestNodes = 10000
+)
+
+// ManifestFiles are the manifest names we look for, in order of preference.
+var ManifestFiles = []string{"manifest.yaml", "manifest.yml", "manifest.json", "manifest.toml"}
+
+// ManifestV1 is the manifest format, named by a manifest's apiVersion.
+// A manifest without one is read as v1.
+const ManifestV1 = "v1"
+
+// manifestVersion is one manifest format and how it maps onto Manifest.
+type manifestVersion struct {
+	// deprecated are fields still read but on their way out, with what
+	// replaces them.
+	deprecated map[string]string
+	// upgrade moves a decoded manifest's deprecated fields to their
+	// replacements.
+	upgrade func(*Manifest)
+}
+
+// manifestVersions are the formats this package reads, by apiVersion. A
+// change the format can't absorb compatibly gets a new version here, so
+// blueprints written in an older one keep working; fields a version
+// retires go in its deprecated list.
+var manifestVersions = map[string]manifestVersion{
+	ManifestV1: {},
+}
+
+// ManifestVersions lists the apiVersions this package reads.
+func ManifestVersions() []string {
+	return slices.Sorted(maps.Keys(manifestVersions))
+}
+
+// ErrUnknownAPIVersion is returned for a manifest in a format this
+// package doesn't read, typically one newer than it.
+var ErrUnknownAPIVersion = errors.New("unknown manifest apiVersion")
+
+// Manifest describes a blueprint, as its author wrote it.
+type Manifest struct {
+	// APIVersion is the format the manifest is written in.
+	APIVersion  string         `yaml:"apiVersion" toml:"apiVersion"`
+	Name        string         `yaml:"name" toml:"name"`
+	Version     string         `yaml:"version" toml:"version"`
+	Description string         `yaml:"description" toml:"description"`
+	Tags        []string       `yaml:"tags" toml:"tags"`
+	Visibility  string         `yaml:"visibility" toml:"visibility"`
+	License     string         `yaml:"license" toml:"license"`
+	Maintainers []string       `yaml:"maintainers" toml:"maintainers"`
+	Category    string         `yaml:"category" toml:"category"`
+	Icon        string         `yaml:"icon" toml:"icon"`
+	Screenshots []string       `yaml:"screenshots" toml:"screenshots"`
+	Deprecated  *Deprecation   `yaml:"deprecated" toml:"deprecated"`
+	Features    map[string]any `yaml:"features" toml:"features"`
+	// Dependencies are blueprints this one is applied on top of.
+	Dependencies []Dependency `yaml:"dependencies" toml:"dependencies"`
+	Parameters   []Param      `yaml:"parameters" toml:"parameters"`
+	// Warnings are about the way the manifest is written, such as
+	// deprecated fields, none of which stop it from being read.
+	Warnings []string `yaml:"-" toml:"-"`
+}
+
+// Param is a template variable the blueprint asks the user for.
+type Param struct {
+	Name        string `yaml:"name" toml:"name"`
+	Description string `yaml:"description" toml:"description"`
+	Default     any    `yaml:"default" toml:"default"`
+	Required    bool   `yaml:"required" toml:"required"`
+}
+
+var ErrManifestTooLarge = fmt.Errorf("manifest exceeds %d bytes", MaxManifestBytes)
+
+// ParseManifest decodes a manifest in the format implied by name's
+// extension. JSON goes through the YAML decoder, of which it is a subset.
+// Fields Manifest doesn't have are ignored. The manifest is read as its
+// apiVersion says, failing with ErrUnknownAPIVersion for one this package
+// doesn't know, and deprecated fields are noted in its Warnings.
+func ParseManifest(name string, b []byte) (Manifest, error) {
+	return parseManifest(name, b, false)
+}
+
+// ParseManifestStrict is ParseManifest, except that fields Manifest
+// doesn't have are an error, so a misspelled one isn't silently dropped.
+func ParseManifestStrict(name string, b []byte) (Manifest, error) {
+	return parseManifest(name, b, true)
+}
+
+func parseManifest(name string, b []byte, strict bool) (Manifest, error) {
+	man, keys, err := decodeManifest(name, b, strict)
+	if err != nil {
+		return man, err
+	}
+	v := cmp.Or(man.APIVersion, ManifestV1)
+	mv, ok := manifestVersions[v]
+	if !ok {
+		return man, fmt.Errorf("%w %q; this tool reads %s", ErrUnknownAPIVersion, v, strings.Join(ManifestVersions(), ", "))
+	}
+	for _, k := range slices.Sorted(maps.Keys(mv.deprecated)) {
+		if slices.Contains(keys, k) {
+			man.Warnings = append(man.Warnings, fmt.Sprintf("field %q is deprecated in %s; use %q", k, v, mv.deprecated[k]))
+		}
+	}
+	if mv.upgrade != nil {
+		mv.upgrade(&man)
+	}
+	return man, nil
+}
+
+// decodeManifest maps a manifest onto Manifest as it is written, and
+// lists its top-level keys.
+func decodeManifest(name string, b []byte, strict bool) (Manifest, []string, error) {
+	var man Manifest
+	var keys []string
+	if len(b) > MaxManifestBytes {
+		return man, nil, ErrManifestTooLarge
+	}
+	switch path.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		var doc yaml.Node
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return man, nil, err
+		}
+		budget := maxManifestNodes
+		if err := checkYAMLNode(&doc, 0, &budget); err != nil {
+			return man, nil, err
+		}
+		if doc.Kind == 0 {
+			return man, nil, nil // empty document
+		}
+		if top := doc.Content[0]; top.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(top.Content); i += 2 {
+				keys = append(keys, top.Content[i].Value)
+			}
+		}
+		if strict {
+			dec := yaml.NewDecoder(bytes.NewReader(b))
+			dec.KnownFields(true)
+			if err := dec.Decode(&man); err != nil {
+				// one line per field, as "line 3: field descripton not
+				// found in type registry.Manifest"
+				var te *yaml.TypeError
+				if errors.As(err, &te) {
+					return man, nil, errors.New(strings.Join(te.Errors, "; "))
+				}
+				return man, nil, err
+			}
+		} else if err := doc.Decode(&man); err != nil {
+			return man, nil, err
+		}
+	case ".toml":
+		var raw map[string]any
+		if _, err := toml.Decode(string(b), &raw); err != nil {
+			return man, nil, err
+		}
+		budget := maxManifestNodes
+		if err := checkValue(raw, 0, &budget); err != nil {
+			return man, nil, err
+		}
+		keys = slices.Collect(maps.Keys(raw))
+		md, err := toml.Decode(string(b), &man)
+		if err != nil {
+			return man, nil, err
+		}
+		if undecoded := md.Undecoded(); strict && len(undecoded) > 0 {
+			var unknown []string
+			for _, k := range undecoded {
+				unknown = append(unknown, k.String())
+			}
+			return man, nil, fmt.Errorf("unknown fields: %s", strings.Join(unknown, ", "))
+		}
+	default:
+		return man, nil, fmt.Errorf("unsupported manifest format %q", name)
+	}
+	return man, keys, nil
+}
+
+var (
+	errManifestTooDeep = fmt.Errorf("manifest nested deeper than %d levels", maxManifestDepth)
+	errManifestTooBig  = fmt.Errorf("manifest has more than %d nodes", maxManifestNodes)
+)
+
+// checkYAMLNode walks a YAML document, following aliases, so that alias
+// expansion ("billion laughs") counts against the node budget.
+func checkYAMLNode(n *yaml.Node, depth int, budget *int) error {
+	if depth > maxManifestDepth {
+		return errManifestTooDeep
+	}
+	if *budget--; *budget < 0 {
+		return errManifestTooBig
+	}
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		return checkYAMLNode(n.Alias, depth+1, budget)
+	}
+	for _, c := range n.Content {
+		if err := checkYAMLNode(c, depth+1, budget); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkValue(v any, depth int, budget *int) error {
+	if depth > maxManifestDepth {
+		return errManifestTooDeep
+	}
+	if *budget--; *budget < 0 {
+		return errManifestTooBig
+	}
+	switch x := v.(type) {
+	case map[string]any:
+		for _, c := range x {
+			if err := checkValue(c, depth+1, budget); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, c := range x {
+			if err := checkValue(c, depth+1, budget); err != nil {
+				return err
+			}
+		}
+	case []map[string]any:
+		for _, c := range x {
+			if err := checkValue(c, depth+1, budget); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ErrNoManifest means none of ManifestFiles exist for a blueprint.
+var ErrNoManifest = errors.New("no manifest found")
+
+// DefaultsFile sits at the root of a source repo and holds what all its
+// blueprints share, so a monorepo doesn't repeat it in every manifest.
+const DefaultsFile = "blueprints-defaults.yaml"
+
+// Defaults are the manifest fields a repo can set for all its
+// blueprints.
+type Defaults struct {
+	Tags        []string `yaml:"tags"`
+	Maintainers []string `yaml:"maintainers"`
+	License     string   `yaml:"license"`
+	Category    string   `yaml:"category"`
+}
+
+// ParseDefaults decodes a defaults file with the same bounds as manifests.
+// Unknown fields are errors, so a misspelt key doesn't silently do nothing.
+func ParseDefaults(b []byte) (Defaults, error) {
+	var d Defaults
+	if len(b) > MaxManifestBytes {
+		return d, ErrManifestTooLarge
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return d, err
+	}
+	budget := maxManifestNodes
+	if err := checkYAMLNode(&doc, 0, &budget); err != nil {
+		return d, err
+	}
+	if doc.Kind == 0 {
+		return d, nil // empty document
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&d); err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+// Inherit layers the manifest over the defaults. Fields the manifest sets
+// win, except tags, which add to the shared ones.
+func (man *Manifest) Inherit(d Defaults) {
+	if man.License == "" {
+		man.License = d.License
+	}
+	if man.Category == "" {
+		man.Category = d.Category
+	}
+	if len(man.Maintainers) == 0 {
+		man.Maintainers = slices.Clone(d.Maintainers)
+	}
+	tags := slices.Clone(d.Tags)
+	for _, t := range man.Tags {
+		if !slices.Contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	man.Tags = tags
+}
@@ -0,0 +1,499 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package registry reads, changes and writes the dragon blueprint registry,
+// and fetches what it indexes from GitHub releases. It is the logic the
+// dragon-registry updater is built on, for other tools such as the dragon
+// CLI or a registry server to share.
+//
+// A typical change loads the registry, upserts an entry and saves it:
+//
+//	db, err := registry.Load("registry.json")
+//	...
+//	registry.Upsert(&db, entry)
+//	err = registry.Save("registry.json", db, registry.SaveOptions{})
+package registry
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Blueprint is an entry of the registry: the current release of a
+// blueprint and where to download it.
+type Blueprint struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Title is the manifest's name when the configured ID strategy
+	// derives a different one.
+	Title       string `json:"title,omitempty"`
+	Version     string `json:"version"`
+	Repo        string `json:"repo"`
+	Path        string `json:"path"`
+	DownloadURL string `json:"download_url"`
+	SHA256      string `json:"sha256,omitempty"`
+	// Digests are the archive's digests in other algorithms.
+	Digests Digests `json:"digests,omitempty"`
+	// Asset is the release asset the archive was indexed from.
+	Asset     *AssetRef `json:"asset,omitempty"`
+	SourceURL string    `json:"source_url,omitempty"`
+	// Signed is set when the archive's cosign signature was verified.
+	Signed bool `json:"signed,omitempty"`
+	// Provenance is set when the archive's SLSA provenance was verified.
+	Provenance *Provenance `json:"provenance,omitempty"`
+	// Sources are other places the same archive was published.
+	Sources       []string       `json:"sources,omitempty"`
+	Mirrors       []string       `json:"mirrors,omitempty"`
+	Description   string         `json:"description"`
+	Tags          []string       `json:"tags"`
+	Features      map[string]any `json:"features,omitempty"`
+	Dependencies  []Dependency   `json:"dependencies,omitempty"`
+	Visibility    string         `json:"visibility,omitempty"`
+	License       string         `json:"license,omitempty"`
+	LicenseSource string         `json:"license_source,omitempty"`
+	Maintainers   []string       `json:"maintainers,omitempty"`
+	Category      string         `json:"category,omitempty"`
+	Icon          string         `json:"icon,omitempty"`
+	Screenshots   []string       `json:"screenshots,omitempty"`
+	Trust         string         `json:"trust,omitempty"`
+	Previous      []Release      `json:"previous,omitempty"`
+	CreatedAt     time.Time      `json:"created_at,omitzero"`
+	UpdatedAt     time.Time      `json:"updated_at,omitzero"`
+	// Quality rates how complete the entry's metadata is.
+	Quality     *Quality     `json:"quality,omitempty"`
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
+	// Compatibility records which dragon CLI versions scaffold the
+	// release, as tested by the registry rather than claimed.
+	Compatibility []Compatibility `json:"compatibility,omitempty"`
+	// Stale is set while the source repo hasn't released for longer than
+	// the registry's freshness window.
+	Stale *Staleness `json:"stale,omitempty"`
+}
+
+// Database is a whole registry file.
+type Database struct {
+	SchemaVersion int         `json:"schema_version"`
+	Metadata      Metadata    `json:"metadata,omitzero"`
+	Blueprints    []Blueprint `json:"blueprints"`
+}
+
+// Dependency is another blueprint a blueprint is scaffolded on top of.
+type Dependency struct {
+	// Name is "namespace/name"; a bare name means the default namespace.
+	Name string `json:"name" yaml:"name" toml:"name"`
+	// Version is a constraint such as "^1.2"; empty accepts any version.
+	Version string `json:"version,omitempty" yaml:"version" toml:"version"`
+}
+
+// Release is an earlier release of an entry, kept so profiles and
+// locks can pin it after a newer one is published.
+type Release struct {
+	Version     string    `json:"version"`
+	DownloadURL string    `json:"download_url"`
+	SHA256      string    `json:"sha256,omitempty"`
+	Digests     Digests   `json:"digests,omitempty"`
+	Asset       *AssetRef `json:"asset,omitempty"`
+	ReleasedAt  time.Time `json:"released_at,omitzero"`
+}
+
+// AssetRef identifies a GitHub release asset. An asset deleted and
+// uploaded again under the same name keeps its download URL but gets a
+// new ID, so the digests recorded for an archive are only trusted for
+// the asset they were taken from.
+type AssetRef struct {
+	ID   int64 `json:"id"`
+	Size int64 `json:"size"`
+}
+
+// Matches reports whether a is the asset r was recorded for.
+func (r *AssetRef) Matches(a GitHubAsset) bool {
+	return r != nil && r.ID != 0 && r.ID == a.ID && r.Size == a.Size
+}
+
+// Provenance is where an entry's archive was built from, as attested.
+type Provenance struct {
+	Builder    string `json:"builder"`
+	SourceRepo string `json:"source_repo"`
+	Commit     string `json:"commit"`
+}
+
+// Quality is an entry's metadata-completeness score: the percentage of
+// the checks it passes, and the ones it fails.
+type Quality struct {
+	Score   int      `json:"score"`
+	Missing []string `json:"missing,omitempty"`
+}
+
+// Staleness marks an entry whose source repo stopped releasing.
+type Staleness struct {
+	// Since is when the entry was found stale.
+	Since time.Time `json:"since"`
+	// LastRelease is the repo's latest release at the time.
+	LastRelease time.Time `json:"last_release,omitzero"`
+}
+
+// Compatibility is the outcome of scaffolding a release with one
+// version of the dragon CLI.
+type Compatibility struct {
+	CLI string `json:"cli"`
+	// Version is the release tested.
+	Version  string    `json:"version"`
+	Passed   bool      `json:"passed"`
+	TestedAt time.Time `json:"tested_at,omitzero"`
+}
+
+// Tombstone records an entry that was removed on purpose, so a client
+// holding an older index can tell a removal from a damaged index.
+type Tombstone struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	Reason    string    `json:"reason"`
+	RemovedAt time.Time `json:"removed_at"`
+	// SHA256 is the digest of the last release the entry had.
+	SHA256 string `json:"sha256,omitempty"`
+	// Visibility is the entry's, so exports only show removals their
+	// audience could have seen.
+	Visibility string `json:"visibility,omitempty"`
+}
+
+// DefaultNamespace holds the official blueprints and every entry that
+// predates namespaces.
+const DefaultNamespace = "getdragon"
+
+// Namespaces and names are lowercase identifiers that are safe in URLs and
+// file names.
+var identRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]{0,62}[a-z0-9])?$`)
+
+// FullName returns the entry's "namespace/name" identifier.
+func (b Blueprint) FullName() string {
+	return b.Namespace + "/" + b.Name
+}
+
+// SplitRef splits "namespace/name" into its parts. A bare name has an
+// empty namespace.
+func SplitRef(ref string) (namespace, name string) {
+	if ns, n, ok := strings.Cut(ref, "/"); ok {
+		return ns, n
+	}
+	return "", ref
+}
+
+// ValidateIdent checks that s can be used as a namespace or name; kind
+// names it in the error.
+func ValidateIdent(kind, s string) error {
+	if !identRe.MatchString(s) {
+		return fmt.Errorf("invalid %s %q: use lowercase letters, digits, '.', '_' and '-'", kind, s)
+	}
+	return nil
+}
+
+var (
+	ErrNotFound  = errors.New("blueprint not found")
+	ErrAmbiguous = errors.New("ambiguous blueprint name")
+)
+
+// Find finds the entry for ref. A bare name resolves to the default
+// namespace first, then to the only namespace that has it.
+func Find(db Database, ref string) (Blueprint, error) {
+	ns, name := SplitRef(ref)
+	if ns != "" {
+		for _, bp := range db.Blueprints {
+			if bp.Namespace == ns && bp.Name == name {
+				return bp, nil
+			}
+		}
+		return Blueprint{}, fmt.Errorf("%s: %w", ref, ErrNotFound)
+	}
+
+	var matches []Blueprint
+	for _, bp := range db.Blueprints {
+		if bp.Name != name {
+			continue
+		}
+		if bp.Namespace == DefaultNamespace {
+			return bp, nil
+		}
+		matches = append(matches, bp)
+	}
+	switch len(matches) {
+	case 0:
+		return Blueprint{}, fmt.Errorf("%s: %w", ref, ErrNotFound)
+	case 1:
+		return matches[0], nil
+	}
+	var names []string
+	for _, bp := range matches {
+		names = append(names, bp.FullName())
+	}
+	return Blueprint{}, fmt.Errorf("%w %q: one of %s", ErrAmbiguous, ref, strings.Join(names, ", "))
+}
+
+// Lookup returns the entry named ns/name.
+func (db *Database) Lookup(ns, name string) (Blueprint, bool) {
+	for _, bp := range db.Blueprints {
+		if bp.Namespace == ns && bp.Name == name {
+			return bp, true
+		}
+	}
+	return Blueprint{}, false
+}
+
+// Upsert adds entry to db or replaces the entry of the same name. It keeps
+// the original creation time so digests can tell new blueprints from
+// updated ones, remembers the replaced release in Previous so it can still
+// be pinned, and reports whether the entry is new. A release older than
+// the current one, such as a backport, is added to Previous instead.
+func Upsert(db *Database, entry Blueprint) bool {
+	buryTombstone(&db.Metadata, entry.FullName())
+	for i := range db.Blueprints {
+		old := db.Blueprints[i]
+		if old.Namespace == entry.Namespace && old.Name == entry.Name {
+			if OlderVersion(entry.Version, old.Version) {
+				rel, _ := entry.Release(entry.Version)
+				db.Blueprints[i].Previous = AddRelease(old.Previous, rel)
+				return false
+			}
+			entry.CreatedAt = old.CreatedAt
+			if entry.Sources == nil && entry.Version == old.Version && entry.SHA256 == old.SHA256 {
+				entry.Sources = old.Sources
+			}
+			if entry.Previous == nil {
+				entry.Previous = old.Previous
+			}
+			if _, known := entry.Release(old.Version); !known {
+				prev, _ := old.Release(old.Version)
+				entry.Previous = append([]Release{prev}, entry.Previous...)
+			}
+			// a re-release of a previous version becomes current again
+			entry.Previous = slices.DeleteFunc(slices.Clone(entry.Previous), func(r Release) bool {
+				return r.Version == entry.Version
+			})
+			db.Blueprints[i] = entry
+			return false
+		}
+	}
+	db.Blueprints = append(db.Blueprints, entry)
+	return true
+}
+
+// Remove deletes the named entry and leaves a tombstone in its place.
+func Remove(db *Database, name, reason string, now time.Time) (Tombstone, error) {
+	i := slices.IndexFunc(db.Blueprints, func(bp Blueprint) bool { return bp.FullName() == name })
+	if i < 0 {
+		return Tombstone{}, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	bp := db.Blueprints[i]
+	t := Tombstone{Name: name, Version: bp.Version, Reason: reason, RemovedAt: now, SHA256: bp.SHA256, Visibility: bp.Visibility}
+	db.Blueprints = slices.Delete(db.Blueprints, i, i+1)
+	buryTombstone(&db.Metadata, name)
+	db.Metadata.Tombstones = append(db.Metadata.Tombstones, t)
+	return t, nil
+}
+
+// Tombstone returns the tombstone of the named entry, if it was removed.
+func (m Metadata) Tombstone(name string) (Tombstone, bool) {
+	i := slices.IndexFunc(m.Tombstones, func(t Tombstone) bool { return t.Name == name })
+	if i < 0 {
+		return Tombstone{}, false
+	}
+	return m.Tombstones[i], true
+}
+
+// buryTombstone drops the tombstone of an entry that is published again.
+func buryTombstone(m *Metadata, name string) {
+	m.Tombstones = slices.DeleteFunc(m.Tombstones, func(t Tombstone) bool { return t.Name == name })
+	if len(m.Tombstones) == 0 {
+		m.Tombstones = nil
+	}
+}
+
+// Release returns the download of the given version of bp, current or
+// previous.
+func (bp Blueprint) Release(version string) (Release, bool) {
+	if bp.Version == version {
+		return Release{Version: bp.Version, DownloadURL: bp.DownloadURL, SHA256: bp.SHA256, Digests: bp.Digests, Asset: bp.Asset, ReleasedAt: bp.UpdatedAt}, true
+	}
+	for _, r := range bp.Previous {
+		if r.Version == version {
+			return r, true
+		}
+	}
+	return Release{}, false
+}
+
+// Versions lists every release of bp, current first.
+func (bp Blueprint) Versions() []Release {
+	cur, _ := bp.Release(bp.Version)
+	return append([]Release{cur}, bp.Previous...)
+}
+
+// OlderVersion reports whether a precedes b. Versions that aren't semver
+// are never older, so they replace the current release as before.
+func OlderVersion(a, b string) bool {
+	va, err := ParseSemver(a)
+	if err != nil {
+		return false
+	}
+	vb, err := ParseSemver(b)
+	if err != nil {
+		return false
+	}
+	return va.Compare(vb) < 0
+}
+
+// AddRelease puts r into the history, replacing a release of the same
+// version, and keeps the history newest first.
+func AddRelease(history []Release, r Release) []Release {
+	history = slices.DeleteFunc(slices.Clone(history), func(p Release) bool {
+		return p.Version == r.Version
+	})
+	history = append(history, r)
+	sortReleases(history)
+	return history
+}
+
+// sortReleases orders releases newest version first, breaking ties by
+// release time. Tags that aren't semver go after all that are, in
+// lexical order, so the order is total and the same for any input order.
+func sortReleases(rs []Release) {
+	slices.SortStableFunc(rs, func(a, b Release) int {
+		va, errA := ParseSemver(a.Version)
+		vb, errB := ParseSemver(b.Version)
+		switch {
+		case errA == nil && errB == nil:
+			if c := vb.Compare(va); c != 0 {
+				return c
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		}
+		return cmp.Or(
+			strings.Compare(a.Version, b.Version),
+			b.ReleasedAt.Compare(a.ReleasedAt),
+		)
+	})
+}
+
+// Clone deep-copies db so the copy can be changed without affecting
+// others that share its slices and maps.
+func (db Database) Clone() Database {
+	out := db
+	out.Metadata.Tombstones = slices.Clone(db.Metadata.Tombstones)
+	out.Metadata.RequiredDigests = slices.Clone(db.Metadata.RequiredDigests)
+	out.Blueprints = make([]Blueprint, len(db.Blueprints))
+	for i, bp := range db.Blueprints {
+		bp.Mirrors = slices.Clone(bp.Mirrors)
+		bp.Sources = slices.Clone(bp.Sources)
+		bp.Tags = slices.Clone(bp.Tags)
+		bp.Maintainers = slices.Clone(bp.Maintainers)
+		bp.Screenshots = slices.Clone(bp.Screenshots)
+		bp.Features = cloneValue(bp.Features).(map[string]any)
+		bp.Dependencies = slices.Clone(bp.Dependencies)
+		bp.Digests = maps.Clone(bp.Digests)
+		bp.Previous = slices.Clone(bp.Previous)
+		for j := range bp.Previous {
+			bp.Previous[j].Digests = maps.Clone(bp.Previous[j].Digests)
+			if a := bp.Previous[j].Asset; a != nil {
+				ac := *a
+				bp.Previous[j].Asset = &ac
+			}
+		}
+		if bp.Asset != nil {
+			a := *bp.Asset
+			bp.Asset = &a
+		}
+		bp.Compatibility = slices.Clone(bp.Compatibility)
+		if bp.Provenance != nil {
+			p := *bp.Provenance
+			bp.Provenance = &p
+		}
+		if bp.Deprecation != nil {
+			d := *bp.Deprecation
+			bp.Deprecation = &d
+		}
+		if bp.Stale != nil {
+			st := *bp.Stale
+			bp.Stale = &st
+		}
+		if bp.Quality != nil {
+			q := *bp.Quality
+			q.Missing = slices.Clone(q.Missing)
+			bp.Quality = &q
+		}
+		out.Blueprints[i] = bp
+	}
+	return out
+}
+
+// cloneValue deep-copies a value decoded from JSON or YAML: the maps and
+// slices in it, down to the scalars they hold.
+func cloneValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = cloneValue(e)
+		}
+		return out
+	case []any:
+		if v == nil {
+			return v
+		}
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = cloneValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+// Normalize puts db in the one form it is written in, so the same
+// registry always encodes to the same bytes: entries sorted by full name,
+// previous releases newest first, tombstones by name, and nil slices
+// replaced so they encode as [] rather than null. It copies what it
+// reorders, leaving db's slices as they were.
+func Normalize(db Database) Database {
+	db.Blueprints = slices.Clone(db.Blueprints)
+	if db.Blueprints == nil {
+		db.Blueprints = []Blueprint{}
+	}
+	for i := range db.Blueprints {
+		bp := &db.Blueprints[i]
+		if bp.Tags == nil {
+			bp.Tags = []string{}
+		}
+		if len(bp.Previous) > 1 {
+			bp.Previous = slices.Clone(bp.Previous)
+			sortReleases(bp.Previous)
+		}
+	}
+	slices.SortStableFunc(db.Blueprints, func(a, b Blueprint) int {
+		return strings.Compare(a.FullName(), b.FullName())
+	})
+	if len(db.Metadata.Tombstones) > 1 {
+		db.Metadata.Tombstones = slices.Clone(db.Metadata.Tombstones)
+		slices.SortStableFunc(db.Metadata.Tombstones, func(a, b Tombstone) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+	}
+	return db
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSortReleases(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		in   []Release
+		want []string
+	}{
+		{"semver", []Release{{Version: "1.2.0"}, {Version: "1.10.0"}, {Version: "1.9.0"}}, []string{"1.10.0", "1.9.0", "1.2.0"}},
+		{"prerelease", []Release{{Version: "2.0.0-rc.1"}, {Version: "2.0.0"}, {Version: "1.0.0"}}, []string{"2.0.0", "2.0.0-rc.1", "1.0.0"}},
+		{"non-semver last", []Release{{Version: "nightly"}, {Version: "1.0.0"}, {Version: "latest"}, {Version: "0.1.0"}}, []string{"1.0.0", "0.1.0", "latest", "nightly"}},
+		{"same precedence", []Release{{Version: "v1.0.0"}, {Version: "1.0.0"}}, []string{"1.0.0", "v1.0.0"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// every input order sorts the same
+			for i := range c.in {
+				rs := slices.Clone(c.in)
+				slices.Reverse(rs[i:])
+				for j := range rs {
+					rs[j].ReleasedAt = day
+				}
+				sortReleases(rs)
+				var got []string
+				for _, r := range rs {
+					got = append(got, r.Version)
+				}
+				if !slices.Equal(got, c.want) {
+					t.Fatalf("%v, want %v", got, c.want)
+				}
+			}
+		})
+	}
+
+	rs := []Release{{Version: "1.0.0", ReleasedAt: day}, {Version: "1.0.0", ReleasedAt: day.Add(time.Hour)}}
+	sortReleases(rs)
+	if !rs[0].ReleasedAt.After(rs[1].ReleasedAt) {
+		t.Errorf("equal versions: the later release should come first")
+	}
+}
+
+func TestCloneFeatures(t *testing.T) {
+	db := Database{Blueprints: []Blueprint{{
+		Name: "a",
+		Features: map[string]any{
+			"db":     map[string]any{"postgres": "16"},
+			"docker": []any{"compose", map[string]any{"swarm": true}},
+		},
+	}}}
+	c := db.Clone()
+	c.Blueprints[0].Features["db"].(map[string]any)["postgres"] = "17"
+	c.Blueprints[0].Features["docker"].([]any)[0] = "podman"
+	c.Blueprints[0].Features["docker"].([]any)[1].(map[string]any)["swarm"] = false
+
+	f := db.Blueprints[0].Features
+	if got := f["db"].(map[string]any)["postgres"]; got != "16" {
+		t.Errorf("nested map changed through the clone: %v", got)
+	}
+	if got := f["docker"].([]any)[0]; got != "compose" {
+		t.Errorf("nested slice changed through the clone: %v", got)
+	}
+	if got := f["docker"].([]any)[1].(map[string]any)["swarm"]; got != true {
+		t.Errorf("map in a slice changed through the clone: %v", got)
+	}
+	if c := (Database{Blueprints: []Blueprint{{Name: "b"}}}).Clone(); c.Blueprints[0].Features != nil {
+		t.Errorf("nil features cloned as %v", c.Blueprints[0].Features)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registrytest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// GitHub is an in-memory GitHub holding source repos' releases and files,
+// for tests of code built on registry.GitHub. It implements
+// registry.Fetcher for the API and raw.githubusercontent.com URLs that
+// registry.GitHub requests, and http.RoundTripper for those and the
+// assets' download URLs. It is safe for concurrent use.
+type GitHub struct {
+	// Clock stamps the releases added.
+	Clock *Clock
+
+	mu       sync.Mutex
+	releases map[string]registry.GitHubRelease // by "owner/repo@tag"
+	files    map[string][]byte                 // by "owner/repo@tag:path"
+	assets   map[string][]byte                 // by download URL
+	nextID   int64
+}
+
+// NewGitHub returns a GitHub without repos, whose clock starts at Epoch.
+func NewGitHub() *GitHub {
+	return &GitHub{
+		Clock:    NewClock(Epoch),
+		releases: map[string]registry.GitHubRelease{},
+		files:    map[string][]byte{},
+		assets:   map[string][]byte{},
+	}
+}
+
+// AddRelease publishes a release of repo ("owner/repo") tagged tag with
+// assets (name to contents), e.g. blueprint archives made with Archive.
+// It returns the release as the API describes it, with digests.
+func (g *GitHub) AddRelease(repo, tag string, assets map[string][]byte) registry.GitHubRelease {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nextID++
+	rel := registry.GitHubRelease{ID: g.nextID, TagName: tag, PublishedAt: g.Clock.Now()}
+	for _, name := range sortedKeys(assets) {
+		g.nextID++
+		b := assets[name]
+		sum := sha256.Sum256(b)
+		a := registry.GitHubAsset{
+			ID:                 g.nextID,
+			Name:               name,
+			BrowserDownloadURL: fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", repo, tag, name),
+			URL:                fmt.Sprintf("https://api.github.com/repos/%s/releases/assets/%d", repo, g.nextID),
+			Size:               int64(len(b)),
+			Digest:             "sha256:" + hex.EncodeToString(sum[:]),
+		}
+		g.assets[a.BrowserDownloadURL] = b
+		g.assets[a.URL] = b
+		rel.Assets = append(rel.Assets, a)
+	}
+	g.releases[repo+"@"+tag] = rel
+	return rel
+}
+
+// AddFile puts a file in repo at tag, e.g. a blueprint's manifest at
+// blueprints/<name>/manifest.yaml or the repo's defaults file.
+func (g *GitHub) AddFile(repo, tag, p string, content []byte) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.files[repo+"@"+tag+":"+p] = content
+}
+
+// Client returns a registry.GitHub that fetches from g.
+func (g *GitHub) Client() *registry.GitHub {
+	return &registry.GitHub{Fetcher: g}
+}
+
+// Get implements registry.Fetcher.
+func (g *GitHub) Get(ctx context.Context, u string, limit int64) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	code, b := g.lookup(u)
+	if code != http.StatusOK {
+		return nil, &registry.StatusError{URL: u, Code: code, Body: http.StatusText(code)}
+	}
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", u, limit)
+	}
+	return b, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (g *GitHub) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return respond(req, http.StatusMethodNotAllowed, nil, nil), nil
+	}
+	code, b := g.lookup(req.URL.String())
+	return respond(req, code, nil, b), nil
+}
+
+// HTTPClient returns an HTTP client whose requests g answers.
+func (g *GitHub) HTTPClient() *http.Client {
+	return &http.Client{Transport: g}
+}
+
+// lookup answers a GET of u with a status code and body.
+func (g *GitHub) lookup(u string) (int, []byte) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return http.StatusBadRequest, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if b, ok := g.assets[pu.Scheme+"://"+pu.Host+pu.Path]; ok {
+		return http.StatusOK, b
+	}
+	parts := strings.SplitN(strings.TrimPrefix(pu.Path, "/"), "/", 4)
+	switch pu.Host {
+	case "raw.githubusercontent.com":
+		// /owner/repo/tag/path
+		if len(parts) == 4 {
+			if b, ok := g.files[parts[0]+"/"+parts[1]+"@"+parts[2]+":"+parts[3]]; ok {
+				return http.StatusOK, b
+			}
+		}
+	case "api.github.com":
+		// /repos/owner/repo/...
+		if len(parts) < 4 || parts[0] != "repos" {
+			break
+		}
+		repo := parts[1] + "/" + parts[2]
+		if tag, ok := strings.CutPrefix(parts[3], "releases/tags/"); ok {
+			if rel, ok := g.releases[repo+"@"+tag]; ok {
+				b, _ := json.Marshal(rel)
+				return http.StatusOK, b
+			}
+		}
+		if parts[3] == "releases" {
+			var rels []registry.GitHubRelease
+			for k, rel := range g.releases {
+				if strings.HasPrefix(k, repo+"@") {
+					rels = append(rels, rel)
+				}
+			}
+			slices.SortFunc(rels, func(a, b registry.GitHubRelease) int {
+				return b.PublishedAt.Compare(a.PublishedAt)
+			})
+			return page(rels, pu.Query())
+		}
+		if id, ok := strings.CutPrefix(parts[3], "releases/"); ok {
+			if id, ok := strings.CutSuffix(id, "/assets"); ok {
+				for k, rel := range g.releases {
+					if strings.HasPrefix(k, repo+"@") && strconv.FormatInt(rel.ID, 10) == id {
+						return page(rel.Assets, pu.Query())
+					}
+				}
+			}
+		}
+		if p, ok := strings.CutPrefix(parts[3], "contents/"); ok {
+			if b, ok := g.files[repo+"@"+pu.Query().Get("ref")+":"+p]; ok {
+				out, _ := json.Marshal(map[string]string{
+					"type":     "file",
+					"encoding": "base64",
+					"content":  base64.StdEncoding.EncodeToString(b),
+				})
+				return http.StatusOK, out
+			}
+		}
+	}
+	return http.StatusNotFound, nil
+}
+
+// page answers a listing with the page of items that q asks for, 30 per
+// page by default, as GitHub does.
+func page[T any](items []T, q url.Values) (int, []byte) {
+	n, _ := strconv.Atoi(q.Get("per_page"))
+	if n <= 0 {
+		n = 30
+	}
+	p, _ := strconv.Atoi(q.Get("page"))
+	start := max(p-1, 0) * n
+	out := items[min(start, len(items)):min(start+n, len(items))]
+	if out == nil {
+		out = []T{}
+	}
+	b, _ := json.Marshal(out)
+	return http.StatusOK, b
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registrytest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// overHTTP serves g from a real HTTP server, the API under /api and raw
+// files under /raw, and returns a registry.GitHub that reaches it with
+// its own HTTP fetcher rather than through g's Fetcher methods.
+func overHTTP(t *testing.T, g *GitHub) *registry.GitHub {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, p := "api.github.com", strings.TrimPrefix(r.URL.Path, "/api")
+		if rest, ok := strings.CutPrefix(r.URL.Path, "/raw"); ok {
+			host, p = "raw.githubusercontent.com", rest
+		}
+		req := r.Clone(r.Context())
+		req.URL.Scheme, req.URL.Host, req.URL.Path = "https", host, p
+		resp, err := g.RoundTrip(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(srv.Close)
+	return &registry.GitHub{APIURL: srv.URL + "/api", RawURL: srv.URL + "/raw"}
+}
+
+// testGitHub has a repo with more releases than fit a page of the API,
+// one of them with more assets than a release lists inline.
+func testGitHub() *GitHub {
+	g := NewGitHub()
+	for i := range registry.PageSize + 5 {
+		g.Clock.Advance(time.Hour)
+		assets := map[string][]byte{fmt.Sprintf("bp-%d.zip", i): Archive(map[string]string{"manifest.yaml": fmt.Sprintf("version: 1.0.%d\n", i)})}
+		if i == 7 {
+			for j := range 35 {
+				assets[fmt.Sprintf("extra-%02d.zip", j)] = []byte(fmt.Sprint("extra ", j))
+			}
+		}
+		g.AddRelease("acme/blueprints", fmt.Sprintf("v1.0.%d", i), assets)
+	}
+	g.AddFile("acme/blueprints", "v1.0.7", "blueprints/web/manifest.yaml", []byte("name: web\nversion: 1.0.7\n"))
+	return g
+}
+
+func TestGitHubMatchesClient(t *testing.T) {
+	ctx := context.Background()
+	g := testGitHub()
+	clients := map[string]*registry.GitHub{"fetcher": g.Client(), "http": overHTTP(t, g)}
+	got := map[string][]registry.GitHubRelease{}
+	for name, c := range clients {
+		t.Run(name, func(t *testing.T) {
+			rels, err := c.Releases(ctx, "acme/blueprints")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got[name] = rels
+			// every release once, newest first, across pages
+			if len(rels) != registry.PageSize+5 {
+				t.Fatalf("%d releases, want %d", len(rels), registry.PageSize+5)
+			}
+			for i := 1; i < len(rels); i++ {
+				if !rels[i-1].PublishedAt.After(rels[i].PublishedAt) {
+					t.Fatalf("release %d (%s) not older than %s", i, rels[i].TagName, rels[i-1].TagName)
+				}
+			}
+
+			rel, err := c.Release(ctx, "acme/blueprints", "v1.0.7")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rel.Assets) != 36 {
+				t.Errorf("v1.0.7 has %d assets, want 36", len(rel.Assets))
+			}
+			listed, err := c.Assets(ctx, "acme/blueprints", rel.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(listed, rel.Assets) {
+				t.Errorf("listed assets differ from the release's")
+			}
+
+			_, err = c.Release(ctx, "acme/blueprints", "v9.9.9")
+			var se *registry.StatusError
+			if !errors.As(err, &se) || se.Code != http.StatusNotFound {
+				t.Errorf("unknown tag: %v, want a 404 StatusError", err)
+			}
+
+			m, err := c.Manifest(ctx, "acme/blueprints", "v1.0.7", "blueprints/web")
+			if err != nil || m.Name != "web" {
+				t.Errorf("manifest %+v, %v", m, err)
+			}
+		})
+	}
+	if !reflect.DeepEqual(got["fetcher"], got["http"]) {
+		t.Error("the releases listed through the fetcher and over HTTP differ")
+	}
+}
+
+func TestGitHubAssets(t *testing.T) {
+	ctx := context.Background()
+	g := testGitHub()
+	for _, private := range []bool{false, true} {
+		c := g.Client()
+		c.Private = private
+		rel, err := c.Release(ctx, "acme/blueprints", "v1.0.7")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range rel.Assets {
+			if private != registry.IsAssetAPIURL(a.DownloadURL()) {
+				t.Errorf("private %t: %s downloads from %s", private, a.Name, a.DownloadURL())
+			}
+			resp, err := g.HTTPClient().Get(a.DownloadURL())
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil || resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET %s: %d, %v", a.DownloadURL(), resp.StatusCode, err)
+			}
+			sum := sha256.Sum256(b)
+			if a.SHA256() != hex.EncodeToString(sum[:]) || a.Size != int64(len(b)) {
+				t.Errorf("%s: digest %s and size %d, but the file is %x, %d bytes", a.Name, a.SHA256(), a.Size, sum, len(b))
+			}
+		}
+	}
+	if _, err := g.Get(ctx, "https://github.com/acme/blueprints/releases/download/v1.0.7/missing.zip", registry.MaxResponseBytes); err == nil {
+		t.Error("missing asset found")
+	}
+}
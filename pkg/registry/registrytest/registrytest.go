@@ -0,0 +1,257 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package registrytest provides in-memory doubles of a published registry,
+// of a registry.Store and of GitHub, for the tests of tools built on package registry. Nothing
+// touches the network or needs fixture files: seed a Registry, point a
+// registry.Client at it, and the client fetches the index and archives as
+// it would from a real server. Time only moves when the test moves it.
+package registrytest
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// URL is where a Registry answers; its index is URL/registry.json, or
+// any other extension for the other encodings.
+const URL = "https://registry.test"
+
+// Epoch is when a new Clock starts.
+var Epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Clock is a deterministic clock: it reads the same until the test
+// advances it.
+type Clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// NewClock returns a clock reading t.
+func NewClock(t time.Time) *Clock {
+	return &Clock{t: t.UTC()}
+}
+
+// Now returns the clock's time. Its method value fits registry.Client.Now.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+// Advance moves the clock forward by d and returns the new time.
+func (c *Clock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+	return c.t
+}
+
+// Registry is a published registry held in memory. It serves its index
+// and the archives of the entries added with Add over an http.RoundTripper,
+// with the ETag and Last-Modified validators a registry.Client revalidates
+// with. It is safe for concurrent use.
+type Registry struct {
+	// Clock stamps the entries added and the index's Last-Modified.
+	Clock *Clock
+
+	mu       sync.Mutex
+	db       registry.Database
+	archives map[string][]byte // by URL path
+	modified time.Time
+	fail     int
+	requests int
+}
+
+// New returns an empty registry whose clock starts at Epoch.
+func New() *Registry {
+	return &Registry{
+		Clock:    NewClock(Epoch),
+		db:       registry.Database{SchemaVersion: registry.CurrentSchema, Blueprints: []registry.Blueprint{}},
+		archives: map[string][]byte{},
+		modified: Epoch,
+	}
+}
+
+// Seed replaces the registry with db, as loaded from a registry file.
+// Entries keep their download URLs, so archives they name that weren't
+// added with Add are not found.
+func (r *Registry) Seed(db registry.Database) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.db = db.Clone()
+	r.modified = r.Clock.Now()
+}
+
+// Add publishes bp with an archive of files (path to contents), filling in
+// what the updater would: the default namespace, the archive's digest and
+// download URL, and the creation and update times. Adding an entry that
+// exists publishes a new release of it, as registry.Upsert does. It
+// returns the entry as published.
+func (r *Registry) Add(bp registry.Blueprint, files map[string]string) registry.Blueprint {
+	if bp.Namespace == "" {
+		bp.Namespace = registry.DefaultNamespace
+	}
+	b := Archive(files)
+	sum := sha256.Sum256(b)
+	p := fmt.Sprintf("/archives/%s/%s-%s.zip", bp.Namespace, bp.Name, bp.Version)
+	bp.SHA256 = hex.EncodeToString(sum[:])
+	bp.DownloadURL = URL + p
+	now := r.Clock.Now()
+	bp.CreatedAt, bp.UpdatedAt = now, now
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	registry.Upsert(&r.db, bp)
+	r.archives[p] = b
+	r.modified = now
+	published, _ := r.db.Lookup(bp.Namespace, bp.Name)
+	return published
+}
+
+// Remove deletes the entry named ref ("namespace/name"), leaving a
+// tombstone as the remove command does.
+func (r *Registry) Remove(ref, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.Clock.Now()
+	if _, err := registry.Remove(&r.db, ref, reason, now); err != nil {
+		return err
+	}
+	r.modified = now
+	return nil
+}
+
+// Database returns a copy of the registry as published.
+func (r *Registry) Database() registry.Database {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return registry.Normalize(r.db)
+}
+
+// Fail makes every request answer with status code, to test how clients
+// cope with an outage; Fail(0) restores service.
+func (r *Registry) Fail(code int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fail = code
+}
+
+// Requests reports how many requests the registry has answered.
+func (r *Registry) Requests() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests
+}
+
+// RoundTrip answers requests for URL: the index at /registry.<ext> in the
+// encoding the extension names, and the archives of added entries. It
+// implements http.RoundTripper.
+func (r *Registry) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if req.URL.Scheme+"://"+req.URL.Host != URL {
+		return nil, fmt.Errorf("registrytest: no route to %s", req.URL.Host)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+	if r.fail != 0 {
+		return respond(req, r.fail, nil, nil), nil
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return respond(req, http.StatusMethodNotAllowed, nil, nil), nil
+	}
+	p := req.URL.Path
+	if b, ok := r.archives[p]; ok {
+		return respond(req, http.StatusOK, http.Header{"Content-Type": {"application/zip"}}, b), nil
+	}
+	if !strings.HasPrefix(p, "/registry.") {
+		return respond(req, http.StatusNotFound, nil, nil), nil
+	}
+	body, err := registry.Encode(registry.Normalize(r.db), registry.FormatOf(p), false)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	h := http.Header{
+		"Etag":          {`"` + hex.EncodeToString(sum[:8]) + `"`},
+		"Last-Modified": {r.modified.Format(http.TimeFormat)},
+	}
+	if slices.Contains(req.Header.Values("If-None-Match"), h.Get("Etag")) {
+		return respond(req, http.StatusNotModified, h, nil), nil
+	}
+	return respond(req, http.StatusOK, h, body), nil
+}
+
+// HTTPClient returns an HTTP client whose requests r answers.
+func (r *Registry) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Client returns a registry client of r's JSON index on r's clock,
+// caching in cacheDir, e.g. t.TempDir(); empty disables the cache.
+func (r *Registry) Client(cacheDir string) *registry.Client {
+	return &registry.Client{
+		IndexURL: URL + "/registry.json",
+		CacheDir: cacheDir,
+		HTTP:     r.HTTPClient(),
+		Now:      r.Clock.Now,
+	}
+}
+
+// Archive builds a zip of files (path to contents). The same files always
+// make the same bytes, so digests are stable across test runs.
+func Archive(files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range sortedKeys(files) {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: Epoch})
+		if err != nil {
+			panic(err) // only for invalid names
+		}
+		io.WriteString(w, files[name])
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+// respond builds the response to req; HEAD requests get no body.
+func respond(req *http.Request, code int, h http.Header, body []byte) *http.Response {
+	if h == nil {
+		h = http.Header{}
+	}
+	if req.Method == http.MethodHead {
+		body = nil
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
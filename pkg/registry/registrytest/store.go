@@ -0,0 +1,114 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registrytest
+
+import (
+	"sync"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// Store is a registry.Store held in memory. It keeps the registry as the
+// bytes a registry.FileStore would write, so what is loaded back is what
+// the file store would load: normalized, migrated and ordered by name. It
+// is safe for concurrent use.
+type Store struct {
+	mu    sync.Mutex
+	b     []byte // nil until saved, like a missing file
+	err   error
+	saves int
+}
+
+var _ registry.Store = (*Store)(nil)
+
+// NewStore returns a store holding bps, or an empty one, as if never
+// saved, when there are none. Entries without a namespace get the default
+// one.
+func NewStore(bps ...registry.Blueprint) *Store {
+	s := &Store{}
+	if len(bps) > 0 {
+		s.Seed(registry.Database{SchemaVersion: registry.CurrentSchema, Blueprints: bps})
+	}
+	return s
+}
+
+// Seed replaces what the store holds with db, without counting a save.
+func (s *Store) Seed(db registry.Database) {
+	db = db.Clone()
+	for i := range db.Blueprints {
+		if db.Blueprints[i].Namespace == "" {
+			db.Blueprints[i].Namespace = registry.DefaultNamespace
+		}
+	}
+	b, err := encode(db)
+	if err != nil {
+		panic(err) // only for values JSON can't hold
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.b = b
+}
+
+// Load decodes the registry last saved or seeded.
+func (s *Store) Load() (registry.Database, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return registry.Database{}, s.err
+	}
+	if s.b == nil {
+		return registry.Database{SchemaVersion: registry.CurrentSchema, Blueprints: []registry.Blueprint{}}, nil
+	}
+	return registry.Decode(s.b, registry.FormatJSON)
+}
+
+// Save replaces the registry with db.
+func (s *Store) Save(db registry.Database) error {
+	b, err := encode(db)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.b = b
+	s.saves++
+	return nil
+}
+
+// Fail makes Load and Save return err, to test how callers cope with a
+// store they can't read or write; Fail(nil) restores it.
+func (s *Store) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Saves reports how many times Save succeeded.
+func (s *Store) Saves() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saves
+}
+
+// Bytes returns the registry as a FileStore would have written it, or
+// nil before anything is saved.
+func (s *Store) Bytes() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.b...)
+}
+
+func encode(db registry.Database) ([]byte, error) {
+	return registry.Encode(registry.Normalize(db), registry.FormatJSON, false)
+}
@@ -0,0 +1,171 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registrytest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// stores returns a file store and a memory store, both empty.
+func stores(t *testing.T) map[string]registry.Store {
+	return map[string]registry.Store{
+		"file":   registry.FileStore{Path: filepath.Join(t.TempDir(), "registry.json")},
+		"memory": NewStore(),
+	}
+}
+
+func storeEntries() []registry.Blueprint {
+	return []registry.Blueprint{
+		{Namespace: "zeta", Name: "api", Version: "1.0.0", Description: "z", DownloadURL: URL + "/z.zip"},
+		{Namespace: registry.DefaultNamespace, Name: "web", Version: "2.0.0", Description: "w", Tags: []string{"http"}, DownloadURL: URL + "/w.zip"},
+		{Namespace: "acme", Name: "cli", Version: "0.1.0", Description: "c", DownloadURL: URL + "/c.zip",
+			Previous: []registry.Release{{Version: "0.0.1"}, {Version: "0.0.9"}}},
+	}
+}
+
+// TestStoreLikeFile runs the same steps on both stores and expects the
+// same results.
+func TestStoreLikeFile(t *testing.T) {
+	steps := []struct {
+		name string
+		run  func(t *testing.T, s registry.Store) any
+	}{
+		{"empty", func(t *testing.T, s registry.Store) any {
+			db, err := s.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if db.Blueprints == nil || len(db.Blueprints) != 0 || db.SchemaVersion != registry.CurrentSchema {
+				t.Errorf("empty store loaded %+v", db)
+			}
+			return db
+		}},
+		{"round trip", func(t *testing.T, s registry.Store) any {
+			in := registry.Database{SchemaVersion: registry.CurrentSchema, Blueprints: storeEntries()}
+			if err := s.Save(in); err != nil {
+				t.Fatal(err)
+			}
+			out, err := s.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(out, registry.Normalize(in)) {
+				t.Errorf("loaded %+v\nwant %+v", out, registry.Normalize(in))
+			}
+			return out
+		}},
+		{"missing entry", func(t *testing.T, s registry.Store) any {
+			s.Save(registry.Database{SchemaVersion: registry.CurrentSchema, Blueprints: storeEntries()})
+			db, err := s.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := db.Lookup("acme", "missing"); ok {
+				t.Error("found acme/missing")
+			}
+			_, err = registry.Find(db, "missing")
+			if !errors.Is(err, registry.ErrNotFound) {
+				t.Errorf("Find(missing) = %v, want ErrNotFound", err)
+			}
+			return err.Error()
+		}},
+		{"ordering", func(t *testing.T, s registry.Store) any {
+			s.Save(registry.Database{SchemaVersion: registry.CurrentSchema, Blueprints: storeEntries()})
+			db, err := s.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, bp := range db.Blueprints {
+				names = append(names, bp.FullName())
+			}
+			want := []string{"acme/cli", registry.DefaultNamespace + "/web", "zeta/api"}
+			if !reflect.DeepEqual(names, want) {
+				t.Errorf("order %v, want %v", names, want)
+			}
+			cli, _ := db.Lookup("acme", "cli")
+			if cli.Previous[0].Version != "0.0.9" {
+				t.Errorf("previous releases %+v, want newest first", cli.Previous)
+			}
+			return names
+		}},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			got := map[string]any{}
+			for name, s := range stores(t) {
+				got[name] = step.run(t, s)
+			}
+			if !reflect.DeepEqual(got["file"], got["memory"]) {
+				t.Errorf("file store: %+v\nmemory store: %+v", got["file"], got["memory"])
+			}
+		})
+	}
+}
+
+func TestStoreBytesMatchFile(t *testing.T) {
+	db := registry.Database{SchemaVersion: registry.CurrentSchema, Blueprints: storeEntries()}
+	fs := registry.FileStore{Path: filepath.Join(t.TempDir(), "registry.json")}
+	if err := fs.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(fs.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := NewStore()
+	if ms.Bytes() != nil {
+		t.Error("bytes before any save")
+	}
+	if err := ms.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ms.Bytes(), want) {
+		t.Errorf("memory store holds\n%s\nfile store wrote\n%s", ms.Bytes(), want)
+	}
+	if ms.Saves() != 1 {
+		t.Errorf("Saves() = %d, want 1", ms.Saves())
+	}
+}
+
+func TestStoreSeedAndFail(t *testing.T) {
+	s := NewStore(registry.Blueprint{Name: "web", Version: "1.0.0", DownloadURL: URL + "/w.zip"})
+	db, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.Lookup(registry.DefaultNamespace, "web"); !ok {
+		t.Errorf("seeded entry not found in %+v", db.Blueprints)
+	}
+	if s.Saves() != 0 {
+		t.Errorf("seeding counted %d saves", s.Saves())
+	}
+
+	boom := errors.New("disk full")
+	s.Fail(boom)
+	if err := s.Save(db); !errors.Is(err, boom) {
+		t.Errorf("Save = %v, want %v", err, boom)
+	}
+	if _, err := s.Load(); !errors.Is(err, boom) {
+		t.Errorf("Load = %v, want %v", err, boom)
+	}
+	s.Fail(nil)
+	if _, err := s.Load(); err != nil {
+		t.Errorf("Load after Fail(nil) = %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schema is the JSON Schema (draft 2020-12) of the registry's JSON
+// encoding, for consumers to validate against.
+//
+//go:embed registry.schema.json
+var Schema []byte
+
+var schemaRoot = sync.OnceValue(func() map[string]any {
+	var m map[string]any
+	if err := json.Unmarshal(Schema, &m); err != nil {
+		panic("registry.schema.json: " + err.Error())
+	}
+	return m
+})
+
+// SchemaError is a place where a document breaks the Schema.
+type SchemaError struct {
+	// Path is a JSON pointer to the offending value, e.g.
+	// "/blueprints/0/version".
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	return cmp.Or(e.Path, "/") + ": " + e.Message
+}
+
+// ValidateSchema checks a registry in its JSON encoding against the
+// Schema. The error is for a document that isn't JSON at all.
+func ValidateSchema(doc []byte) ([]SchemaError, error) {
+	var v any
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return nil, err
+	}
+	var sv schemaValidator
+	sv.check(schemaRoot(), v, "")
+	return sv.errs, nil
+}
+
+// ValidateBlueprint checks one entry against the Schema's definition of
+// an entry.
+func ValidateBlueprint(bp Blueprint) []SchemaError {
+	b, err := json.Marshal(bp)
+	if err != nil {
+		return []SchemaError{{Message: err.Error()}}
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return []SchemaError{{Message: err.Error()}}
+	}
+	var sv schemaValidator
+	sv.check(map[string]any{"$ref": "#/$defs/blueprint"}, v, "")
+	return sv.errs
+}
+
+// schemaValidator implements the part of JSON Schema that the Schema
+// uses: $ref within the document, type, enum, required, properties,
+// additionalProperties, items, pattern, format (uri and date-time),
+// minimum and maximum.
+type schemaValidator struct {
+	errs []SchemaError
+}
+
+var schemaPatterns sync.Map // pattern -> *regexp.Regexp
+
+func (sv *schemaValidator) fail(at, format string, args ...any) {
+	sv.errs = append(sv.errs, SchemaError{Path: at, Message: fmt.Sprintf(format, args...)})
+}
+
+func (sv *schemaValidator) check(s map[string]any, v any, at string) {
+	if ref, ok := s["$ref"].(string); ok {
+		def, ok := resolveSchemaRef(ref)
+		if !ok {
+			sv.fail(at, "schema reference %q not found", ref)
+			return
+		}
+		sv.check(def, v, at)
+	}
+	if t, ok := s["type"]; ok && !hasSchemaType(t, v) {
+		sv.fail(at, "is %s, want %s", jsonType(v), schemaTypes(t))
+		return
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		// enums list scalars; maps and slices aren't comparable
+		if t := jsonType(v); t == "object" || t == "array" || !slices.Contains(enum, v) {
+			sv.fail(at, "%v is not one of %v", v, enum)
+		}
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		sv.checkObject(s, v, at)
+	case []any:
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, e := range v {
+				sv.check(items, e, fmt.Sprintf("%s/%d", at, i))
+			}
+		}
+	case string:
+		sv.checkString(s, v, at)
+	case float64:
+		if min, ok := s["minimum"].(float64); ok && v < min {
+			sv.fail(at, "%v is less than %v", v, min)
+		}
+		if max, ok := s["maximum"].(float64); ok && v > max {
+			sv.fail(at, "%v is greater than %v", v, max)
+		}
+	}
+}
+
+func (sv *schemaValidator) checkObject(s map[string]any, v map[string]any, at string) {
+	required, _ := s["required"].([]any)
+	for _, r := range required {
+		if _, ok := v[r.(string)]; !ok {
+			sv.fail(at, "missing required field %q", r)
+		}
+	}
+	props, _ := s["properties"].(map[string]any)
+	extra, _ := s["additionalProperties"].(map[string]any)
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		p := at + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(k)
+		if ps, ok := props[k].(map[string]any); ok {
+			sv.check(ps, v[k], p)
+		} else if extra != nil {
+			sv.check(extra, v[k], p)
+		}
+	}
+}
+
+func (sv *schemaValidator) checkString(s map[string]any, v string, at string) {
+	if pat, ok := s["pattern"].(string); ok {
+		re, ok := schemaPatterns.Load(pat)
+		if !ok {
+			re, _ = schemaPatterns.LoadOrStore(pat, regexp.MustCompile(pat))
+		}
+		if !re.(*regexp.Regexp).MatchString(v) {
+			sv.fail(at, "%q does not match %s", v, pat)
+			return // one problem per value is enough
+		}
+	}
+	switch s["format"] {
+	case "uri":
+		if u, err := url.Parse(v); err != nil || u.Scheme == "" || u.Host == "" {
+			sv.fail(at, "%q is not an absolute URL", v)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			sv.fail(at, "%q is not an RFC 3339 timestamp", v)
+		}
+	}
+}
+
+// resolveSchemaRef finds a "#/$defs/name" definition.
+func resolveSchemaRef(ref string) (map[string]any, bool) {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return nil, false
+	}
+	defs, _ := schemaRoot()["$defs"].(map[string]any)
+	def, ok := defs[name].(map[string]any)
+	return def, ok
+}
+
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+func schemaTypes(t any) string {
+	if ts, ok := t.([]any); ok {
+		var names []string
+		for _, n := range ts {
+			names = append(names, n.(string))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func hasSchemaType(t any, v any) bool {
+	have := jsonType(v)
+	for _, want := range strings.Split(schemaTypes(t), " or ") {
+		if want == have || (want == "number" && have == "integer") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Semver is a parsed MAJOR.MINOR.PATCH[-PRERELEASE] version. Build
+// metadata is ignored, as it is for precedence.
+type Semver struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+// ParseSemver parses a version, tolerating a leading "v" and missing
+// minor or patch parts ("1.2" is 1.2.0).
+func ParseSemver(s string) (Semver, error) {
+	var v Semver
+	s = strings.TrimPrefix(s, "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, v.Pre, _ = strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 || s == "" {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+func (v Semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare orders versions by semver precedence.
+func (v Semver) Compare(o Semver) int {
+	if c := cmp.Compare(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+	a, b := strings.Split(v.Pre, "."), strings.Split(o.Pre, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		an, aerr := strconv.Atoi(a[i])
+		bn, berr := strconv.Atoi(b[i])
+		var c int
+		switch {
+		case aerr == nil && berr == nil:
+			c = cmp.Compare(an, bn)
+		case aerr == nil:
+			c = -1 // numeric identifiers sort first
+		case berr == nil:
+			c = 1
+		default:
+			c = strings.Compare(a[i], b[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// VersionMatches reports whether version satisfies constraint, a
+// space-separated list of comparisons that must all hold: "1.2.3",
+// "=1.2.3", ">=1.2", "<2", "^1.2" (same major), "~1.2" (same minor).
+// An empty constraint or "*" matches anything.
+func VersionMatches(constraint, version string) (bool, error) {
+	for _, term := range strings.Fields(constraint) {
+		if term == "*" {
+			continue
+		}
+		op := term[:len(term)-len(strings.TrimLeft(term, "<>=^~"))]
+		want, err := ParseSemver(term[len(op):])
+		if err != nil {
+			return false, fmt.Errorf("constraint %q: %w", constraint, err)
+		}
+		v, err := ParseSemver(version)
+		if err != nil {
+			return false, err
+		}
+		c := v.Compare(want)
+		var ok bool
+		switch op {
+		case "", "=":
+			ok = c == 0
+		case ">":
+			ok = c > 0
+		case ">=":
+			ok = c >= 0
+		case "<":
+			ok = c < 0
+		case "<=":
+			ok = c <= 0
+		case "^":
+			ok = c >= 0 && v.Major == want.Major
+			if want.Major == 0 {
+				ok = ok && v.Minor == want.Minor
+			}
+		case "~":
+			ok = c >= 0 && v.Major == want.Major && v.Minor == want.Minor
+		default:
+			return false, fmt.Errorf("constraint %q: unknown operator %q", constraint, op)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+// Store is where a registry is kept between runs. A store that has never
+// been saved to loads as an empty registry.
+type Store interface {
+	Load() (Database, error)
+	Save(db Database) error
+}
+
+// FileStore keeps a registry in a file, with Load and Save.
+type FileStore struct {
+	Path    string
+	Options SaveOptions
+}
+
+// Load reads the registry file.
+func (s FileStore) Load() (Database, error) {
+	return Load(s.Path)
+}
+
+// Save replaces the registry file with db.
+func (s FileStore) Save(db Database) error {
+	return Save(s.Path, db, s.Options)
+}
@@ -0,0 +1,274 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// Limits for release archives. Declared sizes in zip headers are checked up
+// front; archive/zip additionally fails reads that run past them.
+const (
+	maxArchiveBytes    = 100 << 20
+	maxArchiveEntries  = 10000
+	maxArchiveUnpacked = 512 << 20
+	maxArchiveRatio    = 200
+)
+
+// archiveInfo describes the contents of a blueprint archive.
+type archiveInfo struct {
+	zr *zip.Reader
+	// Root is the single top-level directory all files live under, if any.
+	Root string
+	// Files are the regular files, slash-separated and relative to Root.
+	Files []string
+	// Manifest is the manifest file name found at the root, if any.
+	Manifest string
+}
+
+// inspectArchive validates a zip archive's layout and lists its files.
+// Entries with absolute or parent-relative paths are rejected outright,
+// since a client extracting them would write outside its target directory.
+func inspectArchive(ctx context.Context, r io.ReaderAt, size int64) (*archiveInfo, error) {
+	if size > maxArchiveBytes {
+		return nil, fmt.Errorf("archive exceeds %d bytes", maxArchiveBytes)
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if len(zr.File) > maxArchiveEntries {
+		return nil, fmt.Errorf("archive has more than %d entries", maxArchiveEntries)
+	}
+
+	info := &archiveInfo{zr: zr}
+	var total uint64
+	var names []string
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := checkArchivePath(f.Name); err != nil {
+			return nil, err
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		total += f.UncompressedSize64
+		if total > maxArchiveUnpacked {
+			return nil, fmt.Errorf("archive unpacks to more than %d bytes", maxArchiveUnpacked)
+		}
+		if f.UncompressedSize64 > 1<<20 && f.UncompressedSize64/max(f.CompressedSize64, 1) > maxArchiveRatio {
+			return nil, fmt.Errorf("%s: suspicious compression ratio", f.Name)
+		}
+		names = append(names, f.Name)
+	}
+
+	// blueprint zips are usually built from a single directory
+	root := ""
+	if len(names) > 0 {
+		if first, _, ok := strings.Cut(names[0], "/"); ok {
+			root = first + "/"
+			for _, n := range names {
+				if !strings.HasPrefix(n, root) {
+					root = ""
+					break
+				}
+			}
+		}
+	}
+	info.Root = strings.TrimSuffix(root, "/")
+	for _, n := range names {
+		rel := strings.TrimPrefix(n, root)
+		info.Files = append(info.Files, rel)
+		if info.Manifest == "" {
+			for _, m := range registry.ManifestFiles {
+				if rel == m {
+					info.Manifest = m
+				}
+			}
+		}
+	}
+	return info, nil
+}
+
+func checkArchivePath(name string) error {
+	if name == "" || strings.Contains(name, "\\") || strings.HasPrefix(name, "/") || strings.Contains(name, ":") {
+		return fmt.Errorf("%q: unsafe path in archive", name)
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return fmt.Errorf("%q: unsafe path in archive", name)
+		}
+	}
+	return nil
+}
+
+// readFile returns the contents of a file in the archive, relative to Root,
+// refusing to read more than limit bytes.
+func (a *archiveInfo) readFile(rel string, limit int64) ([]byte, error) {
+	name := rel
+	if a.Root != "" {
+		name = path.Join(a.Root, rel)
+	}
+	for _, f := range a.zr.File {
+		if f.Name != name {
+			continue
+		}
+		if f.UncompressedSize64 > uint64(limit) {
+			return nil, fmt.Errorf("%s exceeds %d bytes", rel, limit)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		b, err := io.ReadAll(io.LimitReader(rc, limit+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(b)) > limit {
+			return nil, fmt.Errorf("%s exceeds %d bytes", rel, limit)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("%s: %w", rel, os.ErrNotExist)
+}
+
+// manifest parses the archive's manifest, rejecting unknown fields if
+// strict.
+func (a *archiveInfo) manifest(strict bool) (registry.Manifest, error) {
+	if a.Manifest == "" {
+		return registry.Manifest{}, registry.ErrNoManifest
+	}
+	b, err := a.readFile(a.Manifest, registry.MaxManifestBytes)
+	if err != nil {
+		return registry.Manifest{}, err
+	}
+	if strict {
+		return registry.ParseManifestStrict(a.Manifest, b)
+	}
+	return registry.ParseManifest(a.Manifest, b)
+}
+
+// downloadArchive streams a release asset into a temp file, capped at
+// maxArchiveBytes. The caller must close and remove the file.
+func downloadArchive(ctx context.Context, url string) (*os.File, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	resp, err := defaultClient.do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return nil, 0, &registry.StatusError{URL: url, Code: resp.StatusCode, Body: string(b), RequestID: responseRequestID(resp)}
+	}
+	if resp.ContentLength > maxArchiveBytes {
+		return nil, 0, fmt.Errorf("%s: archive exceeds %d bytes", url, maxArchiveBytes)
+	}
+
+	f, err := os.CreateTemp("", "dragon-asset-*.zip")
+	if err != nil {
+		return nil, 0, err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxArchiveBytes+1))
+	if err == nil && n > maxArchiveBytes {
+		err = fmt.Errorf("%s: archive exceeds %d bytes", url, maxArchiveBytes)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, 0, err
+	}
+	return f, n, nil
+}
+
+// assetScan holds what we learn from looking inside a release archive.
+type assetScan struct {
+	Manifest    registry.Manifest
+	ManifestErr error
+	// License is the SPDX id detected from a LICENSE file at the root.
+	License string
+	// Files lists the archive's files relative to its root.
+	Files []string
+	// SHA256 is the hex digest of the archive.
+	SHA256 string
+	// Digests are its digests in the other configured algorithms.
+	Digests registry.Digests
+	// Readme is the README at the root, if any.
+	Readme []byte
+	// TemplateRefs maps variables referenced by templates to the files
+	// using them; TemplateErrors lists templates that failed to parse.
+	TemplateRefs   map[string][]string
+	TemplateErrors []string
+}
+
+// scanAsset downloads a release asset and inspects its contents, hashing
+// it with SHA-256 and the algorithms in algs.
+func scanAsset(ctx context.Context, url string, tmpl templateRules, strict bool, algs []string) (*assetScan, error) {
+	f, n, err := downloadArchive(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	info, err := inspectArchive(ctx, f, n)
+	if err != nil {
+		return nil, err
+	}
+
+	scan := &assetScan{Files: info.Files}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if scan.Digests, err = fileDigests(f, append([]string{registry.DigestSHA256}, algs...)...); err != nil {
+		return nil, err
+	}
+	scan.SHA256 = scan.Digests[registry.DigestSHA256]
+	delete(scan.Digests, registry.DigestSHA256)
+	scan.Manifest, scan.ManifestErr = info.manifest(strict)
+	if errors.Is(scan.ManifestErr, os.ErrNotExist) {
+		scan.ManifestErr = registry.ErrNoManifest
+	}
+	for _, name := range licenseFiles {
+		b, err := info.readFile(name, maxLicenseBytes)
+		if err == nil {
+			scan.License = detectLicense(string(b))
+			break
+		}
+	}
+	for _, name := range readmeFiles {
+		if b, err := info.readFile(name, maxReadmeBytes); err == nil {
+			scan.Readme = b
+			break
+		}
+	}
+	if tmpl.Lint != lintOff {
+		scan.TemplateRefs, scan.TemplateErrors = scanTemplates(info, tmpl)
+	}
+	return scan, nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// assetRules decide which release assets are blueprints.
+type assetRules struct {
+	// Include lists glob patterns (path.Match syntax) an asset name must
+	// match one of.
+	Include []string `yaml:"include"`
+	// Exclude lists glob patterns that rule an asset out, e.g.
+	// "*-sources.zip".
+	Exclude []string `yaml:"exclude"`
+	// Extensions are stripped from the asset name to get the blueprint
+	// name. When a release has the same blueprint in several formats, the
+	// earliest extension in this list wins.
+	Extensions []string `yaml:"extensions"`
+	// Naming is the convention asset names follow.
+	Naming assetNaming `yaml:"naming"`
+}
+
+// assetNaming is a naming convention for blueprint assets, such as
+// <name>-<version>.zip.
+type assetNaming struct {
+	// Pattern is the asset name without its extension, with a {name} and
+	// optionally a {version} placeholder, e.g. "{name}-{version}". Empty
+	// means the whole name is the blueprint name.
+	Pattern string `yaml:"pattern"`
+	// Check is off, warn (the default) or error: what to do with assets
+	// that don't follow the pattern or whose version disagrees with the
+	// tag or manifest.
+	Check string `yaml:"check"`
+}
+
+func (n assetNaming) check() string {
+	if n.Check == "" {
+		return lintWarn
+	}
+	return n.Check
+}
+
+// regexp compiles the pattern. Versions start with a digit, optionally
+// after a "v", so names may contain dashes.
+func (n assetNaming) regexp() (*regexp.Regexp, error) {
+	if strings.Count(n.Pattern, "{name}") != 1 {
+		return nil, errors.New("pattern needs one {name}")
+	}
+	if strings.Count(n.Pattern, "{version}") > 1 {
+		return nil, errors.New("pattern has more than one {version}")
+	}
+	expr := regexp.QuoteMeta(n.Pattern)
+	expr = strings.Replace(expr, regexp.QuoteMeta("{name}"), `(?P<name>.+?)`, 1)
+	expr = strings.Replace(expr, regexp.QuoteMeta("{version}"), `(?P<version>v?[0-9][0-9A-Za-z.+-]*)`, 1)
+	return regexp.Compile("^" + expr + "$")
+}
+
+// crossCheck compares the version in the asset name with the manifest's
+// and the tag's.
+func (n assetNaming) crossCheck(sel selectedAsset, manifestVersion, tag string) []string {
+	if sel.Version == "" {
+		return nil
+	}
+	var problems []string
+	v := strings.TrimPrefix(sel.Version, "v")
+	if mv := strings.TrimPrefix(manifestVersion, "v"); mv != "" && mv != v {
+		problems = append(problems, fmt.Sprintf("asset name says version %s, manifest says %s", v, mv))
+	}
+	if tv := strings.TrimPrefix(tag, "v"); tv != v {
+		problems = append(problems, fmt.Sprintf("asset name says version %s, tag is %s", v, tag))
+	}
+	return problems
+}
+
+func (r assetRules) validate() error {
+	if len(r.Include) == 0 {
+		return errors.New("assets.include must list at least one pattern")
+	}
+	if len(r.Extensions) == 0 {
+		return errors.New("assets.extensions must list at least one extension")
+	}
+	for _, p := range append(append([]string{}, r.Include...), r.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("assets: bad pattern %q: %w", p, err)
+		}
+	}
+	if r.Naming.Pattern != "" {
+		if _, err := r.Naming.regexp(); err != nil {
+			return fmt.Errorf("assets.naming: %w", err)
+		}
+	}
+	if err := (templateRules{Lint: r.Naming.check()}).validate(); err != nil {
+		return fmt.Errorf("assets.naming.check: want off, warn or error, got %q", r.Naming.Check)
+	}
+	return nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// blueprintName returns the asset name without its configured extension
+// and that extension's rank, or ok=false if none applies.
+func (r assetRules) blueprintName(asset string) (name string, rank int, ok bool) {
+	best := -1
+	for i, ext := range r.Extensions {
+		// prefer the longest match so ".tar.gz" beats ".gz"
+		if strings.HasSuffix(asset, ext) && len(asset) > len(ext) && (best < 0 || len(ext) > len(r.Extensions[best])) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", 0, false
+	}
+	return strings.TrimSuffix(asset, r.Extensions[best]), best, true
+}
+
+// selectedAsset is a release asset chosen as a blueprint.
+type selectedAsset struct {
+	Name    string // blueprint name derived from the asset
+	Version string // version in the asset name, if the naming has one
+	Asset   registry.GitHubAsset
+	// Nonconforming is set when the name doesn't follow assets.naming.
+	Nonconforming bool
+}
+
+// selectAssets applies the rules to a release's assets, returning one
+// asset per blueprint in release order.
+func (r assetRules) selectAssets(assets []registry.GitHubAsset) []selectedAsset {
+	type pick struct {
+		sel  selectedAsset
+		rank int
+		pos  int
+	}
+	var naming *regexp.Regexp
+	if r.Naming.Pattern != "" {
+		naming, _ = r.Naming.regexp() // checked by validate
+	}
+	byName := map[string]pick{}
+	for i, a := range assets {
+		if !matchAny(r.Include, a.Name) || matchAny(r.Exclude, a.Name) {
+			continue
+		}
+		stem, rank, ok := r.blueprintName(a.Name)
+		if !ok {
+			continue
+		}
+		sel := selectedAsset{Name: stem, Asset: a}
+		if naming != nil {
+			if m := naming.FindStringSubmatch(stem); m != nil {
+				sel.Name = m[naming.SubexpIndex("name")]
+				if vi := naming.SubexpIndex("version"); vi >= 0 {
+					sel.Version = m[vi]
+				}
+			} else {
+				sel.Nonconforming = true
+			}
+		}
+		if prev, seen := byName[sel.Name]; seen && prev.rank <= rank {
+			continue
+		}
+		byName[sel.Name] = pick{sel, rank, i}
+	}
+	picks := make([]pick, 0, len(byName))
+	for _, p := range byName {
+		picks = append(picks, p)
+	}
+	sort.Slice(picks, func(i, j int) bool { return picks[i].pos < picks[j].pos })
+	out := make([]selectedAsset, len(picks))
+	for i, p := range picks {
+		out[i] = p.sel
+	}
+	return out
+}
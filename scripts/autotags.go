@@ -0,0 +1,119 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// autoTagPrefix marks tags derived from archive contents, as opposed to
+// those the author wrote in the manifest.
+const autoTagPrefix = "auto:"
+
+// tagsConfig configures tag handling.
+type tagsConfig struct {
+	// Auto inspects each archive and appends derived "auto:" tags.
+	Auto bool `yaml:"auto"`
+}
+
+// languageExts maps source extensions to language tags. Template
+// extensions are stripped first, so main.go.tmpl counts as Go.
+var languageExts = map[string]string{
+	".go":    "go",
+	".rs":    "rust",
+	".py":    "python",
+	".js":    "javascript",
+	".mjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  "java",
+	".kt":    "kotlin",
+	".rb":    "ruby",
+	".php":   "php",
+	".cs":    "csharp",
+	".swift": "swift",
+	".ex":    "elixir",
+	".tf":    "terraform",
+	".proto": "protobuf",
+}
+
+// markerFiles map file base names to the tag their presence implies.
+var markerFiles = map[string]string{
+	"Dockerfile":          "docker",
+	"docker-compose.yml":  "compose",
+	"docker-compose.yaml": "compose",
+	"compose.yml":         "compose",
+	"compose.yaml":        "compose",
+	"go.mod":              "go",
+	"Cargo.toml":          "rust",
+	"package.json":        "node",
+	"pyproject.toml":      "python",
+	"requirements.txt":    "python",
+	"pom.xml":             "maven",
+	"build.gradle":        "gradle",
+	"build.gradle.kts":    "gradle",
+	"Chart.yaml":          "helm",
+	"kustomization.yaml":  "kustomize",
+	"Makefile":            "make",
+}
+
+// deriveTags returns the auto tags implied by an archive's file list.
+func deriveTags(files []string, tmpl templateRules) []string {
+	set := map[string]bool{}
+	for _, f := range files {
+		for _, ext := range tmpl.Extensions {
+			f = strings.TrimSuffix(f, ext)
+		}
+		base := path.Base(f)
+		if t, ok := markerFiles[base]; ok {
+			set[t] = true
+		}
+		if strings.HasPrefix(base, "Dockerfile.") {
+			set["docker"] = true
+		}
+		if strings.HasPrefix(f, ".github/workflows/") {
+			set["github-actions"] = true
+		}
+		if f == ".gitlab-ci.yml" {
+			set["gitlab-ci"] = true
+		}
+		if t, ok := languageExts[path.Ext(base)]; ok {
+			set[t] = true
+		}
+	}
+	tags := make([]string, 0, len(set))
+	for t := range set {
+		tags = append(tags, autoTagPrefix+t)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// mergeAutoTags appends auto tags to the author's tags. Authors cannot
+// write auto: tags themselves, and an auto tag the author already gave
+// plainly is redundant.
+func mergeAutoTags(tags, auto []string) []string {
+	var out []string
+	for _, t := range tags {
+		if !strings.HasPrefix(t, autoTagPrefix) {
+			out = append(out, t)
+		}
+	}
+	for _, t := range auto {
+		if !slices.Contains(out, strings.TrimPrefix(t, autoTagPrefix)) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
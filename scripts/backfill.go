@@ -0,0 +1,152 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// releaseHistory collects every release each entry had across the
+// revisions of the registry, keeping the first record of each.
+type releaseHistory struct {
+	seen map[string]map[string]registry.Release
+	// changed lists the releases whose archive changed between revisions.
+	changed map[string]bool
+}
+
+func newReleaseHistory() *releaseHistory {
+	return &releaseHistory{seen: map[string]map[string]registry.Release{}, changed: map[string]bool{}}
+}
+
+// add records the releases of the entries of one revision. Releases
+// without a time take the revision's.
+func (h *releaseHistory) add(db registry.Database, when time.Time) {
+	for _, bp := range db.Blueprints {
+		name := bp.FullName()
+		if h.seen[name] == nil {
+			h.seen[name] = map[string]registry.Release{}
+		}
+		for _, rel := range bp.Versions() {
+			if rel.Version == "" || rel.DownloadURL == "" {
+				continue
+			}
+			if rel.ReleasedAt.IsZero() {
+				rel.ReleasedAt = when
+			}
+			first, ok := h.seen[name][rel.Version]
+			switch {
+			case !ok:
+				h.seen[name][rel.Version] = rel
+			case first.SHA256 != "" && rel.SHA256 != "" && first.SHA256 != rel.SHA256:
+				h.changed[name+" "+rel.Version] = true
+			}
+		}
+	}
+}
+
+// backfill adds the releases entries of db had but no longer list to
+// their previous releases, and returns them by entry. Entries removed
+// since are left alone.
+func (h *releaseHistory) backfill(db *registry.Database) map[string][]string {
+	added := map[string][]string{}
+	for i := range db.Blueprints {
+		bp := &db.Blueprints[i]
+		name := bp.FullName()
+		for version, rel := range h.seen[name] {
+			if _, known := bp.Release(version); known {
+				continue
+			}
+			bp.Previous = registry.AddRelease(bp.Previous, rel)
+			added[name] = append(added[name], version)
+		}
+		sort.Slice(added[name], func(a, b int) bool { return registry.OlderVersion(added[name][b], added[name][a]) })
+	}
+	return added
+}
+
+// runBackfillVersions walks the history of the registry and restores the
+// releases each entry had before previous releases were kept.
+func runBackfillVersions(args []string) error {
+	flags := flag.NewFlagSet("backfill-versions", flag.ExitOnError)
+	file := flags.String("file", registryPath(), "registry file whose git history to walk")
+	dir := flags.String("dir", "", "read the *.json snapshots in this directory instead")
+	dryRun := flags.Bool("dry-run", false, "list the releases found without writing")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	var revs []revision
+	if *dir != "" {
+		revs, err = dirRevisions(*dir)
+	} else {
+		revs, err = gitRevisions(*file)
+	}
+	if err != nil {
+		return err
+	}
+	if len(revs) == 0 {
+		return errors.New("no revisions of the registry found")
+	}
+
+	h := newReleaseHistory()
+	for _, rev := range revs {
+		db, err := registry.Decode(rev.Data, registry.FormatJSON)
+		if err != nil {
+			// a revision that no longer decodes has nothing to offer
+			fmt.Fprintf(os.Stderr, "%s: skipped: %v\n", rev.ID, err)
+			continue
+		}
+		when, _ := time.Parse(time.DateOnly, rev.When)
+		h.add(db, when)
+	}
+	for _, c := range slices.Sorted(maps.Keys(h.changed)) {
+		fmt.Fprintf(os.Stderr, "%s: archive changed over time; keeping the first one recorded\n", c)
+	}
+
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	preview := st.snapshot().Clone()
+	added := h.backfill(&preview)
+	if !*dryRun && len(added) > 0 {
+		err := st.update(func(db *registry.Database) error {
+			added = h.backfill(db)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	n := 0
+	for _, name := range slices.Sorted(maps.Keys(added)) {
+		fmt.Printf("%s: %s\n", name, strings.Join(added[name], ", "))
+		n += len(added[name])
+	}
+	verb := "restored"
+	if *dryRun {
+		verb = "would restore"
+	}
+	fmt.Fprintf(os.Stderr, "%s %d releases of %d entries from %d revisions\n", verb, n, len(added), len(revs))
+	return nil
+}
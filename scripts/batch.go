@@ -0,0 +1,153 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// releaseRef names one release of a source repo.
+type releaseRef struct {
+	Repo, Tag string
+}
+
+// updateAllReleases indexes every published release of repo, to
+// bootstrap or repair the registry in one run.
+func updateAllReleases(ctx context.Context, cfg config, repo string, dryRun bool) (changeSet, error) {
+	rels, err := repoReleases(ctx, repo)
+	if err != nil {
+		return newChangeSet(repo, ""), err
+	}
+	return updateReleases(ctx, cfg, rels, nil, dryRun)
+}
+
+// updateSources indexes the latest release of every repo in the sources
+// file, or with all every release of each. A source whose releases can't
+// be listed is reported and left out, and fails the run in the end.
+func updateSources(ctx context.Context, cfg config, all, dryRun bool) (changeSet, error) {
+	sf, err := loadSources(cfg.Discovery.sources())
+	if err != nil {
+		return newChangeSet("", ""), fmt.Errorf("sources: %w", err)
+	}
+	if len(sf.Sources) == 0 {
+		return newChangeSet("", ""), fmt.Errorf("%s lists no sources", cfg.Discovery.sources())
+	}
+	var rels []releaseRef
+	var failed []string
+	for _, s := range sf.Sources {
+		if all {
+			rs, err := repoReleases(ctx, s.Repo)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", s.Repo, err)
+				failed = append(failed, s.Repo)
+				continue
+			}
+			rels = append(rels, rs...)
+			continue
+		}
+		rel, err := latestRelease(ctx, s.Repo)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "%s: %v\n", s.Repo, err)
+			failed = append(failed, s.Repo)
+		case rel.TagName == "":
+			fmt.Fprintf(os.Stderr, "%s: no release yet\n", s.Repo)
+		default:
+			rels = append(rels, releaseRef{s.Repo, rel.TagName})
+		}
+	}
+	return updateReleases(ctx, cfg, rels, failed, dryRun)
+}
+
+// repoReleases lists repo's published releases, oldest first so that
+// each one's versions supersede the last.
+func repoReleases(ctx context.Context, repo string) ([]releaseRef, error) {
+	id, _, err := verifyRepo(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	rels, err := github().Releases(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list releases: %w", err)
+	}
+	rels = slices.DeleteFunc(rels, func(r registry.GitHubRelease) bool { return r.Draft })
+	slices.SortStableFunc(rels, func(a, b registry.GitHubRelease) int { return a.PublishedAt.Compare(b.PublishedAt) })
+	refs := make([]releaseRef, len(rels))
+	for i, r := range rels {
+		refs[i] = releaseRef{id, r.TagName}
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d releases\n", id, len(refs))
+	return refs, nil
+}
+
+// updateReleases indexes the releases in turn. One that fails is reported
+// and the rest are still indexed; the run fails once they're done, naming
+// them along with what failed before. The change set covers the whole run:
+// its repo is set only if they all come from one, and its skipped assets
+// are named <repo>@<tag>/<asset>.
+func updateReleases(ctx context.Context, cfg config, rels []releaseRef, failed []string, dryRun bool) (changeSet, error) {
+	all := newChangeSet("", "")
+	all.RunID = correlationID(ctx)
+	all.DryRun = dryRun
+	if len(rels) > 0 && !slices.ContainsFunc(rels, func(r releaseRef) bool { return r.Repo != rels[0].Repo }) {
+		all.Repo = rels[0].Repo
+	}
+	if p := registryPath(); p == "-" || strings.HasPrefix(p, embeddedPrefix) {
+		return all, errors.New("an embedded or piped registry can't be updated release by release")
+	}
+
+	var before registry.Database
+	if !dryRun {
+		st, err := openStore(registryPath(), cfg)
+		if err != nil {
+			return all, fmt.Errorf("load registry: %w", err)
+		}
+		before = *st.snapshot()
+	}
+	for _, rel := range rels {
+		cs, err := updateRegistry(ctx, cfg, rel.Repo, rel.Tag, dryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "update %s@%s: %v\n", rel.Repo, rel.Tag, err)
+			failed = append(failed, rel.Repo+"@"+rel.Tag)
+			continue
+		}
+		for _, sk := range cs.Skipped {
+			all.Skipped = append(all.Skipped, skippedAsset{Name: cs.Repo + "@" + rel.Tag + "/" + sk.Name, Reason: sk.Reason})
+		}
+		all.Pending = append(all.Pending, cs.Pending...)
+		all.Pruned = append(all.Pruned, cs.Pruned...)
+		if dryRun {
+			// nothing was written, so each release is diffed on its own
+			all.Added = append(all.Added, cs.Added...)
+			all.Updated = append(all.Updated, cs.Updated...)
+			all.Unchanged = append(all.Unchanged, cs.Unchanged...)
+			all.Removed = append(all.Removed, cs.Removed...)
+		}
+	}
+	if !dryRun {
+		st, err := openStore(registryPath(), cfg)
+		if err != nil {
+			return all, fmt.Errorf("load registry: %w", err)
+		}
+		all.diffDB(before, *st.snapshot())
+	}
+	if len(failed) > 0 {
+		return all, fmt.Errorf("failed: %s", strings.Join(failed, ", "))
+	}
+	return all, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// outputConfig controls how registry.json is written.
+type outputConfig struct {
+	// Canonical writes RFC 8785 canonical JSON instead of indented JSON,
+	Canonical bool `yaml:"canonical"`
+	// Formats lists extra encodings (proto, cbor) written next to each
+	// registry file, e.g. registry.pb beside registry.json.
+	Formats []string `yaml:"formats"`
+	// Sign publishes a signature next to each registry file written.
+	Sign indexSigning `yaml:"sign"`
+	// TUF publishes TUF metadata for the registry files.
+	TUF tufConfig `yaml:"tuf"`
+}
+
+func (o outputConfig) validate() error {
+	for _, f := range o.Formats {
+		if f != registry.FormatProto && f != registry.FormatCBOR && f != registry.FormatYAML {
+			return fmt.Errorf("output.formats: unknown format %q: want proto, cbor or yaml", f)
+		}
+	}
+	if err := o.Sign.validate(); err != nil {
+		return err
+	}
+	return o.TUF.validate()
+}
+
+// runCanonical prints the canonical form of a registry file, or its
+// SHA-256, which is what signatures and the transparency log cover.
+func runCanonical(args []string) error {
+	flags := flag.NewFlagSet("canonical", flag.ExitOnError)
+	in := flags.String("i", registryPath(), "registry file")
+	out := flags.String("o", "", "write here instead of stdout")
+	digest := flags.Bool("sha256", false, "print the SHA-256 of the canonical form instead")
+	flags.Parse(args)
+
+	db, err := loadDB(*in)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", *in, err)
+	}
+	b, err := registry.CanonicalJSON(registry.Normalize(db))
+	if err != nil {
+		return err
+	}
+	if *digest {
+		sum := sha256.Sum256(b)
+		fmt.Println(hex.EncodeToString(sum[:]))
+		return nil
+	}
+	if *out != "" {
+		return registry.WriteFileAtomic(*out, b)
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
@@ -0,0 +1,230 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// changeSet is the machine-readable outcome of an update, for
+// notifications, PR bodies and summaries.
+type changeSet struct {
+	Repo string `json:"repo,omitempty"`
+	Tag  string `json:"tag,omitempty"`
+	// RunID is the correlation ID of the run that made the changes.
+	RunID string `json:"run_id,omitempty"`
+	// DryRun marks a change set that was only computed, not written.
+	DryRun  bool           `json:"dry_run,omitempty"`
+	Added   []changedEntry `json:"added"`
+	Updated []changedEntry `json:"updated"`
+	// Unchanged are the entries the release indexed as they already were.
+	Unchanged []changedEntry `json:"unchanged,omitempty"`
+	Removed   []changedEntry `json:"removed"`
+	// Pending entries were held for review instead of published.
+	Pending []changedEntry `json:"pending"`
+	Skipped []skippedAsset `json:"skipped"`
+	// Pruned are previous versions dropped by the retention rules.
+	Pruned []prunedRelease `json:"pruned"`
+}
+
+type changedEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// From is the version an updated entry had before.
+	From   string      `json:"from,omitempty"`
+	Fields []fieldDiff `json:"fields,omitempty"`
+	// Reason is why a removed entry was removed, from its tombstone.
+	Reason string `json:"reason,omitempty"`
+}
+
+// fieldDiff is one changed field, with the JSON values on either side.
+type fieldDiff struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+type skippedAsset struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// newChangeSet returns an empty change set whose lists encode as [].
+func newChangeSet(repo, tag string) changeSet {
+	return changeSet{
+		Repo:    repo,
+		Tag:     tag,
+		Added:   []changedEntry{},
+		Updated: []changedEntry{},
+		Removed: []changedEntry{},
+		Pending: []changedEntry{},
+		Skipped: []skippedAsset{},
+		Pruned:  []prunedRelease{},
+	}
+}
+
+// skip reports and records an asset that was not indexed.
+func (cs *changeSet) skip(name, reason string) {
+	fmt.Fprintf(os.Stderr, "%s: skipped: %s\n", name, reason)
+	cs.Skipped = append(cs.Skipped, skippedAsset{Name: name, Reason: reason})
+}
+
+// diffIgnored are fields that change as bookkeeping whenever an entry does.
+var diffIgnored = map[string]bool{"updated_at": true, "previous": true}
+
+// diffEntry lists the fields that differ between two versions of an entry.
+func diffEntry(old, cur registry.Blueprint) []fieldDiff {
+	var a, b map[string]any
+	oj, _ := json.Marshal(old)
+	cj, _ := json.Marshal(cur)
+	json.Unmarshal(oj, &a)
+	json.Unmarshal(cj, &b)
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	var out []fieldDiff
+	for k := range keys {
+		if diffIgnored[k] || reflect.DeepEqual(a[k], b[k]) || (emptyJSON(a[k]) && emptyJSON(b[k])) {
+			continue
+		}
+		out = append(out, fieldDiff{Field: k, Old: a[k], New: b[k]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return out
+}
+
+// emptyJSON reports whether a decoded JSON value is null or an empty list
+// or object, which all mean a field is unset.
+func emptyJSON(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// diffDB fills the added, updated and removed lists from two registries.
+func (cs *changeSet) diffDB(before, after registry.Database) {
+	old := map[string]registry.Blueprint{}
+	for _, bp := range before.Blueprints {
+		old[bp.FullName()] = bp
+	}
+	for _, bp := range after.Blueprints {
+		prev, ok := old[bp.FullName()]
+		delete(old, bp.FullName())
+		if !ok {
+			cs.Added = append(cs.Added, changedEntry{Name: bp.FullName(), Version: bp.Version})
+		} else if fields := diffEntry(prev, bp); len(fields) > 0 {
+			cs.Updated = append(cs.Updated, changedEntry{Name: bp.FullName(), Version: bp.Version, From: prev.Version, Fields: fields})
+		}
+	}
+	for name, bp := range old {
+		t, _ := after.Metadata.Tombstone(name)
+		cs.Removed = append(cs.Removed, changedEntry{Name: name, Version: bp.Version, Reason: t.Reason})
+	}
+	sort.Slice(cs.Removed, func(i, j int) bool { return cs.Removed[i].Name < cs.Removed[j].Name })
+}
+
+// unchanged records the indexed entries the diff found no change in.
+func (cs *changeSet) unchanged(indexed []changedEntry) {
+	changed := map[string]bool{}
+	for _, e := range cs.Added {
+		changed[e.Name] = true
+	}
+	for _, e := range cs.Updated {
+		changed[e.Name] = true
+	}
+	for _, e := range indexed {
+		if !changed[e.Name] {
+			cs.Unchanged = append(cs.Unchanged, e)
+			changed[e.Name] = true
+		}
+	}
+}
+
+// writeDiff prints cs as a diff for review: + for added entries, ~ for
+// updated ones with their changed fields, = for unchanged and - for
+// removed ones, then what was held or skipped.
+func writeDiff(w io.Writer, cs changeSet) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range cs.Added {
+		fmt.Fprintf(bw, "+ %s %s\n", e.Name, e.Version)
+	}
+	for _, e := range cs.Updated {
+		if e.From != e.Version {
+			fmt.Fprintf(bw, "~ %s %s -> %s\n", e.Name, e.From, e.Version)
+		} else {
+			fmt.Fprintf(bw, "~ %s %s\n", e.Name, e.Version)
+		}
+		for _, f := range e.Fields {
+			fmt.Fprintf(bw, "    %s: %s -> %s\n", f.Field, diffValue(f.Old), diffValue(f.New))
+		}
+	}
+	for _, e := range cs.Unchanged {
+		fmt.Fprintf(bw, "= %s %s\n", e.Name, e.Version)
+	}
+	for _, e := range cs.Removed {
+		fmt.Fprintf(bw, "- %s %s\n", e.Name, e.Version)
+	}
+	for _, p := range cs.Pruned {
+		fmt.Fprintf(bw, "- %s %s (retention)\n", p.Name, p.Version)
+	}
+	for _, e := range cs.Pending {
+		fmt.Fprintf(bw, "? %s %s (held for review)\n", e.Name, e.Version)
+	}
+	for _, s := range cs.Skipped {
+		fmt.Fprintf(bw, "! %s: %s\n", s.Name, s.Reason)
+	}
+	fmt.Fprintf(bw, "%d added, %d updated, %d unchanged, %d removed\n", len(cs.Added), len(cs.Updated), len(cs.Unchanged), len(cs.Removed))
+	return bw.Flush()
+}
+
+// diffValue renders one side of a field diff on a single line.
+func diffValue(v any) string {
+	if v == nil {
+		return "(none)"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	const max = 120
+	if len(b) > max {
+		return string(b[:max]) + "…"
+	}
+	return string(b)
+}
+
+// writeChangeSet writes cs as JSON to w.
+func writeChangeSet(w io.Writer, cs changeSet) error {
+	b, err := json.MarshalIndent(cs, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// maxChecksumsBytes caps a checksums file read into memory.
+const maxChecksumsBytes = 1 << 20
+
+// isChecksumsAsset reports whether a release asset lists the digests of
+// the others, as goreleaser's checksums.txt ("<project>_<version>_checksums.txt"
+// with a name template) or sha256sum's SHA256SUMS do.
+func isChecksumsAsset(name string) bool {
+	name = strings.ToLower(name)
+	return name == "checksums.txt" || strings.HasSuffix(name, "_checksums.txt") ||
+		strings.HasSuffix(name, "-checksums.txt") || name == "sha256sums" || name == "sha256sums.txt"
+}
+
+// parseChecksums reads sha256sum output: a hex digest and a file name per
+// line, the name marked with '*' in binary mode. Lines with digests of
+// another length, from other algorithms, are ignored.
+func parseChecksums(b []byte) (map[string]string, error) {
+	sums := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		if _, err := hex.DecodeString(sum); !ok || name == "" || err != nil {
+			return nil, fmt.Errorf("line %d: want \"<digest>  <file>\"", n)
+		}
+		if len(sum) != 64 {
+			continue
+		}
+		sums[name] = strings.ToLower(sum)
+	}
+	return sums, sc.Err()
+}
+
+// releaseChecksums collects the digests listed by a release's checksums
+// assets, keyed by asset name.
+func releaseChecksums(ctx context.Context, assets []registry.GitHubAsset) (map[string]string, error) {
+	sums := map[string]string{}
+	for _, a := range assets {
+		if !isChecksumsAsset(a.Name) {
+			continue
+		}
+		b, err := defaultClient.getLimit(ctx, a.DownloadURL(), maxChecksumsBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Name, err)
+		}
+		listed, err := parseChecksums(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Name, err)
+		}
+		for name, sum := range listed {
+			if prev, ok := sums[name]; ok && prev != sum {
+				return nil, fmt.Errorf("%s: %s is listed with two digests", a.Name, name)
+			}
+			sums[name] = sum
+		}
+	}
+	return sums, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// collectionsFile declares curated sets of blueprints, such as a
+// microservices starter pack. Unlike profiles they don't pin versions.
+type collectionsFile struct {
+	Collections map[string]collection `yaml:"collections"`
+}
+
+type collection struct {
+	Title       string `yaml:"title" json:"title"`
+	Description string `yaml:"description" json:"description"`
+	// Blueprints are entry references, in the order they are presented.
+	Blueprints []string `yaml:"blueprints" json:"blueprints"`
+}
+
+// loadCollections reads a collections file. A missing file declares none.
+func loadCollections(p string) (collectionsFile, error) {
+	var cf collectionsFile
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cf, nil
+		}
+		return cf, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cf); err != nil && !errors.Is(err, io.EOF) {
+		return cf, fmt.Errorf("%s: %w", p, err)
+	}
+	for name, c := range cf.Collections {
+		if err := registry.ValidateIdent("collection", name); err != nil {
+			return cf, fmt.Errorf("%s: %w", p, err)
+		}
+		if len(c.Blueprints) == 0 {
+			return cf, fmt.Errorf("%s: collection %s lists no blueprints", p, name)
+		}
+	}
+	return cf, nil
+}
+
+// resolveCollection returns the entries of a collection in its order,
+// failing if any is not in the registry.
+func resolveCollection(db registry.Database, cf collectionsFile, name string) ([]registry.Blueprint, error) {
+	c, ok := cf.Collections[name]
+	if !ok {
+		return nil, fmt.Errorf("collection %s: %w", name, registry.ErrNotFound)
+	}
+	var out []registry.Blueprint
+	var errs []error
+	for _, ref := range c.Blueprints {
+		bp, err := registry.Find(db, ref)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		out = append(out, bp)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("collection %s: %w", name, err)
+	}
+	return out, nil
+}
+
+// inCollection reports whether bp is a member of the named collection.
+func inCollection(db registry.Database, cf collectionsFile, name string, bp registry.Blueprint) bool {
+	for _, ref := range cf.Collections[name].Blueprints {
+		if m, err := registry.Find(db, ref); err == nil && m.FullName() == bp.FullName() {
+			return true
+		}
+	}
+	return false
+}
+
+// runCollections lists the collections, or the entries of one.
+func runCollections(args []string) error {
+	flags := flag.NewFlagSet("collections", flag.ExitOnError)
+	file := flags.String("collections", "collections.yaml", "collections file")
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+
+	cf, err := loadCollections(*file)
+	if err != nil {
+		return fmt.Errorf("load collections: %w", err)
+	}
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	if flags.NArg() == 0 {
+		if *asJSON {
+			return printJSON(cf.Collections)
+		}
+		names := make([]string, 0, len(cf.Collections))
+		for name := range cf.Collections {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var errs []error
+		for _, name := range names {
+			c := cf.Collections[name]
+			fmt.Printf("%s\t%d blueprints\t%s\n", name, len(c.Blueprints), c.Title)
+			if _, err := resolveCollection(db, cf, name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	bps, err := resolveCollection(db, cf, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(bps)
+	}
+	c := cf.Collections[flags.Arg(0)]
+	fmt.Printf("%s\n%s\n\n", c.Title, c.Description)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tDESCRIPTION")
+	for _, bp := range bps {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", bp.FullName(), bp.Version, truncateText(bp.Description, 60))
+	}
+	return w.Flush()
+}
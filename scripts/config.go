@@ -0,0 +1,201 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is the updater's configuration, read from dragon-registry.yaml.
+// Every section is optional; defaults reproduce the original behaviour.
+type config struct {
+	Assets     assetRules       `yaml:"assets"`
+	Templates  templateRules    `yaml:"templates"`
+	Stats      statsConfig      `yaml:"stats"`
+	Output     outputConfig     `yaml:"output"`
+	Features   featuresConfig   `yaml:"features"`
+	Manifests  manifestConfig   `yaml:"manifests"`
+	Tags       tagsConfig       `yaml:"tags"`
+	Review     reviewConfig     `yaml:"review"`
+	Trust      trustConfig      `yaml:"trust"`
+	Sync       syncFilter       `yaml:"sync"`
+	Rehost     rehostConfig     `yaml:"rehost"`
+	Conflicts  conflictConfig   `yaml:"conflicts"`
+	Validation validationConfig `yaml:"validation"`
+	Text       textConfig       `yaml:"text"`
+	HTTP       httpConfig       `yaml:"http"`
+	Mirrors    mirrorConfig     `yaml:"mirrors"`
+	Search     searchConfig     `yaml:"search"`
+	Details    detailsConfig    `yaml:"details"`
+	Retention  retentionConfig  `yaml:"retention"`
+	IDs        idConfig         `yaml:"ids"`
+	Signatures signatureConfig  `yaml:"signatures"`
+	Provenance provenanceConfig `yaml:"provenance"`
+	Snapshots  snapshotConfig   `yaml:"snapshots"`
+	Serve      serveConfig      `yaml:"serve"`
+	Fallback   fallbackConfig   `yaml:"fallback"`
+	Matrix     matrixConfig     `yaml:"matrix"`
+	Discovery  discoveryConfig  `yaml:"discovery"`
+	Webhooks   webhookConfig    `yaml:"webhooks"`
+	Freshness  freshnessConfig  `yaml:"freshness"`
+	Digests    digestConfig     `yaml:"digests"`
+	Images     imageConfig      `yaml:"images"`
+	Queue      queueConfig      `yaml:"queue"`
+}
+
+func defaultConfig() config {
+	return config{
+		Assets: assetRules{
+			Include:    []string{"*.zip"},
+			Extensions: []string{".zip"},
+		},
+		Templates: templateRules{
+			Lint:       lintOff,
+			Extensions: []string{".tmpl"},
+		},
+	}
+}
+
+// registryFlag is the --registry-path given before the command.
+var registryFlag string
+
+// embedded holds the registry snapshot pinned into binaries built with
+// -tags embed, and nil otherwise.
+var embedded fs.FS
+
+// embeddedPrefix marks a registry path inside embedded.
+const embeddedPrefix = "embedded:"
+
+// registryPath returns the registry the commands read and write:
+// --registry-path, $REGISTRY_PATH or registry.json, or the embedded
+// snapshot in binaries that have one. "-" means stdin for reading and
+// stdout for writing.
+func registryPath() string {
+	if registryFlag != "" {
+		return registryFlag
+	}
+	if p := os.Getenv("REGISTRY_PATH"); p != "" {
+		return p
+	}
+	if embedded != nil {
+		return embeddedPrefix + "registry.json"
+	}
+	return "registry.json"
+}
+
+// configPath returns $REGISTRY_CONFIG or dragon-registry.yaml.
+func configPath() string {
+	if p := os.Getenv("REGISTRY_CONFIG"); p != "" {
+		return p
+	}
+	return "dragon-registry.yaml"
+}
+
+// loadConfig reads the config at p over the defaults. A missing file is
+// not an error unless REGISTRY_CONFIG pointed at it.
+func loadConfig(p string) (config, error) {
+	cfg := defaultConfig()
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && os.Getenv("REGISTRY_CONFIG") == "" {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Assets.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Templates.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Output.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Trust.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Sync.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Conflicts.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Validation.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Text.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.HTTP.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Search.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Details.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Retention.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.IDs.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Signatures.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Provenance.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Snapshots.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Serve.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Fallback.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Matrix.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Discovery.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Webhooks.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Freshness.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Digests.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Images.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Queue.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", p, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// Ways to settle two source repos publishing the same blueprint name.
+const (
+	// resolveFail refuses the update.
+	resolveFail = "fail"
+	// resolveNamespace moves the newcomer into a namespace named after
+	// the owner of its source repo.
+	resolveNamespace = "namespace"
+	// resolveTrust keeps whichever entry has the higher trust level.
+	resolveTrust = "trust"
+)
+
+// conflictConfig decides what happens when an entry would replace one
+// published by a different source repo.
+type conflictConfig struct {
+	// Resolve is fail (the default), namespace or trust.
+	Resolve string `yaml:"resolve"`
+}
+
+var errConflict = errors.New("name conflict between sources")
+
+func (c conflictConfig) validate() error {
+	switch c.Resolve {
+	case "", resolveFail, resolveNamespace, resolveTrust:
+		return nil
+	}
+	return fmt.Errorf("conflicts.resolve: unknown resolution %q: want fail, namespace or trust", c.Resolve)
+}
+
+// sourceOwner returns the owner of a repo recorded as github.com/owner/repo
+// or owner/repo.
+func sourceOwner(repo string) string {
+	repo = strings.TrimPrefix(repo, "github.com/")
+	owner, _, _ := strings.Cut(repo, "/")
+	return strings.ToLower(owner)
+}
+
+// sameSource reports whether two entries come from the same repo. Entries
+// that predate recording the repo are assumed to.
+func sameSource(a, b registry.Blueprint) bool {
+	if a.Repo == "" || b.Repo == "" {
+		return true
+	}
+	return strings.EqualFold(strings.TrimPrefix(a.Repo, "github.com/"), strings.TrimPrefix(b.Repo, "github.com/"))
+}
+
+// upsertResolved adds or replaces entry like registry.Upsert, settling a
+// clash with an entry from another source according to c.
+func upsertResolved(db *registry.Database, entry registry.Blueprint, c conflictConfig) error {
+	old, ok := db.Lookup(entry.Namespace, entry.Name)
+	if !ok || sameSource(old, entry) {
+		registry.Upsert(db, entry)
+		return nil
+	}
+	clash := fmt.Errorf("%s: %w: held by %s, claimed by %s", entry.FullName(), errConflict, old.Repo, entry.Repo)
+	switch c.Resolve {
+	case resolveTrust:
+		switch {
+		case trustLevelOf(old) == trustLevelOf(entry):
+			return fmt.Errorf("%w, with the same trust level", clash)
+		case trustAtLeast(old.Trust, trustLevelOf(entry)):
+			fmt.Fprintf(os.Stderr, "%v: kept the %s entry\n", clash, trustLevelOf(old))
+			return nil
+		}
+		registry.Upsert(db, entry)
+		return nil
+	case resolveNamespace:
+		ns := sourceOwner(entry.Repo)
+		if ns == entry.Namespace {
+			return clash
+		}
+		if err := registry.ValidateIdent("namespace", ns); err != nil {
+			return fmt.Errorf("%w: %v", clash, err)
+		}
+		entry.Namespace = ns
+		if other, ok := db.Lookup(ns, entry.Name); ok && !sameSource(other, entry) {
+			return fmt.Errorf("%w, and %s is taken by %s", clash, entry.FullName(), other.Repo)
+		}
+		fmt.Fprintf(os.Stderr, "%v: stored as %s\n", clash, entry.FullName())
+		registry.Upsert(db, entry)
+		return nil
+	}
+	return clash
+}
+
+// trustLevelOf returns bp's trust level, counting entries without one as
+// community like trustAtLeast does.
+func trustLevelOf(bp registry.Blueprint) string {
+	if bp.Trust == "" {
+		return trustCommunity
+	}
+	return bp.Trust
+}
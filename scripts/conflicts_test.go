@@ -0,0 +1,92 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+func TestUpsertResolved(t *testing.T) {
+	held := func(repo, trust string) registry.Blueprint {
+		return registry.Blueprint{Namespace: registry.DefaultNamespace, Name: "api", Version: "1.0.0", Repo: repo, Trust: trust}
+	}
+	claim := func(repo, trust string) registry.Blueprint {
+		return registry.Blueprint{Namespace: registry.DefaultNamespace, Name: "api", Version: "2.0.0", Repo: repo, Trust: trust}
+	}
+
+	cases := []struct {
+		name    string
+		db      []registry.Blueprint
+		entry   registry.Blueprint
+		resolve string
+		// want is every entry afterwards as namespace/name@version from
+		// repo
+		want     []string
+		conflict bool
+	}{
+		{"new name", nil, claim("github.com/acme/bp", ""), "", []string{"getdragon/api@2.0.0 github.com/acme/bp"}, false},
+		{"same source", []registry.Blueprint{held("github.com/acme/bp", "")}, claim("github.com/acme/bp", ""), "", []string{"getdragon/api@2.0.0 github.com/acme/bp"}, false},
+		{"same source, other spelling", []registry.Blueprint{held("github.com/Acme/BP", "")}, claim("acme/bp", ""), "", []string{"getdragon/api@2.0.0 acme/bp"}, false},
+		{"no recorded repo", []registry.Blueprint{held("", "")}, claim("github.com/acme/bp", ""), "", []string{"getdragon/api@2.0.0 github.com/acme/bp"}, false},
+
+		{"fail by default", []registry.Blueprint{held("github.com/getDragon-dev/bp", "")}, claim("github.com/acme/bp", ""), "", []string{"getdragon/api@1.0.0 github.com/getDragon-dev/bp"}, true},
+		{"fail", []registry.Blueprint{held("github.com/getDragon-dev/bp", "")}, claim("github.com/acme/bp", ""), resolveFail, []string{"getdragon/api@1.0.0 github.com/getDragon-dev/bp"}, true},
+
+		{"trust: higher newcomer wins", []registry.Blueprint{held("github.com/acme/bp", trustCommunity)}, claim("github.com/getDragon-dev/bp", trustOfficial), resolveTrust,
+			[]string{"getdragon/api@2.0.0 github.com/getDragon-dev/bp"}, false},
+		{"trust: higher holder kept", []registry.Blueprint{held("github.com/getDragon-dev/bp", trustOfficial)}, claim("github.com/acme/bp", trustPartner), resolveTrust,
+			[]string{"getdragon/api@1.0.0 github.com/getDragon-dev/bp"}, false},
+		{"trust: unset counts as community", []registry.Blueprint{held("github.com/acme/bp", "")}, claim("github.com/other/bp", trustCommunity), resolveTrust,
+			[]string{"getdragon/api@1.0.0 github.com/acme/bp"}, true},
+
+		{"namespace: moved to the owner", []registry.Blueprint{held("github.com/getDragon-dev/bp", "")}, claim("github.com/Acme/bp", ""), resolveNamespace,
+			[]string{"acme/api@2.0.0 github.com/Acme/bp", "getdragon/api@1.0.0 github.com/getDragon-dev/bp"}, false},
+		{"namespace: already the owner's", []registry.Blueprint{{Namespace: "acme", Name: "api", Version: "1.0.0", Repo: "github.com/other/bp"}},
+			registry.Blueprint{Namespace: "acme", Name: "api", Version: "2.0.0", Repo: "github.com/acme/bp"}, resolveNamespace,
+			[]string{"acme/api@1.0.0 github.com/other/bp"}, true},
+		{"namespace: owner's name taken too", []registry.Blueprint{held("github.com/getDragon-dev/bp", ""), {Namespace: "acme", Name: "api", Version: "1.0.0", Repo: "github.com/third/bp"}},
+			claim("github.com/acme/bp", ""), resolveNamespace,
+			[]string{"acme/api@1.0.0 github.com/third/bp", "getdragon/api@1.0.0 github.com/getDragon-dev/bp"}, true},
+		{"namespace: owner isn't a valid namespace", []registry.Blueprint{held("github.com/getDragon-dev/bp", "")}, claim("github.com/-bad_/bp", ""), resolveNamespace,
+			[]string{"getdragon/api@1.0.0 github.com/getDragon-dev/bp"}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			db := registry.Database{Blueprints: slices.Clone(c.db)}
+			err := upsertResolved(&db, c.entry, conflictConfig{Resolve: c.resolve})
+			if errors.Is(err, errConflict) != c.conflict {
+				t.Errorf("err = %v, want conflict: %t", err, c.conflict)
+			}
+			var got []string
+			for _, bp := range registry.Normalize(db).Blueprints {
+				got = append(got, bp.FullName()+"@"+bp.Version+" "+bp.Repo)
+			}
+			if !slices.Equal(got, c.want) {
+				t.Errorf("registry %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestConflictConfigValidate(t *testing.T) {
+	for _, r := range []string{"", resolveFail, resolveNamespace, resolveTrust} {
+		if err := (conflictConfig{Resolve: r}).validate(); err != nil {
+			t.Errorf("validate(%q) = %v", r, err)
+		}
+	}
+	if err := (conflictConfig{Resolve: "newest"}).validate(); err == nil {
+		t.Error("validate(newest) accepted an unknown resolution")
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry/conformancetest"
+)
+
+func TestConformance(t *testing.T) {
+	h := middleware(serveTestRegistry(t, config{}, conformancetest.Fixture()), serveLimits{})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	conformancetest.Run(t, conformancetest.Server{URL: srv.URL})
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// runConvert translates a registry between encodings, picking each side's
+// format from its file extension.
+func runConvert(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	in := flags.String("i", registryPath(), "input registry (.json, .yaml, .pb or .cbor; - for stdin)")
+	out := flags.String("o", "", "output registry (.json, .yaml, .pb or .cbor; - for stdout as JSON)")
+	flags.Parse(args)
+	if *out == "" {
+		return errors.New("usage: convert [-i registry.json] -o registry.pb")
+	}
+
+	db, err := loadDB(*in)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", *in, err)
+	}
+	b, err := registry.Encode(registry.Normalize(db), registry.FormatOf(*out), false)
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+	if err := registry.WriteFileAtomic(*out, b); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d entries to %s (%d bytes)\n", len(db.Blueprints), *out, len(b))
+	return nil
+}
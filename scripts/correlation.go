@@ -0,0 +1,78 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+)
+
+// requestIDHeader carries correlation IDs on the requests we send.
+const requestIDHeader = "X-Request-Id"
+
+// Correlation IDs let a report like "publish failed" be matched to the CI
+// log of the run and to the requests it made. A run's ID doubles as its
+// trace ID when telemetry is on.
+type correlationKey struct{}
+
+// newCorrelationID returns 16 random bytes in hex, the shape of a trace ID.
+func newCorrelationID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// runCorrelationID is the ID of this run: $DRAGON_RUN_ID when a workflow
+// wants to choose it, or a new one.
+func runCorrelationID() string {
+	if id := os.Getenv("DRAGON_RUN_ID"); id != "" {
+		return id
+	}
+	return newCorrelationID()
+}
+
+func withCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// correlationID returns the ID of the run or job ctx belongs to.
+func correlationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+var requestSeq atomic.Int64
+
+// requestID numbers a request within its run: <run id>.<n>.
+func requestID(ctx context.Context) string {
+	id := correlationID(ctx)
+	if id == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s.%d", id, requestSeq.Add(1))
+}
+
+// responseRequestID names a failed response for the error message: the ID
+// GitHub assigned it, or the one we sent.
+func responseRequestID(resp *http.Response) string {
+	if id := resp.Header.Get("X-GitHub-Request-Id"); id != "" {
+		return id
+	}
+	if resp.Request != nil {
+		return resp.Request.Header.Get(requestIDHeader)
+	}
+	return ""
+}
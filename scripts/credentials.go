@@ -0,0 +1,407 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// credService names the credentials in the OS keychain.
+const credService = "dragon-registry"
+
+var errNoCredential = errors.New("not logged in")
+
+// credStore keeps one token per host for maintainers working locally.
+// Workflows keep using GITHUB_TOKEN, which takes precedence.
+type credStore interface {
+	get(host string) (string, error)
+	set(host, token string) error
+	remove(host string) error
+	String() string
+}
+
+// credentialStore picks the OS credential store: the Windows Credential
+// Manager, or the keychain when its tool is installed, and otherwise a
+// file readable only by the user.
+func credentialStore() credStore {
+	switch runtime.GOOS {
+	case "windows":
+		return credentialManager()
+	case "darwin":
+		if _, err := exec.LookPath("security"); err == nil {
+			return macKeychain{}
+		}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		if _, err := exec.LookPath("secret-tool"); err == nil {
+			return secretService{}
+		}
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return fileCreds{path: filepath.Join(dir, "dragon-registry", "credentials.json")}
+}
+
+// keychainTimeout bounds calls into the keychain, which can block on a
+// locked or missing keyring.
+const keychainTimeout = 10 * time.Second
+
+func keychainCmd(stdin string, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keychainTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var out, errb bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errb
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(errb.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// macKeychain uses the login keychain through security(1).
+type macKeychain struct{}
+
+func (macKeychain) String() string { return "macOS keychain" }
+
+func (macKeychain) get(host string) (string, error) {
+	tok, err := keychainCmd("", "security", "find-generic-password", "-s", credService, "-a", host, "-w")
+	if err != nil || tok == "" {
+		return "", errNoCredential
+	}
+	return tok, nil
+}
+
+func (m macKeychain) set(host, token string) error {
+	// security only takes the password as an argument, which anyone can
+	// read with ps, so the command is fed to its interactive mode on stdin
+	// instead, the password hex-encoded (-X) to need no quoting. -U
+	// replaces an existing item.
+	if strings.ContainsAny(host, "\" \n") {
+		return fmt.Errorf("host %q can't be stored in the keychain", host)
+	}
+	cmd := fmt.Sprintf("add-generic-password -U -s %s -a \"%s\" -X %s\n", credService, host, hex.EncodeToString([]byte(token)))
+	if _, err := keychainCmd(cmd, "security", "-i"); err != nil {
+		return err
+	}
+	// security -i exits 0 whatever its commands did
+	if got, err := m.get(host); err != nil || got != token {
+		return errors.New("security: the keychain did not take the token")
+	}
+	return nil
+}
+
+func (macKeychain) remove(host string) error {
+	_, err := keychainCmd("", "security", "delete-generic-password", "-s", credService, "-a", host)
+	return err
+}
+
+// secretService uses the freedesktop Secret Service (GNOME Keyring,
+// KWallet) through secret-tool(1).
+type secretService struct{}
+
+func (secretService) String() string { return "Secret Service keyring" }
+
+func (secretService) get(host string) (string, error) {
+	tok, err := keychainCmd("", "secret-tool", "lookup", "service", credService, "host", host)
+	if err != nil || tok == "" {
+		return "", errNoCredential
+	}
+	return tok, nil
+}
+
+func (secretService) set(host, token string) error {
+	_, err := keychainCmd(token, "secret-tool", "store", "--label", credService+" ("+host+")", "service", credService, "host", host)
+	return err
+}
+
+func (secretService) remove(host string) error {
+	_, err := keychainCmd("", "secret-tool", "clear", "service", credService, "host", host)
+	return err
+}
+
+// fileCreds is the fallback where no keychain is available: a JSON map of
+// hosts to tokens, mode 0600.
+type fileCreds struct{ path string }
+
+func (f fileCreds) String() string { return f.path }
+
+func (f fileCreds) read() (map[string]string, error) {
+	m := map[string]string{}
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+	return m, nil
+}
+
+func (f fileCreds) write(m map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, append(b, '\n'), 0o600)
+}
+
+func (f fileCreds) get(host string) (string, error) {
+	m, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	if m[host] == "" {
+		return "", errNoCredential
+	}
+	return m[host], nil
+}
+
+func (f fileCreds) set(host, token string) error {
+	m, err := f.read()
+	if err != nil {
+		return err
+	}
+	m[host] = token
+	return f.write(m)
+}
+
+func (f fileCreds) remove(host string) error {
+	m, err := f.read()
+	if err != nil {
+		return err
+	}
+	delete(m, host)
+	return f.write(m)
+}
+
+// credentialHost maps a request host to the host its token is stored
+// under: the API, upload and raw hosts all use the github.com login.
+func credentialHost(host string) string {
+	switch host {
+	case "api.github.com", "uploads.github.com", "raw.githubusercontent.com":
+		return "github.com"
+	}
+	return host
+}
+
+// storedAuth sends the token saved by login. The keychain is only asked
+// when a request needs credentials, once per host.
+type storedAuth struct {
+	store credStore
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func (s *storedAuth) token(host string) string {
+	host = credentialHost(host)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tok, ok := s.tokens[host]; ok {
+		return tok
+	}
+	tok, _ := s.store.get(host)
+	if s.tokens == nil {
+		s.tokens = map[string]string{}
+	}
+	s.tokens[host] = tok
+	return tok
+}
+
+func (s *storedAuth) authorize(_ context.Context, _ *http.Client, req *http.Request) error {
+	if tok := s.token(req.URL.Hostname()); tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+	return nil
+}
+
+// apiBase returns the REST API root of a GitHub host: http.github_api
+// for the host it is on, else the host's usual root.
+func apiBase(host string) string {
+	if u, err := url.Parse(defaultClient.api); err == nil && credentialHost(u.Hostname()) == host {
+		return defaultClient.api
+	}
+	if host == "github.com" {
+		return publicGitHubAPI
+	}
+	return "https://" + host + "/api/v3"
+}
+
+// deviceLogin runs the OAuth device flow: the user opens a URL, enters a
+// code, and the token is polled for until they approve.
+func deviceLogin(ctx context.Context, hc *http.Client, host, clientID, scopes string) (string, error) {
+	var code struct {
+		DeviceCode      string `json:"device_code"`
+		UserCode        string `json:"user_code"`
+		VerificationURI string `json:"verification_uri"`
+		ExpiresIn       int    `json:"expires_in"`
+		Interval        int    `json:"interval"`
+	}
+	form := url.Values{"client_id": {clientID}, "scope": {scopes}}
+	if err := postForm(ctx, hc, "https://"+host+"/login/device/code", form, &code); err != nil {
+		return "", fmt.Errorf("device code: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Open %s and enter the code %s\n", code.VerificationURI, code.UserCode)
+
+	interval := time.Duration(max(code.Interval, 5)) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(max(code.ExpiresIn, 60))*time.Second)
+	defer cancel()
+	form = url.Values{
+		"client_id":   {clientID},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return "", errors.New("the code expired before it was entered")
+		case <-time.After(interval):
+		}
+		var tok struct {
+			AccessToken string `json:"access_token"`
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if err := postForm(ctx, hc, "https://"+host+"/login/oauth/access_token", form, &tok); err != nil {
+			return "", err
+		}
+		switch tok.Error {
+		case "":
+			return tok.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return "", fmt.Errorf("%s: %s", tok.Error, tok.Description)
+		}
+	}
+}
+
+func postForm(ctx context.Context, hc *http.Client, u string, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	return doJSON(hc, req, out)
+}
+
+// whoami returns the login the token belongs to.
+func whoami(ctx context.Context, hc *http.Client, host, token string) (string, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", apiBase(host)+"/user", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := doJSON(hc, req, &user); err != nil {
+		return "", err
+	}
+	return user.Login, nil
+}
+
+// runLogin stores a token for the maintainer commands, from the device
+// flow or, with --with-token, from stdin.
+func runLogin(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("login", flag.ExitOnError)
+	host := flags.String("host", "github.com", "GitHub host to log in to")
+	withToken := flags.Bool("with-token", false, "read a token from stdin instead of running the device flow")
+	clientID := flags.String("client-id", os.Getenv("DRAGON_REGISTRY_CLIENT_ID"), "OAuth app client ID for the device flow (default $DRAGON_REGISTRY_CLIENT_ID)")
+	scopes := flags.String("scopes", "repo", "scopes to request")
+	status := flags.Bool("status", false, "show who is logged in and where the token is kept")
+	flags.Parse(args)
+
+	store := credentialStore()
+	if *status {
+		tok, err := store.get(*host)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *host, err)
+		}
+		login, err := whoami(ctx, http.DefaultClient, *host, tok)
+		if err != nil {
+			return fmt.Errorf("%s: stored token doesn't work: %w", *host, err)
+		}
+		fmt.Printf("logged in to %s as %s (token in %s)\n", *host, login, store)
+		return nil
+	}
+
+	var tok string
+	if *withToken {
+		line, err := bufio.NewReader(io.LimitReader(os.Stdin, 4<<10)).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		tok = strings.TrimSpace(line)
+		if tok == "" {
+			return errors.New("no token on stdin")
+		}
+	} else {
+		if *clientID == "" {
+			return errors.New("the device flow needs --client-id or $DRAGON_REGISTRY_CLIENT_ID; or pass a token with --with-token")
+		}
+		var err error
+		if tok, err = deviceLogin(ctx, http.DefaultClient, *host, *clientID, *scopes); err != nil {
+			return fmt.Errorf("login: %w", err)
+		}
+	}
+	login, err := whoami(ctx, http.DefaultClient, *host, tok)
+	if err != nil {
+		return fmt.Errorf("check token: %w", err)
+	}
+	if err := store.set(*host, tok); err != nil {
+		return fmt.Errorf("store token: %w", err)
+	}
+	fmt.Printf("logged in to %s as %s (token in %s)\n", *host, login, store)
+	return nil
+}
+
+// runLogout forgets the stored token.
+func runLogout(args []string) error {
+	flags := flag.NewFlagSet("logout", flag.ExitOnError)
+	host := flags.String("host", "github.com", "GitHub host to log out of")
+	flags.Parse(args)
+
+	store := credentialStore()
+	if err := store.remove(*host); err != nil {
+		return fmt.Errorf("remove token: %w", err)
+	}
+	fmt.Printf("logged out of %s\n", *host)
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	// errorNotFound is ERROR_NOT_FOUND, for a target with no credential.
+	errorNotFound syscall.Errno = 1168
+)
+
+// credentialW is CREDENTIALW.
+type credentialW struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credentialManager is the Windows Credential Manager, where tokens are
+// kept as generic credentials named dragon-registry:<host>.
+func credentialManager() credStore { return winCredentials{} }
+
+type winCredentials struct{}
+
+func (winCredentials) String() string { return "Windows Credential Manager" }
+
+func credTarget(host string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(credService + ":" + host)
+}
+
+func (winCredentials) get(host string) (string, error) {
+	target, err := credTarget(host)
+	if err != nil {
+		return "", err
+	}
+	var c *credentialW
+	ok, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&c)))
+	if ok == 0 {
+		if errors.Is(err, errorNotFound) {
+			return "", errNoCredential
+		}
+		return "", err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(c)))
+	if c.CredentialBlobSize == 0 {
+		return "", errNoCredential
+	}
+	return string(unsafe.Slice(c.CredentialBlob, c.CredentialBlobSize)), nil
+}
+
+func (winCredentials) set(host, token string) error {
+	target, err := credTarget(host)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(host)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return errors.New("empty token")
+	}
+	blob := []byte(token)
+	c := credentialW{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if ok, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&c)), 0); ok == 0 {
+		return err
+	}
+	return nil
+}
+
+func (winCredentials) remove(host string) error {
+	target, err := credTarget(host)
+	if err != nil {
+		return err
+	}
+	ok, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if ok == 0 && !errors.Is(err, errorNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// deadEntry is an entry whose source is gone, or couldn't be checked.
+type deadEntry struct {
+	Entry   string `json:"entry"`
+	Version string `json:"version"`
+	Reason  string `json:"reason"`
+}
+
+// deadReport is what prune --dead found and did.
+type deadReport struct {
+	CheckedAt time.Time `json:"checked_at"`
+	// Action is what was done with the dead entries: removed,
+	// deprecated, or none on a dry run.
+	Action string      `json:"action"`
+	Dead   []deadEntry `json:"dead"`
+	// Unchecked are entries whose source couldn't be reached. They are
+	// kept: a failing request is not a missing source.
+	Unchecked []deadEntry `json:"unchecked,omitempty"`
+}
+
+// sourceChecker asks GitHub whether the repos, releases and assets
+// entries come from still exist, each repo and release once.
+type sourceChecker struct {
+	repos    map[string]error
+	releases map[string]registry.GitHubRelease
+	errs     map[string]error
+}
+
+func newSourceChecker() *sourceChecker {
+	return &sourceChecker{repos: map[string]error{}, releases: map[string]registry.GitHubRelease{}, errs: map[string]error{}}
+}
+
+// errSourceGone marks a source GitHub answered 404 for.
+var errSourceGone = errors.New("no longer exists")
+
+func notFound(err error) error {
+	var se *registry.StatusError
+	if errors.As(err, &se) && (se.Code == http.StatusNotFound || se.Code == http.StatusGone) {
+		return errSourceGone
+	}
+	return err
+}
+
+// check returns why bp's source is gone, errSourceGone-wrapped, or
+// another error if that couldn't be established.
+func (c *sourceChecker) check(ctx context.Context, bp registry.Blueprint) error {
+	repo, err := parseRepo(bp.Repo)
+	if err != nil {
+		return err
+	}
+	if err := c.repo(ctx, repo); err != nil {
+		return fmt.Errorf("repo %s: %w", repo, err)
+	}
+	// a release asset, possibly of a rehosting repo
+	if owner, name, tag, asset, ok := releaseAssetURL(bp.DownloadURL); ok {
+		rel, err := c.release(ctx, owner+"/"+name, tag)
+		if err != nil {
+			return fmt.Errorf("release %s of %s/%s: %w", tag, owner, name, err)
+		}
+		if !slices.ContainsFunc(rel.Assets, func(a registry.GitHubAsset) bool { return a.Name == asset }) {
+			return fmt.Errorf("asset %s of release %s: %w", asset, tag, errSourceGone)
+		}
+		return nil
+	}
+	status, _, err := probeLink(ctx, bp.DownloadURL, 30*time.Second)
+	if status == http.StatusNotFound || status == http.StatusGone {
+		return fmt.Errorf("download URL: %w (%d)", errSourceGone, status)
+	}
+	return err
+}
+
+func (c *sourceChecker) repo(ctx context.Context, repo string) error {
+	key := strings.ToLower(repo)
+	if err, ok := c.repos[key]; ok {
+		return err
+	}
+	_, err := defaultClient.get(ctx, defaultClient.apiURL("/repos/%s", repo))
+	c.repos[key] = notFound(err)
+	return c.repos[key]
+}
+
+func (c *sourceChecker) release(ctx context.Context, repo, tag string) (registry.GitHubRelease, error) {
+	key := strings.ToLower(repo) + "@" + tag
+	if err, ok := c.errs[key]; ok {
+		return registry.GitHubRelease{}, err
+	}
+	if rel, ok := c.releases[key]; ok {
+		return rel, nil
+	}
+	rel, err := github().Release(ctx, repo, tag)
+	if err != nil {
+		c.errs[key] = notFound(err)
+		return rel, c.errs[key]
+	}
+	c.releases[key] = rel
+	return rel, nil
+}
+
+// releaseAssetURL splits a GitHub release download URL.
+func releaseAssetURL(s string) (owner, repo, tag, asset string, ok bool) {
+	u, err := url.Parse(s)
+	if err != nil || u.Host != "github.com" {
+		return "", "", "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if len(parts) != 6 || parts[2] != "releases" || parts[3] != "download" {
+		return "", "", "", "", false
+	}
+	return parts[0], parts[1], parts[4], parts[5], true
+}
+
+// pruneDead checks every entry's source and removes, or deprecates,
+// the entries whose source is gone.
+func pruneDead(ctx context.Context, cfg config, dryRun, deprecate bool, report string) error {
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	rep := deadReport{CheckedAt: time.Now().UTC(), Action: "removed"}
+	switch {
+	case dryRun:
+		rep.Action = "none"
+	case deprecate:
+		rep.Action = "deprecated"
+	}
+	c := newSourceChecker()
+	tx := st.begin()
+	bps := st.snapshot().Blueprints
+	for _, bp := range bps {
+		err := c.check(ctx, bp)
+		if err == nil {
+			continue
+		}
+		e := deadEntry{Entry: bp.FullName(), Version: bp.Version, Reason: err.Error()}
+		if !errors.Is(err, errSourceGone) {
+			fmt.Fprintf(os.Stderr, "%s: unchecked: %v\n", e.Entry, err)
+			rep.Unchecked = append(rep.Unchecked, e)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v\n", e.Entry, err)
+		rep.Dead = append(rep.Dead, e)
+		name, reason := e.Entry, "source gone: "+e.Reason
+		if deprecate {
+			tx.stage(func(db *registry.Database) error {
+				for i := range db.Blueprints {
+					if db.Blueprints[i].FullName() == name && db.Blueprints[i].Deprecation == nil {
+						db.Blueprints[i].Deprecation = &registry.Deprecation{Reason: reason}
+					}
+				}
+				return nil
+			})
+		} else {
+			tx.stage(func(db *registry.Database) error {
+				_, err := registry.Remove(db, name, reason, rep.CheckedAt)
+				return err
+			})
+		}
+	}
+	if !dryRun {
+		if err := tx.commit(); err != nil {
+			return fmt.Errorf("update registry: %w", err)
+		}
+	}
+	verb := "removed"
+	if deprecate {
+		verb = "deprecated"
+	}
+	if dryRun {
+		verb = "would have " + verb
+	}
+	fmt.Printf("%s %d of %d entries; %d unchecked\n", verb, len(rep.Dead), len(bps), len(rep.Unchecked))
+	if report == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	return registry.WriteFileAtomic(report, append(b, '\n'))
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// digestRef is a release already in the registry, found by its digest.
+type digestRef struct {
+	Entry       string
+	Version     string
+	DownloadURL string
+	// Current is set for an entry's current release, which also has its
+	// source URL.
+	Current   bool
+	SourceURL string
+}
+
+// indexDigests maps archive digests to the releases that carry them,
+// current releases first so they win over previous ones.
+func indexDigests(db registry.Database) map[string]digestRef {
+	idx := map[string]digestRef{}
+	for _, bp := range db.Blueprints {
+		if bp.SHA256 != "" {
+			idx[bp.SHA256] = digestRef{Entry: bp.FullName(), Version: bp.Version, DownloadURL: bp.DownloadURL, Current: true, SourceURL: bp.SourceURL}
+		}
+	}
+	for _, bp := range db.Blueprints {
+		for _, r := range bp.Previous {
+			if _, ok := idx[r.SHA256]; r.SHA256 != "" && !ok {
+				idx[r.SHA256] = digestRef{Entry: bp.FullName(), Version: r.Version, DownloadURL: r.DownloadURL}
+			}
+		}
+	}
+	return idx
+}
+
+// republished reports whether url is another place the release ref was
+// already indexed from: the same entry and version, at a URL the entry
+// doesn't know yet.
+func (ref digestRef) republished(entry registry.Blueprint, url string) bool {
+	return ref.Current && ref.Entry == entry.FullName() && ref.Version == entry.Version &&
+		url != ref.DownloadURL && url != ref.SourceURL
+}
+
+// isMirror reports whether url is a copy in the mirror releases.
+func (rc rehostConfig) isMirror(url string) bool {
+	return rc.Repo != "" && strings.HasPrefix(url, "https://github.com/"+rc.Repo+"/releases/download/")
+}
+
+// addSource records url as another place entry's current release is
+// published.
+func addSource(db *registry.Database, entry, url string) error {
+	for i := range db.Blueprints {
+		bp := &db.Blueprints[i]
+		if bp.FullName() != entry {
+			continue
+		}
+		if !slices.Contains(bp.Sources, url) {
+			bp.Sources = append(bp.Sources, url)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: %w", entry, registry.ErrNotFound)
+}
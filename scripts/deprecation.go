@@ -0,0 +1,112 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// qualifyDeprecation puts a bare replacement name in the default
+// namespace, as dependencies are.
+func qualifyDeprecation(d *registry.Deprecation) *registry.Deprecation {
+	if d == nil || d.Replacement == "" {
+		return d
+	}
+	out := *d
+	if ns, name := registry.SplitRef(d.Replacement); ns == "" {
+		out.Replacement = registry.DefaultNamespace + "/" + name
+	}
+	return &out
+}
+
+// printWarnings tells the user about the entries they resolved.
+func printWarnings(w io.Writer, ws []registry.Warning) {
+	for _, x := range ws {
+		fmt.Fprintf(w, "warning: %s\n", x)
+	}
+}
+
+// runDeprecate marks entries deprecated, or with --undo no longer so.
+// The mark lasts until the entry is published again, when its manifest
+// decides.
+func runDeprecate(args []string) error {
+	flags := flag.NewFlagSet("deprecate", flag.ExitOnError)
+	reason := flags.String("reason", "", "why users should move off the entries (required)")
+	replacement := flags.String("replacement", "", "entry to use instead")
+	sunset := flags.String("sunset", "", "date the entries are due to be removed, YYYY-MM-DD")
+	undo := flags.Bool("undo", false, "clear the deprecation instead")
+	flags.Parse(args)
+	if flags.NArg() == 0 || (*reason == "") == !*undo {
+		return errors.New("usage: deprecate --reason <text> [--replacement name] [--sunset YYYY-MM-DD] <namespace/name | name>...\n       deprecate --undo <namespace/name | name>...")
+	}
+
+	var d *registry.Deprecation
+	if !*undo {
+		d = &registry.Deprecation{Reason: *reason, Replacement: *replacement}
+		if *sunset != "" {
+			t, err := time.Parse(time.DateOnly, *sunset)
+			if err != nil {
+				return fmt.Errorf("--sunset: %w", err)
+			}
+			d.Sunset = t
+		}
+		d = qualifyDeprecation(d)
+	}
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	if d != nil && d.Replacement != "" {
+		if _, err := registry.Find(*st.snapshot(), d.Replacement); err != nil {
+			return fmt.Errorf("--replacement: %w", err)
+		}
+	}
+	tx := st.begin()
+	for _, ref := range flags.Args() {
+		bp, err := registry.Find(*st.snapshot(), ref)
+		if err != nil {
+			return err
+		}
+		name := bp.FullName()
+		if d != nil && d.Replacement == name {
+			return fmt.Errorf("%s can't replace itself", name)
+		}
+		tx.stage(func(db *registry.Database) error {
+			for i := range db.Blueprints {
+				if db.Blueprints[i].FullName() == name {
+					db.Blueprints[i].Deprecation = d
+					return nil
+				}
+			}
+			return fmt.Errorf("%s: %w", name, registry.ErrNotFound)
+		})
+		if d != nil {
+			fmt.Fprintf(os.Stderr, "deprecated %s\n", name)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s is no longer deprecated\n", name)
+		}
+	}
+	if err := tx.commit(); err != nil {
+		return fmt.Errorf("update registry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+var errDependencyCycle = errors.New("dependency cycle")
+
+// checkDependencies normalizes manifest dependencies to full names and
+// drops malformed ones, reporting them as findings.
+func checkDependencies(deps []registry.Dependency, self string) ([]registry.Dependency, []string) {
+	var out []registry.Dependency
+	var findings []string
+	for _, d := range deps {
+		ns, name := registry.SplitRef(d.Name)
+		if ns == "" {
+			ns = registry.DefaultNamespace
+		}
+		if err := registry.ValidateIdent("namespace", ns); err != nil {
+			findings = append(findings, fmt.Sprintf("dependency %q: %v", d.Name, err))
+			continue
+		}
+		if err := registry.ValidateIdent("name", name); err != nil {
+			findings = append(findings, fmt.Sprintf("dependency %q: %v", d.Name, err))
+			continue
+		}
+		if _, err := registry.VersionMatches(d.Version, "0.0.0"); err != nil {
+			findings = append(findings, fmt.Sprintf("dependency %q: %v", d.Name, err))
+			continue
+		}
+		d.Name = ns + "/" + name
+		if d.Name == self {
+			findings = append(findings, "depends on itself")
+			continue
+		}
+		out = append(out, d)
+	}
+	return out, findings
+}
+
+// closureEntry is one blueprint of a dependency closure, resolved to the
+// version the registry has.
+type closureEntry struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	DownloadURL string   `json:"download_url"`
+	Mirrors     []string `json:"mirrors,omitempty"`
+	// RequiredBy lists the dependents with their constraints, e.g.
+	// "getdragon/rest-api (^1.2)". Empty for the root.
+	RequiredBy []string `json:"required_by,omitempty"`
+}
+
+// depClosure is everything needed to scaffold Root, in install order:
+// dependencies before their dependents, Root last.
+type depClosure struct {
+	Root    string         `json:"root"`
+	Entries []closureEntry `json:"entries"`
+	// Warnings are about the entries of the closure, e.g. deprecations.
+	Warnings []registry.Warning `json:"warnings,omitempty"`
+}
+
+// dependencyClosure resolves ref and all its transitive dependencies,
+// checking each against the constraints placed on it.
+func dependencyClosure(db registry.Database, ref string) (depClosure, error) {
+	root, err := registry.Find(db, ref)
+	if err != nil {
+		return depClosure{}, err
+	}
+	byName := map[string]registry.Blueprint{}
+	for _, bp := range db.Blueprints {
+		byName[bp.FullName()] = bp
+	}
+
+	var order []string
+	requiredBy := map[string][]string{}
+	state := map[string]int{} // 1 visiting, 2 done
+	var visit func(bp registry.Blueprint, path []string) error
+	visit = func(bp registry.Blueprint, path []string) error {
+		name := bp.FullName()
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("%w: %s -> %s", errDependencyCycle, strings.Join(path, " -> "), name)
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, d := range bp.Dependencies {
+			dep, ok := byName[d.Name]
+			if !ok {
+				return fmt.Errorf("%s requires %s: %w", name, d.Name, registry.ErrNotFound)
+			}
+			ok, err := registry.VersionMatches(d.Version, dep.Version)
+			if err != nil {
+				return fmt.Errorf("%s requires %s: %w", name, d.Name, err)
+			}
+			if !ok {
+				return fmt.Errorf("%s requires %s %s, but the registry has %s", name, d.Name, d.Version, dep.Version)
+			}
+			by := name
+			if d.Version != "" {
+				by += " (" + d.Version + ")"
+			}
+			requiredBy[d.Name] = append(requiredBy[d.Name], by)
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+	if err := visit(root, nil); err != nil {
+		return depClosure{}, err
+	}
+
+	c := depClosure{Root: root.FullName()}
+	now := time.Now()
+	for _, name := range order {
+		bp := byName[name]
+		c.Warnings = append(c.Warnings, bp.Warnings(now)...)
+		c.Entries = append(c.Entries, closureEntry{
+			Name:        name,
+			Version:     bp.Version,
+			DownloadURL: bp.DownloadURL,
+			Mirrors:     bp.Mirrors,
+			RequiredBy:  requiredBy[name],
+		})
+	}
+	return c, nil
+}
+
+// runDeps prints the dependency closure of a blueprint.
+func runDeps(args []string) error {
+	flags := flag.NewFlagSet("deps", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: deps [--json] <namespace/name | name>")
+	}
+
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	c, err := dependencyClosure(db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(c)
+	}
+	printWarnings(os.Stderr, c.Warnings)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tREQUIRED BY\tURL")
+	for _, e := range c.Entries {
+		by := strings.Join(e.RequiredBy, ", ")
+		if by == "" {
+			by = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Name, e.Version, by, e.DownloadURL)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,281 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime/quotedprintable"
+	"net/http"
+	"net/smtp"
+	"os"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// digest is the data handed to the email templates.
+type digest struct {
+	Period  string
+	Since   time.Time
+	Until   time.Time
+	New     []registry.Blueprint
+	Updated []registry.Blueprint
+}
+
+func (d digest) Empty() bool { return len(d.New) == 0 && len(d.Updated) == 0 }
+
+func buildDigest(db registry.Database, period string, until time.Time) (digest, error) {
+	var window time.Duration
+	switch period {
+	case "daily":
+		window = 24 * time.Hour
+	case "weekly":
+		window = 7 * 24 * time.Hour
+	default:
+		return digest{}, fmt.Errorf("unknown period %q (want daily or weekly)", period)
+	}
+	d := digest{Period: period, Since: until.Add(-window), Until: until}
+	in := func(t time.Time) bool { return t.After(d.Since) && !t.After(d.Until) }
+	for _, bp := range db.Blueprints {
+		switch {
+		case in(bp.CreatedAt):
+			d.New = append(d.New, bp)
+		case in(bp.UpdatedAt):
+			d.Updated = append(d.Updated, bp)
+		}
+	}
+	byName := func(s []registry.Blueprint) {
+		slices.SortFunc(s, func(a, b registry.Blueprint) int { return strings.Compare(a.FullName(), b.FullName()) })
+	}
+	byName(d.New)
+	byName(d.Updated)
+	return d, nil
+}
+
+const digestText = `Dragon registry {{.Period}} digest
+{{.Since.Format "2006-01-02"}} to {{.Until.Format "2006-01-02"}}
+{{if .Empty}}
+No blueprints were added or updated.
+{{end}}{{if .New}}
+New blueprints:
+{{range .New}}  - {{.FullName}} {{.Version}}: {{.Description}}
+    {{.DownloadURL}}
+{{end}}{{end}}{{if .Updated}}
+Updated blueprints:
+{{range .Updated}}  - {{.FullName}} {{.Version}}: {{.Description}}
+    {{.DownloadURL}}
+{{end}}{{end}}`
+
+const digestHTML = `<!DOCTYPE html>
+<html><body>
+<h1>Dragon registry {{.Period}} digest</h1>
+<p>{{.Since.Format "2006-01-02"}} to {{.Until.Format "2006-01-02"}}</p>
+{{if .Empty}}<p>No blueprints were added or updated.</p>{{end}}
+{{if .New}}<h2>New blueprints</h2>
+<ul>{{range .New}}
+<li><a href="{{.DownloadURL}}">{{.FullName}}</a> {{.Version}}: {{.Description}}</li>{{end}}
+</ul>{{end}}
+{{if .Updated}}<h2>Updated blueprints</h2>
+<ul>{{range .Updated}}
+<li><a href="{{.DownloadURL}}">{{.FullName}}</a> {{.Version}}: {{.Description}}</li>{{end}}
+</ul>{{end}}
+</body></html>
+`
+
+var (
+	digestTextTmpl = texttemplate.Must(texttemplate.New("text").Parse(digestText))
+	digestHTMLTmpl = htmltemplate.Must(htmltemplate.New("html").Parse(digestHTML))
+)
+
+func renderDigest(d digest) (text, html string, err error) {
+	var tb, hb bytes.Buffer
+	if err := digestTextTmpl.Execute(&tb, d); err != nil {
+		return "", "", err
+	}
+	if err := digestHTMLTmpl.Execute(&hb, d); err != nil {
+		return "", "", err
+	}
+	return tb.String(), hb.String(), nil
+}
+
+// runDigest renders the digest and prints it, or mails it when --send is set.
+// SMTP settings come from SMTP_HOST, SMTP_PORT, SMTP_USERNAME and
+// SMTP_PASSWORD (Amazon SES works through its SMTP interface); SendGrid
+// uses SENDGRID_API_KEY.
+func runDigest(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("digest", flag.ExitOnError)
+	period := fs.String("period", "weekly", "digest window: daily or weekly")
+	format := fs.String("format", "text", "body printed when not sending: text or html")
+	send := fs.String("send", "", "deliver via smtp or sendgrid instead of printing")
+	from := fs.String("from", os.Getenv("DIGEST_FROM"), "sender address")
+	to := fs.String("to", os.Getenv("DIGEST_TO"), "comma-separated recipients")
+	skipEmpty := fs.Bool("skip-empty", false, "do nothing when there is no activity")
+	fs.Parse(args)
+
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	// digests go to mailing lists, so only public entries are included
+	d, err := buildDigest(filterVisible(db, anonymous), *period, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if d.Empty() && *skipEmpty {
+		return nil
+	}
+	text, html, err := renderDigest(d)
+	if err != nil {
+		return fmt.Errorf("render digest: %w", err)
+	}
+
+	if *send == "" {
+		switch *format {
+		case "text":
+			fmt.Print(text)
+		case "html":
+			fmt.Print(html)
+		default:
+			return fmt.Errorf("unknown format %q", *format)
+		}
+		return nil
+	}
+
+	if *from == "" || *to == "" {
+		return errors.New("sending a digest needs --from and --to")
+	}
+	var rcpt []string
+	for _, addr := range strings.Split(*to, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			rcpt = append(rcpt, addr)
+		}
+	}
+	subject := fmt.Sprintf("Dragon registry %s digest: %d new, %d updated", d.Period, len(d.New), len(d.Updated))
+	switch *send {
+	case "smtp":
+		err = sendSMTP(*from, rcpt, subject, text, html)
+	case "sendgrid":
+		err = sendSendGrid(ctx, *from, rcpt, subject, text, html)
+	default:
+		return fmt.Errorf("unknown delivery %q (want smtp or sendgrid)", *send)
+	}
+	if err != nil {
+		return fmt.Errorf("send digest: %w", err)
+	}
+	fmt.Printf("digest sent to %d recipients\n", len(rcpt))
+	return nil
+}
+
+func sendSMTP(from string, to []string, subject, text, html string) error {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return errors.New("missing SMTP_HOST env")
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	var auth smtp.Auth
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return err
+	}
+	boundary := hex.EncodeToString(b[:])
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", boundary)
+	for _, part := range []struct{ typ, body string }{{"text/plain", text}, {"text/html", html}} {
+		fmt.Fprintf(&msg, "--%s\r\nContent-Type: %s; charset=utf-8\r\n", boundary, part.typ)
+		fmt.Fprintf(&msg, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		// keeps lines within SMTP's limit and non-ASCII names intact
+		qp := quotedprintable.NewWriter(&msg)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return err
+		}
+		if err := qp.Close(); err != nil {
+			return err
+		}
+		msg.WriteString("\r\n")
+	}
+	fmt.Fprintf(&msg, "--%s--\r\n", boundary)
+
+	return smtp.SendMail(host+":"+port, auth, from, to, msg.Bytes())
+}
+
+func sendSendGrid(ctx context.Context, from string, to []string, subject, text, html string) error {
+	key := os.Getenv("SENDGRID_API_KEY")
+	if key == "" {
+		return errors.New("missing SENDGRID_API_KEY env")
+	}
+	type address struct {
+		Email string `json:"email"`
+	}
+	type personalization struct {
+		To []address `json:"to"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	var p struct {
+		Personalizations []personalization `json:"personalizations"`
+		From             address           `json:"from"`
+		Subject          string            `json:"subject"`
+		Content          []content         `json:"content"`
+	}
+	var rcpt personalization
+	for _, addr := range to {
+		rcpt.To = append(rcpt.To, address{addr})
+	}
+	p.Personalizations = []personalization{rcpt}
+	p.From = address{from}
+	p.Subject = subject
+	p.Content = []content{{"text/plain", text}, {"text/html", html}}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequestWithContext(ctx, "POST", "https://api.sendgrid.com/v3/mail/send", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := defaultClient.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("POST sendgrid: %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// topicRe matches GitHub topics.
+var topicRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// discoveryConfig finds blueprint repos in a GitHub org by topic.
+type discoveryConfig struct {
+	// Org is the org (or user) whose repos are listed. Empty disables
+	// discovery.
+	Org string `yaml:"org"`
+	// Topic marks blueprint repos. Defaults to dragon-blueprint.
+	Topic string `yaml:"topic"`
+	// Sources is the source list discovery keeps. Defaults to
+	// sources.yaml.
+	Sources string `yaml:"sources"`
+}
+
+func (d discoveryConfig) validate() error {
+	if d.Org != "" && !repoOwnerRe.MatchString(d.Org) {
+		return fmt.Errorf("discovery.org: invalid org %q", d.Org)
+	}
+	if d.Topic != "" && !topicRe.MatchString(d.Topic) {
+		return fmt.Errorf("discovery.topic: invalid topic %q", d.Topic)
+	}
+	return nil
+}
+
+func (d discoveryConfig) topic() string { return orDefault(d.Topic, "dragon-blueprint") }
+
+func (d discoveryConfig) sources() string { return orDefault(d.Sources, "sources.yaml") }
+
+// sourcesFile lists the repos blueprints are indexed from.
+type sourcesFile struct {
+	Sources []source `yaml:"sources"`
+}
+
+type source struct {
+	// Repo is owner/repo.
+	Repo string `yaml:"repo"`
+	// Dir holds the repo's manifests, one directory per blueprint.
+	// Defaults to blueprints.
+	Dir string `yaml:"dir,omitempty"`
+	// Namespace is where its entries go, over $REGISTRY_NAMESPACE.
+	Namespace string `yaml:"namespace,omitempty"`
+	// Assets replaces the assets rules for its releases.
+	Assets *assetRules `yaml:"assets,omitempty"`
+	// Discovered marks the sources discover added. It drops them again
+	// when the repo stops qualifying; sources added by hand are left
+	// alone.
+	Discovered bool `yaml:"discovered,omitempty"`
+}
+
+// loadSources reads a sources file. A missing file lists no sources.
+func loadSources(p string) (sourcesFile, error) {
+	var sf sourcesFile
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return sf, nil
+		}
+		return sf, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&sf); err != nil && !errors.Is(err, io.EOF) {
+		return sf, fmt.Errorf("%s: %w", p, err)
+	}
+	for i, s := range sf.Sources {
+		repo, err := parseRepo(s.Repo)
+		if err != nil {
+			return sf, fmt.Errorf("%s: %w", p, err)
+		}
+		sf.Sources[i].Repo = repo
+		if err := s.validate(); err != nil {
+			return sf, fmt.Errorf("%s: %s: %w", p, repo, err)
+		}
+	}
+	return sf, nil
+}
+
+func (s source) validate() error {
+	if d := s.Dir; d != "" && (path.IsAbs(d) || !fs.ValidPath(d) || d == ".") {
+		return fmt.Errorf("dir: %q is not a relative path in the repo", d)
+	}
+	if s.Namespace != "" {
+		if err := registry.ValidateIdent("namespace", s.Namespace); err != nil {
+			return err
+		}
+	}
+	if s.Assets != nil {
+		return s.Assets.validate()
+	}
+	return nil
+}
+
+func (s source) dir() string { return orDefault(s.Dir, "blueprints") }
+
+// source returns how repo is listed, or its defaults if it isn't.
+func (sf sourcesFile) source(repo string) source {
+	if i := slices.IndexFunc(sf.Sources, func(s source) bool { return strings.EqualFold(s.Repo, repo) }); i >= 0 {
+		return sf.Sources[i]
+	}
+	return source{Repo: repo}
+}
+
+func (sf sourcesFile) has(repo string) bool {
+	return slices.ContainsFunc(sf.Sources, func(s source) bool { return strings.EqualFold(s.Repo, repo) })
+}
+
+// syncSources adds the repos found that aren't listed yet and drops the
+// discovered sources that weren't found.
+func syncSources(sf *sourcesFile, found []string) (added, removed []string) {
+	sf.Sources = slices.DeleteFunc(sf.Sources, func(s source) bool {
+		gone := s.Discovered && !slices.ContainsFunc(found, func(r string) bool { return strings.EqualFold(r, s.Repo) })
+		if gone {
+			removed = append(removed, s.Repo)
+		}
+		return gone
+	})
+	for _, r := range found {
+		if !sf.has(r) {
+			sf.Sources = append(sf.Sources, source{Repo: r, Discovered: true})
+			added = append(added, r)
+		}
+	}
+	return added, removed
+}
+
+// discoverRepos lists the repos of org tagged with topic, leaving out
+// archived repos and forks, which inherit their upstream's topics.
+func discoverRepos(ctx context.Context, org, topic string) ([]string, error) {
+	type repo struct {
+		FullName string   `json:"full_name"`
+		Topics   []string `json:"topics"`
+		Archived bool     `json:"archived"`
+		Fork     bool     `json:"fork"`
+	}
+	repos, err := registry.List[repo](ctx, defaultClient, defaultClient.apiURL("/orgs/%s/repos", org))
+	var se *registry.StatusError
+	if errors.As(err, &se) && se.Code == http.StatusNotFound {
+		// a user rather than an org
+		repos, err = registry.List[repo](ctx, defaultClient, defaultClient.apiURL("/users/%s/repos", org))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var found []string
+	for _, r := range repos {
+		if slices.Contains(r.Topics, topic) && !r.Archived && !r.Fork {
+			found = append(found, r.FullName)
+		}
+	}
+	slices.Sort(found)
+	return found, nil
+}
+
+// repoRelease is a repo's latest release.
+type repoRelease struct {
+	TagName     string    `json:"tag_name"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// latestRelease returns repo's latest release; its tag is "" if it has
+// none.
+func latestRelease(ctx context.Context, repo string) (repoRelease, error) {
+	var rel repoRelease
+	b, err := defaultClient.get(ctx, defaultClient.apiURL("/repos/%s/releases/latest", repo))
+	var se *registry.StatusError
+	if errors.As(err, &se) && se.Code == http.StatusNotFound {
+		return rel, nil
+	}
+	if err != nil {
+		return rel, err
+	}
+	if err := json.Unmarshal(b, &rel); err != nil {
+		return rel, fmt.Errorf("decode release: %w", err)
+	}
+	return rel, nil
+}
+
+// runDiscover brings the source list in line with the blueprint repos of
+// discovery.org, and can queue the releases of sources not indexed yet.
+func runDiscover(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "print the changes without writing the sources file")
+	enqueue := flags.Bool("enqueue", false, "queue the latest release of each discovered source with no entries yet")
+	dir := flags.String("queue-dir", "", "queue directory, with --enqueue; default queue.dir or .dragon-queue")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	d := cfg.Discovery
+	if d.Org == "" {
+		return errors.New("discovery.org is not set")
+	}
+	sf, err := loadSources(d.sources())
+	if err != nil {
+		return fmt.Errorf("sources: %w", err)
+	}
+	found, err := discoverRepos(ctx, d.Org, d.topic())
+	if err != nil {
+		return fmt.Errorf("discover %s: %w", d.Org, err)
+	}
+	added, removed := syncSources(&sf, found)
+	for _, r := range added {
+		fmt.Fprintf(os.Stderr, "added %s\n", r)
+	}
+	for _, r := range removed {
+		fmt.Fprintf(os.Stderr, "removed %s\n", r)
+	}
+	fmt.Fprintf(os.Stderr, "%d repos tagged %s in %s, %d sources\n", len(found), d.topic(), d.Org, len(sf.Sources))
+	if *dryRun {
+		return nil
+	}
+	if len(added) > 0 || len(removed) > 0 {
+		b, err := yaml.Marshal(sf)
+		if err != nil {
+			return err
+		}
+		if err := registry.WriteFileAtomic(d.sources(), b); err != nil {
+			return err
+		}
+	}
+	if !*enqueue {
+		return nil
+	}
+
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	q, err := cfg.Queue.open(*dir, 0)
+	if err != nil {
+		return fmt.Errorf("open queue: %w", err)
+	}
+	for _, s := range sf.Sources {
+		indexed := slices.ContainsFunc(db.Blueprints, func(bp registry.Blueprint) bool { return strings.EqualFold(bp.Repo, repoID(s.Repo)) })
+		if !s.Discovered || indexed {
+			continue
+		}
+		rel, err := latestRelease(ctx, s.Repo)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.Repo, err)
+		}
+		tag := rel.TagName
+		if tag == "" {
+			fmt.Fprintf(os.Stderr, "%s: no release yet\n", s.Repo)
+			continue
+		}
+		if _, err := q.push(ctx, updateJob{Repo: s.Repo, Tag: tag, EnqueuedAt: time.Now().UTC()}); err != nil {
+			return fmt.Errorf("enqueue: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "queued %s@%s\n", s.Repo, tag)
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// downloadRollup is the download counts ingested from proxy and CDN
+// access logs, kept in the file named by stats.downloads.
+type downloadRollup struct {
+	// Ingested holds the digests of the logs already counted, so feeding
+	// the same log twice doesn't count it twice.
+	Ingested []string `json:"ingested"`
+	// Versions counts downloads per entry and version.
+	Versions map[string]map[string]int64 `json:"versions"`
+	// Daily counts downloads per UTC day and entry.
+	Daily map[string]map[string]int64 `json:"daily"`
+}
+
+func readRollup(p string) (downloadRollup, error) {
+	r := downloadRollup{Versions: map[string]map[string]int64{}, Daily: map[string]map[string]int64{}}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return r, fmt.Errorf("%s: %w", p, err)
+	}
+	if r.Versions == nil {
+		r.Versions = map[string]map[string]int64{}
+	}
+	if r.Daily == nil {
+		r.Daily = map[string]map[string]int64{}
+	}
+	return r, nil
+}
+
+func writeRollup(p string, r downloadRollup) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return registry.WriteFileAtomic(p, append(b, '\n'))
+}
+
+func (r *downloadRollup) add(name, version string, at time.Time) {
+	if r.Versions[name] == nil {
+		r.Versions[name] = map[string]int64{}
+	}
+	r.Versions[name][version]++
+	day := at.UTC().Format(time.DateOnly)
+	if r.Daily[day] == nil {
+		r.Daily[day] = map[string]int64{}
+	}
+	r.Daily[day][name]++
+}
+
+// popularity sums the downloads of each entry on or after since; a zero
+// since counts everything.
+func (r downloadRollup) popularity(since time.Time) map[string]int64 {
+	out := map[string]int64{}
+	if since.IsZero() {
+		for name, versions := range r.Versions {
+			for _, n := range versions {
+				out[name] += n
+			}
+		}
+		return out
+	}
+	from := since.UTC().Format(time.DateOnly)
+	for day, names := range r.Daily {
+		if day < from {
+			continue
+		}
+		for name, n := range names {
+			out[name] += n
+		}
+	}
+	return out
+}
+
+// logHit is a successful archive download found in an access log.
+type logHit struct {
+	Time time.Time
+	Path string
+}
+
+// combinedRe matches the NCSA combined and common log formats that nginx,
+// Apache and most CDNs export.
+var combinedRe = regexp.MustCompile(`^\S+ \S+ \S+ \[([^\]]+)\] "(\S+) (\S+)[^"]*" (\d{3}) `)
+
+// parseLogLine extracts a download from one log line. Only complete
+// downloads count: GETs answered 200, not range requests or redirects.
+func parseLogLine(format, line string) (logHit, bool) {
+	var method, target, status string
+	var at time.Time
+	switch format {
+	case "json":
+		var rec struct {
+			Time   string `json:"time"`
+			Method string `json:"method"`
+			Path   string `json:"path"`
+			Status int    `json:"status"`
+		}
+		if json.Unmarshal([]byte(line), &rec) != nil {
+			return logHit{}, false
+		}
+		t, err := time.Parse(time.RFC3339, rec.Time)
+		if err != nil {
+			return logHit{}, false
+		}
+		method, target, status, at = rec.Method, rec.Path, strconv.Itoa(rec.Status), t
+		if method == "" {
+			method = "GET"
+		}
+	default:
+		m := combinedRe.FindStringSubmatch(line)
+		if m == nil {
+			return logHit{}, false
+		}
+		t, err := time.Parse("02/Jan/2006:15:04:05 -0700", m[1])
+		if err != nil {
+			return logHit{}, false
+		}
+		method, target, status, at = m[2], m[3], m[4], t
+	}
+	if method != "GET" || status != "200" {
+		return logHit{}, false
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return logHit{}, false
+	}
+	return logHit{Time: at, Path: u.Path}, true
+}
+
+// archiveRef names the release an archive belongs to.
+type archiveRef struct{ Name, Version string }
+
+// archiveIndex maps the URL paths of every known archive, current and
+// previous releases and their mirrors, to the release. Logs record paths,
+// not hosts, so hosts are ignored.
+func archiveIndex(db registry.Database) map[string]archiveRef {
+	idx := map[string]archiveRef{}
+	addURL := func(raw string, ref archiveRef) {
+		if u, err := url.Parse(raw); err == nil && u.Path != "" {
+			idx[u.Path] = ref
+		}
+	}
+	for _, bp := range db.Blueprints {
+		cur := archiveRef{bp.FullName(), bp.Version}
+		for _, u := range append([]string{bp.DownloadURL, bp.SourceURL}, bp.Mirrors...) {
+			addURL(u, cur)
+		}
+		for _, r := range bp.Previous {
+			addURL(r.DownloadURL, archiveRef{bp.FullName(), r.Version})
+		}
+	}
+	return idx
+}
+
+// openLog opens a log file, decompressing .gz files.
+func openLog(p string) (io.ReadCloser, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(p, ".gz") {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{zr, f}, nil
+}
+
+// ingestLog counts the downloads in one log into r. match maps a request
+// path to a release.
+func ingestLog(r *downloadRollup, p, format string, match func(string) (archiveRef, bool)) (counted, skipped int, err error) {
+	rc, err := openLog(p)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rc.Close()
+	sc := bufio.NewScanner(rc)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		hit, ok := parseLogLine(format, sc.Text())
+		if !ok {
+			continue
+		}
+		ref, ok := match(hit.Path)
+		if !ok {
+			skipped++
+			continue
+		}
+		r.add(ref.Name, ref.Version, hit.Time)
+		counted++
+	}
+	return counted, skipped, sc.Err()
+}
+
+// runStatsIngest rolls access logs up into per-entry, per-version
+// download counts.
+func runStatsIngest(args []string) error {
+	flags := flag.NewFlagSet("stats ingest", flag.ExitOnError)
+	format := flags.String("format", "combined", "log format: combined (nginx/Apache/CDN) or json (one object with time, method, path and status per line)")
+	pattern := flags.String("pattern", "", "regexp with name and version groups mapping request paths to releases, for proxies that don't serve the registry's URLs")
+	flags.Parse(args)
+	if *format != "combined" && *format != "json" {
+		return fmt.Errorf("unknown log format %q", *format)
+	}
+	if flags.NArg() == 0 {
+		return errors.New("usage: stats ingest [--format combined|json] [--pattern re] <log>...")
+	}
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if cfg.Stats.Downloads == "" {
+		return errors.New("no stats.downloads file configured")
+	}
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	idx := archiveIndex(db)
+	match := func(p string) (archiveRef, bool) {
+		ref, ok := idx[p]
+		return ref, ok
+	}
+	if *pattern != "" {
+		re, err := regexp.Compile(*pattern)
+		if err != nil {
+			return fmt.Errorf("--pattern: %w", err)
+		}
+		ni, vi := re.SubexpIndex("name"), re.SubexpIndex("version")
+		if ni < 0 || vi < 0 {
+			return errors.New("--pattern needs (?P<name>...) and (?P<version>...) groups")
+		}
+		match = func(p string) (archiveRef, bool) {
+			m := re.FindStringSubmatch(p)
+			if m == nil {
+				return archiveRef{}, false
+			}
+			ns, name := registry.SplitRef(m[ni])
+			if ns == "" {
+				ns = registry.DefaultNamespace
+			}
+			return archiveRef{ns + "/" + name, m[vi]}, true
+		}
+	}
+
+	r, err := readRollup(cfg.Stats.Downloads)
+	if err != nil {
+		return fmt.Errorf("read downloads: %w", err)
+	}
+	for _, p := range flags.Args() {
+		// hashed uncompressed, so a log and its rotated .gz are one log
+		rc, err := openLog(p)
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if slices.Contains(r.Ingested, sum) {
+			fmt.Printf("%s: already ingested\n", p)
+			continue
+		}
+		counted, skipped, err := ingestLog(&r, p, *format, match)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		r.Ingested = append(r.Ingested, sum)
+		fmt.Printf("%s: %d downloads, %d unknown archives\n", p, counted, skipped)
+	}
+	return writeRollup(cfg.Stats.Downloads, r)
+}
+
+// runStatsDownloads prints the ingested download counts.
+func runStatsDownloads(args []string) error {
+	flags := flag.NewFlagSet("stats downloads", flag.ExitOnError)
+	since := flags.String("since", "", "only count downloads on or after this date (YYYY-MM-DD)")
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if cfg.Stats.Downloads == "" {
+		return errors.New("no stats.downloads file configured")
+	}
+	r, err := readRollup(cfg.Stats.Downloads)
+	if err != nil {
+		return fmt.Errorf("read downloads: %w", err)
+	}
+	if *since == "" {
+		if *asJSON {
+			return printJSON(r.Versions)
+		}
+		names := make([]string, 0, len(r.Versions))
+		for name := range r.Versions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tVERSION\tDOWNLOADS")
+		for _, name := range names {
+			versions := make([]string, 0, len(r.Versions[name]))
+			for v := range r.Versions[name] {
+				versions = append(versions, v)
+			}
+			sort.Strings(versions)
+			for _, v := range versions {
+				fmt.Fprintf(w, "%s\t%s\t%d\n", name, v, r.Versions[name][v])
+			}
+		}
+		return w.Flush()
+	}
+	from, err := time.Parse(time.DateOnly, *since)
+	if err != nil {
+		return fmt.Errorf("--since: %w", err)
+	}
+	pop := r.popularity(from)
+	if *asJSON {
+		return printJSON(pop)
+	}
+	counts := make(map[string]int, len(pop))
+	for name, n := range pop {
+		counts[name] = int(n)
+	}
+	printCounts("downloads since "+*since, counts)
+	return nil
+}
@@ -0,0 +1,28 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build embed
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// embeddedFiles is the snapshot the image build stages in embedded/: a
+// registry.json and, optionally, the static site under site/.
+//
+//go:embed all:embedded
+var embeddedFiles embed.FS
+
+func init() {
+	embedded, _ = fs.Sub(embeddedFiles, "embedded")
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// fallbackConfig holds the templates that fill in what a manifest leaves
+// out, e.g. when a release has no manifest at all.
+type fallbackConfig struct {
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+}
+
+// Defaults reproduce the fixed fallbacks used before they were
+// configurable.
+const (
+	defaultFallbackName        = "{{.Asset}}"
+	defaultFallbackVersion     = `{{trimPrefix "v" .Tag}}`
+	defaultFallbackDescription = "{{.Asset}} blueprint"
+)
+
+// fallbackData is what the templates can refer to.
+type fallbackData struct {
+	// Repo is owner/repo and RepoName its last part.
+	Repo     string
+	RepoName string
+	Tag      string
+	// Asset is the blueprint name taken from the asset, AssetFile the
+	// asset's file name and AssetVersion the version in it, if any.
+	Asset        string
+	AssetFile    string
+	AssetVersion string
+	Namespace    string
+}
+
+var fallbackFuncs = template.FuncMap{
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"title": func(s string) string {
+		words := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '_' || r == ' ' })
+		for i, w := range words {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+		return strings.Join(words, " ")
+	},
+}
+
+func (f fallbackConfig) templates() map[string]string {
+	return map[string]string{
+		"name":        orDefault(f.Name, defaultFallbackName),
+		"version":     orDefault(f.Version, defaultFallbackVersion),
+		"description": orDefault(f.Description, defaultFallbackDescription),
+	}
+}
+
+func (f fallbackConfig) validate() error {
+	for field, text := range f.templates() {
+		if _, err := template.New(field).Funcs(fallbackFuncs).Option("missingkey=error").Parse(text); err != nil {
+			return fmt.Errorf("fallback.%s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// render executes the template for field.
+func (f fallbackConfig) render(field string, d fallbackData) (string, error) {
+	t, err := template.New(field).Funcs(fallbackFuncs).Option("missingkey=error").Parse(f.templates()[field])
+	if err != nil {
+		return "", fmt.Errorf("fallback.%s: %w", field, err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, d); err != nil {
+		return "", fmt.Errorf("fallback.%s: %w", field, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// fill sets the fields the manifest left empty from the templates.
+func (f fallbackConfig) fill(man *registry.Manifest, d fallbackData) error {
+	d.RepoName = path.Base(d.Repo)
+	for _, fl := range []struct {
+		field string
+		v     *string
+	}{
+		{"name", &man.Name},
+		{"version", &man.Version},
+		{"description", &man.Description},
+	} {
+		if *fl.v != "" {
+			continue
+		}
+		s, err := f.render(fl.field, d)
+		if err != nil {
+			return err
+		}
+		*fl.v = s
+	}
+	return nil
+}
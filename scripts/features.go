@@ -0,0 +1,220 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// Feature value types in the vocabulary.
+const (
+	featureBool   = "bool"
+	featureString = "string"
+	featureEnum   = "enum"
+)
+
+// featureRe matches feature names: slash-separated lowercase segments such
+// as "docker" or "ci/github-actions".
+var featureRe = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*(/[a-z0-9][a-z0-9.-]*)*$`)
+
+// featuresConfig points at the feature vocabulary.
+type featuresConfig struct {
+	// Vocabulary is a YAML file declaring the known features. Empty
+	// accepts any well-formed feature.
+	Vocabulary string `yaml:"vocabulary"`
+}
+
+// manifestConfig is how manifests are read.
+type manifestConfig struct {
+	// Strict fails a manifest with fields the updater doesn't know,
+	// which are otherwise ignored, so a typo such as "descripton" can't
+	// quietly leave the fallback description in place.
+	Strict bool `yaml:"strict"`
+}
+
+// featureVocab declares which features manifests may use and their types.
+type featureVocab struct {
+	Features map[string]featureDef `yaml:"features"`
+}
+
+type featureDef struct {
+	// Type is bool, string or enum.
+	Type        string `yaml:"type"`
+	Description string `yaml:"description"`
+	// Values are the allowed values of an enum.
+	Values []string `yaml:"values"`
+}
+
+// loadFeatureVocab reads a vocabulary file. An empty path means no
+// vocabulary.
+func loadFeatureVocab(p string) (*featureVocab, error) {
+	if p == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var v featureVocab
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&v); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	for name, def := range v.Features {
+		if !featureRe.MatchString(name) {
+			return nil, fmt.Errorf("%s: invalid feature name %q", p, name)
+		}
+		switch def.Type {
+		case featureBool, featureString:
+		case featureEnum:
+			if len(def.Values) == 0 {
+				return nil, fmt.Errorf("%s: enum feature %q has no values", p, name)
+			}
+		default:
+			return nil, fmt.Errorf("%s: feature %q: want type bool, string or enum, got %q", p, name, def.Type)
+		}
+	}
+	return &v, nil
+}
+
+// checkFeatures validates manifest features against the vocabulary and
+// returns the usable ones, normalized to bool or string values. Invalid
+// features are dropped and reported as findings.
+func checkFeatures(in map[string]any, vocab *featureVocab) (map[string]any, []string) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+	out := map[string]any{}
+	var findings []string
+	for name, raw := range in {
+		if !featureRe.MatchString(name) {
+			findings = append(findings, fmt.Sprintf("invalid feature name %q", name))
+			continue
+		}
+		typ := ""
+		var def featureDef
+		if vocab != nil {
+			var ok bool
+			if def, ok = vocab.Features[name]; !ok {
+				findings = append(findings, fmt.Sprintf("unknown feature %q", name))
+				continue
+			}
+			typ = def.Type
+		}
+		v, err := featureValue(raw, typ, def.Values)
+		if err != nil {
+			findings = append(findings, fmt.Sprintf("feature %q: %v", name, err))
+			continue
+		}
+		out[name] = v
+	}
+	sort.Strings(findings)
+	if len(out) == 0 {
+		out = nil
+	}
+	return out, findings
+}
+
+// featureValue checks one value against its declared type; an empty type
+// accepts booleans and scalars.
+func featureValue(raw any, typ string, values []string) (any, error) {
+	switch x := raw.(type) {
+	case bool:
+		if typ != "" && typ != featureBool {
+			return nil, fmt.Errorf("want %s, got bool", typ)
+		}
+		return x, nil
+	case string, int, int64, uint64, float64:
+		if typ == featureBool {
+			return nil, fmt.Errorf("want bool, got %v", raw)
+		}
+		// numbers are accepted as strings, e.g. db/postgres: 16
+		s := fmt.Sprint(x)
+		if typ == featureEnum && !slices.Contains(values, s) {
+			return nil, fmt.Errorf("%q is not one of %s", s, strings.Join(values, ", "))
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unsupported value %v", raw)
+}
+
+// featureFilter selects entries by feature: "docker" requires the feature
+// to be set (and true, for booleans), "db/postgres=16" a specific value.
+type featureFilter struct {
+	Name  string
+	Value string // empty: any value
+}
+
+func parseFeatureFilters(args []string) []featureFilter {
+	var out []featureFilter
+	for _, a := range args {
+		name, value, _ := strings.Cut(a, "=")
+		out = append(out, featureFilter{Name: name, Value: value})
+	}
+	return out
+}
+
+// matchFeatures reports whether bp satisfies every filter.
+func matchFeatures(bp registry.Blueprint, filters []featureFilter) bool {
+	for _, f := range filters {
+		v, ok := bp.Features[f.Name]
+		if !ok || v == false {
+			return false
+		}
+		if f.Value != "" && fmt.Sprint(v) != f.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// runFeatures lists the entries having the given features, or with no
+// arguments how often each feature is used.
+func runFeatures(args []string) error {
+	flags := flag.NewFlagSet("features", flag.ExitOnError)
+	flags.Parse(args)
+
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	db = filterVisible(db, anonymous)
+	if flags.NArg() == 0 {
+		counts := map[string]int{}
+		for _, bp := range db.Blueprints {
+			for name := range bp.Features {
+				counts[name]++
+			}
+		}
+		printCounts("features", counts)
+		return nil
+	}
+	filters := parseFeatureFilters(flags.Args())
+	for _, bp := range db.Blueprints {
+		if matchFeatures(bp, filters) {
+			fmt.Printf("%s\t%s\n", bp.FullName(), bp.Version)
+		}
+	}
+	return nil
+}
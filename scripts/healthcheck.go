@@ -0,0 +1,240 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// Link states in a health report.
+const (
+	linkOK   = "ok"
+	linkSlow = "slow"
+	linkDead = "dead"
+)
+
+type linkResult struct {
+	Entry     string `json:"entry"`
+	Kind      string `json:"kind"` // download or mirror
+	URL       string `json:"url"`
+	State     string `json:"state"`
+	Status    int    `json:"status,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type healthReport struct {
+	CheckedAt time.Time    `json:"checked_at"`
+	Total     int          `json:"total"`
+	Dead      int          `json:"dead"`
+	Slow      int          `json:"slow"`
+	Results   []linkResult `json:"results"`
+}
+
+// probeLink checks that url answers with a 2xx. HEAD is tried first; some
+// hosts reject it, so a one-byte ranged GET is the fallback.
+func probeLink(ctx context.Context, url string, timeout time.Duration) (status int, latency time.Duration, err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	for _, method := range []string{"HEAD", "GET"} {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return 0, 0, err
+		}
+		if method == "GET" {
+			req.Header.Set("Range", "bytes=0-0")
+		}
+		// once: a retry would hide the failure and skew the latency
+		resp, err := defaultClient.doOnce(req)
+		if err != nil {
+			return 0, time.Since(start), err
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<10))
+		resp.Body.Close()
+		status = resp.StatusCode
+		if method == "HEAD" && (status == http.StatusMethodNotAllowed || status == http.StatusForbidden) {
+			continue
+		}
+		break
+	}
+	latency = time.Since(start)
+	if status/100 != 2 {
+		return status, latency, fmt.Errorf("status %d", status)
+	}
+	return status, latency, nil
+}
+
+// checkLinks probes every download URL and mirror with bounded concurrency.
+func checkLinks(ctx context.Context, db registry.Database, concurrency int, timeout, slow time.Duration) healthReport {
+	var jobs []linkResult
+	for _, bp := range db.Blueprints {
+		if bp.DownloadURL != "" {
+			jobs = append(jobs, linkResult{Entry: bp.FullName(), Kind: "download", URL: bp.DownloadURL})
+		}
+		for _, m := range bp.Mirrors {
+			jobs = append(jobs, linkResult{Entry: bp.FullName(), Kind: "mirror", URL: m})
+		}
+	}
+
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r *linkResult) {
+			defer func() { <-sem; wg.Done() }()
+			status, latency, err := probeLink(ctx, r.URL, timeout)
+			r.Status, r.LatencyMS = status, latency.Milliseconds()
+			switch {
+			case err != nil:
+				r.State, r.Error = linkDead, err.Error()
+			case latency > slow:
+				r.State = linkSlow
+			default:
+				r.State = linkOK
+			}
+		}(&jobs[i])
+	}
+	wg.Wait()
+
+	rep := healthReport{CheckedAt: time.Now().UTC(), Total: len(jobs), Results: jobs}
+	for _, r := range jobs {
+		switch r.State {
+		case linkDead:
+			rep.Dead++
+		case linkSlow:
+			rep.Slow++
+		}
+	}
+	sort.SliceStable(rep.Results, func(i, j int) bool { return rep.Results[i].Entry < rep.Results[j].Entry })
+	return rep
+}
+
+func runHealthcheck(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("healthcheck", flag.ExitOnError)
+	concurrency := flags.Int("concurrency", 8, "parallel probes")
+	timeout := flags.Duration("timeout", 15*time.Second, "per-link timeout")
+	slow := flags.Duration("slow", 3*time.Second, "latency above which a link is reported slow")
+	out := flags.String("o", "", "write the JSON report here instead of stdout")
+	issue := flags.String("issue", "", "open an issue in this owner/repo when links are dead or slow")
+	fail := flags.Bool("fail", false, "exit non-zero when any link is dead")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	rep := checkLinks(ctx, db, *concurrency, *timeout, *slow)
+
+	b, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		fmt.Println(string(b))
+	} else if err := os.WriteFile(*out, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "checked %d links: %d dead, %d slow\n", rep.Total, rep.Dead, rep.Slow)
+	if cfg.Mirrors.Scores != "" {
+		if err := scoreMirrors(cfg, rep, db); err != nil {
+			return fmt.Errorf("score mirrors: %w", err)
+		}
+	}
+
+	if *issue != "" && rep.Dead+rep.Slow > 0 {
+		if err := openHealthIssue(ctx, *issue, rep); err != nil {
+			return fmt.Errorf("open issue: %w", err)
+		}
+	}
+	if *fail && rep.Dead > 0 {
+		return fmt.Errorf("%d dead links", rep.Dead)
+	}
+	return nil
+}
+
+// openHealthIssue files the unhealthy links as a GitHub issue.
+func openHealthIssue(ctx context.Context, repo string, rep healthReport) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Healthcheck at %s found %d dead and %d slow links out of %d.\n\n",
+		rep.CheckedAt.Format(time.RFC3339), rep.Dead, rep.Slow, rep.Total)
+	body.WriteString("| Entry | Kind | State | Status | Latency | URL |\n|---|---|---|---|---|---|\n")
+	for _, r := range rep.Results {
+		if r.State == linkOK {
+			continue
+		}
+		status := fmt.Sprint(r.Status)
+		if r.Error != "" && r.Status == 0 {
+			status = r.Error
+		}
+		fmt.Fprintf(&body, "| %s | %s | %s | %s | %dms | %s |\n", r.Entry, r.Kind, r.State, status, r.LatencyMS, r.URL)
+	}
+	payload, err := json.Marshal(map[string]any{
+		"title": fmt.Sprintf("Registry healthcheck: %d dead, %d slow links", rep.Dead, rep.Slow),
+		"body":  body.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return defaultClient.githubJSON(ctx, "POST", defaultClient.apiURL("/repos/%s/issues", repo), bytes.NewReader(payload), "application/json", nil)
+}
+
+// scoreMirrors updates the mirror scores and, if configured, puts every
+// entry's healthiest mirror first.
+func scoreMirrors(cfg config, rep healthReport, db registry.Database) error {
+	s, err := readMirrorScores(cfg.Mirrors.Scores)
+	if err != nil {
+		return err
+	}
+	s.record(rep, db)
+	if err := writeMirrorScores(cfg.Mirrors.Scores, s); err != nil {
+		return err
+	}
+	if !cfg.Mirrors.Reorder {
+		return nil
+	}
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return err
+	}
+	// leave the registry file alone when the order already holds
+	probe := st.snapshot().Clone()
+	if orderMirrors(&probe, s) == 0 {
+		return nil
+	}
+	changed := 0
+	err = st.update(func(db *registry.Database) error {
+		changed = orderMirrors(db, s)
+		return nil
+	})
+	if err == nil && changed > 0 {
+		fmt.Fprintf(os.Stderr, "reordered the mirrors of %d entries\n", changed)
+	}
+	return err
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// cacheNone turns the response cache off in http.cache.
+const cacheNone = "none"
+
+// responseCache keeps the GET responses that carry a validator (ETag or
+// Last-Modified) on disk, keyed by URL, so later runs revalidate them with
+// a conditional request rather than fetch them again. GitHub doesn't count
+// a 304 against the rate limit, so unchanged releases and manifests cost
+// no quota. A nil *responseCache caches nothing.
+type responseCache struct {
+	dir string
+}
+
+// cachedResponse is the header of a cache file; the body follows it on
+// the next line.
+type cachedResponse struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	StoredAt     time.Time `json:"stored_at"`
+}
+
+// newResponseCache returns the cache http.cache configures: a directory,
+// by default under the user cache directory, or none.
+func newResponseCache(setting string) *responseCache {
+	switch setting {
+	case cacheNone:
+		return nil
+	case "":
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return nil
+		}
+		setting = filepath.Join(dir, "dragon-registry", "http")
+	}
+	return &responseCache{dir: setting}
+}
+
+func (c *responseCache) path(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// lookup returns the cached response to a GET of u, if any.
+func (c *responseCache) lookup(u string) (cachedResponse, []byte, bool) {
+	var meta cachedResponse
+	if c == nil {
+		return meta, nil, false
+	}
+	b, err := os.ReadFile(c.path(u))
+	if err != nil {
+		return meta, nil, false
+	}
+	head, body, ok := bytes.Cut(b, []byte("\n"))
+	if !ok || json.Unmarshal(head, &meta) != nil || meta.URL != u {
+		return cachedResponse{}, nil, false
+	}
+	return meta, body, true
+}
+
+// revalidate makes req conditional on the cached response to it, and
+// returns that response's body; nil when nothing is cached.
+func (c *responseCache) revalidate(req *http.Request) []byte {
+	meta, body, ok := c.lookup(req.URL.String())
+	if !ok {
+		return nil
+	}
+	if meta.ETag != "" {
+		req.Header.Set("If-None-Match", meta.ETag)
+	}
+	if meta.LastModified != "" {
+		req.Header.Set("If-Modified-Since", meta.LastModified)
+	}
+	return body
+}
+
+// store caches body as the response to a GET of u, if resp carries a
+// validator to revalidate it with. The cache is an optimization, so
+// failing to write it isn't an error. Files are private to the user:
+// they may hold private repos' data.
+func (c *responseCache) store(u string, resp *http.Response, body []byte) {
+	meta := cachedResponse{
+		URL:          u,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		StoredAt:     time.Now().UTC(),
+	}
+	if c == nil || meta.ETag == "" && meta.LastModified == "" {
+		return
+	}
+	head, err := json.Marshal(meta)
+	if err != nil {
+		return
+	}
+	p := c.path(u)
+	if os.MkdirAll(filepath.Dir(p), 0o700) != nil {
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(append(append(head, '\n'), body...))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = registry.ReplaceFile(f.Name(), p)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
@@ -0,0 +1,505 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// Auth strategies for http.auth.
+const (
+	authNone      = "none"
+	authPAT       = "pat"
+	authGitHubApp = "github-app"
+	authOIDC      = "oidc"
+)
+
+// httpConfig selects how the updater authenticates to GitHub and which
+// extra headers it sends to other hosts.
+type httpConfig struct {
+	// Auth is none, pat, github-app or oidc. Empty means pat when
+	// GITHUB_TOKEN is set and otherwise the token saved by login, if any.
+	Auth string `yaml:"auth"`
+	// GitHubAPI is the root of the GitHub REST API, e.g.
+	// https://github.example.com/api/v3 for GitHub Enterprise. Defaults
+	// to https://api.github.com; its host receives credentials.
+	GitHubAPI string `yaml:"github_api"`
+	// AuthHosts receive credentials, in addition to the github.com hosts
+	// and the API's.
+	AuthHosts []string      `yaml:"auth_hosts"`
+	App       githubAppAuth `yaml:"app"`
+	OIDC      oidcAuth      `yaml:"oidc"`
+	// Headers maps a host to headers sent with every request to it.
+	// Values are expanded from the environment, so secrets stay out of
+	// the file.
+	Headers map[string]map[string]string `yaml:"headers"`
+	// Retries retries requests that fail transiently.
+	Retries retryConfig `yaml:"retries"`
+	// Cache is the directory responses are cached in, to revalidate
+	// rather than fetch them again; none disables it. Default
+	// dragon-registry/http in the user cache directory.
+	Cache string `yaml:"cache"`
+}
+
+func (h httpConfig) validate() error {
+	if h.GitHubAPI != "" {
+		u, err := url.Parse(h.GitHubAPI)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("http.github_api: want an http(s) URL, got %q", h.GitHubAPI)
+		}
+	}
+	switch h.Auth {
+	case "", authNone, authPAT:
+	case authGitHubApp:
+		if h.App.ID == "" || h.App.InstallationID == "" {
+			return errors.New("http.app: id and installation_id are required")
+		}
+	case authOIDC:
+		if h.OIDC.ExchangeURL == "" {
+			return errors.New("http.oidc: exchange_url is required")
+		}
+	default:
+		return fmt.Errorf("http.auth: want none, pat, github-app or oidc, got %q", h.Auth)
+	}
+	return h.Retries.validate()
+}
+
+// authStrategy adds credentials to a request bound for an auth host.
+type authStrategy interface {
+	authorize(ctx context.Context, base *http.Client, req *http.Request) error
+}
+
+type noAuth struct{}
+
+func (noAuth) authorize(context.Context, *http.Client, *http.Request) error { return nil }
+
+// tokenAuth sends a personal access token, or the workflow's GITHUB_TOKEN.
+type tokenAuth struct{ token string }
+
+func (t tokenAuth) authorize(_ context.Context, _ *http.Client, req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return nil
+}
+
+// cachedToken holds a short-lived token until shortly before it expires.
+type cachedToken struct {
+	mu  sync.Mutex
+	tok string
+	exp time.Time
+}
+
+func (c *cachedToken) get(fetch func() (string, time.Time, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tok != "" && time.Until(c.exp) > 5*time.Minute {
+		return c.tok, nil
+	}
+	tok, exp, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	c.tok, c.exp = tok, exp
+	return tok, nil
+}
+
+// githubAppAuth signs a JWT as the app and exchanges it for an
+// installation token. The private key is read from
+// $GITHUB_APP_PRIVATE_KEY or, failing that, private_key_file.
+type githubAppAuth struct {
+	ID             string `yaml:"id"`
+	InstallationID string `yaml:"installation_id"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+	// APIURL defaults to http.github_api.
+	APIURL string `yaml:"api_url"`
+
+	cache *cachedToken
+}
+
+func (a githubAppAuth) authorize(ctx context.Context, base *http.Client, req *http.Request) error {
+	tok, err := a.cache.get(func() (string, time.Time, error) { return a.installationToken(ctx, base) })
+	if err != nil {
+		return fmt.Errorf("github app token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tok)
+	return nil
+}
+
+func (a githubAppAuth) privateKey() (*rsa.PrivateKey, error) {
+	b := []byte(os.Getenv("GITHUB_APP_PRIVATE_KEY"))
+	if len(b) == 0 {
+		if a.PrivateKeyFile == "" {
+			return nil, errors.New("no private key: set GITHUB_APP_PRIVATE_KEY or http.app.private_key_file")
+		}
+		var err error
+		if b, err = os.ReadFile(a.PrivateKeyFile); err != nil {
+			return nil, err
+		}
+	}
+	blk, _ := pem.Decode(b)
+	if blk == nil {
+		return nil, errors.New("private key is not PEM")
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(blk.Bytes); err == nil {
+		return k, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(blk.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rk, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return rk, nil
+}
+
+// appJWT is the RS256 token GitHub expects from an app. iat is backdated
+// to allow for clock drift; GitHub rejects lifetimes over ten minutes.
+func (a githubAppAuth) appJWT(now time.Time) (string, error) {
+	key, err := a.privateKey()
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.ID,
+	})
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+func (a githubAppAuth) installationToken(ctx context.Context, base *http.Client) (string, time.Time, error) {
+	jwt, err := a.appJWT(time.Now())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	u := fmt.Sprintf("%s/app/installations/%s/access_tokens", strings.TrimSuffix(a.APIURL, "/"), a.InstallationID)
+	req, _ := http.NewRequestWithContext(ctx, "POST", u, nil)
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	var out struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := doJSON(base, req, &out); err != nil {
+		return "", time.Time{}, err
+	}
+	return out.Token, out.ExpiresAt, nil
+}
+
+// oidcAuth exchanges the GitHub Actions OIDC token for an access token
+// at a token exchange service (RFC 8693), so the workflow holds no
+// long-lived secret. The job needs the id-token: write permission.
+type oidcAuth struct {
+	Audience    string `yaml:"audience"`
+	ExchangeURL string `yaml:"exchange_url"`
+
+	cache *cachedToken
+}
+
+func (o oidcAuth) authorize(ctx context.Context, base *http.Client, req *http.Request) error {
+	tok, err := o.cache.get(func() (string, time.Time, error) { return o.exchange(ctx, base) })
+	if err != nil {
+		return fmt.Errorf("oidc token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tok)
+	return nil
+}
+
+// idToken asks the Actions runtime for an OIDC token.
+func (o oidcAuth) idToken(ctx context.Context, base *http.Client) (string, error) {
+	reqURL, reqTok := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"), os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if reqURL == "" || reqTok == "" {
+		return "", errors.New("not running in GitHub Actions with id-token: write")
+	}
+	if o.Audience != "" {
+		reqURL += "&audience=" + url.QueryEscape(o.Audience)
+	}
+	req, _ := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	req.Header.Set("Authorization", "Bearer "+reqTok)
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := doJSON(base, req, &out); err != nil {
+		return "", err
+	}
+	return out.Value, nil
+}
+
+func (o oidcAuth) exchange(ctx context.Context, base *http.Client) (string, time.Time, error) {
+	id, err := o.idToken(ctx, base)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	form := url.Values{
+		"grant_type":         {"urn:ietf:params:oauth:grant-type:token-exchange"},
+		"subject_token":      {id},
+		"subject_token_type": {"urn:ietf:params:oauth:token-type:id_token"},
+	}
+	req, _ := http.NewRequestWithContext(ctx, "POST", o.ExchangeURL, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := doJSON(base, req, &out); err != nil {
+		return "", time.Time{}, err
+	}
+	if out.AccessToken == "" {
+		return "", time.Time{}, errors.New("exchange returned no access_token")
+	}
+	exp := time.Now().Add(time.Duration(orDefault(out.ExpiresIn, 3600)) * time.Second)
+	return out.AccessToken, exp, nil
+}
+
+// doJSON sends req without credentials and decodes a 2xx JSON response.
+func doJSON(c *http.Client, req *http.Request, out any) error {
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return &registry.StatusError{Method: req.Method, URL: req.URL.String(), Code: resp.StatusCode, Body: string(b), RequestID: responseRequestID(resp)}
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, registry.MaxResponseBytes)).Decode(out)
+}
+
+func orDefault[T comparable](v, def T) T {
+	var zero T
+	if v == zero {
+		return def
+	}
+	return v
+}
+
+// githubHosts always receive credentials; no other host does unless it
+// serves http.github_api or is listed in http.auth_hosts.
+var githubHosts = []string{"api.github.com", "uploads.github.com", "github.com", "raw.githubusercontent.com"}
+
+// publicGitHubAPI is the API of github.com, the default http.github_api.
+const publicGitHubAPI = "https://api.github.com"
+
+// httpClient is the one place outbound requests go through: providers,
+// sync, verification and mirroring all share it. Tests swap its
+// underlying client to serve canned responses.
+type httpClient struct {
+	hc        *http.Client
+	auth      authStrategy
+	api       string
+	authHosts []string
+	headers   map[string]map[string]string
+	retry     retryConfig
+	cache     *responseCache
+}
+
+// defaultClient is configured from dragon-registry.yaml in main.
+var defaultClient = newHTTPClient(httpConfig{}, http.DefaultClient)
+
+func newHTTPClient(h httpConfig, hc *http.Client) *httpClient {
+	c := &httpClient{
+		hc:        hc,
+		api:       strings.TrimSuffix(orDefault(h.GitHubAPI, publicGitHubAPI), "/"),
+		authHosts: append(slices.Clone(githubHosts), h.AuthHosts...),
+		headers:   h.Headers,
+		retry:     h.Retries,
+		cache:     newResponseCache(h.Cache),
+	}
+	if u, err := url.Parse(c.api); err == nil && !slices.Contains(c.authHosts, u.Hostname()) {
+		c.authHosts = append(c.authHosts, u.Hostname())
+	}
+	switch h.Auth {
+	case authGitHubApp:
+		app := h.App
+		app.APIURL = orDefault(app.APIURL, c.api)
+		app.cache = &cachedToken{}
+		c.auth = app
+	case authOIDC:
+		o := h.OIDC
+		o.cache = &cachedToken{}
+		c.auth = o
+	case authNone:
+		c.auth = noAuth{}
+	default:
+		if tok := os.Getenv("GITHUB_TOKEN"); tok != "" {
+			c.auth = tokenAuth{tok}
+		} else {
+			c.auth = &storedAuth{store: credentialStore()}
+		}
+	}
+	return c
+}
+
+// apiURL is the GitHub API URL of the path format, expanded with a.
+func (c *httpClient) apiURL(format string, a ...any) string {
+	return c.api + fmt.Sprintf(format, a...)
+}
+
+// authenticated reports whether requests to GitHub carry credentials.
+func (c *httpClient) authenticated() bool {
+	switch a := c.auth.(type) {
+	case noAuth:
+		return false
+	case *storedAuth:
+		return a.token("github.com") != ""
+	}
+	return true
+}
+
+// doOnce sends req once, with the credentials and headers for its host.
+func (c *httpClient) doOnce(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	if id := requestID(req.Context()); id != "" {
+		req.Header.Set(requestIDHeader, id)
+	}
+	if tp := spanFrom(req.Context()).traceparent(); tp != "" {
+		req.Header.Set("traceparent", tp)
+	}
+	for k, v := range c.headers[host] {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	if slices.Contains(c.authHosts, host) {
+		if err := c.auth.authorize(req.Context(), c.hc, req); err != nil {
+			return nil, err
+		}
+	}
+	if registry.IsAssetAPIURL(req.URL.String()) {
+		// the file rather than its metadata; GitHub redirects to a
+		// signed link, and the credentials aren't sent on to it
+		req.Header.Set("Accept", "application/octet-stream")
+	}
+	return c.hc.Do(req)
+}
+
+func (c *httpClient) get(ctx context.Context, url string) ([]byte, error) {
+	return c.getLimit(ctx, url, registry.MaxResponseBytes)
+}
+
+// Get implements registry.Fetcher.
+func (c *httpClient) Get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	return c.getLimit(ctx, url, limit)
+}
+
+// getLimit is get with an explicit cap on the response size.
+func (c *httpClient) getLimit(ctx context.Context, url string, limit int64) (body []byte, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	ctx, sp := startSpan(ctx, "GET", spanKindClient, attrs{
+		"http.request.method": "GET",
+		"url.full":            url,
+		"server.address":      req.URL.Host,
+	})
+	req = req.WithContext(ctx)
+	start := time.Now()
+	status := 0
+	defer func() {
+		if status != 0 {
+			sp.set("http.response.status_code", status)
+		}
+		sp.finish(err)
+		metricHTTPDuration.record(time.Since(start).Seconds(), attrs{
+			"http.request.method":       "GET",
+			"server.address":            req.URL.Host,
+			"http.response.status_code": status,
+		})
+	}()
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	cached := c.cache.revalidate(req)
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	status = resp.StatusCode
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		sp.set("http.cache", "revalidated")
+		if int64(len(cached)) > limit {
+			return nil, fmt.Errorf("GET %s: response exceeds %d bytes", url, limit)
+		}
+		return cached, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return nil, &registry.StatusError{URL: url, Code: resp.StatusCode, Body: string(b), RequestID: responseRequestID(resp)}
+	}
+	body, err = io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err == nil && int64(len(body)) > limit {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", url, limit)
+	}
+	if err == nil {
+		c.cache.store(url, resp, body)
+	}
+	return body, err
+}
+
+// githubJSON sends a request to the GitHub API and decodes the response
+// into out, if given. It fails early when no credentials are configured.
+func (c *httpClient) githubJSON(ctx context.Context, method, u string, body io.Reader, contentType string, out any) error {
+	if !c.authenticated() {
+		return errors.New("no GitHub credentials: set GITHUB_TOKEN, run login or configure http.auth")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if sr, ok := body.(*io.SectionReader); ok {
+		req.ContentLength = sr.Size() // uploads are rejected when chunked
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return &registry.StatusError{Method: method, URL: u, Code: resp.StatusCode, Body: string(b), RequestID: responseRequestID(resp)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, registry.MaxResponseBytes)).Decode(out)
+}
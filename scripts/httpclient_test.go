@@ -0,0 +1,105 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// useGitHubAPI points defaultClient's http.github_api at path on srv,
+// without a response cache, until the test ends.
+func useGitHubAPI(t *testing.T, srv *httptest.Server, path string) {
+	t.Helper()
+	old := defaultClient
+	t.Cleanup(func() { defaultClient = old })
+	defaultClient = newHTTPClient(httpConfig{GitHubAPI: srv.URL + path, Cache: cacheNone}, srv.Client())
+}
+
+// TestGitHubAPI points http.github_api at a fake GitHub Enterprise
+// server and checks API calls go there, with credentials.
+func TestGitHubAPI(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	var (
+		mu    sync.Mutex
+		seen  []string
+		auths []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.URL.Path)
+		auths = append(auths, r.Header.Get("Authorization"))
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v3/repos/acme/bp/license":
+			w.Write([]byte(`{"license":{"spdx_id":"MIT"}}`))
+		case "/api/v3/repos/acme/bp/releases/tags/v1.0.0":
+			w.Write([]byte(`{"id":1,"tag_name":"v1.0.0","assets":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	useGitHubAPI(t, srv, "/api/v3/")
+
+	ctx := context.Background()
+	lic, err := fetchRepoLicense(ctx, "acme/bp", "v1.0.0")
+	if err != nil || lic != "MIT" {
+		t.Fatalf("license = %q, %v", lic, err)
+	}
+	rel, err := github().Release(ctx, "acme/bp", "v1.0.0")
+	if err != nil || rel.TagName != "v1.0.0" {
+		t.Fatalf("release = %+v, %v", rel, err)
+	}
+	want := []string{"/api/v3/repos/acme/bp/license", "/api/v3/repos/acme/bp/releases/tags/v1.0.0"}
+	if len(seen) != len(want) {
+		t.Fatalf("requests = %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Errorf("request %d = %s, want %s", i, seen[i], want[i])
+		}
+		if auths[i] != "Bearer test-token" {
+			t.Errorf("request %d Authorization = %q", i, auths[i])
+		}
+	}
+
+	u, _ := url.Parse(srv.URL)
+	if got := apiBase(credentialHost(u.Hostname())); got != srv.URL+"/api/v3" {
+		t.Errorf("apiBase(%s) = %s", u.Hostname(), got)
+	}
+	if got := apiBase("github.com"); got != publicGitHubAPI {
+		t.Errorf("apiBase(github.com) = %s", got)
+	}
+}
+
+func TestHTTPConfigGitHubAPI(t *testing.T) {
+	for _, tc := range []struct {
+		api string
+		ok  bool
+	}{
+		{"", true},
+		{"https://api.github.com", true},
+		{"https://github.example.com/api/v3", true},
+		{"github.example.com/api/v3", false},
+		{"ftp://github.example.com", false},
+		{"https://", false},
+	} {
+		err := httpConfig{GitHubAPI: tc.api}.validate()
+		if (err == nil) != tc.ok {
+			t.Errorf("validate(%q) = %v", tc.api, err)
+		}
+	}
+}
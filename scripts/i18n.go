@@ -0,0 +1,248 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"cmp"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Messages are written in English and translated through the catalogs in
+// locales/, one JSON file per language mapping English format strings, as
+// the code passes them to fmt, to their translations:
+//
+//	{"collection %s not found": "Sammlung %s nicht gefunden"}
+//
+// A message is translated after it is rendered: it is matched against the
+// catalog's formats, and the text each verb stood for is put into the
+// translation, itself translated if it is a message too. So the error
+// chains commands return translate part by part, the code needs no
+// changes to be localized, and whatever a catalog lacks stays English.
+// A translation may reorder the arguments with explicit indexes (%[2]s).
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// defaultLang is the language messages are written in.
+const defaultLang = "en"
+
+// catalog is the translations into one language.
+type catalog struct {
+	lang  string
+	exact map[string]string
+	pats  []msgPattern // most specific first
+}
+
+// msgPattern matches messages rendered from one format.
+type msgPattern struct {
+	re *regexp.Regexp
+	to string // the translation, every verb as %s
+	// literal is how much of the format isn't verbs; the more, the more
+	// specific the pattern
+	literal int
+}
+
+// verbRe matches a fmt verb, with its argument index, flags, width and
+// precision.
+var verbRe = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*\d*(\.\d+)?[a-zA-Z%]`)
+
+// compilePattern turns an English format and its translation into a
+// pattern, or fails when they don't take the same arguments.
+func compilePattern(from, to string) (msgPattern, error) {
+	var re strings.Builder
+	re.WriteString("^")
+	verbs, last, literal := 0, 0, 0
+	for _, m := range verbRe.FindAllStringIndex(from, -1) {
+		re.WriteString(regexp.QuoteMeta(from[last:m[0]]))
+		literal += m[0] - last
+		if from[m[0]:m[1]] == "%%" {
+			re.WriteString("%")
+		} else {
+			re.WriteString("(.+?)")
+			verbs++
+		}
+		last = m[1]
+	}
+	re.WriteString(regexp.QuoteMeta(from[last:]) + "$")
+	literal += len(from) - last
+
+	// the captured text is already formatted: every verb becomes %s,
+	// keeping explicit argument indexes
+	n, highest := 0, 0
+	out := verbRe.ReplaceAllStringFunc(to, func(v string) string {
+		if v == "%%" {
+			return v
+		}
+		idx := verbRe.FindStringSubmatch(v)[1]
+		if idx != "" {
+			n, _ = strconv.Atoi(strings.Trim(idx, "[]"))
+		} else {
+			n++
+		}
+		highest = max(highest, n)
+		return "%" + idx + "s"
+	})
+	if highest != verbs {
+		return msgPattern{}, fmt.Errorf("%q: translation %q takes different arguments", from, to)
+	}
+	return msgPattern{re: regexp.MustCompile(re.String()), to: out, literal: literal}, nil
+}
+
+// catalogs are the embedded catalogs by language tag, loaded on first use.
+var catalogs = sync.OnceValue(func() map[string]*catalog {
+	out := map[string]*catalog{}
+	files, _ := fs.Glob(localeFS, "locales/*.json")
+	for _, f := range files {
+		b, err := localeFS.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var msgs map[string]string
+		if err := json.Unmarshal(b, &msgs); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", f, err)
+			continue
+		}
+		c := &catalog{lang: strings.TrimSuffix(path.Base(f), ".json"), exact: map[string]string{}}
+		for from, to := range msgs {
+			if !verbRe.MatchString(from) {
+				c.exact[from] = to
+				continue
+			}
+			p, err := compilePattern(from, to)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", f, err)
+				continue
+			}
+			c.pats = append(c.pats, p)
+		}
+		// "%s: blueprint not found" before "load registry: %v"
+		slices.SortFunc(c.pats, func(a, b msgPattern) int {
+			return cmp.Or(cmp.Compare(b.literal, a.literal), strings.Compare(a.re.String(), b.re.String()))
+		})
+		out[strings.ToLower(c.lang)] = c
+	}
+	return out
+})
+
+// translate returns msg in c's language, or msg itself when c has no
+// translation. A nil catalog is English.
+func (c *catalog) translate(msg string) string {
+	if c == nil || msg == "" {
+		return msg
+	}
+	if t, ok := c.exact[msg]; ok {
+		return t
+	}
+	for _, p := range c.pats {
+		m := p.re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		args := make([]any, len(m)-1)
+		for i, s := range m[1:] {
+			args[i] = c.translate(s)
+		}
+		return fmt.Sprintf(p.to, args...)
+	}
+	return msg
+}
+
+// sprintf formats and translates a message.
+func (c *catalog) sprintf(format string, args ...any) string {
+	return c.translate(fmt.Sprintf(format, args...))
+}
+
+// language returns the tag of c's language.
+func (c *catalog) language() string {
+	if c == nil {
+		return defaultLang
+	}
+	return c.lang
+}
+
+// catalogFor picks the catalog for the first of tags that has one, by
+// exact tag or by its base language (de-AT finds de). English, and tags
+// no catalog covers, give nil.
+func catalogFor(tags ...string) *catalog {
+	all := catalogs()
+	for _, t := range tags {
+		t = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(t), "_", "-"))
+		base, _, _ := strings.Cut(t, "-")
+		if base == defaultLang {
+			return nil
+		}
+		if c, ok := all[t]; ok {
+			return c
+		}
+		if c, ok := all[base]; ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// acceptLanguages returns the tags of an Accept-Language header, most
+// preferred first.
+func acceptLanguages(h string) []string {
+	type pref struct {
+		tag string
+		q   float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(h, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if tag != "" && tag != "*" && q > 0 {
+			prefs = append(prefs, pref{tag, q})
+		}
+	}
+	slices.SortStableFunc(prefs, func(a, b pref) int { return cmp.Compare(b.q, a.q) })
+	tags := make([]string, len(prefs))
+	for i, p := range prefs {
+		tags[i] = p.tag
+	}
+	return tags
+}
+
+// cliCatalog is the catalog for the CLI's output: the language named by
+// $DRAGON_REGISTRY_LANG, or else the POSIX locale ($LC_ALL, $LC_MESSAGES,
+// $LANG), e.g. de_DE.UTF-8.
+var cliCatalog = sync.OnceValue(func() *catalog {
+	for _, env := range []string{"DRAGON_REGISTRY_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		// de_DE.UTF-8@euro -> de_DE; C and POSIX are English
+		v, _, _ = strings.Cut(v, ".")
+		v, _, _ = strings.Cut(v, "@")
+		if v == "C" || v == "POSIX" {
+			return nil
+		}
+		return catalogFor(v)
+	}
+	return nil
+})
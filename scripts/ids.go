@@ -0,0 +1,100 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Ways of deriving an entry's name, which identifies it within its
+// namespace.
+const (
+	idManifest = "manifest"
+	idRepo     = "repo"
+	idUUID     = "uuid"
+)
+
+// idConfig picks how entry names are derived. Registries that federate
+// can't always rely on manifest names being unique across sources.
+type idConfig struct {
+	Strategy string `yaml:"strategy"`
+}
+
+func (c idConfig) validate() error {
+	switch c.Strategy {
+	case "", idManifest, idRepo, idUUID:
+		return nil
+	}
+	return fmt.Errorf("ids.strategy: want manifest, repo or uuid, got %q", c.Strategy)
+}
+
+// slugSource is what a name can be derived from.
+type slugSource struct {
+	// Repo is the source repo ID, e.g. github.com/getDragon-dev/dragon-blueprints.
+	Repo string
+	// Path is the blueprint's directory in the repo.
+	Path string
+	// Name is the name the manifest gives.
+	Name string
+}
+
+// slugStrategy derives the name of an entry. It must return the same name
+// every time the same blueprint is indexed, so updates find the entry.
+type slugStrategy interface {
+	slug(s slugSource) string
+}
+
+func (c idConfig) strategy() slugStrategy {
+	switch c.Strategy {
+	case idRepo:
+		return repoSlug{}
+	case idUUID:
+		return uuidSlug{}
+	}
+	return manifestSlug{}
+}
+
+// manifestSlug uses the manifest name as is.
+type manifestSlug struct{}
+
+func (manifestSlug) slug(s slugSource) string { return s.Name }
+
+// repoSlug prefixes the name with the repo's, e.g.
+// dragon-blueprints-cli-tool.
+type repoSlug struct{}
+
+func (repoSlug) slug(s slugSource) string {
+	repo := strings.ToLower(path.Base(s.Repo))
+	if s.Name == repo || strings.HasPrefix(s.Name, repo+"-") {
+		return s.Name
+	}
+	return repo + "-" + s.Name
+}
+
+// uuidSlug names the entry with a version 5 UUID of its location in the
+// source repo, so it stays put when the manifest name changes.
+type uuidSlug struct{}
+
+// urlNamespace is the RFC 4122 namespace for names that are URLs.
+var urlNamespace = [16]byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+func (uuidSlug) slug(s slugSource) string {
+	h := sha1.New()
+	h.Write(urlNamespace[:])
+	h.Write([]byte("https://" + s.Repo + "/tree/HEAD/" + s.Path))
+	u := h.Sum(nil)[:16]
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
@@ -0,0 +1,354 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // decoders for the formats accepted
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// maxImagePixels bounds the images decoded, whatever their file size.
+const maxImagePixels = 40 << 20
+
+// imageConfig re-hosts the icons and screenshots entries reference: each
+// is downloaded, checked, scaled down to fit and re-encoded, and written
+// to Dir under its digest, and the entry points at the copy under BaseURL
+// instead, so the catalog never hotlinks a broken or oversized image.
+type imageConfig struct {
+	// Dir receives the images; empty leaves the URLs as manifests give
+	// them.
+	Dir string `yaml:"dir"`
+	// BaseURL is where Dir is published.
+	BaseURL string `yaml:"base_url"`
+	// MaxBytes caps an image as downloaded. Defaults to 5MiB.
+	MaxBytes int64 `yaml:"max_bytes"`
+	// Icon and Screenshot bound the dimensions of each kind of image.
+	Icon       imageBounds `yaml:"icon"`
+	Screenshot imageBounds `yaml:"screenshot"`
+	// JPEGQuality is what photos are re-encoded at. Defaults to 85.
+	JPEGQuality int `yaml:"jpeg_quality"`
+}
+
+// imageBounds are the dimensions an image may have. A larger one is
+// scaled down to fit; a smaller one is rejected.
+type imageBounds struct {
+	MaxWidth  int `yaml:"max_width"`
+	MaxHeight int `yaml:"max_height"`
+	MinWidth  int `yaml:"min_width"`
+	MinHeight int `yaml:"min_height"`
+}
+
+func (c imageConfig) validate() error {
+	if (c.Dir == "") != (c.BaseURL == "") {
+		return errors.New("images: dir and base_url go together")
+	}
+	if u, err := url.Parse(c.BaseURL); c.BaseURL != "" && (err != nil || u.Scheme != "https" || u.Host == "") {
+		return fmt.Errorf("images.base_url: %q is not an https URL", c.BaseURL)
+	}
+	if c.MaxBytes < 0 {
+		return errors.New("images.max_bytes must not be negative")
+	}
+	if c.JPEGQuality < 0 || c.JPEGQuality > 100 {
+		return errors.New("images.jpeg_quality must be 1 to 100")
+	}
+	for kind, b := range map[string]imageBounds{"icon": c.Icon, "screenshot": c.Screenshot} {
+		if b.MaxWidth < 0 || b.MaxHeight < 0 || b.MinWidth < 0 || b.MinHeight < 0 {
+			return fmt.Errorf("images.%s: dimensions must not be negative", kind)
+		}
+	}
+	for kind, b := range map[string]imageBounds{"icon": c.icon(), "screenshot": c.screenshot()} {
+		if b.MinWidth > b.MaxWidth || b.MinHeight > b.MaxHeight {
+			return fmt.Errorf("images.%s: the minimum dimensions exceed the maximum", kind)
+		}
+	}
+	return nil
+}
+
+func (c imageConfig) icon() imageBounds {
+	return imageBounds{
+		MaxWidth:  orDefault(c.Icon.MaxWidth, 512),
+		MaxHeight: orDefault(c.Icon.MaxHeight, 512),
+		MinWidth:  orDefault(c.Icon.MinWidth, 32),
+		MinHeight: orDefault(c.Icon.MinHeight, 32),
+	}
+}
+
+func (c imageConfig) screenshot() imageBounds {
+	return imageBounds{
+		MaxWidth:  orDefault(c.Screenshot.MaxWidth, 1600),
+		MaxHeight: orDefault(c.Screenshot.MaxHeight, 1200),
+		MinWidth:  orDefault(c.Screenshot.MinWidth, 320),
+		MinHeight: orDefault(c.Screenshot.MinHeight, 200),
+	}
+}
+
+// hosted reports whether u is already one of our copies.
+func (c imageConfig) hosted(u string) bool {
+	return strings.HasPrefix(u, strings.TrimSuffix(c.BaseURL, "/")+"/")
+}
+
+// hostImages replaces the entry's icon and screenshots with optimized
+// copies. An image that can't be fetched or fails the checks is dropped,
+// and reported in the returned warnings.
+func hostImages(ctx context.Context, c imageConfig, bp *registry.Blueprint) []string {
+	if c.Dir == "" {
+		return nil
+	}
+	var warnings []string
+	if bp.Icon != "" {
+		u, err := c.host(ctx, bp.Icon, c.icon())
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("icon %s: %v; dropped", bp.Icon, err))
+		}
+		bp.Icon = u
+	}
+	var shots []string
+	for _, s := range bp.Screenshots {
+		u, err := c.host(ctx, s, c.screenshot())
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("screenshot %s: %v; dropped", s, err))
+			continue
+		}
+		shots = append(shots, u)
+	}
+	bp.Screenshots = shots
+	return warnings
+}
+
+// host stores an optimized copy of the image at u and returns its URL.
+func (c imageConfig) host(ctx context.Context, u string, bounds imageBounds) (string, error) {
+	if c.hosted(u) {
+		return u, nil
+	}
+	b, err := fetchImage(ctx, u, orDefault(c.MaxBytes, 5<<20))
+	if err != nil {
+		return "", err
+	}
+	out, ext, err := optimizeImage(b, bounds, orDefault(c.JPEGQuality, 85))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(out)
+	name := hex.EncodeToString(sum[:]) + ext
+	p := filepath.Join(c.Dir, name)
+	if _, err := os.Stat(p); err != nil {
+		if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+			return "", err
+		}
+		if err := registry.WriteFileAtomic(p, out); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(c.BaseURL, "/") + "/" + name, nil
+}
+
+// fetchImage downloads an image of at most limit bytes.
+func fetchImage(ctx context.Context, u string, limit int64) ([]byte, error) {
+	if pu, err := url.Parse(u); err != nil || pu.Scheme != "https" {
+		return nil, errors.New("not an https URL")
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", u, nil)
+	resp, err := defaultClient.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, &registry.StatusError{URL: u, Code: resp.StatusCode, RequestID: responseRequestID(resp)}
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err == nil && int64(len(b)) > limit {
+		err = fmt.Errorf("larger than %d bytes", limit)
+	}
+	return b, err
+}
+
+// optimizeImage checks a PNG, JPEG or GIF image against bounds, scales it
+// down to fit and re-encodes it: photos as JPEG, the rest as PNG, which
+// also drops their metadata. An animated GIF keeps its first frame. The
+// original is kept when re-encoding doesn't make it smaller.
+func optimizeImage(b []byte, bounds imageBounds, quality int) ([]byte, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, "", errors.New("not a PNG, JPEG or GIF image")
+	}
+	switch {
+	case cfg.Width < bounds.MinWidth || cfg.Height < bounds.MinHeight:
+		return nil, "", fmt.Errorf("%dx%d is smaller than %dx%d", cfg.Width, cfg.Height, bounds.MinWidth, bounds.MinHeight)
+	case cfg.Width*cfg.Height > maxImagePixels:
+		return nil, "", fmt.Errorf("%dx%d has too many pixels", cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, "", fmt.Errorf("decode %s: %w", format, err)
+	}
+	scaled := fitImage(img, bounds.MaxWidth, bounds.MaxHeight)
+	var buf bytes.Buffer
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+		err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: quality})
+	} else {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, scaled)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if scaled == img && format != "gif" && len(b) <= buf.Len() {
+		return b, ext, nil
+	}
+	return buf.Bytes(), ext, nil
+}
+
+// fitImage scales img down, keeping its aspect ratio, to fit within
+// maxW×maxH. Each pixel is the average of the ones it covers.
+func fitImage(img image.Image, maxW, maxH int) image.Image {
+	r := img.Bounds()
+	sw, sh := r.Dx(), r.Dy()
+	if sw <= maxW && sh <= maxH {
+		return img
+	}
+	scale := min(float64(maxW)/float64(sw), float64(maxH)/float64(sh))
+	dw, dh := max(1, int(float64(sw)*scale+0.5)), max(1, int(float64(sh)*scale+0.5))
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		y0 := r.Min.Y + y*sh/dh
+		y1 := max(r.Min.Y+(y+1)*sh/dh, y0+1)
+		for x := range dw {
+			x0 := r.Min.X + x*sw/dw
+			x1 := max(r.Min.X+(x+1)*sw/dw, x0+1)
+			var sr, sg, sb, sa, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					sr, sg, sb, sa = sr+uint64(cr), sg+uint64(cg), sb+uint64(cb), sa+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(sr / n >> 8), uint8(sg / n >> 8), uint8(sb / n >> 8), uint8(sa / n >> 8)})
+		}
+	}
+	return dst
+}
+
+// hostedImageRe matches the names host gives images.
+var hostedImageRe = regexp.MustCompile(`^[0-9a-f]{64}\.(png|jpg)$`)
+
+// pruneImages removes the images in the directory no entry references.
+func pruneImages(c imageConfig, db registry.Database) error {
+	keep := map[string]bool{}
+	for _, bp := range db.Blueprints {
+		for _, u := range append([]string{bp.Icon}, bp.Screenshots...) {
+			if c.hosted(u) {
+				keep[u[strings.LastIndex(u, "/")+1:]] = true
+			}
+		}
+	}
+	entries, err := os.ReadDir(c.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if hostedImageRe.MatchString(e.Name()) && !keep[e.Name()] {
+			if err := os.Remove(filepath.Join(c.Dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runImages re-hosts the icons and screenshots of the given entries, or
+// of all of them, that images.dir doesn't hold yet, and removes the
+// images no entry references any more.
+func runImages(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("images", flag.ExitOnError)
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if cfg.Images.Dir == "" {
+		return errors.New("images.dir is not set")
+	}
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	db := *st.snapshot()
+	want := map[string]bool{}
+	for _, ref := range flags.Args() {
+		bp, err := registry.Find(db, ref)
+		if err != nil {
+			return err
+		}
+		want[bp.FullName()] = true
+	}
+	// download outside the lock; the entries are matched up again below
+	done := map[string]registry.Blueprint{}
+	for _, bp := range db.Blueprints {
+		if len(want) > 0 && !want[bp.FullName()] {
+			continue
+		}
+		before := bp
+		bp.Screenshots = slices.Clone(bp.Screenshots)
+		for _, w := range hostImages(ctx, cfg.Images, &bp) {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", bp.FullName(), w)
+		}
+		if bp.Icon != before.Icon || !slices.Equal(bp.Screenshots, before.Screenshots) {
+			done[bp.FullName()] = bp
+			fmt.Printf("%s\t%d images\n", bp.FullName(), len(bp.Screenshots)+min(len(bp.Icon), 1))
+		}
+	}
+	if len(done) > 0 {
+		err := st.update(func(db *registry.Database) error {
+			for i, bp := range db.Blueprints {
+				if d, ok := done[bp.FullName()]; ok && bp.Version == d.Version {
+					db.Blueprints[i].Icon, db.Blueprints[i].Screenshots = d.Icon, d.Screenshots
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "updated the images of %d entries\n", len(done))
+	return pruneImages(cfg.Images, *st.snapshot())
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// indexSigning signs every registry file written with cosign, so clients
+// can check the index wasn't tampered with. The signature goes next to the
+// file as <file>.sig.
+type indexSigning struct {
+	// Key is a cosign key reference: a key file, env://VAR, or a KMS URI
+	// such as awskms:///alias/dragon-registry. Rotating the key is a
+	// matter of changing it and running sign.
+	Key string `yaml:"key"`
+	// Keyless signs with a certificate for the workflow's OIDC identity
+	// instead, written as <file>.pem.
+	Keyless bool `yaml:"keyless"`
+	// Cosign is the cosign binary, found on $PATH by default.
+	Cosign string `yaml:"cosign"`
+}
+
+func (s indexSigning) enabled() bool { return s.Key != "" || s.Keyless }
+
+func (s indexSigning) validate() error {
+	if s.Key != "" && s.Keyless {
+		return errors.New("output.sign: key and keyless are exclusive")
+	}
+	return nil
+}
+
+// sign writes the signature of the file at p. With a key it also writes
+// the public key as <file>.pub, so clients can tell when it was rotated.
+func (s indexSigning) sign(ctx context.Context, p string) error {
+	cosign := orDefault(s.Cosign, "cosign")
+	args := []string{"sign-blob", "--yes", "--output-signature", p + ".sig"}
+	if s.Keyless {
+		args = append(args, "--output-certificate", p+".pem")
+	} else {
+		args = append(args, "--key", s.Key)
+	}
+	if _, err := runCosign(ctx, cosign, append(args, p)...); err != nil {
+		return fmt.Errorf("sign %s: %w", p, err)
+	}
+	if s.Keyless {
+		return nil
+	}
+	pub, err := runCosign(ctx, cosign, "public-key", "--key", s.Key)
+	if err != nil {
+		return fmt.Errorf("public key: %w", err)
+	}
+	return registry.WriteFileAtomic(p+".pub", pub)
+}
+
+// registryFiles lists the files saveDB writes for the registry at p.
+func registryFiles(p string, out outputConfig) []string {
+	files := []string{p}
+	for _, f := range out.Formats {
+		files = append(files, registry.SiblingPath(p, f))
+	}
+	return files
+}
+
+// runSign signs the registry files as they are, e.g. right after rotating
+// the signing key.
+func runSign(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("sign", flag.ExitOnError)
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if !cfg.Output.Sign.enabled() {
+		return errors.New("output.sign is not configured")
+	}
+	if registryPath() == "-" {
+		return errors.New("sign needs a registry file, not stdin")
+	}
+	for _, p := range registryFiles(registryPath(), cfg.Output) {
+		if _, err := os.Stat(p); err != nil {
+			return err
+		}
+		if err := cfg.Output.Sign.sign(ctx, p); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "signed %s\n", p)
+	}
+	return nil
+}
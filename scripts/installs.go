@@ -0,0 +1,230 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// installPing is what the dragon CLI sends, when the user opted in, after
+// scaffolding a blueprint. It carries nothing about the user or machine.
+type installPing struct {
+	Blueprint  string `json:"blueprint"`
+	Version    string `json:"version"`
+	CLIVersion string `json:"cli_version"`
+}
+
+// installStats aggregates pings; individual pings are never stored.
+type installStats struct {
+	// Versions counts installs per entry and version.
+	Versions map[string]map[string]int64 `json:"versions"`
+	// Daily counts installs per UTC day and entry.
+	Daily map[string]map[string]int64 `json:"daily"`
+	// CLI counts installs per dragon CLI version.
+	CLI map[string]int64 `json:"cli"`
+}
+
+// versionRe bounds the version strings accepted from clients.
+var versionRe = regexp.MustCompile(`^v?[0-9A-Za-z][0-9A-Za-z.+-]{0,63}$`)
+
+const (
+	// maxCLIVersion bounds a CLI version, which must also be semver.
+	maxCLIVersion = 32
+	// maxCLIVersions caps the CLI versions counted apart; installs by
+	// any further ones are counted as cliOther.
+	maxCLIVersions = 200
+	cliOther       = "other"
+)
+
+// validCLIVersion reports whether v may be counted: a semver version,
+// optionally with a leading v, of bounded length.
+func validCLIVersion(v string) bool {
+	return len(v) <= maxCLIVersion && semverRe.MatchString(strings.TrimPrefix(v, "v"))
+}
+
+func readInstallStats(p string) (installStats, error) {
+	s := installStats{}
+	b, err := os.ReadFile(p)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return s, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return s, fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	if s.Versions == nil {
+		s.Versions = map[string]map[string]int64{}
+	}
+	if s.Daily == nil {
+		s.Daily = map[string]map[string]int64{}
+	}
+	if s.CLI == nil {
+		s.CLI = map[string]int64{}
+	}
+	return s, nil
+}
+
+// installCounter collects pings in memory; flush persists them.
+type installCounter struct {
+	path string
+
+	mu    sync.Mutex
+	stats installStats
+	dirty bool
+}
+
+func openInstallCounter(p string) (*installCounter, error) {
+	s, err := readInstallStats(p)
+	if err != nil {
+		return nil, err
+	}
+	return &installCounter{path: p, stats: s}, nil
+}
+
+func (c *installCounter) record(name string, ping installPing, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stats.Versions[name] == nil {
+		c.stats.Versions[name] = map[string]int64{}
+	}
+	c.stats.Versions[name][ping.Version]++
+	day := at.UTC().Format(time.DateOnly)
+	if c.stats.Daily[day] == nil {
+		c.stats.Daily[day] = map[string]int64{}
+	}
+	c.stats.Daily[day][name]++
+	cli := ping.CLIVersion
+	if _, ok := c.stats.CLI[cli]; !ok && len(c.stats.CLI) >= maxCLIVersions {
+		cli = cliOther
+	}
+	c.stats.CLI[cli]++
+	c.dirty = true
+}
+
+// flush writes the counts if they changed since the last flush.
+func (c *installCounter) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c.stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := registry.WriteFileAtomic(c.path, append(b, '\n')); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// installHandler serves POST /v1/telemetry/install. Pings are counted
+// only for releases the registry knows, so the stats can't be filled with
+// made-up names, and nothing about the request besides the ping is kept.
+// snapshot returns the entries the server shows.
+func installHandler(snapshot func() *registry.Database, c *installCounter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		dec := json.NewDecoder(io.LimitReader(r.Body, 1<<10))
+		dec.DisallowUnknownFields() // no room for anything else
+		var ping installPing
+		if err := dec.Decode(&ping); err != nil {
+			writeError(w, r, http.StatusBadRequest, "bad ping: "+err.Error())
+			return
+		}
+		if !versionRe.MatchString(ping.Version) {
+			writeError(w, r, http.StatusBadRequest, "bad ping: version is required")
+			return
+		}
+		if !validCLIVersion(ping.CLIVersion) {
+			writeError(w, r, http.StatusBadRequest, fmt.Sprintf("bad ping: cli_version must be a semver version of at most %d characters", maxCLIVersion))
+			return
+		}
+		ns, name := registry.SplitRef(ping.Blueprint)
+		if ns == "" {
+			ns = registry.DefaultNamespace
+		}
+		bp, ok := snapshot().Lookup(ns, name)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "unknown blueprint")
+			return
+		}
+		if _, ok := bp.Release(ping.Version); !ok {
+			writeError(w, r, http.StatusNotFound, "unknown version")
+			return
+		}
+		c.record(bp.FullName(), ping, time.Now())
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// runStatsInstalls prints the install counts.
+func runStatsInstalls(args []string) error {
+	flags := flag.NewFlagSet("stats installs", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if cfg.Stats.Installs == "" {
+		return errors.New("no stats.installs file configured")
+	}
+	s, err := readInstallStats(cfg.Stats.Installs)
+	if err != nil {
+		return fmt.Errorf("read installs: %w", err)
+	}
+	if *asJSON {
+		return printJSON(s)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tINSTALLS")
+	for _, name := range slices.Sorted(maps.Keys(s.Versions)) {
+		for _, v := range slices.Sorted(maps.Keys(s.Versions[name])) {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", name, v, s.Versions[name][v])
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	cli := make(map[string]int, len(s.CLI))
+	for v, n := range s.CLI {
+		cli[v] = int(n)
+	}
+	printCounts("cli versions", cli)
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+func TestInstallHandler(t *testing.T) {
+	db := testEntries(1)
+	c, err := openInstallCounter(filepath.Join(t.TempDir(), "installs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := installHandler(func() *registry.Database { return &db }, c)
+
+	cases := []struct {
+		name   string
+		method string
+		body   string
+		want   int
+	}{
+		{"counted", http.MethodPost, `{"blueprint": "a-0", "version": "1.0.0", "cli_version": "0.4.0"}`, http.StatusNoContent},
+		{"v prefix", http.MethodPost, `{"blueprint": "a-0", "version": "1.0.0", "cli_version": "v0.4.1-rc.1"}`, http.StatusNoContent},
+		{"get", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"unknown field", http.MethodPost, `{"blueprint": "a-0", "version": "1.0.0", "cli_version": "0.4.0", "os": "linux"}`, http.StatusBadRequest},
+		{"cli not semver", http.MethodPost, `{"blueprint": "a-0", "version": "1.0.0", "cli_version": "nightly"}`, http.StatusBadRequest},
+		{"cli too long", http.MethodPost, `{"blueprint": "a-0", "version": "1.0.0", "cli_version": "0.4.0-` + strings.Repeat("a", 32) + `"}`, http.StatusBadRequest},
+		{"unknown blueprint", http.MethodPost, `{"blueprint": "nope", "version": "1.0.0", "cli_version": "0.4.0"}`, http.StatusNotFound},
+		{"unknown version", http.MethodPost, `{"blueprint": "a-0", "version": "9.9.9", "cli_version": "0.4.0"}`, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tc.method, "/v1/telemetry/install", strings.NewReader(tc.body)))
+			if w.Code != tc.want {
+				t.Fatalf("%d, want %d: %s", w.Code, tc.want, w.Body)
+			}
+			if w.Code >= 400 {
+				var e apiError
+				if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil || e.Error == "" {
+					t.Errorf("error body %q is not an API error", w.Body)
+				}
+			}
+		})
+	}
+	if got := c.stats.CLI["0.4.0"]; got != 1 {
+		t.Errorf("installs by CLI 0.4.0: %d, want 1", got)
+	}
+}
+
+func TestInstallCounterCapsCLIVersions(t *testing.T) {
+	c := &installCounter{stats: installStats{Versions: map[string]map[string]int64{}, Daily: map[string]map[string]int64{}, CLI: map[string]int64{}}}
+	for i := range maxCLIVersions + 10 {
+		c.record("getdragon/a-0", installPing{Version: "1.0.0", CLIVersion: fmt.Sprintf("0.%d.0", i)}, time.Now())
+	}
+	c.record("getdragon/a-0", installPing{Version: "1.0.0", CLIVersion: "0.0.0"}, time.Now())
+	if n := len(c.stats.CLI); n != maxCLIVersions+1 {
+		t.Errorf("%d CLI versions kept, want %d and %q", n, maxCLIVersions, cliOther)
+	}
+	if got := c.stats.CLI[cliOther]; got != 10 {
+		t.Errorf("%q: %d, want 10", cliOther, got)
+	}
+	if got := c.stats.CLI["0.0.0"]; got != 2 {
+		t.Errorf("a version already counted: %d, want 2", got)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// digestConfig chooses the digests recorded for archives besides SHA-256,
+// which every entry has.
+type digestConfig struct {
+	// Algorithms are computed for every archive indexed, e.g. sha512 or
+	// blake3.
+	Algorithms []string `yaml:"algorithms"`
+	// Require lists the algorithms clients must verify archives with. It
+	// is published as the registry's required_digests.
+	Require []string `yaml:"require"`
+}
+
+func (d digestConfig) validate() error {
+	for _, alg := range d.Algorithms {
+		if !registry.SupportedDigest(alg) {
+			return fmt.Errorf("digests.algorithms: unsupported algorithm %q; want one of %v", alg, registry.DigestAlgorithms())
+		}
+	}
+	for _, alg := range d.Require {
+		if alg != registry.DigestSHA256 && !slices.Contains(d.Algorithms, alg) {
+			return fmt.Errorf("digests.require: %q is not computed; add it to digests.algorithms", alg)
+		}
+	}
+	return nil
+}
+
+// extra are the configured algorithms other than SHA-256.
+func (d digestConfig) extra() []string {
+	var algs []string
+	for _, alg := range d.Algorithms {
+		if alg != registry.DigestSHA256 && !slices.Contains(algs, alg) {
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// pickDigests returns the digests in algs, or nil unless d has all of
+// them.
+func pickDigests(d registry.Digests, algs []string) registry.Digests {
+	out := registry.Digests{}
+	for _, alg := range algs {
+		if d[alg] == "" {
+			return nil
+		}
+		out[alg] = d[alg]
+	}
+	return out
+}
+
+// fileDigests hashes everything readable from r with each of algs.
+func fileDigests(r io.Reader, algs ...string) (registry.Digests, error) {
+	d, err := registry.NewDigester(algs...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(d, r); err != nil {
+		return nil, err
+	}
+	return d.Sum(), nil
+}
+
+// assetDigests computes digests of a release asset by streaming it
+// through the hashes, without keeping the archive in memory or on disk.
+func assetDigests(ctx context.Context, url string, algs ...string) (registry.Digests, error) {
+	d, err := registry.NewDigester(algs...)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	resp, err := defaultClient.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return nil, &registry.StatusError{URL: url, Code: resp.StatusCode, Body: string(b), RequestID: responseRequestID(resp)}
+	}
+	n, err := io.Copy(d, io.LimitReader(resp.Body, maxArchiveBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > maxArchiveBytes {
+		return nil, fmt.Errorf("%s: archive exceeds %d bytes", url, maxArchiveBytes)
+	}
+	return d.Sum(), nil
+}
+
+// assetExtraDigests returns the configured digests of a release asset
+// whose SHA-256 is sum, besides that one. Like assetDigest it prefers the
+// archive already downloaded for scanning, then the digests recorded when
+// the same archive was indexed before, and only downloads the asset when
+// those don't cover every algorithm.
+func assetExtraDigests(ctx context.Context, dc digestConfig, a registry.GitHubAsset, sum string, scan *assetScan, db registry.Database) (registry.Digests, error) {
+	algs := dc.extra()
+	if len(algs) == 0 {
+		return nil, nil
+	}
+	if scan != nil {
+		if d := pickDigests(scan.Digests, algs); d != nil {
+			return d, nil
+		}
+	}
+	for _, bp := range db.Blueprints {
+		for _, r := range bp.Versions() {
+			if r.SHA256 == sum && r.DownloadURL == a.DownloadURL() {
+				if d := pickDigests(r.Digests, algs); d != nil {
+					return d, nil
+				}
+			}
+		}
+	}
+	d, err := assetDigests(ctx, a.DownloadURL(), append([]string{registry.DigestSHA256}, algs...)...)
+	if err != nil {
+		return nil, err
+	}
+	if d[registry.DigestSHA256] != sum {
+		return nil, fmt.Errorf("archive hashes to %s, not %s", d[registry.DigestSHA256], sum)
+	}
+	return pickDigests(d, algs), nil
+}
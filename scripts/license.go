@@ -0,0 +1,99 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// Values of Blueprint.LicenseSource: whether the license was declared in
+// the manifest or detected from the archive's LICENSE file or the repo.
+const (
+	licenseFromManifest = "manifest"
+	licenseFromArchive  = "archive"
+	licenseFromRepo     = "repo"
+)
+
+const maxLicenseBytes = 256 << 10
+
+var licenseFiles = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "COPYING"}
+
+// licenseMarkers identify common licenses by phrases from their text, most
+// specific first (the AGPL and LGPL texts both mention the GPL).
+var licenseMarkers = []struct {
+	spdx    string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"MIT", []string{"permission is hereby granted, free of charge", "the above copyright notice and this permission notice shall be included"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+}
+
+// detectLicense returns the SPDX id of a license text, or "" if it isn't
+// recognised.
+func detectLicense(text string) string {
+	t := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	for _, m := range licenseMarkers {
+		all := true
+		for _, p := range m.phrases {
+			if !strings.Contains(t, p) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return m.spdx
+		}
+	}
+	return ""
+}
+
+// fetchRepoLicense asks GitHub which license the repo has at ref. It
+// returns "" when there is none or GitHub can't classify it.
+func fetchRepoLicense(ctx context.Context, repo, ref string) (string, error) {
+	u := defaultClient.apiURL("/repos/%s/license?ref=%s", repo, url.QueryEscape(ref))
+	b, err := defaultClient.get(ctx, u)
+	var se *registry.StatusError
+	if errors.As(err, &se) && se.Code == http.StatusNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		License struct {
+			SPDXID string `json:"spdx_id"`
+		} `json:"license"`
+	}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return "", err
+	}
+	if resp.License.SPDXID == "NOASSERTION" {
+		return "", nil
+	}
+	return resp.License.SPDXID, nil
+}
@@ -0,0 +1,238 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// semverRe matches the versions the registry schema accepts: a full
+// MAJOR.MINOR.PATCH, with no leading "v".
+var semverRe = regexp.MustCompile(`^(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// lintManifest checks a manifest the way the updater will read it, so
+// mistakes show up in the author's CI instead of as an entry built from
+// fallback values.
+func lintManifest(name string, b []byte, cfg config, vocab *featureVocab) []finding {
+	var out []finding
+	add := func(sev, rule, format string, args ...any) {
+		out = append(out, finding{Rule: rule, Severity: sev, Message: fmt.Sprintf(format, args...)})
+	}
+	man, err := registry.ParseManifest(name, b)
+	if err != nil {
+		add(lintError, "parse", "%v", err)
+		return out
+	}
+	for _, k := range unknownManifestKeys(name, b) {
+		add(lintError, "unknown-field", "unknown field %q is ignored", k)
+	}
+	for _, w := range man.Warnings {
+		add(lintWarn, "deprecated-field", "%s", w)
+	}
+
+	switch {
+	case man.Name == "":
+		add(lintWarn, "missing-name", "no name; the asset name is used")
+	case checkManifestName(man.Name) != nil:
+		add(lintError, "invalid-name", "%v", checkManifestName(man.Name))
+	}
+	switch {
+	case man.Version == "":
+		add(lintWarn, "missing-version", "no version; the release tag is used")
+	case !semverRe.MatchString(man.Version):
+		add(lintError, "invalid-version", "version %q is not semver (MAJOR.MINOR.PATCH, without a v)", man.Version)
+	}
+	max := cfg.Text.descriptionMax()
+	switch {
+	case strings.TrimSpace(man.Description) == "":
+		add(lintWarn, "missing-description", "no description; the fallback template is used")
+	case len(man.Description) > max:
+		add(lintError, "description-too-long", "description has %d bytes; the catalog cuts it at %d", len(man.Description), max)
+	case sanitizeText(man.Description, max) != man.Description:
+		add(lintWarn, "description-markup", "the catalog shows the description as %q", sanitizeText(man.Description, max))
+	}
+	for _, t := range man.Tags {
+		if strings.HasPrefix(t, autoTagPrefix) {
+			add(lintError, "invalid-tag", "tag %q: the %s prefix is reserved for generated tags", t, autoTagPrefix)
+		} else if err := registry.ValidateIdent("tag", t); err != nil {
+			add(lintError, "invalid-tag", "%v", err)
+		}
+	}
+	if err := validateVisibility(man.Visibility); err != nil {
+		add(lintError, "invalid-visibility", "%v", err)
+	}
+	if man.Icon != "" {
+		if u, err := url.Parse(man.Icon); err != nil || u.Scheme != "https" || u.Host == "" {
+			add(lintError, "invalid-icon", "icon %q is not an https URL", man.Icon)
+		}
+	}
+	for _, s := range man.Screenshots {
+		if u, err := url.Parse(s); err != nil || u.Scheme != "https" || u.Host == "" {
+			add(lintError, "invalid-screenshot", "screenshot %q is not an https URL", s)
+		}
+	}
+	_, findings := checkFeatures(man.Features, vocab)
+	for _, f := range findings {
+		add(lintWarn, "feature", "%s", f)
+	}
+	self := man.Name
+	if ns, n := registry.SplitRef(man.Name); ns == "" {
+		self = registry.DefaultNamespace + "/" + n
+	}
+	_, findings = checkDependencies(man.Dependencies, self)
+	for _, f := range findings {
+		add(lintError, "invalid-dependency", "%s", f)
+	}
+	seen := map[string]bool{}
+	for i, p := range man.Parameters {
+		switch {
+		case !paramNameRe.MatchString(p.Name):
+			add(lintError, "invalid-parameter", "parameter %d: invalid name %q: use letters, digits and '_'", i+1, p.Name)
+		case seen[p.Name]:
+			add(lintError, "invalid-parameter", "parameter %q is defined twice", p.Name)
+		case strings.TrimSpace(p.Description) == "":
+			add(lintWarn, "undocumented-parameter", "parameter %q has no description", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	if d := man.Deprecated; d != nil {
+		if strings.TrimSpace(d.Reason) == "" {
+			add(lintError, "invalid-deprecation", "deprecated needs a reason")
+		}
+		if d.Replacement != "" && checkManifestName(d.Replacement) != nil {
+			add(lintError, "invalid-deprecation", "replacement: %v", checkManifestName(d.Replacement))
+		}
+	}
+	return out
+}
+
+// unknownManifestKeys lists keys the manifest sets that no field reads,
+// at the top level and in each parameter and dependency.
+func unknownManifestKeys(name string, b []byte) []string {
+	var raw map[string]any
+	var err error
+	if filepath.Ext(name) == ".toml" {
+		_, err = toml.Decode(string(b), &raw)
+	} else {
+		err = yaml.Unmarshal(b, &raw)
+	}
+	if err != nil {
+		return nil // reported by the parse
+	}
+	var out []string
+	check := func(prefix string, m map[string]any, t reflect.Type) {
+		known := map[string]bool{}
+		for f := range t.Fields() {
+			known[f.Tag.Get("yaml")] = true
+		}
+		for k := range m {
+			if !known[k] {
+				out = append(out, prefix+k)
+			}
+		}
+	}
+	check("", raw, reflect.TypeFor[registry.Manifest]())
+	for field, t := range map[string]reflect.Type{
+		"parameters":   reflect.TypeFor[registry.Param](),
+		"dependencies": reflect.TypeFor[registry.Dependency](),
+	} {
+		items, _ := raw[field].([]any)
+		for i, item := range items {
+			if m, ok := item.(map[string]any); ok {
+				check(fmt.Sprintf("%s[%d].", field, i), m, t)
+			}
+		}
+	}
+	if d, ok := raw["deprecated"].(map[string]any); ok {
+		check("deprecated.", d, reflect.TypeFor[registry.Deprecation]())
+	}
+	slices.Sort(out)
+	return out
+}
+
+// manifestIn finds the manifest of the blueprint in dir.
+func manifestIn(dir string) (string, error) {
+	for _, f := range registry.ManifestFiles {
+		p := filepath.Join(dir, f)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %w", dir, registry.ErrNoManifest)
+}
+
+// runLintManifest lints the given manifests, or those of the blueprints
+// in the given directories; by default the one in the working directory.
+func runLintManifest(args []string) error {
+	flags := flag.NewFlagSet("lint-manifest", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the findings as JSON")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	vocab, err := loadFeatureVocab(cfg.Features.Vocabulary)
+	if err != nil {
+		return fmt.Errorf("features: %w", err)
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	results := map[string][]finding{}
+	var files []string
+	var errs int
+	for _, p := range paths {
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+			if p, err = manifestIn(p); err != nil {
+				return err
+			}
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		fs := lintManifest(p, b, cfg, vocab)
+		files = append(files, p)
+		results[p] = orEmpty(fs)
+		if hasErrors(fs) {
+			errs++
+		}
+	}
+	if *asJSON {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, p := range files {
+			for _, f := range results[p] {
+				fmt.Printf("%s: %s\n", p, f)
+			}
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("%d of %d manifests have errors", errs, len(files))
+	}
+	return nil
+}
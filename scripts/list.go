@@ -0,0 +1,66 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// runList prints the registry's entries, optionally narrowed to a
+// namespace or tag.
+func runList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	ns := flags.String("namespace", "", "only list entries in this namespace")
+	tag := flags.String("tag", "", "only list entries with this tag")
+	asJSON := flags.Bool("json", false, "print the entries as JSON")
+	order := flags.String("sort", "name", "order entries by name or quality")
+	flags.Parse(args)
+	if *order != "name" && *order != "quality" {
+		return fmt.Errorf("--sort: want name or quality, got %q", *order)
+	}
+
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	bps := slices.DeleteFunc(slices.Clone(db.Blueprints), func(bp registry.Blueprint) bool {
+		return (*ns != "" && bp.Namespace != *ns) || (*tag != "" && !slices.Contains(bp.Tags, *tag))
+	})
+	slices.SortFunc(bps, func(a, b registry.Blueprint) int { return strings.Compare(a.FullName(), b.FullName()) })
+	if *order == "quality" {
+		sortByQuality(bps)
+	}
+	if *asJSON {
+		return printJSON(bps)
+	}
+	if len(bps) == 0 {
+		fmt.Println(cliCatalog().translate("no entries"))
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVERSION\tUPDATED\tQUALITY\tTAGS")
+	for _, bp := range bps {
+		updated := "-"
+		if !bp.UpdatedAt.IsZero() {
+			updated = bp.UpdatedAt.Format(time.DateOnly)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", bp.FullName(), bp.Version, updated, formatQuality(bp), strings.Join(bp.Tags, ","))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,219 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// loadReport summarizes a load test.
+type loadReport struct {
+	Requests   int            `json:"requests"`
+	Errors     int            `json:"errors"`
+	Status     map[string]int `json:"status"`
+	Seconds    float64        `json:"seconds"`
+	RPS        float64        `json:"rps"`
+	LatencyP50 float64        `json:"latency_p50_ms"`
+	LatencyP90 float64        `json:"latency_p90_ms"`
+	LatencyP99 float64        `json:"latency_p99_ms"`
+	LatencyMax float64        `json:"latency_max_ms"`
+}
+
+func (r loadReport) errorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// percentile returns the p-th percentile of sorted latencies, in ms.
+func percentile(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := min(int(float64(len(sorted))*p), len(sorted)-1)
+	return float64(sorted[i].Microseconds()) / 1000
+}
+
+// loadTest sends GETs to urls in turn from concurrency workers until
+// duration passes or total requests were sent (0 for no limit). A rate
+// above 0 caps the requests per second across workers.
+func loadTest(ctx context.Context, hc *http.Client, urls []string, concurrency, total int, rate float64, duration time.Duration) loadReport {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var tick <-chan time.Time
+	if rate > 0 {
+		t := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer t.Stop()
+		tick = t.C
+	}
+	var (
+		mu        sync.Mutex
+		n         int
+		latencies []time.Duration
+		rep       = loadReport{Status: map[string]int{}}
+	)
+	// next claims the index of the next request, or false when done
+	next := func() (int, bool) {
+		if tick != nil {
+			select {
+			case <-ctx.Done():
+				return 0, false
+			case <-tick:
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil || (total > 0 && n >= total) {
+			return 0, false
+		}
+		n++
+		return n - 1, true
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Go(func() {
+			for {
+				i, ok := next()
+				if !ok {
+					return
+				}
+				status, d := loadRequest(ctx, hc, urls[i%len(urls)])
+				if status == "" {
+					// cut off by the end of the test
+					continue
+				}
+				mu.Lock()
+				rep.Requests++
+				rep.Status[status]++
+				if status[0] != '2' && status[0] != '3' {
+					rep.Errors++
+				}
+				latencies = append(latencies, d)
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	rep.Seconds = time.Since(start).Seconds()
+	if rep.Seconds > 0 {
+		rep.RPS = float64(rep.Requests) / rep.Seconds
+	}
+	slices.Sort(latencies)
+	rep.LatencyP50 = percentile(latencies, 0.50)
+	rep.LatencyP90 = percentile(latencies, 0.90)
+	rep.LatencyP99 = percentile(latencies, 0.99)
+	if len(latencies) > 0 {
+		rep.LatencyMax = percentile(latencies, 1)
+	}
+	return rep
+}
+
+// loadRequest fetches u and reads the body. It returns the status code,
+// or "error" when the request failed, and "" when the test ended first.
+func loadRequest(ctx context.Context, hc *http.Client, u string) (string, time.Duration) {
+	start := time.Now()
+	req, _ := http.NewRequestWithContext(ctx, "GET", u, nil)
+	req.Header.Set(requestIDHeader, requestID(ctx))
+	resp, err := hc.Do(req)
+	if err == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	d := time.Since(start)
+	switch {
+	case ctx.Err() != nil:
+		return "", d
+	case err != nil:
+		return "error", d
+	}
+	return fmt.Sprint(resp.StatusCode), d
+}
+
+// runLoadtest checks that a deployment holds up under load before it is
+// exposed publicly, failing when the given limits are exceeded.
+func runLoadtest(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	concurrency := flags.Int("concurrency", 10, "concurrent connections")
+	duration := flags.Duration("duration", 30*time.Second, "how long to run")
+	total := flags.Int("requests", 0, "stop after this many requests (0 for no limit)")
+	rate := flags.Float64("rate", 0, "requests per second across connections (0 for as fast as possible)")
+	timeout := flags.Duration("timeout", 10*time.Second, "per-request timeout")
+	maxP99 := flags.Duration("max-p99", 0, "fail if the 99th percentile latency exceeds this")
+	maxErrors := flags.Float64("max-error-rate", 0.01, "fail if more than this fraction of requests fail")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return errors.New("usage: loadtest [flags] <url>...")
+	}
+	if *concurrency < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+
+	hc := &http.Client{
+		Timeout: *timeout,
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxIdleConnsPerHost: *concurrency,
+		},
+	}
+	fmt.Fprintf(os.Stderr, "loadtest: %d connections for %s against %d URLs\n", *concurrency, *duration, flags.NArg())
+	rep := loadTest(ctx, hc, flags.Args(), *concurrency, *total, *rate, *duration)
+
+	if *asJSON {
+		if err := printJSON(rep); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "requests\t%d in %.1fs (%.1f/s)\n", rep.Requests, rep.Seconds, rep.RPS)
+		fmt.Fprintf(w, "errors\t%d (%.2f%%)\n", rep.Errors, 100*rep.errorRate())
+		fmt.Fprintf(w, "latency\tp50 %.1fms  p90 %.1fms  p99 %.1fms  max %.1fms\n", rep.LatencyP50, rep.LatencyP90, rep.LatencyP99, rep.LatencyMax)
+		codes := make([]string, 0, len(rep.Status))
+		for c := range rep.Status {
+			codes = append(codes, c)
+		}
+		sort.Strings(codes)
+		for _, c := range codes {
+			fmt.Fprintf(w, "status %s\t%d\n", c, rep.Status[c])
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	if rep.Requests == 0 {
+		errs = append(errs, errors.New("no requests completed"))
+	}
+	if rep.errorRate() > *maxErrors {
+		errs = append(errs, fmt.Errorf("error rate %.2f%% exceeds %.2f%%", 100*rep.errorRate(), 100**maxErrors))
+	}
+	if p99 := time.Duration(rep.LatencyP99 * float64(time.Millisecond)); *maxP99 > 0 && p99 > *maxP99 {
+		errs = append(errs, fmt.Errorf("p99 latency %s exceeds %s", p99.Round(time.Millisecond), *maxP99))
+	}
+	return errors.Join(errs...)
+}
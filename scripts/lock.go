@@ -0,0 +1,179 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// lockVersion is the format version of dragon-lock.json.
+const lockVersion = 1
+
+// lockFile pins a blueprint and its dependencies to exact versions and
+// archive digests, so a scaffold can be reproduced later.
+type lockFile struct {
+	LockVersion int       `json:"lock_version"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Registry is the SHA-256 of the canonical registry the lock was
+	// resolved against.
+	Registry   string        `json:"registry"`
+	Root       string        `json:"root"`
+	Blueprints []lockedEntry `json:"blueprints"`
+}
+
+// lockedEntry is one pinned blueprint, in install order.
+type lockedEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"`
+}
+
+// fileSHA256 hashes everything readable from r.
+func fileSHA256(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// assetSHA256 computes the digest of a release asset by streaming it
+// through the hash, without keeping the archive in memory or on disk.
+func assetSHA256(ctx context.Context, url string) (string, error) {
+	d, err := assetDigests(ctx, url, registry.DigestSHA256)
+	if err != nil {
+		return "", err
+	}
+	return d[registry.DigestSHA256], nil
+}
+
+// assetDigest returns the SHA-256 of a release asset. It prefers the
+// archive already downloaded for scanning, then the digest the release
+// publishes (GitHub's, or one listed in its checksums file), then the one
+// recorded when the same asset was indexed before, and only downloads the
+// asset when none is known. The same asset means the same URL, GitHub ID
+// and size: one deleted and uploaded again under its name keeps the URL.
+func assetDigest(ctx context.Context, a registry.GitHubAsset, sums map[string]string, scan *assetScan, db registry.Database) (string, error) {
+	reported := a.SHA256()
+	if listed := sums[a.Name]; listed != "" {
+		if reported != "" && reported != listed {
+			return "", fmt.Errorf("checksums file lists %s but GitHub reports %s", listed, reported)
+		}
+		reported = listed
+	}
+	if scan != nil {
+		if reported != "" && reported != scan.SHA256 {
+			return "", fmt.Errorf("archive hashes to %s but the release publishes %s", scan.SHA256, reported)
+		}
+		return scan.SHA256, nil
+	}
+	if reported != "" {
+		return reported, nil
+	}
+	for _, bp := range db.Blueprints {
+		for _, r := range bp.Versions() {
+			sameURL := r.DownloadURL == a.DownloadURL() || bp.SourceURL == a.DownloadURL() && r.Version == bp.Version
+			if r.SHA256 != "" && sameURL && r.Asset.Matches(a) {
+				return r.SHA256, nil
+			}
+		}
+	}
+	return assetSHA256(ctx, a.DownloadURL())
+}
+
+// buildLock resolves ref's dependency closure into a lock, returning the
+// warnings about its entries too. Entries without a recorded digest are
+// downloaded and hashed unless offline is set.
+func buildLock(ctx context.Context, db registry.Database, ref string, offline bool) (lockFile, []registry.Warning, error) {
+	c, err := dependencyClosure(db, ref)
+	if err != nil {
+		return lockFile{}, nil, err
+	}
+	canon, err := registry.CanonicalJSON(registry.Normalize(db))
+	if err != nil {
+		return lockFile{}, nil, err
+	}
+	sum := sha256.Sum256(canon)
+	lock := lockFile{
+		LockVersion: lockVersion,
+		GeneratedAt: time.Now().UTC(),
+		Registry:    hex.EncodeToString(sum[:]),
+		Root:        c.Root,
+	}
+	digests := map[string]string{}
+	for _, bp := range db.Blueprints {
+		digests[bp.FullName()] = bp.SHA256
+	}
+	for _, e := range c.Entries {
+		digest := digests[e.Name]
+		if digest == "" {
+			if offline {
+				return lockFile{}, nil, fmt.Errorf("%s: no digest recorded", e.Name)
+			}
+			if digest, err = assetSHA256(ctx, e.DownloadURL); err != nil {
+				return lockFile{}, nil, fmt.Errorf("%s: %w", e.Name, err)
+			}
+		}
+		lock.Blueprints = append(lock.Blueprints, lockedEntry{
+			Name:    e.Name,
+			Version: e.Version,
+			URL:     e.DownloadURL,
+			SHA256:  digest,
+		})
+	}
+	return lock, c.Warnings, nil
+}
+
+// runLock writes a dragon-lock.json for a blueprint.
+func runLock(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("lock", flag.ExitOnError)
+	out := flags.String("o", "dragon-lock.json", "lock file to write, - for stdout")
+	offline := flags.Bool("offline", false, "fail instead of downloading archives that have no recorded digest")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: lock [-o dragon-lock.json] <namespace/name | name>")
+	}
+
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	lock, warnings, err := buildLock(ctx, db, flags.Arg(0), *offline)
+	if err != nil {
+		return err
+	}
+	printWarnings(os.Stderr, warnings)
+	b, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		fmt.Println(string(b))
+		return nil
+	}
+	if err := os.WriteFile(*out, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("locked %d blueprints to %s\n", len(lock.Blueprints), *out)
+	return nil
+}
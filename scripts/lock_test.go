@@ -0,0 +1,58 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+func TestAssetDigestReupload(t *testing.T) {
+	archive := []byte("re-uploaded archive")
+	sum := sha256.Sum256(archive)
+	fresh := hex.EncodeToString(sum[:])
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+	defer srv.Close()
+
+	url := srv.URL + "/releases/download/v1.0.0/web-api.zip"
+	stale := "0000000000000000000000000000000000000000000000000000000000000000"
+	db := registry.Database{Blueprints: []registry.Blueprint{{
+		Name: "web-api", Version: "1.0.0", DownloadURL: url, SHA256: stale,
+		Asset: &registry.AssetRef{ID: 7, Size: 1024},
+	}}}
+	for _, tc := range []struct {
+		name string
+		a    registry.GitHubAsset
+		want string
+	}{
+		{"same asset", registry.GitHubAsset{ID: 7, Size: 1024, BrowserDownloadURL: url}, stale},
+		{"re-uploaded", registry.GitHubAsset{ID: 8, Size: int64(len(archive)), BrowserDownloadURL: url}, fresh},
+		{"same ID, other size", registry.GitHubAsset{ID: 7, Size: int64(len(archive)), BrowserDownloadURL: url}, fresh},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := assetDigest(context.Background(), tc.a, nil, nil, db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("digest %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
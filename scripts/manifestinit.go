@@ -0,0 +1,382 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// paramNameRe matches parameter names, which templates use as variables.
+var paramNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// maxSuggestedTags bounds the tag vocabulary shown while prompting.
+const maxSuggestedTags = 20
+
+func runManifest(args []string) error {
+	if len(args) == 0 || args[0] != "init" {
+		return errors.New("usage: manifest init [--out manifest.yaml] [--force]")
+	}
+	flags := flag.NewFlagSet("manifest init", flag.ExitOnError)
+	out := flags.String("out", "manifest.yaml", "file to write")
+	force := flags.Bool("force", false, "overwrite an existing file")
+	flags.Parse(args[1:])
+
+	if _, err := os.Stat(*out); err == nil && !*force {
+		return fmt.Errorf("%s exists; use --force to overwrite it", *out)
+	}
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	vocab, err := loadFeatureVocab(cfg.Features.Vocabulary)
+	if err != nil {
+		return fmt.Errorf("features: %w", err)
+	}
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	mi := manifestInit{
+		in:      bufio.NewReader(os.Stdin),
+		out:     os.Stderr,
+		cfg:     cfg,
+		vocab:   vocab,
+		tags:    tagsInUse(db),
+		license: localLicense(),
+	}
+	man, err := mi.run()
+	if err != nil {
+		return err
+	}
+	b, err := encodeManifest(man)
+	if err != nil {
+		return err
+	}
+	// what we write must be what the updater reads back
+	if _, err := registry.ParseManifest(*out, b); err != nil {
+		return fmt.Errorf("generated manifest does not parse: %w", err)
+	}
+	if err := registry.WriteFileAtomic(*out, b); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s\n", *out)
+	return nil
+}
+
+// manifestInit asks for a manifest field by field, checking each answer
+// the way the updater will.
+type manifestInit struct {
+	in    *bufio.Reader
+	out   io.Writer
+	cfg   config
+	vocab *featureVocab
+	// tags are the tags entries already use, most used first: the
+	// vocabulary authors should pick from.
+	tags []tagCount
+	// license is the SPDX id found in ./LICENSE, offered as the default.
+	license string
+}
+
+type tagCount struct {
+	Tag   string
+	Count int
+}
+
+func (mi *manifestInit) run() (registry.Manifest, error) {
+	var man registry.Manifest
+	var err error
+	if man.Name, err = mi.ask("name (namespace/name or name)", "", checkManifestName); err != nil {
+		return man, err
+	}
+	if man.Version, err = mi.ask("version", "0.1.0", func(s string) error {
+		_, err := registry.ParseSemver(s)
+		return err
+	}); err != nil {
+		return man, err
+	}
+	max := mi.cfg.Text.descriptionMax()
+	if man.Description, err = mi.ask("description", "", func(s string) error {
+		if s == "" {
+			return errors.New("a description is required")
+		}
+		if clean := sanitizeText(s, max); clean != s {
+			return fmt.Errorf("the catalog would show this as %q; use plain text of at most %d characters", clean, max)
+		}
+		return nil
+	}); err != nil {
+		return man, err
+	}
+	if man.Visibility, err = mi.ask("visibility (public, internal, private)", visibilityPublic, validateVisibility); err != nil {
+		return man, err
+	}
+	if man.Visibility == visibilityPublic {
+		man.Visibility = ""
+	}
+	if man.License, err = mi.ask("license (SPDX id)", mi.license, nil); err != nil {
+		return man, err
+	}
+	if man.Tags, err = mi.askTags(); err != nil {
+		return man, err
+	}
+	if man.Features, err = mi.askFeatures(); err != nil {
+		return man, err
+	}
+	man.Parameters, err = mi.askParams()
+	return man, err
+}
+
+// ask prompts for one answer until check accepts it. An empty answer
+// takes def.
+func (mi *manifestInit) ask(label, def string, check func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(mi.out, "%s [%s]: ", label, def)
+		} else {
+			fmt.Fprintf(mi.out, "%s: ", label)
+		}
+		line, err := mi.in.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			if errors.Is(err, io.EOF) {
+				return "", errors.New("input ended before the manifest was complete")
+			}
+			return "", err
+		}
+		ans := cmp.Or(strings.TrimSpace(line), def)
+		if check != nil {
+			if err := check(ans); err != nil {
+				fmt.Fprintf(mi.out, "  %v\n", err)
+				continue
+			}
+		}
+		return ans, nil
+	}
+}
+
+func (mi *manifestInit) askTags() ([]string, error) {
+	if len(mi.tags) > 0 {
+		var names []string
+		for _, t := range mi.tags[:min(len(mi.tags), maxSuggestedTags)] {
+			names = append(names, t.Tag)
+		}
+		fmt.Fprintf(mi.out, "tags in use: %s\n", strings.Join(names, ", "))
+	}
+	var tags []string
+	_, err := mi.ask("tags (comma-separated)", "", func(s string) error {
+		tags = nil
+		for _, t := range splitList(s) {
+			if strings.HasPrefix(t, autoTagPrefix) {
+				return fmt.Errorf("tag %q: the %s prefix is reserved for generated tags", t, autoTagPrefix)
+			}
+			if err := registry.ValidateIdent("tag", t); err != nil {
+				return err
+			}
+			if !slices.Contains(tags, t) {
+				tags = append(tags, t)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		if !slices.ContainsFunc(mi.tags, func(c tagCount) bool { return c.Tag == t }) && len(mi.tags) > 0 {
+			fmt.Fprintf(mi.out, "  note: no entry uses %q yet\n", t)
+		}
+	}
+	return tags, nil
+}
+
+func (mi *manifestInit) askFeatures() (map[string]any, error) {
+	if mi.vocab != nil && len(mi.vocab.Features) > 0 {
+		fmt.Fprintln(mi.out, "known features:")
+		for _, name := range slices.Sorted(maps.Keys(mi.vocab.Features)) {
+			def := mi.vocab.Features[name]
+			typ := def.Type
+			if def.Type == featureEnum {
+				typ = strings.Join(def.Values, "|")
+			}
+			fmt.Fprintf(mi.out, "  %s (%s) %s\n", name, typ, def.Description)
+		}
+	}
+	var features map[string]any
+	_, err := mi.ask("features (comma-separated, name or name=value)", "", func(s string) error {
+		in := map[string]any{}
+		for _, f := range splitList(s) {
+			name, value, ok := strings.Cut(f, "=")
+			switch {
+			case !ok:
+				in[name] = true
+			case value == "true" || value == "false":
+				in[name] = value == "true"
+			default:
+				in[name] = value
+			}
+		}
+		var findings []string
+		features, findings = checkFeatures(in, mi.vocab)
+		if len(findings) > 0 {
+			return errors.New(strings.Join(findings, "; "))
+		}
+		return nil
+	})
+	return features, err
+}
+
+func (mi *manifestInit) askParams() ([]registry.Param, error) {
+	var params []registry.Param
+	for {
+		name, err := mi.ask("parameter name (empty to finish)", "", func(s string) error {
+			if s == "" {
+				return nil
+			}
+			if !paramNameRe.MatchString(s) {
+				return fmt.Errorf("invalid parameter name %q: use letters, digits and '_'", s)
+			}
+			if slices.ContainsFunc(params, func(p registry.Param) bool { return p.Name == s }) {
+				return fmt.Errorf("parameter %q is already defined", s)
+			}
+			return nil
+		})
+		if err != nil || name == "" {
+			return params, err
+		}
+		p := registry.Param{Name: name}
+		if p.Description, err = mi.ask("  description", "", nil); err != nil {
+			return nil, err
+		}
+		def, err := mi.ask("  default (empty for none)", "", nil)
+		if err != nil {
+			return nil, err
+		}
+		if def != "" {
+			p.Default = def
+		}
+		req, err := mi.ask("  required (y/n)", "n", func(s string) error {
+			if s != "y" && s != "n" {
+				return errors.New("answer y or n")
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		p.Required = req == "y"
+		params = append(params, p)
+	}
+}
+
+func checkManifestName(s string) error {
+	ns, name, ok := strings.Cut(s, "/")
+	if !ok {
+		return registry.ValidateIdent("name", s)
+	}
+	if err := registry.ValidateIdent("namespace", ns); err != nil {
+		return err
+	}
+	return registry.ValidateIdent("name", name)
+}
+
+// splitList splits a comma-separated answer, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// tagsInUse counts the author tags across entries, most used first.
+func tagsInUse(db registry.Database) []tagCount {
+	counts := map[string]int{}
+	for _, bp := range db.Blueprints {
+		for _, t := range bp.Tags {
+			if !strings.HasPrefix(t, autoTagPrefix) {
+				counts[t]++
+			}
+		}
+	}
+	var out []tagCount
+	for t, n := range counts {
+		out = append(out, tagCount{t, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Tag < out[j].Tag
+	})
+	return out
+}
+
+// localLicense detects the license in the working directory, if any.
+func localLicense() string {
+	for _, name := range []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "COPYING"} {
+		if b, err := os.ReadFile(name); err == nil {
+			return detectLicense(string(b))
+		}
+	}
+	return ""
+}
+
+// initManifest is the YAML layout manifest init writes: the fields of a
+// manifest, leaving out the ones the author didn't set.
+type initManifest struct {
+	APIVersion  string         `yaml:"apiVersion"`
+	Name        string         `yaml:"name"`
+	Version     string         `yaml:"version"`
+	Description string         `yaml:"description"`
+	Visibility  string         `yaml:"visibility,omitempty"`
+	License     string         `yaml:"license,omitempty"`
+	Tags        []string       `yaml:"tags,omitempty"`
+	Features    map[string]any `yaml:"features,omitempty"`
+	Parameters  []initParam    `yaml:"parameters,omitempty"`
+}
+
+type initParam struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Default     any    `yaml:"default,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+}
+
+func encodeManifest(man registry.Manifest) ([]byte, error) {
+	m := initManifest{
+		APIVersion:  registry.ManifestV1,
+		Name:        man.Name,
+		Version:     man.Version,
+		Description: man.Description,
+		Visibility:  man.Visibility,
+		License:     man.License,
+		Tags:        man.Tags,
+		Features:    man.Features,
+	}
+	for _, p := range man.Parameters {
+		m.Parameters = append(m.Parameters, initParam(p))
+	}
+	return yaml.Marshal(m)
+}
@@ -0,0 +1,270 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// matrixConfig is the dragon CLI versions matrix-test scaffolds
+// blueprints with.
+type matrixConfig struct {
+	// CLI maps dragon CLI versions to the binaries of those versions.
+	CLI map[string]string `yaml:"cli"`
+	// Args scaffold a blueprint, with {archive} replaced by the release
+	// archive and {dir} by the empty directory to scaffold into.
+	// Defaults to new --from {archive} --dir {dir} --yes.
+	Args []string `yaml:"args"`
+	// Timeout bounds one scaffold. Defaults to 2m.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (m matrixConfig) validate() error {
+	for v := range m.CLI {
+		if _, err := registry.ParseSemver(v); err != nil {
+			return fmt.Errorf("matrix.cli: %q is not a version", v)
+		}
+	}
+	if len(m.Args) > 0 && !slices.ContainsFunc(m.Args, func(a string) bool { return strings.Contains(a, "{dir}") }) {
+		return errors.New("matrix.args must scaffold into {dir}")
+	}
+	if m.Timeout < 0 {
+		return errors.New("matrix.timeout must not be negative")
+	}
+	return nil
+}
+
+func (m matrixConfig) args() []string {
+	if len(m.Args) == 0 {
+		return []string{"new", "--from", "{archive}", "--dir", "{dir}", "--yes"}
+	}
+	return m.Args
+}
+
+func (m matrixConfig) timeout() time.Duration { return orDefault(m.Timeout, 2*time.Minute) }
+
+// versions lists the CLI versions to test, oldest first.
+func (m matrixConfig) versions() []string {
+	return slices.SortedFunc(maps.Keys(m.CLI), compareSemver)
+}
+
+func compareSemver(a, b string) int {
+	va, _ := registry.ParseSemver(a)
+	vb, _ := registry.ParseSemver(b)
+	return va.Compare(vb)
+}
+
+// runMatrixTest scaffolds entries with every configured dragon CLI and
+// records which versions work with them.
+func runMatrixTest(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("matrix-test", flag.ExitOnError)
+	base := flags.String("base", "", "test only the entries that changed relative to this registry")
+	only := flags.String("cli", "", "comma-separated CLI versions to test; default all of matrix.cli")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	versions := cfg.Matrix.versions()
+	if *only != "" {
+		versions = splitList(*only)
+		for _, v := range versions {
+			if _, ok := cfg.Matrix.CLI[v]; !ok {
+				return fmt.Errorf("--cli: %s is not in matrix.cli", v)
+			}
+		}
+	}
+	if len(versions) == 0 {
+		return errors.New("no CLI versions to test; set matrix.cli")
+	}
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	db := *st.snapshot()
+	bps := db.Blueprints
+	switch {
+	case flags.NArg() > 0:
+		bps = nil
+		for _, ref := range flags.Args() {
+			bp, err := registry.Find(db, ref)
+			if err != nil {
+				return err
+			}
+			bps = append(bps, bp)
+		}
+	case *base != "":
+		old, err := loadDB(*base)
+		if err != nil {
+			return fmt.Errorf("load %s: %w", *base, err)
+		}
+		changed := changedEntries(old, db)
+		bps = slices.DeleteFunc(slices.Clone(bps), func(bp registry.Blueprint) bool { return !slices.Contains(changed, bp.FullName()) })
+	}
+
+	var runs, failed int
+	tx := st.begin()
+	for _, bp := range bps {
+		results, err := testEntry(ctx, cfg.Matrix, bp, versions)
+		if err != nil {
+			// not the blueprint's fault as far as we know: record nothing
+			fmt.Fprintf(os.Stderr, "SKIP %s@%s: %v\n", bp.FullName(), bp.Version, err)
+			continue
+		}
+		for _, r := range results {
+			runs++
+			if !r.Passed {
+				failed++
+			}
+		}
+		name, version := bp.FullName(), bp.Version
+		tx.stage(func(db *registry.Database) error {
+			for i := range db.Blueprints {
+				// a newer release since has not been tested
+				if db.Blueprints[i].FullName() == name && db.Blueprints[i].Version == version {
+					recordCompatibility(&db.Blueprints[i], results)
+				}
+			}
+			return nil
+		})
+	}
+	if err := tx.commit(); err != nil {
+		return fmt.Errorf("update registry: %w", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d runs failed", failed, runs)
+	}
+	return nil
+}
+
+// testEntry downloads an entry's archive and scaffolds it with each CLI
+// version. An error means the archive couldn't be had, so nothing was
+// tested.
+func testEntry(ctx context.Context, m matrixConfig, bp registry.Blueprint, versions []string) ([]registry.Compatibility, error) {
+	f, _, err := downloadArchive(ctx, bp.DownloadURL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	if bp.SHA256 != "" {
+		if _, err := f.Seek(0, 0); err != nil {
+			return nil, err
+		}
+		sum, err := fileSHA256(f)
+		if err != nil {
+			return nil, err
+		}
+		if sum != bp.SHA256 {
+			return nil, fmt.Errorf("archive hashes to %s, not %s", sum, bp.SHA256)
+		}
+	}
+	var results []registry.Compatibility
+	for _, v := range versions {
+		err := scaffold(ctx, m, m.CLI[v], f.Name())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "FAIL %s@%s with dragon %s: %v\n", bp.FullName(), bp.Version, v, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "ok   %s@%s with dragon %s\n", bp.FullName(), bp.Version, v)
+		}
+		results = append(results, registry.Compatibility{CLI: v, Version: bp.Version, Passed: err == nil, TestedAt: time.Now().UTC()})
+	}
+	return results, nil
+}
+
+// scaffold runs one CLI over an archive in a fresh temp dir, with a home
+// of its own so nothing from this machine's config or cache leaks in.
+// It passes if the CLI exits cleanly having written something.
+func scaffold(ctx context.Context, m matrixConfig, bin, archive string) error {
+	root, err := os.MkdirTemp("", "dragon-matrix-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(root)
+	dir, home := filepath.Join(root, "out"), filepath.Join(root, "home")
+	for _, d := range []string{dir, home} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			return err
+		}
+	}
+	// the CLI runs in root, so a relative binary is relative to here
+	if strings.ContainsRune(bin, '/') || strings.ContainsRune(bin, filepath.Separator) {
+		if bin, err = filepath.Abs(bin); err != nil {
+			return err
+		}
+	}
+	r := strings.NewReplacer("{archive}", archive, "{dir}", dir)
+	var args []string
+	for _, a := range m.args() {
+		args = append(args, r.Replace(a))
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(),
+		"HOME="+home,
+		"USERPROFILE="+home,
+		"XDG_CONFIG_HOME="+filepath.Join(home, ".config"),
+		"XDG_CACHE_HOME="+filepath.Join(home, ".cache"),
+		"XDG_DATA_HOME="+filepath.Join(home, ".local", "share"),
+	)
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out after %s", m.timeout())
+		}
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, lastLine(msg))
+		}
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return errors.New("scaffolded nothing")
+	}
+	return nil
+}
+
+// recordCompatibility replaces bp's results for the versions tested,
+// dropping any for other releases.
+func recordCompatibility(bp *registry.Blueprint, results []registry.Compatibility) {
+	tested := map[string]bool{}
+	for _, r := range results {
+		tested[r.CLI] = true
+	}
+	out := slices.DeleteFunc(bp.Compatibility, func(c registry.Compatibility) bool {
+		return c.Version != bp.Version || tested[c.CLI]
+	})
+	out = append(out, results...)
+	slices.SortFunc(out, func(a, b registry.Compatibility) int { return compareSemver(a.CLI, b.CLI) })
+	bp.Compatibility = out
+}
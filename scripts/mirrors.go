@@ -0,0 +1,220 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// mirrorConfig turns healthcheck results into a running score per mirror.
+type mirrorConfig struct {
+	// Scores is the file the scores are kept in; empty disables scoring.
+	Scores string `yaml:"scores"`
+	// Reorder rewrites each entry's mirror list, healthiest first, after
+	// every healthcheck.
+	Reorder bool `yaml:"reorder"`
+}
+
+// mirrorAlpha weighs the latest check against the history: one bad run
+// moves a mirror down, but it takes a few to bury it.
+const mirrorAlpha = 0.3
+
+// unscoredMirror is the score of a mirror never checked, between a
+// healthy mirror and a dead one.
+const unscoredMirror = 0.5
+
+// mirrorHealth is the history of one mirror URL.
+type mirrorHealth struct {
+	Checks int `json:"checks"`
+	// Availability is the moving average of successful checks, 0 to 1.
+	Availability float64 `json:"availability"`
+	// LatencyMS is the moving average latency of successful checks.
+	LatencyMS   float64   `json:"latency_ms"`
+	LastChecked time.Time `json:"last_checked"`
+	LastOK      time.Time `json:"last_ok,omitzero"`
+}
+
+// score is availability discounted by latency: a mirror answering in a
+// second scores half of one answering instantly.
+func (h mirrorHealth) score() float64 {
+	if h.Checks == 0 {
+		return unscoredMirror
+	}
+	return h.Availability * 1000 / (1000 + h.LatencyMS)
+}
+
+func (h *mirrorHealth) record(r linkResult, at time.Time) {
+	ok := 0.0
+	if r.State != linkDead {
+		ok = 1
+	}
+	if h.Checks == 0 {
+		h.Availability = ok
+	} else {
+		h.Availability += mirrorAlpha * (ok - h.Availability)
+	}
+	// latency only means something for answers
+	switch {
+	case ok == 0:
+	case h.LastOK.IsZero():
+		h.LatencyMS = float64(r.LatencyMS)
+	default:
+		h.LatencyMS += mirrorAlpha * (float64(r.LatencyMS) - h.LatencyMS)
+	}
+	h.Checks++
+	h.LastChecked = at
+	if ok == 1 {
+		h.LastOK = at
+	}
+}
+
+// mirrorScores maps mirror URLs to their history.
+type mirrorScores map[string]*mirrorHealth
+
+func readMirrorScores(p string) (mirrorScores, error) {
+	s := mirrorScores{}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", p, err)
+	}
+	return s, nil
+}
+
+func writeMirrorScores(p string, s mirrorScores) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return registry.WriteFileAtomic(p, append(b, '\n'))
+}
+
+// record folds the mirror results of a healthcheck into the scores and
+// forgets mirrors the registry no longer lists.
+func (s mirrorScores) record(rep healthReport, db registry.Database) {
+	listed := map[string]bool{}
+	for _, bp := range db.Blueprints {
+		for _, m := range bp.Mirrors {
+			listed[m] = true
+		}
+	}
+	for u := range s {
+		if !listed[u] {
+			delete(s, u)
+		}
+	}
+	for _, r := range rep.Results {
+		if r.Kind != "mirror" {
+			continue
+		}
+		if s[r.URL] == nil {
+			s[r.URL] = &mirrorHealth{}
+		}
+		s[r.URL].record(r, rep.CheckedAt)
+	}
+}
+
+func (s mirrorScores) score(u string) float64 {
+	if h := s[u]; h != nil {
+		return h.score()
+	}
+	return unscoredMirror
+}
+
+// orderMirrors sorts every entry's mirrors by score, best first, keeping
+// the listed order among equals. It reports how many entries changed.
+func orderMirrors(db *registry.Database, s mirrorScores) int {
+	changed := 0
+	for i := range db.Blueprints {
+		ms := db.Blueprints[i].Mirrors
+		if len(ms) < 2 {
+			continue
+		}
+		sorted := slices.Clone(ms)
+		slices.SortStableFunc(sorted, func(a, b string) int {
+			switch sa, sb := s.score(a), s.score(b); {
+			case sa > sb:
+				return -1
+			case sa < sb:
+				return 1
+			}
+			return 0
+		})
+		if !slices.Equal(ms, sorted) {
+			db.Blueprints[i].Mirrors = sorted
+			changed++
+		}
+	}
+	return changed
+}
+
+// runMirrors prints the score of every mirror, entry by entry, in the
+// order clients should try them.
+func runMirrors(args []string) error {
+	flags := flag.NewFlagSet("mirrors", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the scores as JSON")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if cfg.Mirrors.Scores == "" {
+		return errors.New("mirrors.scores is not configured")
+	}
+	s, err := readMirrorScores(cfg.Mirrors.Scores)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(s)
+	}
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	orderMirrors(&db, s)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENTRY\tMIRROR\tSCORE\tAVAILABILITY\tLATENCY\tLAST OK")
+	for _, bp := range db.Blueprints {
+		for _, m := range bp.Mirrors {
+			h := s[m]
+			if h == nil {
+				fmt.Fprintf(tw, "%s\t%s\t%.2f\t-\t-\t-\n", bp.FullName(), m, unscoredMirror)
+				continue
+			}
+			if h.LastOK.IsZero() {
+				fmt.Fprintf(tw, "%s\t%s\t%.2f\t%.0f%%\t-\tnever\n", bp.FullName(), m, h.score(), h.Availability*100)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%.2f\t%.0f%%\t%.0fms\t%s\n", bp.FullName(), m, h.score(), h.Availability*100, h.LatencyMS, h.LastOK.Format(time.DateOnly))
+		}
+	}
+	return tw.Flush()
+}
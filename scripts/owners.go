@@ -0,0 +1,171 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// ownersFile maps blueprint name patterns to the teams that maintain them.
+// As with CODEOWNERS, the last matching rule wins.
+type ownersFile struct {
+	Rules []ownerRule `yaml:"rules"`
+}
+
+type ownerRule struct {
+	// Pattern is matched against "namespace/name" with path.Match, so
+	// "acme/*" covers a whole namespace.
+	Pattern string   `yaml:"pattern"`
+	Owners  []string `yaml:"owners"`
+}
+
+// loadOwners reads an owners file. A missing file means nobody owns
+// anything.
+func loadOwners(p string) (ownersFile, error) {
+	var of ownersFile
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return of, nil
+		}
+		return of, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&of); err != nil && !errors.Is(err, io.EOF) {
+		return of, fmt.Errorf("%s: %w", p, err)
+	}
+	for _, r := range of.Rules {
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return of, fmt.Errorf("%s: bad pattern %q: %w", p, r.Pattern, err)
+		}
+	}
+	return of, nil
+}
+
+// ownersOf returns the owners of the entry with the given full name.
+func (of ownersFile) ownersOf(fullName string) []string {
+	var owners []string
+	for _, r := range of.Rules {
+		if ok, _ := path.Match(r.Pattern, fullName); ok {
+			owners = r.Owners
+		}
+	}
+	return owners
+}
+
+// canWrite reports whether p may modify the entry with the given full
+// name: admins always can, everyone else must be on an owning team.
+func (of ownersFile) canWrite(p principal, fullName string) bool {
+	if p.Admin {
+		return true
+	}
+	for _, o := range of.ownersOf(fullName) {
+		if slices.Contains(p.Teams, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// changedEntries returns the full names of entries that differ between two
+// registries, including additions and removals.
+func changedEntries(base, head registry.Database) []string {
+	old := map[string]registry.Blueprint{}
+	for _, bp := range base.Blueprints {
+		old[bp.FullName()] = bp
+	}
+	seen := map[string]bool{}
+	var changed []string
+	for _, bp := range head.Blueprints {
+		seen[bp.FullName()] = true
+		if prev, ok := old[bp.FullName()]; !ok || !reflect.DeepEqual(prev, bp) {
+			changed = append(changed, bp.FullName())
+		}
+	}
+	for name := range old {
+		if !seen[name] {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// runOwners prints the owners of the given entries, or of every entry that
+// changed relative to --base, which is what a registry PR needs reviews from.
+func runOwners(args []string) error {
+	flags := flag.NewFlagSet("owners", flag.ExitOnError)
+	file := flags.String("owners", "owners.yaml", "owners file")
+	base := flags.String("base", "", "compare registry.json against this registry and list owners of changed entries")
+	csv := flags.Bool("csv", false, "print owners comma-separated (for gh pr create --reviewer)")
+	flags.Parse(args)
+
+	of, err := loadOwners(*file)
+	if err != nil {
+		return fmt.Errorf("load owners: %w", err)
+	}
+	refs := flags.Args()
+	if *base != "" {
+		old, err := loadDB(*base)
+		if err != nil {
+			return fmt.Errorf("load %s: %w", *base, err)
+		}
+		cur, err := loadDB(registryPath())
+		if err != nil {
+			return fmt.Errorf("load registry: %w", err)
+		}
+		refs = append(refs, changedEntries(old, cur)...)
+	}
+	if len(refs) == 0 {
+		return errors.New("usage: owners [--base registry.json] [namespace/name...]")
+	}
+
+	seen := map[string]bool{}
+	var all []string
+	for _, ref := range refs {
+		ns, name := registry.SplitRef(ref)
+		if ns == "" {
+			ns = registry.DefaultNamespace
+		}
+		owners := of.ownersOf(ns + "/" + name)
+		if !*csv {
+			fmt.Printf("%s/%s\t%s\n", ns, name, strings.Join(owners, " "))
+		}
+		for _, o := range owners {
+			if !seen[o] {
+				seen[o] = true
+				all = append(all, o)
+			}
+		}
+	}
+	if *csv {
+		// gh wants team reviewers as org/team, without the CODEOWNERS "@"
+		for i, o := range all {
+			all[i] = strings.TrimPrefix(o, "@")
+		}
+		fmt.Println(strings.Join(all, ","))
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// testOwners is a CODEOWNERS-like file where later rules override
+// earlier ones.
+var testOwners = ownersFile{Rules: []ownerRule{
+	{Pattern: "*/*", Owners: []string{"@getDragon-dev/registry"}},
+	{Pattern: "acme/*", Owners: []string{"@acme/platform", "@acme/docs"}},
+	{Pattern: "acme/billing-*", Owners: []string{"@acme/billing"}},
+	{Pattern: "acme/legacy", Owners: nil},
+}}
+
+func TestOwnersOf(t *testing.T) {
+	cases := []struct {
+		name string
+		want []string
+	}{
+		{"getdragon/api", []string{"@getDragon-dev/registry"}},
+		{"acme/api", []string{"@acme/platform", "@acme/docs"}},
+		{"acme/billing-api", []string{"@acme/billing"}},
+		// the last match wins even when it lists nobody
+		{"acme/legacy", nil},
+		// * doesn't cross the namespace separator
+		{"acme", nil},
+		{"acme/api/extra", nil},
+	}
+	for _, c := range cases {
+		if got := testOwners.ownersOf(c.name); !slices.Equal(got, c.want) {
+			t.Errorf("ownersOf(%s) = %v, want %v", c.name, got, c.want)
+		}
+	}
+	if got := (ownersFile{}).ownersOf("acme/api"); got != nil {
+		t.Errorf("empty file: owners %v", got)
+	}
+}
+
+func TestCanWrite(t *testing.T) {
+	cases := []struct {
+		name string
+		p    principal
+		ref  string
+		want bool
+	}{
+		{"owning team", principal{Teams: []string{"@acme/platform"}}, "acme/api", true},
+		{"second owning team", principal{Teams: []string{"@acme/docs"}}, "acme/api", true},
+		{"overridden by a later rule", principal{Teams: []string{"@acme/platform"}}, "acme/billing-api", false},
+		{"other team", principal{Teams: []string{"@acme/billing"}}, "acme/api", false},
+		{"no teams", principal{Authenticated: true}, "acme/api", false},
+		{"unowned entry", principal{Teams: []string{"@acme/platform"}}, "acme/legacy", false},
+		{"admin", principal{Admin: true}, "acme/legacy", true},
+		{"team names are exact", principal{Teams: []string{"@Acme/Platform"}}, "acme/api", false},
+	}
+	for _, c := range cases {
+		if got := testOwners.canWrite(c.p, c.ref); got != c.want {
+			t.Errorf("%s: canWrite(%s) = %t, want %t", c.name, c.ref, got, c.want)
+		}
+	}
+}
+
+func TestLoadOwners(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	of, err := loadOwners(filepath.Join(dir, "missing.yaml"))
+	if err != nil || len(of.Rules) != 0 {
+		t.Errorf("missing file: %+v, %v", of, err)
+	}
+	of, err = loadOwners(write("empty.yaml", ""))
+	if err != nil || len(of.Rules) != 0 {
+		t.Errorf("empty file: %+v, %v", of, err)
+	}
+	of, err = loadOwners(write("ok.yaml", "rules:\n  - pattern: acme/*\n    owners: ['@acme/platform']\n"))
+	if err != nil || len(of.Rules) != 1 || of.Rules[0].Owners[0] != "@acme/platform" {
+		t.Errorf("valid file: %+v, %v", of, err)
+	}
+	if _, err := loadOwners(write("bad-pattern.yaml", "rules:\n  - pattern: 'acme/[api'\n    owners: [x]\n")); err == nil {
+		t.Error("accepted a malformed pattern")
+	}
+	if _, err := loadOwners(write("unknown.yaml", "rules:\n  - pattern: acme/*\n    teams: [x]\n")); err == nil {
+		t.Error("accepted an unknown field")
+	}
+}
+
+func TestChangedEntries(t *testing.T) {
+	bp := func(ns, name, version string) registry.Blueprint {
+		return registry.Blueprint{Namespace: ns, Name: name, Version: version}
+	}
+	base := registry.Database{Blueprints: []registry.Blueprint{
+		bp("acme", "api", "1.0.0"),
+		bp("acme", "web", "1.0.0"),
+		bp("getdragon", "cli", "1.0.0"),
+	}}
+	head := registry.Database{Blueprints: []registry.Blueprint{
+		bp("getdragon", "cli", "1.0.0"), // unchanged, reordered
+		bp("acme", "api", "1.1.0"),      // updated
+		bp("acme", "new", "1.0.0"),      // added
+		// acme/web removed
+	}}
+	want := []string{"acme/api", "acme/new", "acme/web"}
+	if got := changedEntries(base, head); !slices.Equal(got, want) {
+		t.Errorf("changedEntries = %v, want %v", got, want)
+	}
+	if got := changedEntries(base, base); len(got) != 0 {
+		t.Errorf("no change reported %v", got)
+	}
+}
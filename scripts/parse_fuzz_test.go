@@ -0,0 +1,73 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+func FuzzParseManifest(f *testing.F) {
+	f.Add([]byte("name: api-service\nversion: 1.0.0\ndescription: API\ntags: [go, api]\n"))
+	f.Add([]byte(`{"name": "cli-tool", "version": "1.0.0", "tags": ["go"]}`))
+	f.Add([]byte("name = \"cli-tool\"\nversion = \"1.0.0\"\ntags = [\"go\"]\n"))
+	f.Add([]byte("a: &a [*a, *a]\n"))
+	f.Add([]byte("a: &a [x, x]\nb: &b [*a, *a]\nc: &c [*b, *b]\nd: [*c, *c]\n"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		for _, name := range []string{"manifest.yaml", "manifest.json", "manifest.toml"} {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				registry.ParseManifest(name, b)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: parse did not finish", name)
+			}
+		}
+	})
+}
+
+func FuzzInspectArchive(f *testing.F) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{
+		"cli-tool/manifest.yaml": "name: cli-tool\n",
+		"cli-tool/main.go":       "package main\n",
+	} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(body))
+	}
+	zw.Close()
+	f.Add(buf.Bytes())
+	f.Add([]byte("PK\x05\x06" + string(make([]byte, 18))))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		info, err := inspectArchive(ctx, bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			return
+		}
+		for _, name := range info.Files {
+			if err := checkArchivePath(name); err != nil {
+				t.Fatalf("unsafe path %q listed", name)
+			}
+		}
+		info.manifest(false)
+		info.manifest(true)
+	})
+}
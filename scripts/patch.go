@@ -0,0 +1,362 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// patchableFields are the fields of an entry PATCH may change: the ones
+// describing it. Anything about the release itself comes from publishing
+// one.
+var patchableFields = []string{"description", "title", "tags", "features", "maintainers", "category", "icon", "deprecation", "visibility"}
+
+// tokensFile lists the API tokens allowed to write through the server.
+// Only digests are kept, so the file is no secret.
+type tokensFile struct {
+	Tokens []apiToken `yaml:"tokens"`
+}
+
+type apiToken struct {
+	// Name identifies the token in logs.
+	Name string `yaml:"name"`
+	// SHA256 is the hex digest of the token.
+	SHA256 string   `yaml:"sha256"`
+	Teams  []string `yaml:"teams"`
+	// Namespaces the token may read private entries of.
+	Namespaces []string `yaml:"namespaces"`
+	Admin      bool     `yaml:"admin"`
+	// Quota replaces serve.usage.quota for the token; an empty one
+	// exempts it.
+	Quota *tokenQuota `yaml:"quota"`
+}
+
+// principal is the client a request bearing t is served as.
+func (t apiToken) principal() principal {
+	return principal{Authenticated: true, Namespaces: t.Namespaces, Teams: t.Teams, Admin: t.Admin}
+}
+
+// writeAccess is what a server needs to accept writes.
+type writeAccess struct {
+	tokens     []apiToken
+	owners     ownersFile
+	vocab      *featureVocab
+	text       textConfig
+	validation validationConfig
+}
+
+// loadTokens reads a tokens file. Usage is counted by name, so names must
+// be unique.
+func loadTokens(p string) (tokensFile, error) {
+	var tf tokensFile
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return tf, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&tf); err != nil && !errors.Is(err, io.EOF) {
+		return tf, fmt.Errorf("%s: %w", p, err)
+	}
+	names := map[string]bool{}
+	for _, t := range tf.Tokens {
+		if d, err := hex.DecodeString(t.SHA256); err != nil || len(d) != sha256.Size || t.Name == "" {
+			return tf, fmt.Errorf("%s: token %q needs a name and the hex sha256 of the token", p, t.Name)
+		}
+		if names[t.Name] {
+			return tf, fmt.Errorf("%s: token name %q is used twice", p, t.Name)
+		}
+		names[t.Name] = true
+		if t.Quota != nil {
+			if err := t.Quota.validate(); err != nil {
+				return tf, fmt.Errorf("%s: token %q: %w", p, t.Name, err)
+			}
+		}
+	}
+	return tf, nil
+}
+
+func loadWriteAccess(cfg config) (*writeAccess, error) {
+	tf, err := loadTokens(cfg.Serve.Tokens)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := loadOwners(cfg.Serve.owners())
+	if err != nil {
+		return nil, fmt.Errorf("owners: %w", err)
+	}
+	vocab, err := loadFeatureVocab(cfg.Features.Vocabulary)
+	if err != nil {
+		return nil, fmt.Errorf("features: %w", err)
+	}
+	return &writeAccess{tokens: tf.Tokens, owners: owners, vocab: vocab, text: cfg.Text, validation: cfg.Validation}, nil
+}
+
+// authenticate finds the token of a bearer request.
+func (wa *writeAccess) authenticate(r *http.Request) (apiToken, bool) {
+	tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || tok == "" {
+		return apiToken{}, false
+	}
+	sum := sha256.Sum256([]byte(tok))
+	for _, t := range wa.tokens {
+		want, _ := hex.DecodeString(t.SHA256)
+		if subtle.ConstantTimeCompare(sum[:], want) == 1 {
+			return t, true
+		}
+	}
+	return apiToken{}, false
+}
+
+// entryETag is the digest of an entry as stored, which a PATCH must
+// match. GET and PATCH return it as a weak ETag, since their bodies
+// aren't the stored bytes.
+func entryETag(bp registry.Blueprint) string {
+	b, err := registry.CanonicalJSON(bp)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// storedETag is the entry ETag of the stored entry called name, or ""
+// if it is gone.
+func (s *server) storedETag(name string) string {
+	for _, bp := range s.st.snapshot().Blueprints {
+		if bp.FullName() == name {
+			return entryETag(bp)
+		}
+	}
+	return ""
+}
+
+var (
+	errPreconditionFailed = errors.New("the entry has changed; fetch it again")
+	errEntryGone          = errors.New("the entry was removed")
+)
+
+// patchRejected is a patch whose result fails validation.
+type patchRejected struct {
+	Findings []finding
+}
+
+func (e *patchRejected) Error() string {
+	var msgs []string
+	for _, f := range e.Findings {
+		msgs = append(msgs, f.String())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// patchBlueprint applies a JSON merge patch (RFC 7386) to an entry. It
+// must carry the entry's ETag in If-Match, so edits made concurrently
+// are not silently lost.
+func (s *server) patchBlueprint(w http.ResponseWriter, r *http.Request) {
+	tok, ok := s.writers.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="dragon-registry"`)
+		writeError(w, r, http.StatusUnauthorized, "a bearer token is required")
+		return
+	}
+	if s.usage != nil && writeQuotaError(w, r, s.usage.check(tok, time.Now(), usagePublish)) {
+		return
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/merge-patch+json" {
+		w.Header().Set("Accept-Patch", "application/merge-patch+json")
+		writeError(w, r, http.StatusUnsupportedMediaType, "send an application/merge-patch+json body")
+		return
+	}
+	var patch map[string]any
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&patch); err != nil || patch == nil {
+		writeError(w, r, http.StatusBadRequest, "the body must be a JSON object")
+		return
+	}
+	for _, k := range slices.Sorted(maps.Keys(patch)) {
+		if !slices.Contains(patchableFields, k) {
+			writeError(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("%s cannot be patched; publish a release instead", k))
+			return
+		}
+	}
+
+	p := tok.principal()
+	bp, err := registry.Find(*s.st.snapshot(), r.PathValue("ref"))
+	if err == nil && !visibleTo(bp, p) && !s.writers.owners.canWrite(p, bp.FullName()) {
+		err = fmt.Errorf("%s: %w", r.PathValue("ref"), registry.ErrNotFound)
+	}
+	if err != nil {
+		writeLookupError(w, r, err)
+		return
+	}
+	name := bp.FullName()
+	if !s.writers.owners.canWrite(p, name) {
+		writeError(w, r, http.StatusForbidden, fmt.Sprintf("%s may not modify %s", tok.Name, name))
+		return
+	}
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		writeError(w, r, http.StatusPreconditionRequired, "If-Match with the entry's ETag is required")
+		return
+	}
+
+	var patched registry.Blueprint
+	err = s.st.update(func(db *registry.Database) error {
+		i := slices.IndexFunc(db.Blueprints, func(bp registry.Blueprint) bool { return bp.FullName() == name })
+		if i < 0 {
+			return errEntryGone
+		}
+		// checked again here: the entry may have changed since the lookup
+		if !etagMatches(ifMatch, entryETag(db.Blueprints[i])) {
+			return errPreconditionFailed
+		}
+		bp, err := s.writers.apply(*db, db.Blueprints[i], patch)
+		if err != nil {
+			return err
+		}
+		db.Blueprints[i] = bp
+		patched = bp
+		return nil
+	})
+	var rejected *patchRejected
+	switch {
+	case errors.Is(err, errPreconditionFailed):
+		writeError(w, r, http.StatusPreconditionFailed, err.Error())
+		return
+	case errors.Is(err, errEntryGone):
+		writeError(w, r, http.StatusNotFound, fmt.Sprintf("%s: %v", name, err))
+		return
+	case errors.As(err, &rejected):
+		writeError(w, r, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "patch %s: %v\n", name, err)
+		writeError(w, r, http.StatusInternalServerError, "save registry")
+		return
+	}
+	if err := s.refresh(); err != nil {
+		fmt.Fprintf(os.Stderr, "patch %s: %v\n", name, err)
+	}
+	fmt.Fprintf(os.Stderr, "patched %s by %s: %s\n", name, tok.Name, strings.Join(slices.Sorted(maps.Keys(patch)), ", "))
+	if s.usage != nil {
+		// counted once accepted; a concurrent edit may overshoot by one
+		s.usage.charge(tok, time.Now(), usagePublish)
+	}
+	w.Header().Set("ETag", "W/"+entryETag(patched))
+	writeJSON(w, r, http.StatusOK, patched)
+}
+
+// apply merges patch into bp and checks the result as the updater would
+// check a release.
+func (wa *writeAccess) apply(db registry.Database, bp registry.Blueprint, patch map[string]any) (registry.Blueprint, error) {
+	b, err := json.Marshal(bp)
+	if err != nil {
+		return bp, err
+	}
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return bp, err
+	}
+	if b, err = json.Marshal(mergePatch(doc, patch)); err != nil {
+		return bp, err
+	}
+	var out registry.Blueprint
+	dec = json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&out); err != nil {
+		return bp, &patchRejected{[]finding{{Rule: "schema", Severity: lintError, Message: err.Error()}}}
+	}
+
+	var problems []finding
+	reject := func(rule, format string, args ...any) {
+		problems = append(problems, finding{Rule: rule, Severity: lintError, Message: fmt.Sprintf(format, args...)})
+	}
+	if _, ok := patch["tags"]; ok {
+		// generated tags stay the updater's
+		var tags []string
+		for _, t := range out.Tags {
+			if strings.HasPrefix(t, autoTagPrefix) {
+				reject("invalid-tag", "tag %q: the %s prefix is reserved for generated tags", t, autoTagPrefix)
+			} else if !slices.Contains(tags, t) {
+				tags = append(tags, t)
+			}
+		}
+		for _, t := range bp.Tags {
+			if strings.HasPrefix(t, autoTagPrefix) {
+				tags = append(tags, t)
+			}
+		}
+		out.Tags = tags
+	}
+	if _, ok := patch["features"]; ok {
+		features, findings := checkFeatures(out.Features, wa.vocab)
+		for _, f := range findings {
+			reject("feature", "%s", f)
+		}
+		out.Features = features
+	}
+	if out.Deprecation = qualifyDeprecation(out.Deprecation); out.Deprecation != nil && out.Deprecation.Replacement != "" {
+		if _, err := registry.Find(db, out.Deprecation.Replacement); err != nil {
+			reject("invalid-deprecation", "replacement: %v", err)
+		}
+	}
+	_, described := patch["description"]
+	sanitizeEntry(&out, wa.text)
+	problems = append(problems, checkEntry(out, wa.validation)...)
+	if hasErrors(problems) {
+		return bp, &patchRejected{problems}
+	}
+	if out.Quality != nil {
+		out.Quality = rerateQuality(out, described)
+	}
+	return out, nil
+}
+
+// mergePatch applies an RFC 7386 merge patch to target: objects merge
+// recursively, null removes a member and anything else replaces it.
+func mergePatch(target any, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
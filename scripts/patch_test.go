@@ -0,0 +1,145 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// patchTestServer serves one entry, getdragon/a-0 titled "Zero", that
+// the web team owns. Token "writer" is on that team, "reader" isn't.
+func patchTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	db := testEntries(1)
+	db.Blueprints[0].Title = "Zero"
+	var cfg config
+	cfg.Serve.Tokens = writeTestTokens(t,
+		apiToken{Name: "writer", Teams: []string{"web"}},
+		apiToken{Name: "reader", Teams: []string{"docs"}},
+	)
+	cfg.Serve.Owners = filepath.Join(t.TempDir(), "owners.yaml")
+	rules := "rules:\n  - pattern: getdragon/*\n    owners: [web]\n"
+	if err := os.WriteFile(cfg.Serve.Owners, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return serveTestRegistry(t, cfg, db)
+}
+
+// getEntry fetches getdragon/a-0 and its ETag.
+func getEntry(t *testing.T, h http.Handler) (registry.Blueprint, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/blueprints/getdragon/a-0", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: %d %s", w.Code, w.Body)
+	}
+	var bp registry.Blueprint
+	if err := json.NewDecoder(w.Body).Decode(&bp); err != nil {
+		t.Fatal(err)
+	}
+	return bp, w.Header().Get("ETag")
+}
+
+// patchEntry sends a merge patch of getdragon/a-0 with token (none if
+// empty) and If-Match.
+func patchEntry(h http.Handler, token, ifMatch, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPatch, "/v1/blueprints/getdragon/a-0", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/merge-patch+json")
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	if ifMatch != "" {
+		r.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestPatchNullDeletes(t *testing.T) {
+	h := patchTestServer(t)
+	_, etag := getEntry(t, h)
+	w := patchEntry(h, "secret-writer", etag, `{"title": null, "tags": ["web"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("patch: %d %s", w.Code, w.Body)
+	}
+	bp, newTag := getEntry(t, h)
+	if bp.Title != "" {
+		t.Errorf("title = %q after patching it to null", bp.Title)
+	}
+	if len(bp.Tags) != 1 || bp.Tags[0] != "web" {
+		t.Errorf("tags = %v, want [web]", bp.Tags)
+	}
+	if bp.Description != "test entry" {
+		t.Errorf("description = %q: fields not in the patch must be kept", bp.Description)
+	}
+	if newTag == etag || w.Header().Get("ETag") != newTag {
+		t.Errorf("ETag %s after patch, response said %s, before %s", newTag, w.Header().Get("ETag"), etag)
+	}
+}
+
+func TestPatchStaleETag(t *testing.T) {
+	h := patchTestServer(t)
+	_, etag := getEntry(t, h)
+	if w := patchEntry(h, "secret-writer", etag, `{"description": "first edit"}`); w.Code != http.StatusOK {
+		t.Fatalf("first patch: %d %s", w.Code, w.Body)
+	}
+	// a second client still holding the old ETag
+	if w := patchEntry(h, "secret-writer", etag, `{"description": "second edit"}`); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale patch: %d %s, want 412", w.Code, w.Body)
+	}
+	if bp, _ := getEntry(t, h); bp.Description != "first edit" {
+		t.Errorf("description = %q, the stale patch was applied", bp.Description)
+	}
+}
+
+func TestPatchRefused(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		token   string
+		ifMatch bool
+		body    string
+		code    int
+	}{
+		{"no token", "", true, `{"description": "x"}`, http.StatusUnauthorized},
+		{"unknown token", "secret-nobody", true, `{"description": "x"}`, http.StatusUnauthorized},
+		{"not an owner", "secret-reader", true, `{"description": "x"}`, http.StatusForbidden},
+		{"no If-Match", "secret-writer", false, `{"description": "x"}`, http.StatusPreconditionRequired},
+		{"release field", "secret-writer", true, `{"sha256": null}`, http.StatusUnprocessableEntity},
+		{"not an object", "secret-writer", true, `["description"]`, http.StatusBadRequest},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h := patchTestServer(t)
+			before, etag := getEntry(t, h)
+			if !c.ifMatch {
+				etag = ""
+			}
+			w := patchEntry(h, c.token, etag, c.body)
+			if w.Code != c.code {
+				t.Fatalf("patch: %d %s, want %d", w.Code, w.Body, c.code)
+			}
+			if c.code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+			if after, _ := getEntry(t, h); after.Description != before.Description {
+				t.Errorf("refused patch changed the entry: %q", after.Description)
+			}
+		})
+	}
+}
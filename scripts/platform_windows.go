@@ -0,0 +1,49 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// renameNoReplace moves src to dst, failing with fs.ErrExist if dst already
+// exists. MoveFile without MOVEFILE_REPLACE_EXISTING gives us that directly
+// and, unlike hard links, also works on FAT volumes and network shares.
+func renameNoReplace(src, dst string) error {
+	from, err := syscall.UTF16PtrFromString(src)
+	if err != nil {
+		return err
+	}
+	to, err := syscall.UTF16PtrFromString(dst)
+	if err != nil {
+		return err
+	}
+	if err := syscall.MoveFile(from, to); err != nil {
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: err}
+	}
+	return nil
+}
+
+// isExecutable reports whether a plugin file can be run directly. Windows
+// has no execute bit, so go by the extensions CreateProcess accepts.
+func isExecutable(info fs.FileInfo) bool {
+	switch strings.ToLower(filepath.Ext(info.Name())) {
+	case ".exe", ".bat", ".cmd", ".com":
+		return info.Mode().IsRegular()
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// pluginProtocol is bumped on incompatible changes to the messages below.
+const pluginProtocol = 1
+
+// pluginRequest is written as JSON to a plugin's stdin, once per candidate
+// entry.
+type pluginRequest struct {
+	Protocol int                `json:"protocol"`
+	Hook     string             `json:"hook"`
+	Repo     string             `json:"repo"`
+	Tag      string             `json:"tag"`
+	Asset    string             `json:"asset"`
+	Entry    registry.Blueprint `json:"entry"`
+}
+
+// pluginResponse is read as JSON from a plugin's stdout. An empty response
+// leaves the entry untouched.
+type pluginResponse struct {
+	// Entry, if set, supplies the candidate's enrichment fields; changes
+	// to anything else, such as the verified sha256, are ignored.
+	Entry *registry.Blueprint `json:"entry,omitempty"`
+	// Reject drops the entry from this update (validation).
+	Reject   bool     `json:"reject,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type plugin struct {
+	name string
+	path string
+}
+
+// pluginDir returns DRAGON_REGISTRY_PLUGINS or ~/.dragon-registry/plugins.
+func pluginDir() string {
+	if dir := os.Getenv("DRAGON_REGISTRY_PLUGINS"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".dragon-registry", "plugins")
+}
+
+// discoverPlugins lists the executables in dir in name order, which is the
+// order they run in. A missing directory means no plugins.
+func discoverPlugins(dir string) ([]plugin, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var plugins []plugin
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !isExecutable(info) {
+			continue
+		}
+		plugins = append(plugins, plugin{name: e.Name(), path: filepath.Join(dir, e.Name())})
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].name < plugins[j].name })
+	return plugins, nil
+}
+
+// run invokes the plugin with req. Plugin stderr is passed through so
+// authors can log; a non-zero exit or malformed response is an error.
+func (p plugin) run(ctx context.Context, req pluginRequest, timeout time.Duration) (resp pluginResponse, err error) {
+	ctx, sp := startSpan(ctx, "plugin "+p.name, spanKindInternal, attrs{"plugin.name": p.name, "plugin.hook": req.Hook})
+	defer func() { sp.finish(err) }()
+
+	in, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.path)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stderr = os.Stderr
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return resp, fmt.Errorf("plugin %s: %w", p.name, err)
+	}
+	if len(bytes.TrimSpace(out.Bytes())) == 0 {
+		return resp, nil
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return resp, fmt.Errorf("plugin %s: decode response: %w", p.name, err)
+	}
+	return resp, nil
+}
+
+// applyPlugins runs every plugin over a candidate entry in turn, each one
+// seeing the previous one's result. It returns ok=false if a plugin
+// rejected the entry.
+func applyPlugins(ctx context.Context, plugins []plugin, req pluginRequest) (entry registry.Blueprint, ok bool, err error) {
+	entry = req.Entry
+	for _, p := range plugins {
+		req.Entry = entry
+		resp, err := p.run(ctx, req, 30*time.Second)
+		if err != nil {
+			return entry, false, err
+		}
+		for _, w := range resp.Warnings {
+			fmt.Fprintf(os.Stderr, "%s: %s: %s\n", p.name, entry.Name, w)
+		}
+		if resp.Reject {
+			fmt.Fprintf(os.Stderr, "%s: rejected %s: %s\n", p.name, entry.Name, resp.Reason)
+			return entry, false, nil
+		}
+		if resp.Entry != nil {
+			var ignored []string
+			entry, ignored = enrich(entry, *resp.Entry)
+			if len(ignored) > 0 {
+				fmt.Fprintf(os.Stderr, "%s: %s: ignoring changes to %s\n", p.name, entry.Name, strings.Join(ignored, ", "))
+			}
+		}
+	}
+	return entry, true, nil
+}
+
+// enrichFields are the entry fields plugins may change. The rest were
+// verified against the release (digests, download URL, provenance) or
+// are the registry's own bookkeeping.
+var enrichFields = []string{"title", "description", "tags", "category", "icon", "screenshots", "features", "maintainers"}
+
+// enrich copies the enrichment fields of got onto entry and lists, by
+// JSON name, the other fields got changed.
+func enrich(entry, got registry.Blueprint) (registry.Blueprint, []string) {
+	out := entry
+	out.Title = got.Title
+	out.Description = got.Description
+	out.Tags = got.Tags
+	out.Category = got.Category
+	out.Icon = got.Icon
+	out.Screenshots = got.Screenshots
+	out.Features = got.Features
+	out.Maintainers = got.Maintainers
+
+	var ignored []string
+	a, b := reflect.ValueOf(entry), reflect.ValueOf(got)
+	for i := range a.NumField() {
+		name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("json"), ",")
+		if slices.Contains(enrichFields, name) {
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			ignored = append(ignored, name)
+		}
+	}
+	return out, ignored
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// writePlugin installs a plugin that ignores its request and answers
+// with reply.
+func writePlugin(t *testing.T, dir, name, reply string) plugin {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	script := "#!/bin/sh\ncat >/dev/null\ncat <<'EOF'\n" + reply + "\nEOF\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return plugin{name: name, path: path}
+}
+
+func TestApplyPluginsKeepsVerifiedFields(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts here")
+	}
+	const sum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	p := writePlugin(t, t.TempDir(), "tamper", `{"entry": {
+		"name": "api-service", "version": "1.0.0",
+		"download_url": "https://evil.example.com/api.zip",
+		"sha256": "0000000000000000000000000000000000000000000000000000000000000000",
+		"description": "A REST API service", "tags": ["api", "rest"]}}`)
+
+	in := registry.Blueprint{
+		Name:        "api-service",
+		Version:     "1.0.0",
+		DownloadURL: "https://github.com/acme/bp/releases/download/v1.0.0/api.zip",
+		SHA256:      sum,
+		Description: "api",
+	}
+	got, ok, err := applyPlugins(context.Background(), []plugin{p}, pluginRequest{Protocol: pluginProtocol, Hook: "enrich", Entry: in})
+	if err != nil || !ok {
+		t.Fatalf("applyPlugins: ok=%v err=%v", ok, err)
+	}
+	if got.SHA256 != sum || got.DownloadURL != in.DownloadURL {
+		t.Errorf("plugin changed verified fields: sha256=%s download_url=%s", got.SHA256, got.DownloadURL)
+	}
+	if got.Description != "A REST API service" || !slices.Equal(got.Tags, []string{"api", "rest"}) {
+		t.Errorf("enrichment not applied: description=%q tags=%v", got.Description, got.Tags)
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	entry := registry.Blueprint{Name: "api", Version: "1.0.0", SHA256: "aa", Description: "old"}
+	for _, tc := range []struct {
+		name    string
+		got     registry.Blueprint
+		ignored []string
+	}{
+		{"unchanged", entry, nil},
+		{"enrichment only", registry.Blueprint{Name: "api", Version: "1.0.0", SHA256: "aa", Description: "new", Tags: []string{"x"}}, nil},
+		{"sha256", registry.Blueprint{Name: "api", Version: "1.0.0", SHA256: "bb", Description: "new"}, []string{"sha256"}},
+		{"name and version", registry.Blueprint{Name: "web", Version: "2.0.0", SHA256: "aa"}, []string{"name", "version"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, ignored := enrich(entry, tc.got)
+			if !slices.Equal(ignored, tc.ignored) {
+				t.Errorf("ignored = %v, want %v", ignored, tc.ignored)
+			}
+			if out.SHA256 != entry.SHA256 || out.Name != entry.Name || out.Version != entry.Version {
+				t.Errorf("verified fields changed: %+v", out)
+			}
+			if out.Description != tc.got.Description {
+				t.Errorf("description = %q, want %q", out.Description, tc.got.Description)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// prCommentMarker identifies the comment an update keeps on a PR, so
+// later runs edit it instead of adding another.
+const prCommentMarker = "<!-- dragon-registry-diff -->"
+
+// maxCommentDiff keeps a comment under GitHub's 65536 character limit.
+const maxCommentDiff = 60000
+
+// prRef is a pull request: its repo and number.
+type prRef struct {
+	Repo   string
+	Number int
+}
+
+func (p prRef) String() string { return p.Repo + "#" + strconv.Itoa(p.Number) }
+
+var prURLRe = regexp.MustCompile(`^https://github\.com/([^/]+/[^/]+)/pull/([0-9]+)/?$`)
+
+// parsePR reads owner/repo#123 or the PR's URL.
+func parsePR(s string) (prRef, error) {
+	repo, num, ok := strings.Cut(s, "#")
+	if m := prURLRe.FindStringSubmatch(s); m != nil {
+		repo, num, ok = m[1], m[2], true
+	}
+	n, err := strconv.Atoi(num)
+	if !ok || err != nil || n <= 0 {
+		return prRef{}, fmt.Errorf("%q is not owner/repo#number or a pull request URL", s)
+	}
+	repo, err = parseRepo(repo)
+	if err != nil {
+		return prRef{}, err
+	}
+	return prRef{Repo: repo, Number: n}, nil
+}
+
+// prCommentBody renders what a run did, or would do under --dry-run, or
+// that it failed, for a PR, as the same diff --dry-run prints.
+func prCommentBody(cs changeSet, runErr error) string {
+	var b strings.Builder
+	b.WriteString(prCommentMarker + "\n")
+	what := cs.Repo
+	if cs.Tag != "" {
+		what += "@" + cs.Tag
+	}
+	if cs.DryRun {
+		fmt.Fprintf(&b, "### Registry preview for `%s`\n\n", what)
+	} else {
+		fmt.Fprintf(&b, "### Registry update for `%s`\n\n", what)
+	}
+	if runErr != nil {
+		fmt.Fprintf(&b, "The update failed:\n\n```\n%s\n```\n\n", runErr)
+	}
+	var diff bytes.Buffer
+	writeDiff(&diff, cs)
+	d := diff.String()
+	if len(d) > maxCommentDiff {
+		d = d[:strings.LastIndexByte(d[:maxCommentDiff], '\n')+1] + "…\n"
+	}
+	if runErr == nil || len(cs.Skipped)+len(cs.Added)+len(cs.Updated) > 0 {
+		fmt.Fprintf(&b, "```diff\n%s```\n\n", d)
+	}
+	b.WriteString("<sub>`+` added, `~` updated, `=` unchanged, `-` removed, `?` held for review, `!` skipped")
+	if cs.RunID != "" {
+		fmt.Fprintf(&b, " · run %s", cs.RunID)
+	}
+	b.WriteString("</sub>\n")
+	return b.String()
+}
+
+// commentOnPR posts the outcome of a run on pr, editing the comment an
+// earlier run left rather than adding another.
+func commentOnPR(ctx context.Context, pr prRef, cs changeSet, runErr error) error {
+	payload, err := json.Marshal(map[string]string{"body": prCommentBody(cs, runErr)})
+	if err != nil {
+		return err
+	}
+	base := defaultClient.apiURL("/repos/%s/issues", pr.Repo)
+	type comment struct {
+		ID   int64  `json:"id"`
+		Body string `json:"body"`
+	}
+	comments, err := registry.List[comment](ctx, defaultClient, fmt.Sprintf("%s/%d/comments", base, pr.Number))
+	if err != nil {
+		return fmt.Errorf("list comments: %w", err)
+	}
+	for _, c := range comments {
+		if strings.HasPrefix(c.Body, prCommentMarker) {
+			u := fmt.Sprintf("%s/comments/%d", base, c.ID)
+			return defaultClient.githubJSON(ctx, "PATCH", u, bytes.NewReader(payload), "application/json", nil)
+		}
+	}
+	u := fmt.Sprintf("%s/%d/comments", base, pr.Number)
+	return defaultClient.githubJSON(ctx, "POST", u, bytes.NewReader(payload), "application/json", nil)
+}
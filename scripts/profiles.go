@@ -0,0 +1,149 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// profilesFile declares named pin sets, such as lts-2025, that
+// standardize which blueprint versions teams use.
+type profilesFile struct {
+	Profiles map[string]profile `yaml:"profiles"`
+}
+
+type profile struct {
+	Description string `yaml:"description"`
+	// Pins maps "namespace/name" to an exact version.
+	Pins map[string]string `yaml:"pins"`
+}
+
+// loadProfiles reads a profiles file. A missing file declares none.
+func loadProfiles(p string) (profilesFile, error) {
+	var pf profilesFile
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return pf, nil
+		}
+		return pf, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&pf); err != nil && !errors.Is(err, io.EOF) {
+		return pf, fmt.Errorf("%s: %w", p, err)
+	}
+	for name := range pf.Profiles {
+		if err := registry.ValidateIdent("profile", name); err != nil {
+			return pf, fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return pf, nil
+}
+
+// pinnedEntry is a profile pin resolved against the registry.
+type pinnedEntry struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	DownloadURL string `json:"download_url"`
+	SHA256      string `json:"sha256,omitempty"`
+	// Latest is set when the registry has a newer version than the pin.
+	Latest string `json:"latest,omitempty"`
+}
+
+// resolveProfile resolves every pin of a profile, failing if any entry or
+// pinned version is unknown.
+func resolveProfile(db registry.Database, pf profilesFile, name string) ([]pinnedEntry, error) {
+	prof, ok := pf.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %s: %w", name, registry.ErrNotFound)
+	}
+	var out []pinnedEntry
+	var errs []error
+	for ref, version := range prof.Pins {
+		bp, err := registry.Find(db, ref)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rel, ok := bp.Release(version)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: version %s is not in the registry", bp.FullName(), version))
+			continue
+		}
+		pe := pinnedEntry{Name: bp.FullName(), Version: rel.Version, DownloadURL: rel.DownloadURL, SHA256: rel.SHA256}
+		if bp.Version != rel.Version {
+			pe.Latest = bp.Version
+		}
+		out = append(out, pe)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// runProfile lists the profiles, or resolves one.
+func runProfile(args []string) error {
+	flags := flag.NewFlagSet("profile", flag.ExitOnError)
+	file := flags.String("profiles", "profiles.yaml", "profiles file")
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+
+	pf, err := loadProfiles(*file)
+	if err != nil {
+		return fmt.Errorf("load profiles: %w", err)
+	}
+	if flags.NArg() == 0 {
+		names := make([]string, 0, len(pf.Profiles))
+		for name := range pf.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s\t%d pins\t%s\n", name, len(pf.Profiles[name].Pins), pf.Profiles[name].Description)
+		}
+		return nil
+	}
+
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	pins, err := resolveProfile(db, pf, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(pins)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPINNED\tLATEST\tURL")
+	for _, p := range pins {
+		latest := p.Latest
+		if latest == "" {
+			latest = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, p.Version, latest, p.DownloadURL)
+	}
+	return w.Flush()
+}
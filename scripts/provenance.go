@@ -0,0 +1,248 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// provenanceConfig says how SLSA provenance published with a release is
+// verified. Attestations are in-toto statements in DSSE envelopes, one per
+// line of a <name>.intoto.jsonl asset, as slsa-github-generator writes
+// them.
+type provenanceConfig struct {
+	// Builder is the builder ID the attestation must name, e.g.
+	// https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml.
+	// Without one, any builder slsa-verifier trusts is accepted.
+	Builder string `yaml:"builder"`
+	// Verifier is the slsa-verifier binary, found on $PATH by default.
+	Verifier string `yaml:"verifier"`
+}
+
+const (
+	attestationSuffix   = ".intoto.jsonl"
+	maxAttestationBytes = 1 << 20
+	dssePayloadType     = "application/vnd.in-toto+json"
+)
+
+var errNoProvenance = errors.New("no provenance")
+
+func (p provenanceConfig) validate() error {
+	if strings.Contains(p.Builder, "@") {
+		return errors.New("provenance.builder: give the builder ID without a ref")
+	}
+	return nil
+}
+
+// inTotoStatement is the part of an attestation we read. The predicate
+// layout depends on the SLSA version.
+type inTotoStatement struct {
+	PredicateType string          `json:"predicateType"`
+	Subject       []inTotoSubject `json:"subject"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// covers reports whether the statement attests an artifact with digest.
+func (s inTotoStatement) covers(digest string) bool {
+	return slices.ContainsFunc(s.Subject, func(sub inTotoSubject) bool {
+		return strings.EqualFold(sub.Digest["sha256"], digest)
+	})
+}
+
+// provenance extracts builder, source and commit from the predicate.
+func (s inTotoStatement) provenance() (registry.Provenance, error) {
+	var p registry.Provenance
+	switch s.PredicateType {
+	case "https://slsa.dev/provenance/v0.2":
+		var pred struct {
+			Builder struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+			Invocation struct {
+				ConfigSource struct {
+					URI    string            `json:"uri"`
+					Digest map[string]string `json:"digest"`
+				} `json:"configSource"`
+			} `json:"invocation"`
+		}
+		if err := json.Unmarshal(s.Predicate, &pred); err != nil {
+			return p, err
+		}
+		p.Builder = pred.Builder.ID
+		p.SourceRepo = sourceRepo(pred.Invocation.ConfigSource.URI)
+		p.Commit = pred.Invocation.ConfigSource.Digest["sha1"]
+	case "https://slsa.dev/provenance/v1":
+		var pred struct {
+			BuildDefinition struct {
+				ResolvedDependencies []struct {
+					URI    string            `json:"uri"`
+					Digest map[string]string `json:"digest"`
+				} `json:"resolvedDependencies"`
+			} `json:"buildDefinition"`
+			RunDetails struct {
+				Builder struct {
+					ID string `json:"id"`
+				} `json:"builder"`
+			} `json:"runDetails"`
+		}
+		if err := json.Unmarshal(s.Predicate, &pred); err != nil {
+			return p, err
+		}
+		p.Builder = pred.RunDetails.Builder.ID
+		// the source checkout is the first resolved dependency
+		if deps := pred.BuildDefinition.ResolvedDependencies; len(deps) > 0 {
+			p.SourceRepo = sourceRepo(deps[0].URI)
+			p.Commit = orDefault(deps[0].Digest["gitCommit"], deps[0].Digest["sha1"])
+		}
+	default:
+		return p, fmt.Errorf("unsupported predicate type %q", s.PredicateType)
+	}
+	if p.Builder == "" || p.SourceRepo == "" || p.Commit == "" {
+		return p, errors.New("attestation lacks builder, source or commit")
+	}
+	return p, nil
+}
+
+// sourceRepo turns "git+https://github.com/owner/repo@refs/tags/v1" into
+// the "github.com/owner/repo" form entries use.
+func sourceRepo(uri string) string {
+	uri = strings.TrimPrefix(uri, "git+")
+	uri = strings.TrimPrefix(uri, "https://")
+	uri, _, _ = strings.Cut(uri, "@")
+	return strings.TrimSuffix(uri, ".git")
+}
+
+// findAttestation looks through the release's attestation assets, the
+// asset's own first, for a statement about the archive with digest.
+func findAttestation(ctx context.Context, assets []registry.GitHubAsset, a registry.GitHubAsset, digest string) (*registry.GitHubAsset, []byte, inTotoStatement, error) {
+	var candidates []*registry.GitHubAsset
+	for i := range assets {
+		if !strings.HasSuffix(assets[i].Name, attestationSuffix) {
+			continue
+		}
+		if assets[i].Name == a.Name+attestationSuffix {
+			candidates = slices.Insert(candidates, 0, &assets[i])
+		} else {
+			candidates = append(candidates, &assets[i])
+		}
+	}
+	for _, c := range candidates {
+		b, err := defaultClient.getLimit(ctx, c.DownloadURL(), maxAttestationBytes)
+		if err != nil {
+			return nil, nil, inTotoStatement{}, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		sc := bufio.NewScanner(bytes.NewReader(b))
+		sc.Buffer(nil, maxAttestationBytes)
+		for sc.Scan() {
+			var env struct {
+				PayloadType string `json:"payloadType"`
+				Payload     string `json:"payload"`
+			}
+			if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+				continue
+			}
+			if err := json.Unmarshal(sc.Bytes(), &env); err != nil {
+				return nil, nil, inTotoStatement{}, fmt.Errorf("%s: %w", c.Name, err)
+			}
+			if env.PayloadType != dssePayloadType {
+				continue
+			}
+			payload, err := base64.StdEncoding.DecodeString(env.Payload)
+			if err != nil {
+				return nil, nil, inTotoStatement{}, fmt.Errorf("%s: payload: %w", c.Name, err)
+			}
+			var st inTotoStatement
+			if err := json.Unmarshal(payload, &st); err != nil {
+				return nil, nil, inTotoStatement{}, fmt.Errorf("%s: statement: %w", c.Name, err)
+			}
+			if st.covers(digest) {
+				return c, b, st, nil
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, nil, inTotoStatement{}, fmt.Errorf("%s: %w", c.Name, err)
+		}
+	}
+	return nil, nil, inTotoStatement{}, errNoProvenance
+}
+
+// verifyProvenance checks the SLSA provenance the release publishes for
+// the asset with the given digest, built from repo at tag, and returns
+// what it attests. It returns errNoProvenance when the release has no
+// attestation for the asset.
+func verifyProvenance(ctx context.Context, pc provenanceConfig, repo, tag string, assets []registry.GitHubAsset, a registry.GitHubAsset, digest string) (*registry.Provenance, error) {
+	att, body, st, err := findAttestation(ctx, assets, a, digest)
+	if err != nil {
+		return nil, err
+	}
+	prov, err := st.provenance()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", att.Name, err)
+	}
+	if prov.SourceRepo != repoID(repo) {
+		return nil, fmt.Errorf("%s: built from %s, not %s", att.Name, prov.SourceRepo, repoID(repo))
+	}
+	if pc.Builder != "" {
+		if id, _, _ := strings.Cut(prov.Builder, "@"); id != pc.Builder {
+			return nil, fmt.Errorf("%s: built by %s, not %s", att.Name, prov.Builder, pc.Builder)
+		}
+	}
+	verifier := orDefault(pc.Verifier, "slsa-verifier")
+	if _, err := exec.LookPath(verifier); err != nil {
+		return nil, fmt.Errorf("slsa-verifier is needed to verify provenance: %w", err)
+	}
+
+	f, err := downloadVerified(ctx, a, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	dir, err := os.MkdirTemp("", "dragon-slsa-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	attPath := filepath.Join(dir, att.Name)
+	if err := os.WriteFile(attPath, body, 0o600); err != nil {
+		return nil, err
+	}
+	args := []string{"verify-artifact", "--provenance-path", attPath,
+		"--source-uri", repoID(repo), "--source-tag", tag}
+	if pc.Builder != "" {
+		args = append(args, "--builder-id", pc.Builder)
+	}
+	args = append(args, f.Name())
+	if _, err := runTool(ctx, "slsa-verifier", verifier, args...); err != nil {
+		return nil, err
+	}
+	return &prov, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// qualityCheck is one thing a complete entry has.
+type qualityCheck struct {
+	Name string
+	ok   func(qualityInput) bool
+}
+
+// qualityInput is what an entry is rated on: the entry, plus what the
+// updater saw while indexing it.
+type qualityInput struct {
+	Entry registry.Blueprint
+	// Described is set when the description came from the author rather
+	// than the fallback template.
+	Described bool
+	Readme    bool
+	Params    []registry.Param
+}
+
+var qualityChecks = []qualityCheck{
+	{"description", func(in qualityInput) bool { return in.Described && in.Entry.Description != "" }},
+	{"icon", func(in qualityInput) bool { return in.Entry.Icon != "" }},
+	{"license", func(in qualityInput) bool { return in.Entry.License != "" }},
+	{"checksum", func(in qualityInput) bool { return in.Entry.SHA256 != "" }},
+	{"signature", func(in qualityInput) bool { return in.Entry.Signed }},
+	{"readme", func(in qualityInput) bool { return in.Readme }},
+	// a blueprint without parameters has nothing to document
+	{"parameters", func(in qualityInput) bool {
+		return !slices.ContainsFunc(in.Params, func(p registry.Param) bool { return strings.TrimSpace(p.Description) == "" })
+	}},
+}
+
+// rateQuality scores an entry by the share of checks it passes.
+func rateQuality(in qualityInput) *registry.Quality {
+	q := &registry.Quality{}
+	for _, c := range qualityChecks {
+		if !c.ok(in) {
+			q.Missing = append(q.Missing, c.Name)
+		}
+	}
+	passed := len(qualityChecks) - len(q.Missing)
+	q.Score = passed * 100 / len(qualityChecks)
+	return q
+}
+
+// rerateQuality rates an entry edited after it was indexed. What only the
+// updater saw, the README and parameters, carries over from its last
+// rating; a description counts as the author's if it was before or has
+// just been written.
+func rerateQuality(bp registry.Blueprint, described bool) *registry.Quality {
+	was := func(check string) bool { return bp.Quality != nil && !slices.Contains(bp.Quality.Missing, check) }
+	in := qualityInput{Entry: bp, Described: described || was("description"), Readme: was("readme")}
+	if !was("parameters") {
+		in.Params = []registry.Param{{}}
+	}
+	return rateQuality(in)
+}
+
+// qualityScore is an entry's score; entries indexed before scoring
+// count as 0.
+func qualityScore(bp registry.Blueprint) int {
+	if bp.Quality == nil {
+		return 0
+	}
+	return bp.Quality.Score
+}
+
+func formatQuality(bp registry.Blueprint) string {
+	if bp.Quality == nil {
+		return "-"
+	}
+	return fmt.Sprint(bp.Quality.Score)
+}
+
+// sortByQuality orders entries best-rated first, then by name.
+func sortByQuality(bps []registry.Blueprint) {
+	slices.SortStableFunc(bps, func(a, b registry.Blueprint) int {
+		if qa, qb := qualityScore(a), qualityScore(b); qa != qb {
+			return qb - qa
+		}
+		return strings.Compare(a.FullName(), b.FullName())
+	})
+}
@@ -0,0 +1,615 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// queueConfig configures the update queue.
+type queueConfig struct {
+	// Dir is where the built-in queue keeps its jobs. Defaults to
+	// .dragon-queue.
+	Dir string `yaml:"dir"`
+	// Command, if set, is an external queue used instead of the built-in
+	// one: a program and its arguments, run once per queue operation (see
+	// execQueue). It is how NATS, SQS and the like are plugged in.
+	Command []string `yaml:"command"`
+	// HookSecretEnv names the environment variable holding the secret
+	// GitHub signs release webhooks with. With it, serve accepts them at
+	// POST /v1/hooks/github and queues the published releases.
+	HookSecretEnv string `yaml:"hook_secret_env"`
+}
+
+func (c queueConfig) validate() error {
+	if len(c.Command) > 0 && c.Command[0] == "" {
+		return errors.New("queue.command: empty program")
+	}
+	return nil
+}
+
+func (c queueConfig) dir() string { return orDefault(c.Dir, ".dragon-queue") }
+
+// open opens the queue configured, with dir, if set, over queue.dir.
+func (c queueConfig) open(dir string, lease time.Duration) (updateQueue, error) {
+	if len(c.Command) > 0 {
+		return &execQueue{command: c.Command, lease: lease}, nil
+	}
+	return openQueue(orDefault(dir, c.dir()), lease)
+}
+
+// updateJob asks the worker to index one release.
+type updateJob struct {
+	Repo       string    `json:"repo"`
+	Tag        string    `json:"tag"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// key identifies a job for deduplication: the same release queued twice
+// while still pending is only processed once.
+func (j updateJob) key() string {
+	sum := sha256.Sum256([]byte(j.Repo + "@" + j.Tag))
+	return hex.EncodeToString(sum[:8])
+}
+
+// errClaimLost is returned for a claim that is no longer the worker's:
+// its lease ran out and the job was handed out again.
+var errClaimLost = errors.New("claim lost: the job was requeued")
+
+// jobClaim is a job a worker holds.
+type jobClaim struct {
+	Job updateJob
+	// id names this claim to the queue, and no other claim of the same
+	// job.
+	id string
+}
+
+// updateQueue holds update jobs until a worker has processed them.
+// Delivery is at least once: a claim that is neither acked nor kept alive
+// with touch is handed out again once its lease runs out.
+type updateQueue interface {
+	// push adds j unless the same release is already pending, reporting
+	// whether it was added.
+	push(ctx context.Context, j updateJob) (bool, error)
+	// claim takes the oldest pending job, or returns nil when there is
+	// none.
+	claim(ctx context.Context) (*jobClaim, error)
+	// touch renews c's lease.
+	touch(ctx context.Context, c *jobClaim) error
+	// ack removes a processed job.
+	ack(ctx context.Context, c *jobClaim) error
+	// nack returns a failed job to the queue, or parks it once it has
+	// used up maxAttempts.
+	nack(ctx context.Context, c *jobClaim, cause error, maxAttempts int) error
+	// recover requeues the jobs whose lease ran out, reporting how many.
+	recover(ctx context.Context) (int, error)
+}
+
+// jobQueue is the built-in queue, kept as one JSON file per job. A claim
+// moves a job from pending/ to processing/, under a name of its own, and
+// the job is only deleted once processed, so a crashed worker's jobs are
+// picked up again. The claimed file's mtime is the lease: workers touch
+// it while they run the job.
+type jobQueue struct {
+	dir   string
+	lease time.Duration
+}
+
+func openQueue(dir string, lease time.Duration) (*jobQueue, error) {
+	for _, sub := range []string{"pending", "processing", "failed"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &jobQueue{dir: dir, lease: lease}, nil
+}
+
+func (q *jobQueue) path(state, name string) string {
+	return filepath.Join(q.dir, state, name+".json")
+}
+
+// claimKey is the job key of a file in processing/, named
+// <key>.<claim token>.json.
+func claimKey(id string) string {
+	key, _, _ := strings.Cut(id, ".")
+	return key
+}
+
+func (q *jobQueue) push(_ context.Context, j updateJob) (bool, error) {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return false, err
+	}
+	// write to a temp file in the queue directory (same volume, so the move
+	// is atomic) and move it into place without replacing, so readers never
+	// see a partial job and a concurrent duplicate push loses cleanly
+	tmp, err := os.CreateTemp(q.dir, ".job-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	if err := renameNoReplace(tmp.Name(), q.path("pending", j.key())); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// inFlight reports whether a job with key is claimed.
+func (q *jobQueue) inFlight(key string) (bool, error) {
+	entries, err := os.ReadDir(filepath.Join(q.dir, "processing"))
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(entries, func(e fs.DirEntry) bool {
+		return claimKey(e.Name()) == key
+	}), nil
+}
+
+func (q *jobQueue) claim(_ context.Context) (*jobClaim, error) {
+	entries, err := os.ReadDir(filepath.Join(q.dir, "pending"))
+	if err != nil {
+		return nil, err
+	}
+	type cand struct {
+		key string
+		mod time.Time
+	}
+	var cands []cand
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		cands = append(cands, cand{strings.TrimSuffix(e.Name(), ".json"), info.ModTime()})
+	}
+	slices.SortFunc(cands, func(a, b cand) int { return a.mod.Compare(b.mod) })
+
+	for _, c := range cands {
+		// if the same release is already in flight, leave the newer job
+		// pending until that one is done
+		busy, err := q.inFlight(c.key)
+		if err != nil {
+			return nil, err
+		}
+		if busy {
+			continue
+		}
+		// the move is atomic, so only one worker wins a given job
+		id := c.key + "." + rand.Text()
+		if err := renameNoReplace(q.path("pending", c.key), q.path("processing", id)); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		now := time.Now()
+		_ = os.Chtimes(q.path("processing", id), now, now)
+		b, err := os.ReadFile(q.path("processing", id))
+		if err != nil {
+			return nil, err
+		}
+		var job updateJob
+		if err := json.Unmarshal(b, &job); err != nil {
+			// an unreadable job can never succeed; park it
+			_ = registry.ReplaceFile(q.path("processing", id), q.path("failed", c.key))
+			continue
+		}
+		return &jobClaim{Job: job, id: id}, nil
+	}
+	return nil, nil
+}
+
+// lost maps a claimed file that is gone to errClaimLost.
+func lost(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return errClaimLost
+	}
+	return err
+}
+
+func (q *jobQueue) touch(_ context.Context, c *jobClaim) error {
+	now := time.Now()
+	return lost(os.Chtimes(q.path("processing", c.id), now, now))
+}
+
+// ack removes c's file only: the file of a later claim of the same job
+// has another name.
+func (q *jobQueue) ack(_ context.Context, c *jobClaim) error {
+	return lost(os.Remove(q.path("processing", c.id)))
+}
+
+func (q *jobQueue) nack(_ context.Context, c *jobClaim, cause error, maxAttempts int) error {
+	j := c.Job
+	j.Attempts++
+	j.LastError = cause.Error()
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	// no O_CREATE: a claim that was lost must stay lost
+	f, err := os.OpenFile(q.path("processing", c.id), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return lost(err)
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	key := claimKey(c.id)
+	if j.Attempts >= maxAttempts {
+		return lost(registry.ReplaceFile(q.path("processing", c.id), q.path("failed", key)))
+	}
+	err = renameNoReplace(q.path("processing", c.id), q.path("pending", key))
+	if errors.Is(err, fs.ErrExist) {
+		// a newer duplicate was queued meanwhile; it covers this job
+		return lost(os.Remove(q.path("processing", c.id)))
+	}
+	return lost(err)
+}
+
+// recover requeues jobs whose worker stopped touching them for longer
+// than the lease, which means it most likely died mid-update.
+func (q *jobQueue) recover(_ context.Context) (int, error) {
+	entries, err := os.ReadDir(filepath.Join(q.dir, "processing"))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < q.lease {
+			continue
+		}
+		id := strings.TrimSuffix(e.Name(), ".json")
+		err = renameNoReplace(q.path("processing", id), q.path("pending", claimKey(id)))
+		switch {
+		case err == nil:
+			n++
+		case errors.Is(err, fs.ErrExist):
+			_ = os.Remove(q.path("processing", id))
+		}
+	}
+	return n, nil
+}
+
+// queueProtocol is bumped on incompatible changes to the messages below.
+const queueProtocol = 1
+
+// queueRequest is written as JSON to an external queue's stdin, one per
+// operation: push, claim, touch, ack or nack.
+type queueRequest struct {
+	Protocol int    `json:"protocol"`
+	Op       string `json:"op"`
+	// LeaseSeconds is how long a claim lasts without a touch.
+	LeaseSeconds int        `json:"lease_seconds,omitempty"`
+	Job          *updateJob `json:"job,omitempty"`
+	// Claim is the ID the queue gave the claim, for touch, ack and nack.
+	Claim       string `json:"claim,omitempty"`
+	Error       string `json:"error,omitempty"`
+	MaxAttempts int    `json:"max_attempts,omitempty"`
+}
+
+// queueResponse is read as JSON from an external queue's stdout.
+type queueResponse struct {
+	// Added answers push.
+	Added bool `json:"added,omitempty"`
+	// Job and Claim answer claim; no job means the queue is empty.
+	Job   *updateJob `json:"job,omitempty"`
+	Claim string     `json:"claim,omitempty"`
+	// Lost answers touch, ack and nack of a claim the queue handed out
+	// again.
+	Lost bool `json:"lost,omitempty"`
+}
+
+// execQueue is an external queue: a program that maps the operations onto
+// NATS JetStream, SQS or whatever the deployment runs, where a claim is a
+// message being processed and its ID the handle acking it (a receipt
+// handle, a reply subject). The queue keeps its own leases and attempt
+// counts; recover is its business.
+type execQueue struct {
+	command []string
+	lease   time.Duration
+}
+
+func (q *execQueue) run(ctx context.Context, req queueRequest) (resp queueResponse, err error) {
+	ctx, sp := startSpan(ctx, "queue "+req.Op, spanKindClient, attrs{"messaging.operation.name": req.Op})
+	defer func() { sp.finish(err) }()
+
+	req.Protocol = queueProtocol
+	req.LeaseSeconds = int(q.lease / time.Second)
+	in, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, q.command[0], q.command[1:]...)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stderr = os.Stderr
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return resp, fmt.Errorf("queue %s: %w", req.Op, err)
+	}
+	if len(bytes.TrimSpace(out.Bytes())) == 0 {
+		return resp, nil
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return resp, fmt.Errorf("queue %s: decode response: %w", req.Op, err)
+	}
+	if resp.Lost {
+		return resp, errClaimLost
+	}
+	return resp, nil
+}
+
+func (q *execQueue) push(ctx context.Context, j updateJob) (bool, error) {
+	resp, err := q.run(ctx, queueRequest{Op: "push", Job: &j})
+	return resp.Added, err
+}
+
+func (q *execQueue) claim(ctx context.Context) (*jobClaim, error) {
+	resp, err := q.run(ctx, queueRequest{Op: "claim"})
+	if err != nil || resp.Job == nil {
+		return nil, err
+	}
+	if resp.Claim == "" {
+		return nil, errors.New("queue claim: no claim ID")
+	}
+	return &jobClaim{Job: *resp.Job, id: resp.Claim}, nil
+}
+
+func (q *execQueue) touch(ctx context.Context, c *jobClaim) error {
+	_, err := q.run(ctx, queueRequest{Op: "touch", Claim: c.id})
+	return err
+}
+
+func (q *execQueue) ack(ctx context.Context, c *jobClaim) error {
+	_, err := q.run(ctx, queueRequest{Op: "ack", Claim: c.id})
+	return err
+}
+
+func (q *execQueue) nack(ctx context.Context, c *jobClaim, cause error, maxAttempts int) error {
+	_, err := q.run(ctx, queueRequest{Op: "nack", Claim: c.id, Job: &c.Job, Error: cause.Error(), MaxAttempts: maxAttempts})
+	return err
+}
+
+func (q *execQueue) recover(context.Context) (int, error) { return 0, nil }
+
+// hold keeps c alive while its job runs, touching it every interval. The
+// returned context is canceled when the claim is lost, so the job stops
+// rather than racing the worker it went to; release stops the touching
+// and reports whether that happened.
+func hold(ctx context.Context, q updateQueue, c *jobClaim, every time.Duration) (context.Context, func() (lost bool)) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			if err := q.touch(ctx, c); errors.Is(err, errClaimLost) {
+				cancel(err)
+				return
+			} else if err != nil {
+				// the next touch may get through before the lease is up
+				fmt.Fprintf(os.Stderr, "%s@%s: renew claim: %v\n", c.Job.Repo, c.Job.Tag, err)
+			}
+		}
+	}()
+	return ctx, func() bool {
+		close(done)
+		<-stopped
+		lost := errors.Is(context.Cause(ctx), errClaimLost)
+		cancel(nil)
+		return lost
+	}
+}
+
+// githubRelease is the part of a GitHub release webhook the queue needs.
+type githubRelease struct {
+	Action  string `json:"action"`
+	Release struct {
+		TagName string `json:"tag_name"`
+	} `json:"release"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// releaseHookHandler queues the releases GitHub reports as published
+// through a webhook signed with secret. Other events are acknowledged and
+// ignored, so a hook subscribed to more than releases does no harm.
+func releaseHookHandler(q updateQueue, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "webhook body too large")
+			return
+		}
+		if !hmac.Equal([]byte(r.Header.Get("X-Hub-Signature-256")), []byte(signEvent(secret, body))) {
+			writeError(w, r, http.StatusUnauthorized, "bad webhook signature")
+			return
+		}
+		var ev githubRelease
+		if r.Header.Get("X-GitHub-Event") != "release" || json.Unmarshal(body, &ev) != nil || ev.Action != "published" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		repo, err := parseRepo(ev.Repository.FullName)
+		if err != nil || ev.Release.TagName == "" {
+			writeError(w, r, http.StatusBadRequest, "release event without a repository or tag")
+			return
+		}
+		added, err := q.push(r.Context(), updateJob{Repo: repo, Tag: ev.Release.TagName, EnqueuedAt: time.Now().UTC()})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "enqueue %s@%s: %v (request %s)\n", repo, ev.Release.TagName, err, correlationID(r.Context()))
+			writeError(w, r, http.StatusServiceUnavailable, "queue unavailable")
+			return
+		}
+		writeJSON(w, r, http.StatusAccepted, map[string]any{"repo": repo, "tag": ev.Release.TagName, "queued": added})
+	})
+}
+
+func runEnqueue(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("enqueue", flag.ExitOnError)
+	dir := flags.String("queue-dir", "", "queue directory; default queue.dir or .dragon-queue")
+	repo := flags.String("repo", os.Getenv("BLUEPRINTS_REPO"), "source repository (owner/name or clone URL)")
+	tag := flags.String("tag", os.Getenv("TAG"), "release tag")
+	flags.Parse(args)
+	if *repo == "" || *tag == "" {
+		return errors.New("enqueue needs --repo and --tag")
+	}
+	// normalized so the same release queued by URL and by name dedupes
+	r, err := parseRepo(*repo)
+	if err != nil {
+		return err
+	}
+	*repo = r
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	q, err := cfg.Queue.open(*dir, 0)
+	if err != nil {
+		return fmt.Errorf("open queue: %w", err)
+	}
+	added, err := q.push(ctx, updateJob{Repo: *repo, Tag: *tag, EnqueuedAt: time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("enqueue: %w", err)
+	}
+	if !added {
+		fmt.Printf("%s@%s already queued\n", *repo, *tag)
+		return nil
+	}
+	fmt.Printf("queued %s@%s\n", *repo, *tag)
+	return nil
+}
+
+func runWorker(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("worker", flag.ExitOnError)
+	dir := flags.String("queue-dir", "", "queue directory; default queue.dir or .dragon-queue")
+	once := flags.Bool("once", false, "exit when the queue is empty")
+	interval := flags.Duration("interval", 5*time.Second, "poll interval when idle")
+	lease := flags.Duration("lease", 10*time.Minute, "requeue jobs whose worker stopped renewing them for this long")
+	maxAttempts := flags.Int("max-attempts", 5, "attempts before a job is moved to failed/")
+	strict := flags.Bool("strict", false, "fail manifests with unknown fields; default manifests.strict")
+	flags.Parse(args)
+	if *lease <= 0 {
+		return errors.New("worker: --lease must be positive")
+	}
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	cfg.Manifests.Strict = cfg.Manifests.Strict || *strict
+	q, err := cfg.Queue.open(*dir, *lease)
+	if err != nil {
+		return fmt.Errorf("open queue: %w", err)
+	}
+	for {
+		if n, err := q.recover(ctx); err != nil {
+			return fmt.Errorf("recover jobs: %w", err)
+		} else if n > 0 {
+			fmt.Fprintf(os.Stderr, "requeued %d stale jobs\n", n)
+		}
+
+		c, err := q.claim(ctx)
+		if err != nil {
+			return fmt.Errorf("claim job: %w", err)
+		}
+		if c == nil {
+			if *once {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(*interval):
+			}
+			continue
+		}
+
+		// each job gets its own ID so its requests and errors can be told
+		// apart in a long-running worker's log
+		jctx := withCorrelationID(ctx, newCorrelationID())
+		jctx, release := hold(jctx, q, c, *lease/3)
+		_, err = updateRegistry(jctx, cfg, c.Job.Repo, c.Job.Tag, false)
+		if release() {
+			// another worker has the job now; its outcome is theirs to report
+			fmt.Fprintf(os.Stderr, "%s@%s: %v (run %s)\n", c.Job.Repo, c.Job.Tag, errClaimLost, correlationID(jctx))
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s@%s: %v (run %s)\n", c.Job.Repo, c.Job.Tag, err, correlationID(jctx))
+			err = q.nack(ctx, c, err, *maxAttempts)
+		} else {
+			err = q.ack(ctx, c)
+		}
+		if errors.Is(err, errClaimLost) {
+			fmt.Fprintf(os.Stderr, "%s@%s: %v (run %s)\n", c.Job.Repo, c.Job.Tag, err, correlationID(jctx))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("settle job: %w", err)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestQueue(t *testing.T, lease time.Duration) *jobQueue {
+	t.Helper()
+	q, err := openQueue(t.TempDir(), lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// expire backdates c's file past the queue's lease.
+func expire(t *testing.T, q *jobQueue, c *jobClaim) {
+	t.Helper()
+	old := time.Now().Add(-2 * q.lease)
+	if err := os.Chtimes(q.path("processing", c.id), old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustClaim(t *testing.T, q updateQueue) *jobClaim {
+	t.Helper()
+	c, err := q.claim(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == nil {
+		t.Fatal("queue is empty")
+	}
+	return c
+}
+
+func TestQueueClaimAck(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, time.Minute)
+	j := updateJob{Repo: "acme/blueprints", Tag: "v1.0.0"}
+	for i, want := range []bool{true, false} {
+		added, err := q.push(ctx, j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != want {
+			t.Errorf("push %d: added %v, want %v", i, added, want)
+		}
+	}
+
+	c := mustClaim(t, q)
+	if c.Job.Repo != j.Repo || c.Job.Tag != j.Tag {
+		t.Errorf("claimed %+v, want %+v", c.Job, j)
+	}
+	// queued again while in flight: held back until the first is done
+	if _, err := q.push(ctx, j); err != nil {
+		t.Fatal(err)
+	}
+	if c2, err := q.claim(ctx); err != nil || c2 != nil {
+		t.Fatalf("claim while in flight = %v, %v; want nothing", c2, err)
+	}
+	if err := q.ack(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.ack(ctx, c); !errors.Is(err, errClaimLost) {
+		t.Errorf("second ack: %v, want errClaimLost", err)
+	}
+	c2 := mustClaim(t, q)
+	if c2.id == c.id {
+		t.Errorf("claims share the ID %s", c.id)
+	}
+}
+
+func TestQueueRecover(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, time.Minute)
+	if _, err := q.push(ctx, updateJob{Repo: "acme/blueprints", Tag: "v1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	a := mustClaim(t, q)
+
+	// a claim renewed within its lease stays put
+	if n, err := q.recover(ctx); err != nil || n != 0 {
+		t.Fatalf("recover of a live claim = %d, %v", n, err)
+	}
+	expire(t, q, a)
+	if err := q.touch(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := q.recover(ctx); err != nil || n != 0 {
+		t.Fatalf("recover of a touched claim = %d, %v", n, err)
+	}
+
+	// one that isn't goes to the next worker...
+	expire(t, q, a)
+	if n, err := q.recover(ctx); err != nil || n != 1 {
+		t.Fatalf("recover of an expired claim = %d, %v; want 1", n, err)
+	}
+	b := mustClaim(t, q)
+
+	// ...and the first worker can no longer settle it or renew it
+	if err := q.touch(ctx, a); !errors.Is(err, errClaimLost) {
+		t.Errorf("touch of a lost claim: %v, want errClaimLost", err)
+	}
+	if err := q.ack(ctx, a); !errors.Is(err, errClaimLost) {
+		t.Errorf("ack of a lost claim: %v, want errClaimLost", err)
+	}
+	if err := q.nack(ctx, a, errors.New("boom"), 5); !errors.Is(err, errClaimLost) {
+		t.Errorf("nack of a lost claim: %v, want errClaimLost", err)
+	}
+	if _, err := os.Stat(q.path("processing", b.id)); err != nil {
+		t.Fatalf("the new claim's job is gone: %v", err)
+	}
+	if err := q.ack(ctx, b); err != nil {
+		t.Errorf("ack of the new claim: %v", err)
+	}
+}
+
+func TestQueueNack(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, time.Minute)
+	if _, err := q.push(ctx, updateJob{Repo: "acme/blueprints", Tag: "v1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	for attempt := 1; attempt <= 2; attempt++ {
+		c := mustClaim(t, q)
+		if c.Job.Attempts != attempt-1 {
+			t.Errorf("attempt %d: job has %d attempts", attempt, c.Job.Attempts)
+		}
+		if err := q.nack(ctx, c, errors.New("boom"), 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c, err := q.claim(ctx); err != nil || c != nil {
+		t.Fatalf("claim after the last attempt = %v, %v; want nothing", c, err)
+	}
+	b, err := os.ReadFile(q.path("failed", updateJob{Repo: "acme/blueprints", Tag: "v1.0.0"}.key()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parked updateJob
+	if err := json.Unmarshal(b, &parked); err != nil {
+		t.Fatal(err)
+	}
+	if parked.Attempts != 2 || parked.LastError != "boom" {
+		t.Errorf("parked %+v", parked)
+	}
+}
+
+func TestHold(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, time.Hour)
+	if _, err := q.push(ctx, updateJob{Repo: "acme/blueprints", Tag: "v1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	c := mustClaim(t, q)
+	expire(t, q, c)
+	jctx, release := hold(ctx, q, c, time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		fi, err := os.Stat(q.path("processing", c.id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if time.Since(fi.ModTime()) < q.lease {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("claim was not renewed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// requeued behind the worker's back: the job is called off
+	if err := os.Remove(q.path("processing", c.id)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-jctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("job context not canceled after the claim was lost")
+	}
+	if !release() {
+		t.Error("release did not report the lost claim")
+	}
+}
+
+func TestReleaseHook(t *testing.T) {
+	q := newTestQueue(t, time.Minute)
+	h := releaseHookHandler(q, "s3cret")
+	published := `{"action":"published","release":{"tag_name":"v1.2.0"},"repository":{"full_name":"acme/blueprints"}}`
+	for _, tc := range []struct {
+		name, event, body, secret string
+		code                      int
+		queued                    bool
+	}{
+		{"unsigned", "release", published, "wrong", http.StatusUnauthorized, false},
+		{"ping", "ping", `{"zen":"hi"}`, "s3cret", http.StatusNoContent, false},
+		{"edited", "release", strings.Replace(published, "published", "edited", 1), "s3cret", http.StatusNoContent, false},
+		{"published", "release", published, "s3cret", http.StatusAccepted, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/hooks/github", strings.NewReader(tc.body))
+			req.Header.Set("X-GitHub-Event", tc.event)
+			req.Header.Set("X-Hub-Signature-256", signEvent(tc.secret, []byte(tc.body)))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tc.code, rec.Body)
+			}
+			_, err := os.Stat(filepath.Join(q.dir, "pending", updateJob{Repo: "acme/blueprints", Tag: "v1.2.0"}.key()+".json"))
+			if queued := err == nil; queued != tc.queued {
+				t.Errorf("queued %v, want %v", queued, tc.queued)
+			}
+		})
+	}
+}
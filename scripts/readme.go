@@ -0,0 +1,243 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// detailsConfig enables a JSON detail document per entry, with its README.
+type detailsConfig struct {
+	// Dir receives <namespace>/<name>.json; empty disables details.
+	Dir string `yaml:"dir"`
+	// ReadmeMax is the most README kept, in bytes. Defaults to 64KiB.
+	ReadmeMax int `yaml:"readme_max"`
+}
+
+func (d detailsConfig) readmeMax() int {
+	if d.ReadmeMax == 0 {
+		return 64 << 10
+	}
+	return d.ReadmeMax
+}
+
+func (d detailsConfig) validate() error {
+	if d.ReadmeMax < 0 {
+		return errors.New("details.readme_max must not be negative")
+	}
+	return nil
+}
+
+// maxReadmeBytes caps the README read before sanitizing and truncating.
+const maxReadmeBytes = 1 << 20
+
+var readmeFiles = []string{"README.md", "readme.md", "README.markdown", "README"}
+
+// Where a README came from.
+const (
+	readmeFromRepo    = "repo"
+	readmeFromArchive = "archive"
+)
+
+// readme is a blueprint's README as published in its detail document.
+type readme struct {
+	Text      string    `json:"readme"`
+	Truncated bool      `json:"readme_truncated,omitempty"`
+	Source    string    `json:"readme_source"`
+	FetchedAt time.Time `json:"readme_fetched_at"`
+}
+
+// blueprintDetail is the detail document of an entry.
+type blueprintDetail struct {
+	registry.Blueprint
+	*readme
+}
+
+// errNoReadme means none of readmeFiles exist for a blueprint.
+var errNoReadme = errors.New("no README found")
+
+// fetchReadme retrieves the blueprint's README from the repo at tag,
+// through the API when the repo is private.
+func fetchReadme(ctx context.Context, repo, tag, dir string, private bool) ([]byte, error) {
+	g := github()
+	g.Private = private
+	for _, file := range readmeFiles {
+		b, err := g.File(ctx, repo, tag, path.Join(dir, file), maxReadmeBytes)
+		var se *registry.StatusError
+		if errors.As(err, &se) && se.Code == http.StatusNotFound {
+			continue
+		}
+		return b, err
+	}
+	return nil, errNoReadme
+}
+
+var (
+	htmlCommentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+	// unsafeLinkRe matches markdown links to script and data URLs
+	unsafeLinkRe = regexp.MustCompile(`(?i)\]\(\s*(javascript|vbscript|data):([^()]|\([^()]*\))*\)`)
+)
+
+// sanitizeReadme keeps the markdown of a README but drops raw HTML, links
+// that run script and control characters, then cuts it to max bytes at a
+// line boundary. It reports whether it had to cut.
+func sanitizeReadme(b []byte, max int) (string, bool) {
+	s := strings.ToValidUTF8(string(b), "")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = htmlCommentRe.ReplaceAllString(s, "")
+	s = scriptRe.ReplaceAllString(s, "")
+	s = htmlTagRe.ReplaceAllString(s, "")
+	s = unsafeLinkRe.ReplaceAllString(s, "](#)")
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(s)
+	if max <= 0 || len(s) <= max {
+		return s, false
+	}
+	cut := strings.ToValidUTF8(s[:max], "")
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, unicode.IsSpace) + "\n\n…", true
+}
+
+// indexReadme gets the README for an entry being indexed: from the repo
+// at tag, or else from the archive scan.
+func indexReadme(ctx context.Context, d detailsConfig, repo, tag, dir string, private bool, scan *assetScan) (*readme, error) {
+	b, err := fetchReadme(ctx, repo, tag, dir, private)
+	source := readmeFromRepo
+	if errors.Is(err, errNoReadme) && scan != nil && scan.Readme != nil {
+		b, err, source = scan.Readme, nil, readmeFromArchive
+	}
+	if err != nil {
+		return nil, err
+	}
+	text, cut := sanitizeReadme(b, d.readmeMax())
+	return &readme{Text: text, Truncated: cut, Source: source, FetchedAt: time.Now().UTC()}, nil
+}
+
+func detailPath(dir string, bp registry.Blueprint) string {
+	return filepath.Join(dir, bp.Namespace, bp.Name+".json")
+}
+
+// readDetail returns the README cached in an entry's detail document.
+func readDetail(dir string, bp registry.Blueprint) *readme {
+	b, err := os.ReadFile(detailPath(dir, bp))
+	if err != nil {
+		return nil
+	}
+	var r readme
+	if json.Unmarshal(b, &r) != nil || r.Source == "" {
+		return nil
+	}
+	return &r
+}
+
+// writeDetails writes the detail document of every public entry, using
+// the READMEs in fresh and keeping cached ones for the rest, and removes
+// documents of entries that are gone or no longer public.
+func writeDetails(d detailsConfig, db registry.Database, fresh map[string]*readme) error {
+	keep := map[string]bool{}
+	for _, bp := range db.Blueprints {
+		if !visibleTo(bp, anonymous) {
+			continue
+		}
+		p := detailPath(d.Dir, bp)
+		keep[p] = true
+		r := fresh[bp.FullName()]
+		if r == nil {
+			r = readDetail(d.Dir, bp)
+		}
+		b, err := json.MarshalIndent(blueprintDetail{bp, r}, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return err
+		}
+		if err := registry.WriteFileAtomic(p, append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	err := filepath.WalkDir(d.Dir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() || filepath.Ext(p) != ".json" || keep[p] {
+			return err
+		}
+		return os.Remove(p)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// runDetails rewrites the detail documents from the registry, keeping
+// the cached READMEs; --fetch refreshes those from each entry's repo.
+func runDetails(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("details", flag.ExitOnError)
+	fetch := flags.Bool("fetch", false, "fetch the READMEs again from each entry's repo at its version")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if cfg.Details.Dir == "" {
+		return errors.New("details.dir is not configured")
+	}
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	fresh := map[string]*readme{}
+	if *fetch {
+		for _, bp := range db.Blueprints {
+			repo, err := parseRepo(bp.Repo)
+			if err != nil || !visibleTo(bp, anonymous) {
+				continue
+			}
+			// the registry doesn't keep the tag; releases are usually vX.Y.Z
+			private := fromPrivateRepo(bp)
+			r, err := indexReadme(ctx, cfg.Details, repo, "v"+bp.Version, bp.Path, private, nil)
+			if errors.Is(err, errNoReadme) {
+				r, err = indexReadme(ctx, cfg.Details, repo, bp.Version, bp.Path, private, nil)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: readme: %v\n", bp.FullName(), err)
+				continue
+			}
+			fresh[bp.FullName()] = r
+		}
+	}
+	return writeDetails(cfg.Details, db, fresh)
+}
@@ -0,0 +1,39 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// github fetches through defaultClient, so requests carry its credentials
+// and headers, from the API http.github_api names.
+func github() *registry.GitHub {
+	return &registry.GitHub{Fetcher: defaultClient, APIURL: defaultClient.api}
+}
+
+// fromPrivateRepo reports whether bp was indexed from a private repo,
+// whose archives are recorded as API download references.
+func fromPrivateRepo(bp registry.Blueprint) bool {
+	return registry.IsAssetAPIURL(bp.DownloadURL) || registry.IsAssetAPIURL(bp.SourceURL)
+}
+
+// fetchManifest retrieves the blueprint's manifest from the repo at tag,
+// through the API when the repo is private. A strict fetch fails on
+// fields a manifest doesn't have.
+func fetchManifest(ctx context.Context, repo, tag, dir string, private, strict bool) (registry.Manifest, error) {
+	g := github()
+	g.Private = private
+	g.StrictManifests = strict
+	return g.Manifest(ctx, repo, tag, dir)
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// rehostConfig re-uploads blueprint archives as release assets of the
+// registry repo itself, a mirror that needs no object storage.
+type rehostConfig struct {
+	// Repo is the owner/repo whose releases hold the copies. Empty
+	// disables re-hosting.
+	Repo string `yaml:"repo"`
+}
+
+// ghReleaseRef is the part of a GitHub release the uploader needs.
+type ghReleaseRef struct {
+	UploadURL string         `json:"upload_url"`
+	Assets    []mirroredFile `json:"assets"`
+}
+
+// mirroredFile is an asset of a mirror release.
+type mirroredFile struct {
+	Name               string `json:"name"`
+	Size               int64  `json:"size"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	// URL is the API endpoint, the only one serving the file when the
+	// mirror repo is private
+	URL string `json:"url"`
+}
+
+// downloadURL is the URL entries record for the copy.
+func (m mirroredFile) downloadURL(private bool) string {
+	if private {
+		return m.URL
+	}
+	return m.BrowserDownloadURL
+}
+
+// mirrorTag is the registry repo release that holds the copies of one
+// source release, e.g. mirror-getDragon-dev-dragon-blueprints-v0.1.1.
+func mirrorTag(repo, tag string) string {
+	return "mirror-" + strings.ReplaceAll(repo, "/", "-") + "-" + tag
+}
+
+// mirrorRelease finds or creates the release for a mirror tag.
+func mirrorRelease(ctx context.Context, rc rehostConfig, repo, tag string) (*ghReleaseRef, error) {
+	mt := mirrorTag(repo, tag)
+	var rel ghReleaseRef
+	err := defaultClient.githubJSON(ctx, "GET", defaultClient.apiURL("/repos/%s/releases/tags/%s", rc.Repo, mt), nil, "", &rel)
+	var se *registry.StatusError
+	if !errors.As(err, &se) || se.Code != http.StatusNotFound {
+		return &rel, err
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"tag_name": mt,
+		"name":     fmt.Sprintf("Mirror of %s %s", repo, tag),
+		"body":     fmt.Sprintf("Copies of the blueprint archives of https://github.com/%s/releases/tag/%s, verified by digest.", repo, tag),
+	})
+	err = defaultClient.githubJSON(ctx, "POST", defaultClient.apiURL("/repos/%s/releases", rc.Repo), bytes.NewReader(payload), "application/json", &rel)
+	return &rel, err
+}
+
+// rehostAsset copies the archive of entry into the mirror release and
+// returns the copy's download URL: its API endpoint when the mirror repo
+// is private, so clients with credentials can fetch it. The archive is
+// downloaded again and must match the digest recorded when it was
+// scanned, so the copy is exactly what was verified.
+func rehostAsset(ctx context.Context, rc rehostConfig, repo, tag string, entry registry.Blueprint) (string, error) {
+	if entry.SHA256 == "" {
+		return "", errors.New("archive was not verified")
+	}
+	f, n, err := downloadArchive(ctx, entry.DownloadURL)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	sum, err := fileSHA256(f)
+	if err != nil {
+		return "", err
+	}
+	if sum != entry.SHA256 {
+		return "", fmt.Errorf("archive changed since it was verified: sha256 %s, want %s", sum, entry.SHA256)
+	}
+
+	_, private, err := verifyRepo(ctx, rc.Repo)
+	if err != nil {
+		return "", fmt.Errorf("mirror repo: %w", err)
+	}
+	rel, err := mirrorRelease(ctx, rc, repo, tag)
+	if err != nil {
+		return "", fmt.Errorf("mirror release: %w", err)
+	}
+	name := fmt.Sprintf("%s.%s-%s.zip", entry.Namespace, entry.Name, entry.Version)
+	for _, a := range rel.Assets {
+		if a.Name == name && a.Size == n {
+			return a.downloadURL(private), nil // uploaded by an earlier run
+		}
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	a, err := uploadAsset(ctx, rel.UploadURL, name, io.NewSectionReader(f, 0, n), "application/zip")
+	if err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+	return a.downloadURL(private), nil
+}
+
+// uploadAsset attaches a file to the release with uploadURL and returns
+// the asset created.
+func uploadAsset(ctx context.Context, uploadURL, name string, r *io.SectionReader, contentType string) (mirroredFile, error) {
+	// upload_url is a URI template: ".../assets{?name,label}"
+	upload, _, _ := strings.Cut(uploadURL, "{")
+	var asset mirroredFile
+	req := upload + "?name=" + url.QueryEscape(name)
+	err := defaultClient.githubJSON(ctx, "POST", req, r, contentType, &asset)
+	return asset, err
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+var (
+	// GitHub user and organisation names: alphanumerics and single
+	// hyphens, not at either end, at most 39 characters.
+	repoOwnerRe = regexp.MustCompile(`^[A-Za-z0-9](?:-?[A-Za-z0-9]){0,38}$`)
+	repoNameRe  = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+)
+
+// parseRepo accepts a source repo as owner/repo, github.com/owner/repo, an
+// https or ssh clone URL (with or without .git), or the scp-like
+// git@github.com:owner/repo form, and returns owner/repo.
+func parseRepo(s string) (string, error) {
+	in := s
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "git@"):
+		host, p, ok := strings.Cut(strings.TrimPrefix(s, "git@"), ":")
+		if !ok {
+			return "", fmt.Errorf("repo %q: missing path", in)
+		}
+		s = host + "/" + p
+	case strings.Contains(s, "://"):
+		u, err := url.Parse(s)
+		if err != nil {
+			return "", fmt.Errorf("repo %q: %w", in, err)
+		}
+		switch u.Scheme {
+		case "https", "http", "ssh", "git":
+		default:
+			return "", fmt.Errorf("repo %q: unsupported scheme %q", in, u.Scheme)
+		}
+		s = u.Hostname() + u.Path
+	}
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "/"), ".git")
+
+	parts := strings.Split(s, "/")
+	if len(parts) == 3 {
+		if host := strings.ToLower(parts[0]); host != "github.com" && host != "www.github.com" {
+			return "", fmt.Errorf("repo %q: only GitHub repos are supported", in)
+		}
+		parts = parts[1:]
+	}
+	if len(parts) != 2 {
+		return "", fmt.Errorf("repo %q: want owner/repo", in)
+	}
+	owner, name := parts[0], parts[1]
+	if !repoOwnerRe.MatchString(owner) {
+		return "", fmt.Errorf("repo %q: invalid owner %q", in, owner)
+	}
+	if !repoNameRe.MatchString(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("repo %q: invalid repository name %q", in, name)
+	}
+	return owner + "/" + name, nil
+}
+
+// repoID is the form entries record their source repo in.
+func repoID(repo string) string {
+	return "github.com/" + repo
+}
+
+// verifyRepo parses s and checks with GitHub that the repo exists. It
+// returns the name as GitHub spells it, which also follows renames, and
+// whether the repo is private.
+func verifyRepo(ctx context.Context, s string) (string, bool, error) {
+	repo, err := parseRepo(s)
+	if err != nil {
+		return "", false, err
+	}
+	b, err := defaultClient.get(ctx, defaultClient.apiURL("/repos/%s", repo))
+	var se *registry.StatusError
+	if errors.As(err, &se) && se.Code == http.StatusNotFound {
+		return "", false, fmt.Errorf("repo %s does not exist or is not visible to this token", repo)
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("repo %s: %w", repo, err)
+	}
+	var info struct {
+		FullName string `json:"full_name"`
+		Private  bool   `json:"private"`
+	}
+	if err := json.Unmarshal(b, &info); err != nil {
+		return "", false, fmt.Errorf("repo %s: %w", repo, err)
+	}
+	if info.FullName != "" {
+		repo = info.FullName
+	}
+	return repo, info.Private, nil
+}
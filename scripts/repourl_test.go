@@ -0,0 +1,121 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseRepo(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string // "" for an error
+	}{
+		{"acme/api", "acme/api"},
+		{"  acme/api  ", "acme/api"},
+		{"github.com/acme/api", "acme/api"},
+		{"www.github.com/acme/api", "acme/api"},
+		{"GitHub.com/Acme/API", "Acme/API"},
+		{"https://github.com/acme/api", "acme/api"},
+		{"https://github.com/acme/api.git", "acme/api"},
+		{"https://github.com/acme/api/", "acme/api"},
+		{"http://github.com/acme/api", "acme/api"},
+		{"https://user@github.com:443/acme/api.git", "acme/api"},
+		{"ssh://git@github.com/acme/api.git", "acme/api"},
+		{"git://github.com/acme/api", "acme/api"},
+		{"git@github.com:acme/api.git", "acme/api"},
+		{"git@github.com:acme/api", "acme/api"},
+		{"acme/my.repo_name-2", "acme/my.repo_name-2"},
+		{"a/b", "a/b"},
+
+		{"", ""},
+		{"acme", ""},
+		{"acme/api/tree/main", ""},
+		{"https://github.com/acme", ""},
+		{"https://gitlab.com/acme/api", ""},
+		{"git@gitlab.com:acme/api.git", ""},
+		{"git@github.com", ""},
+		{"ftp://github.com/acme/api", ""},
+		{"file:///acme/api", ""},
+		{"-acme/api", ""},
+		{"acme-/api", ""},
+		{"ac--me/api", ""},
+		{"ac_me/api", ""},
+		{strings.Repeat("a", 40) + "/api", ""},
+		{"acme/..", ""},
+		{"acme/.", ""},
+		{"acme/" + strings.Repeat("r", 101), ""},
+		{"acme/api repo", ""},
+	}
+	for _, c := range cases {
+		got, err := parseRepo(c.in)
+		if c.want == "" {
+			if err == nil {
+				t.Errorf("parseRepo(%q) = %q, want an error", c.in, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("parseRepo(%q) = %q, %v, want %q", c.in, got, err, c.want)
+		}
+	}
+}
+
+func TestVerifyRepo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/api":
+			w.Write([]byte(`{"full_name": "acme/api", "private": false}`))
+		case "/repos/acme/old-name":
+			// GitHub answers for a renamed repo under its new name
+			w.Write([]byte(`{"full_name": "Acme/new-name", "private": false}`))
+		case "/repos/acme/internal":
+			w.Write([]byte(`{"full_name": "acme/internal", "private": true}`))
+		case "/repos/acme/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	useGitHubAPI(t, srv, "")
+	defaultClient.retry.Attempts = 1
+
+	cases := []struct {
+		in      string
+		want    string
+		private bool
+		err     string
+	}{
+		{"https://github.com/acme/api.git", "acme/api", false, ""},
+		{"acme/old-name", "Acme/new-name", false, ""},
+		{"git@github.com:acme/internal.git", "acme/internal", true, ""},
+		{"acme/missing", "", false, "does not exist"},
+		{"acme/broken", "", false, "repo acme/broken"},
+		{"gitlab.com/acme/api", "", false, "only GitHub"},
+	}
+	for _, c := range cases {
+		got, private, err := verifyRepo(context.Background(), c.in)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("verifyRepo(%q) = %q, %v, want an error containing %q", c.in, got, err, c.err)
+			}
+			continue
+		}
+		if err != nil || got != c.want || private != c.private {
+			t.Errorf("verifyRepo(%q) = %q, %t, %v, want %q, %t", c.in, got, private, err, c.want, c.private)
+		}
+	}
+}
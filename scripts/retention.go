@@ -0,0 +1,175 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// retentionConfig bounds how many previous versions entries keep. A
+// previous version stays if any rule keeps it; with no rules set,
+// everything is kept.
+type retentionConfig struct {
+	// KeepLast keeps the newest N previous versions of each entry.
+	KeepLast int `yaml:"keep_last"`
+	// KeepDays keeps previous versions released in the last D days.
+	KeepDays int `yaml:"keep_days"`
+	// Profiles is the profiles file whose pins are always kept. Defaults
+	// to profiles.yaml.
+	Profiles string `yaml:"profiles"`
+}
+
+func (r retentionConfig) enabled() bool {
+	return r.KeepLast > 0 || r.KeepDays > 0
+}
+
+func (r retentionConfig) profiles() string {
+	if r.Profiles == "" {
+		return "profiles.yaml"
+	}
+	return r.Profiles
+}
+
+func (r retentionConfig) validate() error {
+	if r.KeepLast < 0 || r.KeepDays < 0 {
+		return errors.New("retention.keep_last and keep_days must not be negative")
+	}
+	return nil
+}
+
+// prunedRelease is a previous version dropped by the retention rules.
+type prunedRelease struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// profilePins maps entries to the versions any profile pins.
+func profilePins(pf profilesFile) map[string]map[string]bool {
+	pins := map[string]map[string]bool{}
+	for _, prof := range pf.Profiles {
+		for ref, version := range prof.Pins {
+			ns, name := registry.SplitRef(ref)
+			if ns == "" {
+				ns = registry.DefaultNamespace
+			}
+			full := ns + "/" + name
+			if pins[full] == nil {
+				pins[full] = map[string]bool{}
+			}
+			pins[full][version] = true
+		}
+	}
+	return pins
+}
+
+// applyRetention drops the previous versions no rule keeps. Previous is
+// newest first, as registry.Upsert maintains it.
+func applyRetention(db *registry.Database, r retentionConfig, pins map[string]map[string]bool, now time.Time) []prunedRelease {
+	if !r.enabled() {
+		return nil
+	}
+	cutoff := now.AddDate(0, 0, -r.KeepDays)
+	var pruned []prunedRelease
+	for i := range db.Blueprints {
+		bp := &db.Blueprints[i]
+		var kept []registry.Release
+		for j, rel := range bp.Previous {
+			keep := (r.KeepLast > 0 && j < r.KeepLast) ||
+				(r.KeepDays > 0 && rel.ReleasedAt.After(cutoff)) ||
+				pins[bp.FullName()][rel.Version]
+			if keep {
+				kept = append(kept, rel)
+			} else {
+				pruned = append(pruned, prunedRelease{Name: bp.FullName(), Version: rel.Version})
+			}
+		}
+		if len(kept) != len(bp.Previous) {
+			bp.Previous = slices.Clip(kept)
+		}
+	}
+	return pruned
+}
+
+// stageRetention queues the retention rules to run after the changes
+// already staged, recording what they drop in pruned.
+func stageRetention(tx *txn, r retentionConfig, pruned *[]prunedRelease) error {
+	if !r.enabled() {
+		return nil
+	}
+	pf, err := loadProfiles(r.profiles())
+	if err != nil {
+		return fmt.Errorf("load profiles: %w", err)
+	}
+	pins := profilePins(pf)
+	tx.stage(func(db *registry.Database) error {
+		*pruned = append(*pruned, applyRetention(db, r, pins, time.Now())...)
+		return nil
+	})
+	return nil
+}
+
+// runPrune applies the retention rules to the registry on demand.
+func runPrune(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "list what would be dropped without writing")
+	dead := flags.Bool("dead", false, "instead of applying retention, remove entries whose repo, release or asset is gone")
+	deprecate := flags.Bool("deprecate", false, "with --dead, deprecate dead entries instead of removing them")
+	report := flags.String("report", "", "with --dead, write a JSON report of the dead entries to this file")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if *dead {
+		return pruneDead(ctx, cfg, *dryRun, *deprecate, *report)
+	}
+	if !cfg.Retention.enabled() {
+		return errors.New("no retention rules configured")
+	}
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	var pruned []prunedRelease
+	if *dryRun {
+		pf, err := loadProfiles(cfg.Retention.profiles())
+		if err != nil {
+			return fmt.Errorf("load profiles: %w", err)
+		}
+		db := st.snapshot().Clone()
+		pruned = applyRetention(&db, cfg.Retention, profilePins(pf), time.Now())
+	} else {
+		tx := st.begin()
+		if err := stageRetention(tx, cfg.Retention, &pruned); err != nil {
+			return err
+		}
+		if err := tx.commit(); err != nil {
+			return fmt.Errorf("update registry: %w", err)
+		}
+	}
+	verb := "dropped"
+	if *dryRun {
+		verb = "would drop"
+	}
+	for _, p := range pruned {
+		fmt.Printf("%s %s %s\n", verb, p.Name, p.Version)
+	}
+	fmt.Printf("%s %d previous versions\n", verb, len(pruned))
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	days := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+	// previous releases newest first, as Upsert keeps them
+	entry := func() registry.Database {
+		return registry.Database{Blueprints: []registry.Blueprint{{
+			Namespace: registry.DefaultNamespace, Name: "api", Version: "1.5.0",
+			Previous: []registry.Release{
+				{Version: "1.4.0", ReleasedAt: days(1)},
+				{Version: "1.3.0", ReleasedAt: days(10)},
+				{Version: "1.2.0", ReleasedAt: days(40)},
+				{Version: "1.1.0", ReleasedAt: days(100)},
+			},
+		}}}
+	}
+	pinned := func(name, version string) map[string]map[string]bool {
+		return map[string]map[string]bool{name: {version: true}}
+	}
+
+	cases := []struct {
+		name   string
+		r      retentionConfig
+		pins   map[string]map[string]bool
+		kept   []string
+		pruned []string
+	}{
+		{"no rules", retentionConfig{}, nil, []string{"1.4.0", "1.3.0", "1.2.0", "1.1.0"}, nil},
+		{"keep last 2", retentionConfig{KeepLast: 2}, nil, []string{"1.4.0", "1.3.0"}, []string{"1.2.0", "1.1.0"}},
+		{"keep last more than there are", retentionConfig{KeepLast: 10}, nil, []string{"1.4.0", "1.3.0", "1.2.0", "1.1.0"}, nil},
+		{"keep 30 days", retentionConfig{KeepDays: 30}, nil, []string{"1.4.0", "1.3.0"}, []string{"1.2.0", "1.1.0"}},
+		{"keep a year", retentionConfig{KeepDays: 365}, nil, []string{"1.4.0", "1.3.0", "1.2.0", "1.1.0"}, nil},
+		{"either rule keeps", retentionConfig{KeepLast: 1, KeepDays: 60}, nil, []string{"1.4.0", "1.3.0", "1.2.0"}, []string{"1.1.0"}},
+		{"pinned is kept", retentionConfig{KeepLast: 1}, pinned("getdragon/api", "1.1.0"), []string{"1.4.0", "1.1.0"}, []string{"1.3.0", "1.2.0"}},
+		{"pin of another entry", retentionConfig{KeepLast: 1}, pinned("acme/api", "1.1.0"), []string{"1.4.0"}, []string{"1.3.0", "1.2.0", "1.1.0"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			db := entry()
+			pruned := applyRetention(&db, c.r, c.pins, now)
+			var kept, gone []string
+			for _, rel := range db.Blueprints[0].Previous {
+				kept = append(kept, rel.Version)
+			}
+			for _, p := range pruned {
+				if p.Name != "getdragon/api" {
+					t.Errorf("pruned %s of %s", p.Version, p.Name)
+				}
+				gone = append(gone, p.Version)
+			}
+			if !slices.Equal(kept, c.kept) {
+				t.Errorf("kept %v, want %v", kept, c.kept)
+			}
+			if !slices.Equal(gone, c.pruned) {
+				t.Errorf("pruned %v, want %v", gone, c.pruned)
+			}
+			if db.Blueprints[0].Version != "1.5.0" {
+				t.Errorf("current version changed to %s", db.Blueprints[0].Version)
+			}
+		})
+	}
+}
+
+func TestRetentionValidate(t *testing.T) {
+	for _, c := range []struct {
+		r  retentionConfig
+		ok bool
+	}{
+		{retentionConfig{}, true},
+		{retentionConfig{KeepLast: 3, KeepDays: 90}, true},
+		{retentionConfig{KeepLast: -1}, false},
+		{retentionConfig{KeepDays: -1}, false},
+	} {
+		if err := c.r.validate(); (err == nil) != c.ok {
+			t.Errorf("validate(%+v) = %v", c.r, err)
+		}
+	}
+}
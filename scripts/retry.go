@@ -0,0 +1,154 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// retryConfig decides how outbound requests are retried: after network
+// errors, server errors and rate limiting, so a transient GitHub hiccup
+// doesn't fail a whole update.
+type retryConfig struct {
+	// Attempts is how many times a request is tried in all; 1 disables
+	// retries. Default 4.
+	Attempts int `yaml:"attempts"`
+	// Backoff is the wait before the first retry, doubled for each
+	// further one and jittered. Default 1s.
+	Backoff time.Duration `yaml:"backoff"`
+	// MaxWait caps any one wait, including the one a Retry-After header or
+	// a rate-limit reset asks for; a request that would have to wait
+	// longer fails instead. Default 1m.
+	MaxWait time.Duration `yaml:"max_wait"`
+}
+
+func (r retryConfig) validate() error {
+	if r.Attempts < 0 || r.Backoff < 0 || r.MaxWait < 0 {
+		return errors.New("http.retries: attempts, backoff and max_wait must not be negative")
+	}
+	return nil
+}
+
+func (r retryConfig) attempts() int          { return orDefault(r.Attempts, 4) }
+func (r retryConfig) backoff() time.Duration { return orDefault(r.Backoff, time.Second) }
+func (r retryConfig) maxWait() time.Duration { return orDefault(r.MaxWait, time.Minute) }
+
+// retryable reports whether req can be sent again: its body, if any, can
+// be replayed, and sending it twice does no harm. Other requests are
+// only retried when the server said it didn't process them.
+func retryable(req *http.Request) (replay, idempotent bool) {
+	replay = req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		idempotent = true
+	}
+	return replay, idempotent
+}
+
+// rateLimited reports whether resp turns the request away for exceeding
+// a rate limit, and when to try again if the server says.
+func rateLimited(resp *http.Response, now time.Time) (bool, time.Duration) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+		return false, 0
+	}
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return true, time.Duration(secs) * time.Second
+		}
+		if t, err := http.ParseTime(s); err == nil {
+			return true, max(t.Sub(now), 0)
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			// a second's grace for clock skew
+			return true, max(time.Unix(reset, 0).Sub(now), 0) + time.Second
+		}
+		return true, 0
+	}
+	// a 403 without rate-limit headers is a permission problem
+	return resp.StatusCode == http.StatusTooManyRequests, 0
+}
+
+// retryAfter decides whether the attempt-th try of req, which ended with
+// resp or err, is tried again, after how long, and why.
+func (r retryConfig) retryAfter(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, string, bool) {
+	replay, idempotent := retryable(req)
+	if attempt >= r.attempts() || !replay || req.Context().Err() != nil {
+		return 0, "", false
+	}
+	// exponential backoff with jitter, so clients that failed together
+	// don't retry together
+	backoff := r.backoff() << (attempt - 1)
+	backoff = backoff/2 + rand.N(backoff/2+1)
+	var wait time.Duration
+	var why string
+	switch {
+	case err != nil:
+		if !idempotent {
+			return 0, "", false
+		}
+		wait, why = backoff, err.Error()
+	default:
+		limited, until := rateLimited(resp, time.Now())
+		switch {
+		case limited:
+			wait, why = max(until, backoff), "rate limited"
+		case idempotent && (resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented):
+			wait, why = backoff, resp.Status
+		default:
+			return 0, "", false
+		}
+	}
+	if wait > r.maxWait() {
+		return 0, "", false
+	}
+	return wait, why, true
+}
+
+// do sends req, retrying as c.retry allows. A response it gives up on is
+// returned as is, for the caller to report.
+func (c *httpClient) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := c.doOnce(req)
+		wait, why, ok := c.retry.retryAfter(req, resp, err, attempt)
+		if !ok {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+		}
+		fmt.Fprintf(os.Stderr, "%s %s%s: %s; retrying in %s (attempt %d of %d)\n",
+			req.Method, req.URL.Host, req.URL.Path, why, wait.Round(time.Millisecond), attempt+1, c.retry.attempts())
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// reviewConfig puts a human gate in front of publishing.
+type reviewConfig struct {
+	// Required makes the updater write candidates to Dir instead of
+	// registry.json; approve moves them in.
+	Required bool `yaml:"required"`
+	// Dir holds one file per pending entry, as <namespace>/<name>.json.
+	Dir string `yaml:"dir"`
+}
+
+func (r reviewConfig) dir() string {
+	if r.Dir == "" {
+		return "pending"
+	}
+	return r.Dir
+}
+
+// pendingEntry is a candidate entry awaiting review.
+type pendingEntry struct {
+	Repo        string             `json:"repo"`
+	Tag         string             `json:"tag"`
+	SubmittedAt time.Time          `json:"submitted_at"`
+	Entry       registry.Blueprint `json:"entry"`
+}
+
+// pendingPath is where the candidate for bp is kept. Namespace and name
+// are validated identifiers, so they are safe as path elements.
+func pendingPath(dir string, bp registry.Blueprint) string {
+	return filepath.Join(dir, bp.Namespace, bp.Name+".json")
+}
+
+// writePending stores a candidate, replacing an older candidate for the
+// same entry.
+func writePending(dir string, pe pendingEntry) error {
+	p := pendingPath(dir, pe.Entry)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(pe, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, append(b, '\n'), 0o644)
+}
+
+// readPending returns all candidates, ordered by entry name.
+func readPending(dir string) ([]pendingEntry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []pendingEntry
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var pe pendingEntry
+		if err := json.Unmarshal(b, &pe); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		if pendingPath(dir, pe.Entry) != p {
+			return nil, fmt.Errorf("%s: holds %s", p, pe.Entry.FullName())
+		}
+		out = append(out, pe)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Entry.FullName() < out[j].Entry.FullName() })
+	return out, nil
+}
+
+// removePending deletes a candidate and its namespace directory once
+// empty.
+func removePending(dir string, bp registry.Blueprint) error {
+	p := pendingPath(dir, bp)
+	if err := os.Remove(p); err != nil {
+		return err
+	}
+	os.Remove(filepath.Dir(p)) // fails while other candidates remain
+	return nil
+}
+
+// selectPending picks the candidates named by refs, or all of them.
+func selectPending(dir string, refs []string, all bool) ([]pendingEntry, error) {
+	pending, err := readPending(dir)
+	if err != nil {
+		return nil, err
+	}
+	if all {
+		return pending, nil
+	}
+	if len(refs) == 0 {
+		return nil, errors.New("name the entries to act on, or pass --all")
+	}
+	byName := map[string]pendingEntry{}
+	for _, pe := range pending {
+		byName[pe.Entry.FullName()] = pe
+	}
+	var out []pendingEntry
+	for _, ref := range refs {
+		ns, name := registry.SplitRef(ref)
+		if ns == "" {
+			ns = registry.DefaultNamespace
+		}
+		pe, ok := byName[ns+"/"+name]
+		if !ok {
+			return nil, fmt.Errorf("%s/%s: %w", ns, name, registry.ErrNotFound)
+		}
+		out = append(out, pe)
+	}
+	return out, nil
+}
+
+// runPending lists the candidates awaiting review.
+func runPending(args []string) error {
+	flags := flag.NewFlagSet("pending", flag.ExitOnError)
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	pending, err := readPending(cfg.Review.dir())
+	if err != nil {
+		return fmt.Errorf("read pending: %w", err)
+	}
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	current := map[string]string{}
+	for _, bp := range db.Blueprints {
+		current[bp.FullName()] = bp.Version
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tCHANGE\tSOURCE\tSUBMITTED")
+	for _, pe := range pending {
+		change := "new"
+		if v, ok := current[pe.Entry.FullName()]; ok {
+			change = "update from " + v
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s@%s\t%s\n", pe.Entry.FullName(), pe.Entry.Version, change,
+			pe.Repo, pe.Tag, pe.SubmittedAt.Format(time.DateTime))
+	}
+	return w.Flush()
+}
+
+// runApprove publishes candidates into registry.json.
+func runApprove(args []string) error {
+	flags := flag.NewFlagSet("approve", flag.ExitOnError)
+	all := flags.Bool("all", false, "approve every pending entry")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	sel, err := selectPending(cfg.Review.dir(), flags.Args(), *all)
+	if err != nil {
+		return err
+	}
+	if len(sel) == 0 {
+		fmt.Println(cliCatalog().translate("nothing pending"))
+		return nil
+	}
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	tx := st.begin()
+	tx.stage(func(db *registry.Database) error {
+		for _, pe := range sel {
+			if err := upsertResolved(db, pe.Entry, cfg.Conflicts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var pruned []prunedRelease
+	if err := stageRetention(tx, cfg.Retention, &pruned); err != nil {
+		return err
+	}
+	if err := tx.commit(); err != nil {
+		return fmt.Errorf("update registry: %w", err)
+	}
+	for _, p := range pruned {
+		fmt.Fprintf(os.Stderr, "%s: dropped version %s (retention)\n", p.Name, p.Version)
+	}
+	// only drop the candidates once the registry is safely written
+	for _, pe := range sel {
+		if err := removePending(cfg.Review.dir(), pe.Entry); err != nil {
+			return err
+		}
+		fmt.Printf("approved %s %s\n", pe.Entry.FullName(), pe.Entry.Version)
+	}
+	return nil
+}
+
+// runReject discards candidates.
+func runReject(args []string) error {
+	flags := flag.NewFlagSet("reject", flag.ExitOnError)
+	all := flags.Bool("all", false, "reject every pending entry")
+	reason := flags.String("reason", "", "why, for the log")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	sel, err := selectPending(cfg.Review.dir(), flags.Args(), *all)
+	if err != nil {
+		return err
+	}
+	for _, pe := range sel {
+		if err := removePending(cfg.Review.dir(), pe.Entry); err != nil {
+			return err
+		}
+		if *reason != "" {
+			fmt.Printf("rejected %s %s: %s\n", pe.Entry.FullName(), pe.Entry.Version, *reason)
+		} else {
+			fmt.Printf("rejected %s %s\n", pe.Entry.FullName(), pe.Entry.Version)
+		}
+	}
+	return nil
+}
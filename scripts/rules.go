@@ -0,0 +1,260 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// entryRule is one check entries are validated against. Its severity is
+// error, warn or off (the lint levels), set per registry in
+// validation.rules.
+type entryRule struct {
+	ID      string
+	Default string
+	// Required rules protect the registry itself (names become paths,
+	// visibility gates access) and can't be relaxed.
+	Required bool
+	check    func(registry.Blueprint) string
+}
+
+var entryRules = []entryRule{
+	{ID: "invalid-namespace", Default: lintError, Required: true, check: func(bp registry.Blueprint) string {
+		return errString(registry.ValidateIdent("namespace", bp.Namespace))
+	}},
+	{ID: "invalid-name", Default: lintError, Required: true, check: func(bp registry.Blueprint) string {
+		return errString(registry.ValidateIdent("name", bp.Name))
+	}},
+	{ID: "invalid-visibility", Default: lintError, Required: true, check: func(bp registry.Blueprint) string {
+		return errString(validateVisibility(bp.Visibility))
+	}},
+	{ID: "invalid-trust", Default: lintError, check: func(bp registry.Blueprint) string {
+		if bp.Trust == "" {
+			return ""
+		}
+		return errString(validateTrustLevel(bp.Trust))
+	}},
+	{ID: "invalid-repo", Default: lintError, check: func(bp registry.Blueprint) string {
+		if bp.Repo == "" {
+			return ""
+		}
+		repo, err := parseRepo(bp.Repo)
+		if err != nil {
+			return err.Error()
+		}
+		if bp.Repo != repoID(repo) {
+			return fmt.Sprintf("repo %q is not in github.com/owner/repo form", bp.Repo)
+		}
+		return ""
+	}},
+	{ID: "invalid-icon", Default: lintError, check: func(bp registry.Blueprint) string {
+		if bp.Icon == "" {
+			return ""
+		}
+		if u, err := url.Parse(bp.Icon); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Sprintf("icon %q is not an https URL", bp.Icon)
+		}
+		return ""
+	}},
+	{ID: "invalid-screenshot", Default: lintError, check: func(bp registry.Blueprint) string {
+		for _, s := range bp.Screenshots {
+			if u, err := url.Parse(s); err != nil || u.Scheme != "https" || u.Host == "" {
+				return fmt.Sprintf("screenshot %q is not an https URL", s)
+			}
+		}
+		return ""
+	}},
+	// the published JSON Schema's definition of an entry: required
+	// fields, semver versions, http(s) URLs, digest and tag shapes
+	{ID: "schema", Default: lintError, check: func(bp registry.Blueprint) string {
+		var msgs []string
+		for _, e := range registry.ValidateBlueprint(bp) {
+			msgs = append(msgs, e.Error())
+		}
+		return strings.Join(msgs, "; ")
+	}},
+	{ID: "missing-version", Default: lintError, check: func(bp registry.Blueprint) string {
+		return missing(bp.Version, "version")
+	}},
+	{ID: "missing-download-url", Default: lintError, check: func(bp registry.Blueprint) string {
+		return missing(bp.DownloadURL, "download_url")
+	}},
+	{ID: "missing-description", Default: lintWarn, check: func(bp registry.Blueprint) string {
+		return missing(bp.Description, "description")
+	}},
+	{ID: "missing-license", Default: lintOff, check: func(bp registry.Blueprint) string {
+		return missing(bp.License, "license")
+	}},
+	{ID: "missing-tags", Default: lintOff, check: func(bp registry.Blueprint) string {
+		if len(bp.Tags) == 0 {
+			return "no tags"
+		}
+		return ""
+	}},
+	{ID: "missing-digest", Default: lintOff, check: func(bp registry.Blueprint) string {
+		return missing(bp.SHA256, "sha256")
+	}},
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func missing(v, field string) string {
+	if v == "" {
+		return "missing " + field
+	}
+	return ""
+}
+
+// validationConfig sets the severity of entry rules for this registry.
+type validationConfig struct {
+	// Rules maps rule IDs to error, warn or off; unlisted rules keep
+	// their default.
+	Rules map[string]string `yaml:"rules"`
+}
+
+func (v validationConfig) validate() error {
+	for id, level := range v.Rules {
+		r, ok := findRule(id)
+		if !ok {
+			return fmt.Errorf("validation.rules: unknown rule %q", id)
+		}
+		if err := (templateRules{Lint: level}).validate(); err != nil {
+			return fmt.Errorf("validation.rules.%s: want off, warn or error, got %q", id, level)
+		}
+		if r.Required && level != lintError {
+			return fmt.Errorf("validation.rules.%s: rule is required and can't be relaxed", id)
+		}
+	}
+	return nil
+}
+
+func findRule(id string) (entryRule, bool) {
+	for _, r := range entryRules {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return entryRule{}, false
+}
+
+func (v validationConfig) severity(r entryRule) string {
+	if level, ok := v.Rules[r.ID]; ok {
+		return level
+	}
+	return r.Default
+}
+
+// finding is a rule violation.
+type finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (f finding) String() string {
+	return fmt.Sprintf("%s: %s [%s]", f.Severity, f.Message, f.Rule)
+}
+
+// checkEntry runs the enabled rules against bp.
+func checkEntry(bp registry.Blueprint, v validationConfig) []finding {
+	var out []finding
+	for _, r := range entryRules {
+		sev := v.severity(r)
+		if sev == lintOff {
+			continue
+		}
+		if msg := r.check(bp); msg != "" {
+			out = append(out, finding{Rule: r.ID, Severity: sev, Message: msg})
+		}
+	}
+	return out
+}
+
+// hasErrors reports whether any finding is an error.
+func hasErrors(fs []finding) bool {
+	for _, f := range fs {
+		if f.Severity == lintError {
+			return true
+		}
+	}
+	return false
+}
+
+// printRuleset writes the effective severity of every rule and where it
+// comes from.
+func printRuleset(w io.Writer, v validationConfig) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tSEVERITY\tSOURCE")
+	for _, r := range entryRules {
+		source := "default"
+		switch {
+		case r.Required:
+			source = "required"
+		case v.Rules[r.ID] != "":
+			source = "config"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.ID, v.severity(r), source)
+	}
+	return tw.Flush()
+}
+
+// runValidate checks registry.json against the configured rules.
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	file := flags.String("file", registryPath(), "registry to validate (or give it as the argument)")
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		return errors.New("usage: validate [--file] [registry]")
+	}
+	if flags.NArg() == 1 {
+		*file = flags.Arg(0)
+	}
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	b, err := registryJSON(*file)
+	if err != nil {
+		return err
+	}
+	if err := printRuleset(os.Stdout, cfg.Validation); err != nil {
+		return err
+	}
+	fmt.Println()
+	problems := checkRegistry(b, cfg.Validation)
+	var errs int
+	for _, p := range problems {
+		fmt.Println(p)
+		if strings.HasPrefix(p, lintError+": ") {
+			errs++
+		}
+	}
+	fmt.Printf("%d problems, %d errors\n", len(problems), errs)
+	if errs > 0 {
+		return fmt.Errorf("%s fails validation", *file)
+	}
+	return nil
+}
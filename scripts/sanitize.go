@@ -0,0 +1,120 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// textConfig limits the free text entries carry.
+type textConfig struct {
+	// DescriptionMax is the longest description kept, in characters.
+	// Longer ones are cut at a word boundary. Defaults to 300.
+	DescriptionMax int `yaml:"description_max"`
+}
+
+func (t textConfig) descriptionMax() int {
+	if t.DescriptionMax == 0 {
+		return 300
+	}
+	return t.DescriptionMax
+}
+
+func (t textConfig) validate() error {
+	if t.DescriptionMax < 0 {
+		return errors.New("text.description_max must not be negative")
+	}
+	return nil
+}
+
+var (
+	htmlTagRe   = regexp.MustCompile(`<[^>]*>`)
+	scriptRe    = regexp.MustCompile(`(?is)<script\b.*?</script\s*>|<style\b.*?</style\s*>`)
+	mdImageRe   = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLinkRe    = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdEmphRe    = regexp.MustCompile("(\\*\\*|__|~~|`+)")
+	mdHeadingRe = regexp.MustCompile(`(?m)^\s{0,3}(#{1,6}|>|[-*+])\s+`)
+)
+
+// sanitizeText reduces s to plain, single-line text the catalog can show
+// as is: markup is removed, invisible and control characters dropped,
+// whitespace collapsed and the result cut to max characters. Go has no
+// Unicode normalizer in the standard library, so composed and decomposed
+// forms are left as they are.
+func sanitizeText(s string, max int) string {
+	s = strings.ToValidUTF8(s, "")
+	// markup: tags, then entities, then tags the entities spelled out
+	s = scriptRe.ReplaceAllString(s, " ")
+	s = htmlTagRe.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	s = htmlTagRe.ReplaceAllString(s, " ")
+	s = mdImageRe.ReplaceAllString(s, "$1")
+	s = mdLinkRe.ReplaceAllString(s, "$1")
+	s = mdHeadingRe.ReplaceAllString(s, "")
+	s = mdEmphRe.ReplaceAllString(s, "")
+
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			// zero-width and bidi override characters can disguise text
+			continue
+		case r == '<' || r == '>':
+			// left over from broken markup
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return truncateText(b.String(), max)
+}
+
+// truncateText cuts s to at most max characters, preferring a word
+// boundary and marking the cut with an ellipsis.
+func truncateText(s string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)[:max-1]
+	// scripts without spaces (CJK) are cut anywhere; otherwise back up to
+	// the last space if it isn't too far
+	if i := strings.LastIndexFunc(string(runes), unicode.IsSpace); i >= 0 {
+		if cut := utf8.RuneCountInString(string(runes)[:i]); cut >= max*3/4 {
+			runes = runes[:cut]
+		}
+	}
+	return strings.TrimRightFunc(string(runes), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// sanitizeEntry cleans the free text of bp and reports whether anything
+// changed.
+func sanitizeEntry(bp *registry.Blueprint, t textConfig) bool {
+	clean := sanitizeText(bp.Description, t.descriptionMax())
+	changed := clean != bp.Description
+	bp.Description = clean
+	return changed
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+func TestSanitizeText(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"plain", "A REST API service", "A REST API service"},
+		{"html tags", "<p>A <b>REST</b> service</p>", "A REST service"},
+		{"script", `Nice<script>alert("x")</script> service`, "Nice service"},
+		{"style", "<style>p{color:red}</style>Styled", "Styled"},
+		{"escaped tags", "&lt;img src=x onerror=alert(1)&gt;Hi", "Hi"},
+		{"entities", "Fish &amp; chips &quot;fresh&quot;", `Fish & chips "fresh"`},
+		{"stray brackets", "x > y and 1 < 2", "x y and 1 2"},
+		{"markdown link", "See [the docs](https://example.com) now", "See the docs now"},
+		{"markdown image", "![logo](logo.png) Service", "logo Service"},
+		{"emphasis", "**Fast** and __safe__ with `code` ~~old~~", "Fast and safe with code old"},
+		{"heading and list", "# Title\n- one\n- two\n> quote", "Title one two quote"},
+		{"whitespace", "  many\t\tspaces\n\nand\r\nlines  ", "many spaces and lines"},
+		{"zero width", "in\u200bvisible\u200d", "invisible"},
+		{"bidi override", "evil\u202etxt.exe", "eviltxt.exe"},
+		{"control characters", "bell\a and\x00 nul", "bell and nul"},
+		{"invalid utf-8", "bad\xff\xfebytes", "badbytes"},
+		{"non-latin", "  Служба   API  ", "Служба API"},
+		{"cjk", "<b>服务</b>描述", "服务 描述"},
+		{"emoji", "Rocket 🚀 launch", "Rocket 🚀 launch"},
+		{"empty", "", ""},
+		{"only markup", "<br/><hr>", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := sanitizeText(c.in, 0); got != c.want {
+				t.Errorf("sanitizeText(%q) = %q, want %q", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		max  int
+		want string
+	}{
+		{"fits", "short text", 20, "short text"},
+		{"exactly max", "0123456789", 10, "0123456789"},
+		{"no limit", strings.Repeat("x", 1000), 0, strings.Repeat("x", 1000)},
+		{"word boundary", "the quick brown fox jumps over", 21, "the quick brown fox…"},
+		{"room for the ellipsis", "the quick brown fox jumps over", 20, "the quick brown…"},
+		{"boundary too far back", "a verylongwordthatgoesonandon", 12, "a verylongw…"},
+		{"trailing punctuation", "one, two, three, four", 11, "one, two…"},
+		{"cjk cut anywhere", "服务描述服务描述服务描述", 5, "服务描述…"},
+		{"multibyte counted as characters", "ééééééééééé", 5, "éééé…"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := truncateText(c.in, c.max)
+			if got != c.want {
+				t.Errorf("truncateText(%q, %d) = %q, want %q", c.in, c.max, got, c.want)
+			}
+			if c.max > 0 && utf8.RuneCountInString(got) > c.max {
+				t.Errorf("truncateText(%q, %d) is %d characters", c.in, c.max, utf8.RuneCountInString(got))
+			}
+		})
+	}
+}
+
+func TestSanitizeEntry(t *testing.T) {
+	bp := registry.Blueprint{Description: "<b>Fast</b>   API " + strings.Repeat("word ", 100)}
+	if !sanitizeEntry(&bp, textConfig{DescriptionMax: 50}) {
+		t.Error("sanitizeEntry reported no change")
+	}
+	if n := utf8.RuneCountInString(bp.Description); n > 50 || !strings.HasPrefix(bp.Description, "Fast API word") {
+		t.Errorf("description = %q (%d characters)", bp.Description, n)
+	}
+	if sanitizeEntry(&bp, textConfig{DescriptionMax: 50}) {
+		t.Error("sanitizing a clean description changed it")
+	}
+	if (textConfig{}).descriptionMax() != 300 {
+		t.Errorf("default description_max = %d, want 300", (textConfig{}).descriptionMax())
+	}
+	if err := (textConfig{DescriptionMax: -1}).validate(); err == nil {
+		t.Error("negative description_max accepted")
+	}
+}
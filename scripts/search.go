@@ -0,0 +1,341 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// searchConfig tunes how queries match entries.
+type searchConfig struct {
+	// Synonyms maps a term to terms that mean the same, e.g.
+	// k8s: [kubernetes]. They apply both ways.
+	Synonyms map[string][]string `yaml:"synonyms"`
+}
+
+func (s searchConfig) validate() error {
+	for term, alts := range s.Synonyms {
+		if len(searchTokens(term)) != 1 {
+			return fmt.Errorf("search.synonyms: %q must be a single word", term)
+		}
+		for _, a := range alts {
+			if len(searchTokens(a)) != 1 {
+				return fmt.Errorf("search.synonyms.%s: %q must be a single word", term, a)
+			}
+		}
+	}
+	return nil
+}
+
+// expand returns the term and its synonyms.
+func (s searchConfig) expand(term string) []string {
+	out := []string{term}
+	add := func(t string) {
+		t = strings.ToLower(t)
+		for _, o := range out {
+			if o == t {
+				return
+			}
+		}
+		out = append(out, t)
+	}
+	for k, alts := range s.Synonyms {
+		k = strings.ToLower(k)
+		for _, a := range alts {
+			switch term {
+			case k:
+				add(a)
+			case strings.ToLower(a):
+				add(k)
+			}
+		}
+	}
+	return out
+}
+
+// searchTokens splits s into lowercase words.
+func searchTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Match strengths of a query word against an entry word.
+const (
+	matchExact     = 1.0
+	matchPrefix    = 0.8
+	matchSubstring = 0.6
+	matchTypo      = 0.5
+	// synonymFactor discounts matches found through a synonym, so the
+	// word the user typed ranks first.
+	synonymFactor = 0.9
+)
+
+// maxTypos is the edit distance a word of n letters may be off by. Short
+// words would match too much if any typo were allowed.
+func maxTypos(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and adjacent transpositions
+// ("kuberentes") each cost one.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// wordMatch scores query word q against entry word w.
+func wordMatch(q, w string) float64 {
+	switch {
+	case q == w:
+		return matchExact
+	case len(q) >= 2 && strings.HasPrefix(w, q):
+		return matchPrefix
+	case len(q) >= 3 && strings.Contains(w, q):
+		return matchSubstring
+	}
+	n := len([]rune(q))
+	if k := maxTypos(n); k > 0 {
+		// a typo in the prefix the user has typed so far
+		if lw := []rune(w); len(lw) > n && editDistance(q, string(lw[:n])) <= k {
+			return matchTypo * 0.9
+		}
+		if editDistance(q, w) <= k {
+			return matchTypo
+		}
+	}
+	return 0
+}
+
+// searchField is a weighted part of an entry.
+type searchField struct {
+	weight float64
+	words  []string
+}
+
+func entryFields(bp registry.Blueprint) []searchField {
+	return []searchField{
+		{3, searchTokens(bp.Name + " " + bp.Title)},
+		{2, searchTokens(strings.Join(bp.Tags, " "))},
+		{1.5, searchTokens(bp.Namespace)},
+		{1, searchTokens(bp.Description)},
+	}
+}
+
+// searchResult is an entry that matched, with its relevance.
+type searchResult struct {
+	Entry registry.Blueprint `json:"entry"`
+	Score float64            `json:"score"`
+}
+
+// searchEntries ranks the entries matching every word of query. Each
+// word may match exactly, as a prefix or substring, within a few typos,
+// or through a synonym.
+func searchEntries(bps []registry.Blueprint, query string, sc searchConfig) []searchResult {
+	terms := searchTokens(query)
+	var out []searchResult
+	for _, bp := range bps {
+		fields := entryFields(bp)
+		total := 0.0
+		for _, t := range terms {
+			best := 0.0
+			for i, alt := range sc.expand(t) {
+				factor := 1.0
+				if i > 0 {
+					factor = synonymFactor
+				}
+				for _, f := range fields {
+					for _, w := range f.words {
+						best = max(best, f.weight*factor*wordMatch(alt, w))
+					}
+				}
+			}
+			if best == 0 {
+				total = 0
+				break
+			}
+			total += best
+		}
+		if total > 0 || len(terms) == 0 {
+			out = append(out, searchResult{Entry: bp, Score: total})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Entry.FullName() < out[j].Entry.FullName()
+	})
+	return out
+}
+
+// sortResultsByQuality orders results best-rated first, keeping their
+// relevance order among equals.
+func sortResultsByQuality(res []searchResult) {
+	slices.SortStableFunc(res, func(a, b searchResult) int {
+		return qualityScore(b.Entry) - qualityScore(a.Entry)
+	})
+}
+
+// searchFilter narrows a search to entries having all of its
+// attributes; zero fields don't filter.
+type searchFilter struct {
+	Namespace  string
+	Tags       []string
+	Features   []featureFilter
+	Collection string
+	// Stale, when set, keeps only the entries flagged stale (true) or
+	// only the others (false).
+	Stale *bool
+}
+
+func (f searchFilter) match(db registry.Database, cf collectionsFile, bp registry.Blueprint) bool {
+	if f.Namespace != "" && bp.Namespace != f.Namespace {
+		return false
+	}
+	for _, t := range f.Tags {
+		if !slices.Contains(bp.Tags, t) {
+			return false
+		}
+	}
+	if !matchFeatures(bp, f.Features) {
+		return false
+	}
+	if f.Stale != nil && (bp.Stale != nil) != *f.Stale {
+		return false
+	}
+	return f.Collection == "" || inCollection(db, cf, f.Collection, bp)
+}
+
+// parseStaleFilter parses the stale filter: "" for no filter, else a
+// boolean.
+func parseStaleFilter(s string) (*bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, fmt.Errorf("want true or false, got %q", s)
+	}
+	return &b, nil
+}
+
+// runSearch lists the registry entries matching a query.
+func runSearch(args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := flags.Int("limit", 20, "show at most this many results (0 for all)")
+	asJSON := flags.Bool("json", false, "print the results as JSON")
+	ns := flags.String("namespace", "", "only search entries in this namespace")
+	tags := flags.String("tag", "", "only search entries with all of these comma-separated tags")
+	features := flags.String("feature", "", "only search entries with all of these comma-separated features (name or name=value)")
+	inColl := flags.String("collection", "", "only search the entries of this collection")
+	collFile := flags.String("collections", "collections.yaml", "collections file")
+	order := flags.String("sort", "relevance", "order results by relevance or quality")
+	stale := flags.String("stale", "", "only search entries flagged stale (true) or not (false)")
+	flags.Parse(args)
+	if *order != "relevance" && *order != "quality" {
+		return fmt.Errorf("--sort: want relevance or quality, got %q", *order)
+	}
+	staleOnly, err := parseStaleFilter(*stale)
+	if err != nil {
+		return fmt.Errorf("--stale: %w", err)
+	}
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	f := searchFilter{
+		Namespace:  *ns,
+		Tags:       splitList(*tags),
+		Features:   parseFeatureFilters(splitList(*features)),
+		Collection: *inColl,
+		Stale:      staleOnly,
+	}
+	var cf collectionsFile
+	if *inColl != "" {
+		if cf, err = loadCollections(*collFile); err != nil {
+			return fmt.Errorf("load collections: %w", err)
+		}
+		if _, ok := cf.Collections[*inColl]; !ok {
+			return fmt.Errorf("collection %s: %w", *inColl, registry.ErrNotFound)
+		}
+	}
+	bps := slices.DeleteFunc(slices.Clone(db.Blueprints), func(bp registry.Blueprint) bool {
+		return !f.match(db, cf, bp)
+	})
+	res := searchEntries(bps, strings.Join(flags.Args(), " "), cfg.Search)
+	if *order == "quality" {
+		sortResultsByQuality(res)
+	}
+	if *limit > 0 && len(res) > *limit {
+		res = res[:*limit]
+	}
+	if *asJSON {
+		return printJSON(res)
+	}
+	if len(res) == 0 {
+		fmt.Println(cliCatalog().translate("no matches"))
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVERSION\tSCORE\tQUALITY\tDESCRIPTION")
+	for _, r := range res {
+		desc := truncateText(r.Entry.Description, 60)
+		if r.Entry.Stale != nil {
+			desc = "[stale] " + desc
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.1f\t%s\t%s\n", r.Entry.FullName(), r.Entry.Version, r.Score, formatQuality(r.Entry), desc)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"api", "api", 0},
+		{"", "api", 3},
+		{"kubernetes", "kubernets", 1},   // deletion
+		{"kubernetes", "kubernetess", 1}, // insertion
+		{"kubernetes", "kubernates", 1},  // substitution
+		{"kubernetes", "kuberentes", 1},  // transposition
+		{"kubernetes", "kbuernetse", 2},
+		{"graphql", "grpahlq", 2},
+		{"café", "cafe", 1}, // counted in characters, not bytes
+	}
+	for _, c := range cases {
+		if got := editDistance(c.a, c.b); got != c.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := editDistance(c.b, c.a); got != c.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", c.b, c.a, got, c.want)
+		}
+	}
+}
+
+func TestWordMatch(t *testing.T) {
+	cases := []struct {
+		q, w string
+		want float64
+	}{
+		{"api", "api", matchExact},
+		{"kube", "kubernetes", matchPrefix},
+		{"k", "kubernetes", 0}, // one letter is no prefix
+		{"ern", "kubernetes", matchSubstring},
+		{"er", "kubernetes", 0}, // nor are two a substring
+		{"kubernates", "kubernetes", matchTypo},
+		{"kuberentes", "kubernetes", matchTypo},
+		{"kubr", "kubernetes", matchTypo * 0.9}, // typo in the prefix
+		{"apj", "api", 0},                       // short words need to be exact
+		{"grafana", "graphql", 0},
+		{"servise", "service", matchTypo},
+		{"srvce", "service", 0}, // two typos in a five-letter query
+	}
+	for _, c := range cases {
+		if got := wordMatch(c.q, c.w); got != c.want {
+			t.Errorf("wordMatch(%q, %q) = %v, want %v", c.q, c.w, got, c.want)
+		}
+	}
+}
+
+func TestSearchSynonyms(t *testing.T) {
+	sc := searchConfig{Synonyms: map[string][]string{
+		"k8s":  {"Kubernetes"},
+		"rest": {"api", "http"},
+	}}
+	cases := []struct {
+		term string
+		want []string
+	}{
+		{"k8s", []string{"k8s", "kubernetes"}},
+		{"kubernetes", []string{"kubernetes", "k8s"}},
+		{"api", []string{"api", "rest"}},
+		{"rest", []string{"rest", "api", "http"}},
+		{"grpc", []string{"grpc"}},
+	}
+	for _, c := range cases {
+		got := sc.expand(c.term)
+		// synonyms come from a map; only the first term's place is fixed
+		if got[0] != c.term || !sameSet(got, c.want) {
+			t.Errorf("expand(%q) = %v, want %v", c.term, got, c.want)
+		}
+	}
+
+	for _, bad := range []map[string][]string{
+		{"two words": {"x"}},
+		{"k8s": {"kube rnetes"}},
+		{"k8s": {""}},
+	} {
+		if err := (searchConfig{Synonyms: bad}).validate(); err == nil {
+			t.Errorf("validate(%v) accepted it", bad)
+		}
+	}
+}
+
+func sameSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func TestSearchEntries(t *testing.T) {
+	bps := []registry.Blueprint{
+		{Namespace: "getdragon", Name: "kubernetes-operator", Description: "An operator scaffold"},
+		{Namespace: "getdragon", Name: "api-service", Description: "A REST service", Tags: []string{"http"}},
+		{Namespace: "getdragon", Name: "helm-chart", Description: "Deploys to kubernetes clusters"},
+		{Namespace: "acme", Name: "worker", Description: "Background jobs"},
+	}
+	sc := searchConfig{Synonyms: map[string][]string{"k8s": {"kubernetes"}}}
+	names := func(res []searchResult) []string {
+		var out []string
+		for _, r := range res {
+			out = append(out, r.Entry.FullName())
+		}
+		return out
+	}
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		// a name match outranks one in the description
+		{"kubernetes", []string{"getdragon/kubernetes-operator", "getdragon/helm-chart"}},
+		{"k8s", []string{"getdragon/kubernetes-operator", "getdragon/helm-chart"}},
+		{"kuberentes", []string{"getdragon/kubernetes-operator", "getdragon/helm-chart"}},
+		{"KUBE", []string{"getdragon/kubernetes-operator", "getdragon/helm-chart"}},
+		// every word must match
+		{"kubernetes operator", []string{"getdragon/kubernetes-operator"}},
+		{"rest http", []string{"getdragon/api-service"}},
+		{"acme", []string{"acme/worker"}},
+		{"nothing-like-it", nil},
+		// no words: everything, by name
+		{"", []string{"acme/worker", "getdragon/api-service", "getdragon/helm-chart", "getdragon/kubernetes-operator"}},
+		{"  -- ", []string{"acme/worker", "getdragon/api-service", "getdragon/helm-chart", "getdragon/kubernetes-operator"}},
+	}
+	for _, c := range cases {
+		if got := names(searchEntries(bps, c.query, sc)); !slices.Equal(got, c.want) {
+			t.Errorf("search %q = %v, want %v", c.query, got, c.want)
+		}
+	}
+
+	// the word typed ranks above its synonym
+	exact := searchEntries(bps, "kubernetes", sc)[0].Score
+	syn := searchEntries(bps, "k8s", sc)[0].Score
+	typo := searchEntries(bps, "kuberentes", sc)[0].Score
+	if !(exact > syn && syn > typo) {
+		t.Errorf("scores: exact %v, synonym %v, typo %v; want them in that order", exact, syn, typo)
+	}
+}
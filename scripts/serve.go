@@ -0,0 +1,967 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// serveConfig configures the HTTP server.
+type serveConfig struct {
+	// Addr is the address to listen on. Defaults to :8080.
+	Addr string `yaml:"addr"`
+	// Audience is who the served registry is for: public (the default),
+	// internal or all, as with export. Only a deployment that
+	// authenticates clients in front of the server should widen it.
+	Audience string `yaml:"audience"`
+	// Reload is how often the registry file is checked for changes.
+	// Defaults to 30s; a negative value loads it once.
+	Reload time.Duration `yaml:"reload"`
+	Limits serveLimits   `yaml:"limits"`
+	// Tokens is a file of the API tokens that may write through the
+	// server. Without it the server is read-only.
+	Tokens string `yaml:"tokens"`
+	// Owners is the owners file deciding who may write which entries.
+	// Defaults to owners.yaml.
+	Owners string `yaml:"owners"`
+	// Usage meters the tokens' use of the API.
+	Usage usageConfig `yaml:"usage"`
+}
+
+// serveLimits protect the server from clients, whatever the proxy in
+// front of it does.
+type serveLimits struct {
+	// MaxBodyBytes caps request bodies. Defaults to 64 KiB.
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+	// MaxConcurrent caps the requests in flight; more are answered 503.
+	// Defaults to 256.
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// ReadTimeout and WriteTimeout bound reading a request and writing
+	// its response. Default to 10s and 30s.
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// Timeout bounds handling a request; one that runs over is answered
+	// 503. Defaults to 15s, inside WriteTimeout so the answer gets out.
+	Timeout time.Duration `yaml:"timeout"`
+	// Timeouts set it per route, keyed by the route's ServeMux pattern,
+	// which spans and metrics report as http.route: "GET /v1/search",
+	// "GET /v1/blueprints/{ref...}" and so on.
+	Timeouts map[string]time.Duration `yaml:"timeouts"`
+}
+
+func (s serveConfig) validate() error {
+	if _, err := audiencePrincipal(s.Audience); err != nil {
+		return fmt.Errorf("serve.audience: %w", err)
+	}
+	l := s.Limits
+	if l.MaxBodyBytes < 0 || l.MaxConcurrent < 0 || l.ReadTimeout < 0 || l.WriteTimeout < 0 || l.Timeout < 0 {
+		return errors.New("serve.limits must not be negative")
+	}
+	for route, d := range l.Timeouts {
+		if d <= 0 {
+			return fmt.Errorf("serve.limits.timeouts: %q must be positive", route)
+		}
+	}
+	if s.Usage.File != "" && s.Tokens == "" {
+		return errors.New("serve.usage: metering needs serve.tokens")
+	}
+	return s.Usage.validate()
+}
+
+func (s serveConfig) addr() string { return orDefault(s.Addr, ":8080") }
+
+func (s serveConfig) owners() string { return orDefault(s.Owners, "owners.yaml") }
+
+func (s serveConfig) reload() time.Duration { return orDefault(s.Reload, 30*time.Second) }
+
+func (l serveLimits) maxBodyBytes() int64 { return orDefault(l.MaxBodyBytes, 64<<10) }
+
+func (l serveLimits) maxConcurrent() int { return orDefault(l.MaxConcurrent, 256) }
+
+func (l serveLimits) readTimeout() time.Duration { return orDefault(l.ReadTimeout, 10*time.Second) }
+
+func (l serveLimits) writeTimeout() time.Duration { return orDefault(l.WriteTimeout, 30*time.Second) }
+
+// timeout is how long a request to route may take.
+func (l serveLimits) timeout(route string) time.Duration {
+	return orDefault(l.Timeouts[route], orDefault(l.Timeout, 15*time.Second))
+}
+
+const (
+	// defaultPageSize and maxPageSize bound listings.
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+var metricServerDuration = newHistogram("http.server.request.duration", "s", "Duration of served HTTP requests.", secondsBuckets)
+
+// registryTypes are the content types of the encodings the whole
+// registry is served in, at /registry.json, /registry.pb and so on.
+var registryTypes = map[string]string{
+	registry.FormatJSON:  "application/json; charset=utf-8",
+	registry.FormatYAML:  "application/yaml; charset=utf-8",
+	registry.FormatProto: "application/x-protobuf",
+	registry.FormatCBOR:  "application/cbor",
+}
+
+// served is what one registry is served as, rebuilt whenever it changes.
+type served struct {
+	// db is the part of the registry the audience may see.
+	db registry.Database
+	// all is the whole registry, which requests bearing a token are
+	// served their part of.
+	all         registry.Database
+	collections collectionsFile
+	profiles    profilesFile
+	// downloads totals the counted downloads per entry; nil when
+	// downloads aren't counted.
+	downloads map[string]int64
+}
+
+// server serves one registry: the live one, or a candidate in preview.
+type server struct {
+	cfg      config
+	st       *store
+	audience principal
+	cur      atomic.Pointer[served]
+	// installs counts install pings; nil when they aren't collected.
+	installs *installCounter
+	// writers authorizes writes; nil when the server is read-only.
+	writers *writeAccess
+	// usage meters the tokens' requests; nil when they aren't metered.
+	usage *usageMeter
+	// queue takes the releases GitHub's webhooks report, signed with
+	// hookSecret; nil when the server doesn't accept them.
+	queue      updateQueue
+	hookSecret string
+}
+
+func newServer(cfg config, p string) (*server, error) {
+	audience, err := audiencePrincipal(cfg.Serve.Audience)
+	if err != nil {
+		return nil, err
+	}
+	st, err := openStore(p, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("load registry: %w", err)
+	}
+	s := &server{cfg: cfg, st: st, audience: audience}
+	return s, s.refresh()
+}
+
+// refresh rebuilds what is served from the store and the files next to
+// it.
+func (s *server) refresh() error {
+	all := s.st.snapshot().Clone()
+	if s.cfg.Mirrors.Scores != "" {
+		scores, err := readMirrorScores(s.cfg.Mirrors.Scores)
+		if err != nil {
+			return fmt.Errorf("mirror scores: %w", err)
+		}
+		orderMirrors(&all, scores)
+	}
+	db := filterVisible(all, s.audience)
+	cf, err := loadCollections("collections.yaml")
+	if err != nil {
+		return fmt.Errorf("collections: %w", err)
+	}
+	pf, err := loadProfiles(s.cfg.Retention.profiles())
+	if err != nil {
+		return fmt.Errorf("profiles: %w", err)
+	}
+	var downloads map[string]int64
+	if s.cfg.Stats.Downloads != "" {
+		r, err := readRollup(s.cfg.Stats.Downloads)
+		if err != nil {
+			return fmt.Errorf("downloads: %w", err)
+		}
+		downloads = r.popularity(time.Time{})
+	}
+	s.cur.Store(&served{db: db, all: all, collections: cf, profiles: pf, downloads: downloads})
+	return nil
+}
+
+// view is the part of the registry r may see: the audience's, widened by
+// what its bearer token is granted. A token that isn't known gets the
+// audience's, as if it had none.
+func (s *server) view(w http.ResponseWriter, r *http.Request) registry.Database {
+	cur := s.cur.Load()
+	if s.writers == nil {
+		return cur.db
+	}
+	w.Header().Add("Vary", "Authorization")
+	tok, ok := s.writers.authenticate(r)
+	if !ok {
+		return cur.db
+	}
+	// not for shared caches: another client may see less
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	return filterVisible(cur.all, s.audience.union(tok.principal()))
+}
+
+// watch reloads the registry when its file changes, until ctx is done.
+// A registry that fails to load is reported and the last good one kept.
+func (s *server) watch(ctx context.Context, every time.Duration) {
+	if every < 0 || s.st.path == "-" || strings.HasPrefix(s.st.path, embeddedPrefix) {
+		return
+	}
+	var last time.Time
+	if fi, err := os.Stat(s.st.path); err == nil {
+		last = fi.ModTime()
+	}
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		fi, err := os.Stat(s.st.path)
+		if err != nil || fi.ModTime().Equal(last) {
+			continue
+		}
+		last = fi.ModTime()
+		if err := s.st.reload(); err != nil {
+			fmt.Fprintf(os.Stderr, "reload %s: %v\n", s.st.path, err)
+			continue
+		}
+		if err := s.refresh(); err != nil {
+			fmt.Fprintf(os.Stderr, "reload %s: %v\n", s.st.path, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "reloaded %s: %d entries\n", s.st.path, len(s.cur.Load().db.Blueprints))
+	}
+}
+
+// handler routes the API of the registry.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/blueprints", s.listBlueprints)
+	mux.HandleFunc("GET /v1/search", s.search)
+	mux.HandleFunc("GET /v1/blueprints/{ref...}", s.getBlueprint)
+	if s.writers != nil {
+		mux.HandleFunc("PATCH /v1/blueprints/{ref...}", s.patchBlueprint)
+	}
+	mux.HandleFunc("GET /v1/collections", s.listCollections)
+	mux.HandleFunc("GET /v1/collections/{name}", s.getCollection)
+	mux.HandleFunc("GET /v1/profiles", s.listProfiles)
+	mux.HandleFunc("GET /v1/profiles/{name}", s.getProfile)
+	mux.HandleFunc("GET /v1/stats", s.getStats)
+	mux.HandleFunc("GET /v1/embed/{ref...}", s.getEmbed)
+	for format := range registryTypes {
+		mux.HandleFunc("GET /"+registry.SiblingPath("registry.json", format), s.registryFile(format))
+	}
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("ok\n"))
+	})
+	if s.queue != nil {
+		mux.Handle("POST /v1/hooks/github", releaseHookHandler(s.queue, s.hookSecret))
+	}
+	if s.installs != nil {
+		mux.Handle("/v1/telemetry/install", installHandler(func() *registry.Database { return &s.cur.Load().db }, s.installs))
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, http.StatusNotFound, "no such endpoint")
+	})
+	if s.usage != nil {
+		mux.HandleFunc("GET /v1/me/usage", s.getMyUsage)
+		mux.HandleFunc("GET /v1/usage", s.getUsage)
+		return s.meter(withTimeouts(mux, s.cfg.Serve.Limits))
+	}
+	return withTimeouts(mux, s.cfg.Serve.Limits)
+}
+
+// withTimeouts bounds each request by its route's timeout, answering 503
+// with the usual error body when the handler runs over.
+func withTimeouts(mux *http.ServeMux, l serveLimits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+		setRoute(r.Context(), route)
+		b, _ := json.Marshal(apiError{Error: "request timed out", RequestID: correlationID(r.Context())})
+		// TimeoutHandler keeps the headers set here when it gives up,
+		// and the handler's own when it doesn't
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		http.TimeoutHandler(mux, l.timeout(route), string(b)+"\n").ServeHTTP(w, r)
+	})
+}
+
+func (s *server) listBlueprints(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, offset, err := pageParams(q.Get("limit"), q.Get("offset"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	ns, tag := q.Get("namespace"), q.Get("tag")
+	var bps []registry.Blueprint
+	for _, bp := range s.view(w, r).Blueprints {
+		if (ns == "" || bp.Namespace == ns) && (tag == "" || slices.Contains(bp.Tags, tag)) {
+			bps = append(bps, bp)
+		}
+	}
+	switch q.Get("sort") {
+	case "":
+	case "quality":
+		sortByQuality(bps)
+	default:
+		writeError(w, r, http.StatusBadRequest, "sort must be quality")
+		return
+	}
+	total := len(bps)
+	bps = page(w, r, bps, limit, offset)
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"blueprints": orEmpty(bps),
+		"total":      total,
+		"offset":     offset,
+		"limit":      limit,
+	})
+}
+
+// search ranks the entries matching q, as the search command does,
+// narrowed by namespace, tag, feature, collection and stale. tag and feature
+// may repeat; an entry must match all of them.
+func (s *server) search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, offset, err := pageParams(q.Get("limit"), q.Get("offset"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	stale, err := parseStaleFilter(q.Get("stale"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "stale: "+err.Error())
+		return
+	}
+	cur := s.cur.Load()
+	f := searchFilter{
+		Namespace:  q.Get("namespace"),
+		Tags:       q["tag"],
+		Features:   parseFeatureFilters(q["feature"]),
+		Collection: q.Get("collection"),
+		Stale:      stale,
+	}
+	if f.Collection != "" {
+		if _, ok := cur.collections.Collections[f.Collection]; !ok {
+			writeError(w, r, http.StatusNotFound, "collection "+f.Collection+" not found")
+			return
+		}
+	}
+	db := s.view(w, r)
+	bps := slices.DeleteFunc(slices.Clone(db.Blueprints), func(bp registry.Blueprint) bool {
+		return !f.match(db, cur.collections, bp)
+	})
+	res := searchEntries(bps, q.Get("q"), s.cfg.Search)
+	switch q.Get("sort") {
+	case "", "relevance":
+	case "quality":
+		sortResultsByQuality(res)
+	default:
+		writeError(w, r, http.StatusBadRequest, "sort must be relevance or quality")
+		return
+	}
+	total := len(res)
+	res = page(w, r, res, limit, offset)
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"results": orEmpty(res),
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+	})
+}
+
+// page cuts one page out of a listing, pointing a Link header at the
+// next one if there is more.
+func page[T any](w http.ResponseWriter, r *http.Request, items []T, limit, offset int) []T {
+	// an offset past the end is an empty page; it can't overflow end
+	total := len(items)
+	start := min(offset, total)
+	end := start + min(limit, total-start)
+	if end < total {
+		next := r.URL.Query()
+		next.Set("limit", strconv.Itoa(limit))
+		next.Set("offset", strconv.Itoa(end))
+		w.Header().Set("Link", "<?"+next.Encode()+">; rel=\"next\"")
+	}
+	return items[start:end]
+}
+
+// pageParams parses the limit and offset of a listing.
+func pageParams(limit, offset string) (int, int, error) {
+	l, o := defaultPageSize, 0
+	var err error
+	if limit != "" {
+		if l, err = strconv.Atoi(limit); err != nil || l < 1 || l > maxPageSize {
+			return 0, 0, fmt.Errorf("limit must be 1 to %d", maxPageSize)
+		}
+	}
+	if offset != "" {
+		if o, err = strconv.Atoi(offset); err != nil || o < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return l, o, nil
+}
+
+// getBlueprint serves everything under an entry:
+//
+//	/v1/blueprints/{name}                entry, with its README
+//	/v1/blueprints/{name}/{version}      one release of it
+//	/v1/blueprints/{name}/closure        its dependency closure
+//	/v1/blueprints/{name}/lock           a lock of the closure
+//
+// where {name} is namespace/name or a bare name, resolved as the CLI
+// resolves it. A path that names an entry both ways is taken as
+// namespaced.
+func (s *server) getBlueprint(w http.ResponseWriter, r *http.Request) {
+	db := s.view(w, r)
+	parts := strings.Split(r.PathValue("ref"), "/")
+	var bp registry.Blueprint
+	var err error = registry.ErrNotFound
+	var rest []string
+	if len(parts) >= 2 {
+		bp, err = registry.Find(db, parts[0]+"/"+parts[1])
+		rest = parts[2:]
+	}
+	if errors.Is(err, registry.ErrNotFound) {
+		bp, err = registry.Find(db, parts[0])
+		rest = parts[1:]
+	}
+	if err != nil {
+		writeLookupError(w, r, err)
+		return
+	}
+	warnings := bp.Warnings(time.Now())
+	if d := bp.Deprecation; d != nil && !d.Sunset.IsZero() {
+		w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	switch {
+	case len(rest) == 0:
+		d := blueprintDetail{Blueprint: bp}
+		if s.cfg.Details.Dir != "" {
+			d.readme = readDetail(s.cfg.Details.Dir, bp)
+		}
+		// weak: it names the entry as stored, which If-Match on PATCH
+		// takes, not these bytes, which add the README and warnings
+		if etag := s.storedETag(bp.FullName()); etag != "" {
+			w.Header().Set("ETag", "W/"+etag)
+		}
+		writeJSON(w, r, http.StatusOK, struct {
+			blueprintDetail
+			Warnings []registry.Warning `json:"warnings,omitempty"`
+		}{d, warnings})
+	case len(rest) == 1 && rest[0] == "closure":
+		c, err := dependencyClosure(db, bp.FullName())
+		if err != nil {
+			writeLookupError(w, r, err)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, c)
+	case len(rest) == 1 && rest[0] == "lock":
+		// offline: a request never makes the server download archives
+		lock, warnings, err := buildLock(r.Context(), db, bp.FullName(), true)
+		if err != nil {
+			writeLookupError(w, r, err)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, struct {
+			lockFile
+			Warnings []registry.Warning `json:"warnings,omitempty"`
+		}{lock, warnings})
+	case len(rest) == 1:
+		rel, ok := bp.Release(rest[0])
+		if !ok {
+			writeError(w, r, http.StatusNotFound, fmt.Sprintf("%s has no version %s", bp.FullName(), rest[0]))
+			return
+		}
+		writeJSON(w, r, http.StatusOK, struct {
+			Name string `json:"name"`
+			registry.Release
+			Warnings []registry.Warning `json:"warnings,omitempty"`
+		}{bp.FullName(), rel, warnings})
+	default:
+		writeError(w, r, http.StatusNotFound, "no such endpoint")
+	}
+}
+
+func (s *server) listCollections(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]any{"collections": s.cur.Load().collections.Collections})
+}
+
+func (s *server) getCollection(w http.ResponseWriter, r *http.Request) {
+	cur := s.cur.Load()
+	name := r.PathValue("name")
+	c, ok := cur.collections.Collections[name]
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "collection "+name+" not found")
+		return
+	}
+	bps, err := resolveCollection(s.view(w, r), cur.collections, name)
+	if err != nil {
+		writeLookupError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"name":        name,
+		"title":       c.Title,
+		"description": c.Description,
+		"blueprints":  orEmpty(bps),
+	})
+}
+
+func (s *server) listProfiles(w http.ResponseWriter, r *http.Request) {
+	out := map[string]string{}
+	for name, p := range s.cur.Load().profiles.Profiles {
+		out[name] = p.Description
+	}
+	writeJSON(w, r, http.StatusOK, map[string]any{"profiles": out})
+}
+
+func (s *server) getProfile(w http.ResponseWriter, r *http.Request) {
+	cur := s.cur.Load()
+	name := r.PathValue("name")
+	p, ok := cur.profiles.Profiles[name]
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "profile "+name+" not found")
+		return
+	}
+	pins, err := resolveProfile(s.view(w, r), cur.profiles, name)
+	if err != nil {
+		writeLookupError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]any{"name": name, "description": p.Description, "entries": pins})
+}
+
+func (s *server) getStats(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Stats.History == "" {
+		writeError(w, r, http.StatusNotFound, "no stats history is kept")
+		return
+	}
+	points, err := readHistory(s.cfg.Stats.History)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, "stats history unavailable")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]any{"history": orEmpty(points)})
+}
+
+// registryFile serves the whole registry in format, as the audience
+// sees it.
+func (s *server) registryFile(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := registry.Encode(registry.Normalize(s.cur.Load().db), format, format == registry.FormatJSON && s.cfg.Output.Canonical)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, "encode registry")
+			return
+		}
+		writeBody(w, r, http.StatusOK, registryTypes[format], b)
+	}
+}
+
+func orEmpty[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// writeJSON writes v with an ETag, answering 304 when the client has it.
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, "encode response")
+		return
+	}
+	writeBody(w, r, code, "application/json; charset=utf-8", append(b, '\n'))
+}
+
+// writeBody writes b, with an ETag of its digest unless the handler set
+// one, cacheable for a minute unless it said otherwise.
+func writeBody(w http.ResponseWriter, r *http.Request, code int, contentType string, b []byte) {
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		sum := sha256.Sum256(b)
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "public, max-age=60")
+	}
+	if code == http.StatusOK && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+// etagMatches compares the tags in an If-Match or If-None-Match header
+// with etag, weakly: W/ is ignored on either side.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == etag || t == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// apiError is the body of every error response.
+type apiError struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeError answers with an error, in the language the client prefers
+// if there is a catalog for it.
+func writeError(w http.ResponseWriter, r *http.Request, code int, msg string) {
+	c := catalogFor(acceptLanguages(r.Header.Get("Accept-Language"))...)
+	b, _ := json.Marshal(apiError{Error: c.translate(msg), RequestID: correlationID(r.Context())})
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Language", c.language())
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	w.Write(append(b, '\n'))
+}
+
+// writeLookupError answers an error from resolving entries.
+func writeLookupError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, registry.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, registry.ErrAmbiguous):
+		writeError(w, r, http.StatusConflict, err.Error())
+	default:
+		// unsatisfiable dependencies, unknown pins and the like
+		writeError(w, r, http.StatusUnprocessableEntity, err.Error())
+	}
+}
+
+// incomingRequestIDRe bounds the request IDs accepted from clients, which
+// end up in logs.
+var incomingRequestIDRe = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// statusWriter records the status and size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	code  int
+	bytes int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// routeKey carries where the handler that matched a request records its
+// route. The mux sets Pattern on the request it dispatches, not on the
+// one middleware holds, so middleware can't read it from there.
+type routeKey struct{}
+
+// setRoute records route, a ServeMux pattern, as the route of the request
+// ctx belongs to. The innermost mux to match has the last word.
+func setRoute(ctx context.Context, route string) {
+	if p, ok := ctx.Value(routeKey{}).(*string); ok && route != "" {
+		*p = route
+	}
+}
+
+// middleware gives every request an ID (the client's X-Request-Id if it
+// sent a sane one), a span, a metric, an access log line and the limits.
+func middleware(next http.Handler, l serveLimits) http.Handler {
+	slots := make(chan struct{}, l.maxConcurrent())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(requestIDHeader)
+		if !incomingRequestIDRe.MatchString(id) {
+			id = newCorrelationID()
+		}
+		route := "unmatched"
+		if mux, ok := next.(*http.ServeMux); ok {
+			_, p := mux.Handler(r)
+			route = orDefault(p, route)
+		}
+		ctx := context.WithValue(withCorrelationID(r.Context(), id), routeKey{}, &route)
+		w.Header().Set(requestIDHeader, id)
+		sw := &statusWriter{ResponseWriter: w}
+		r = r.WithContext(ctx)
+
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+		default:
+			w.Header().Set("Retry-After", "1")
+			writeError(sw, r, http.StatusServiceUnavailable, "too many requests in flight")
+			accessLog(r, sw, start, id)
+			return
+		}
+		r.Body = http.MaxBytesReader(sw, r.Body, l.maxBodyBytes())
+
+		ctx, sp := startSpan(ctx, "HTTP "+r.Method, spanKindServer, attrs{"http.request.method": r.Method, "url.path": r.URL.Path})
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		sp.set("http.route", route)
+		sp.set("http.response.status_code", sw.code)
+		var err error
+		if sw.code >= 500 {
+			err = errors.New(http.StatusText(sw.code))
+		}
+		sp.finish(err)
+		metricServerDuration.record(time.Since(start).Seconds(), attrs{"http.request.method": r.Method, "http.route": route, "http.response.status_code": sw.code})
+		accessLog(r, sw, start, id)
+	})
+}
+
+func accessLog(r *http.Request, sw *statusWriter, start time.Time, id string) {
+	fmt.Fprintf(os.Stderr, "%s %s %s %d %d %s %s\n", start.UTC().Format(time.RFC3339), r.Method, r.URL.RequestURI(), sw.code, sw.bytes, time.Since(start).Round(time.Microsecond), id)
+}
+
+// siteFS returns the static site to serve at /: dir, or else the site
+// embedded with the registry, if any.
+func siteFS(dir string) (fs.FS, error) {
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+		return os.DirFS(dir), nil
+	}
+	if embedded == nil {
+		return nil, nil
+	}
+	site, err := fs.Sub(embedded, "site")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fs.Stat(site, "."); err != nil {
+		return nil, nil
+	}
+	return site, nil
+}
+
+// runServe serves the registry over HTTP until interrupted.
+func runServe(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "", "address to listen on; default serve.addr or :8080")
+	siteDir := flags.String("site", "", "directory of a static site to serve at /; default the embedded one, if any")
+	preview := flags.String("preview", "", "also serve this candidate registry, for review")
+	previewPrefix := flags.String("preview-prefix", "/preview/", "path the candidate is served under")
+	previewAddr := flags.String("preview-addr", "", "serve the candidate on this address instead of under --preview-prefix")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	s, err := newServer(cfg, registryPath())
+	if err != nil {
+		return err
+	}
+	if cfg.Stats.Installs != "" {
+		if s.installs, err = openInstallCounter(cfg.Stats.Installs); err != nil {
+			return fmt.Errorf("installs: %w", err)
+		}
+	}
+	if cfg.Serve.Tokens != "" {
+		if s.st.path == "-" || strings.HasPrefix(s.st.path, embeddedPrefix) {
+			return errors.New("serve.tokens: an embedded or piped registry cannot be written")
+		}
+		if s.writers, err = loadWriteAccess(cfg); err != nil {
+			return fmt.Errorf("tokens: %w", err)
+		}
+	}
+	if env := cfg.Queue.HookSecretEnv; env != "" {
+		if s.hookSecret = os.Getenv(env); s.hookSecret == "" {
+			return fmt.Errorf("queue.hook_secret_env: $%s is not set", env)
+		}
+		if s.queue, err = cfg.Queue.open("", 0); err != nil {
+			return fmt.Errorf("open queue: %w", err)
+		}
+	}
+	if cfg.Serve.Usage.File != "" {
+		if s.usage, err = openUsageMeter(cfg.Serve.Usage); err != nil {
+			return fmt.Errorf("usage: %w", err)
+		}
+	}
+	site, err := siteFS(*siteDir)
+	if err != nil {
+		return fmt.Errorf("site: %w", err)
+	}
+	root := http.NewServeMux()
+	if api := s.handler(); site == nil {
+		root.Handle("/", api)
+	} else {
+		// the API keeps its paths; the site gets the rest
+		root.Handle("/v1/", api)
+		root.Handle("/healthz", api)
+		for format := range registryTypes {
+			root.Handle("/"+registry.SiblingPath("registry.json", format), api)
+		}
+		root.Handle("/", http.FileServerFS(site))
+	}
+	if cfg.Images.Dir != "" {
+		// images are named by their digest, so they never change
+		if u, _ := url.Parse(cfg.Images.BaseURL); strings.Trim(u.Path, "/") != "" {
+			prefix := "/" + strings.Trim(u.Path, "/")
+			files := http.StripPrefix(prefix, http.FileServer(http.Dir(cfg.Images.Dir)))
+			root.Handle(prefix+"/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := os.Stat(filepath.Join(cfg.Images.Dir, path.Base(r.URL.Path))); err == nil {
+					w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+				}
+				files.ServeHTTP(w, r)
+			}))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go s.watch(ctx, cfg.Serve.reload())
+
+	type listener struct {
+		what string
+		srv  *http.Server
+	}
+	servers := []listener{{registryPath(), newHTTPServer(cfg.Serve, orDefault(*addr, cfg.Serve.addr()), root)}}
+	if *preview != "" {
+		// a candidate is reviewed as is: not reloaded, no installs counted
+		ps, err := newServer(cfg, *preview)
+		if err != nil {
+			return fmt.Errorf("preview: %w", err)
+		}
+		if *previewAddr != "" {
+			servers = append(servers, listener{"preview of " + *preview, newHTTPServer(cfg.Serve, *previewAddr, ps.handler())})
+		} else {
+			prefix := "/" + strings.Trim(*previewPrefix, "/")
+			root.Handle(prefix+"/", http.StripPrefix(prefix, ps.handler()))
+		}
+	}
+
+	errc := make(chan error, len(servers))
+	for _, l := range servers {
+		ln, err := net.Listen("tcp", l.srv.Addr)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "serving %s on %s\n", l.what, ln.Addr())
+		go func() { errc <- l.srv.Serve(ln) }()
+	}
+	if s.installs != nil || s.usage != nil {
+		go func() {
+			t := time.NewTicker(time.Minute)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					s.flush()
+				}
+			}
+		}()
+	}
+
+	if cfg.Webhooks.Subscribers != "" {
+		// PATCH commits queue events; send them, and retry earlier ones
+		go func() {
+			t := time.NewTicker(time.Minute)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					if err := deliverWebhooks(ctx, cfg.Webhooks); err != nil {
+						fmt.Fprintf(os.Stderr, "webhooks: %v\n", err)
+					}
+				}
+			}
+		}()
+	}
+
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+	}
+	sctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, l := range servers {
+		l.srv.Shutdown(sctx)
+	}
+	s.flush()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// flush persists the install and usage counts, reporting failures.
+func (s *server) flush() {
+	if s.installs != nil {
+		if err := s.installs.flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "installs: %v\n", err)
+		}
+	}
+	if s.usage != nil {
+		if err := s.usage.flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "usage: %v\n", err)
+		}
+	}
+}
+
+func newHTTPServer(sc serveConfig, addr string, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           middleware(h, sc.Limits),
+		ReadHeaderTimeout: sc.Limits.readTimeout(),
+		ReadTimeout:       sc.Limits.readTimeout(),
+		WriteTimeout:      sc.Limits.writeTimeout(),
+		IdleTimeout:       2 * time.Minute,
+	}
+}
@@ -0,0 +1,406 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// newTestServer serves a registry of n entries, a-0 to a-<n-1>.
+func newTestServer(t *testing.T, n int) http.Handler {
+	t.Helper()
+	return serveTestRegistry(t, config{}, testEntries(n))
+}
+
+func testEntries(n int) registry.Database {
+	var db registry.Database
+	for i := range n {
+		db.Blueprints = append(db.Blueprints, registry.Blueprint{
+			Name:        fmt.Sprintf("a-%d", i),
+			Namespace:   registry.DefaultNamespace,
+			Version:     "1.0.0",
+			Description: "test entry",
+			Repo:        "github.com/getDragon-dev/dragon-blueprints",
+			DownloadURL: fmt.Sprintf("https://example.com/a-%d.zip", i),
+		})
+	}
+	return db
+}
+
+// serveTestRegistry serves db with cfg, from a registry file in a temp
+// dir.
+func serveTestRegistry(t *testing.T, cfg config, db registry.Database) http.Handler {
+	t.Helper()
+	b, err := json.Marshal(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), "registry.json")
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newServer(cfg, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Serve.Tokens != "" {
+		// as runServe does
+		if s.writers, err = loadWriteAccess(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s.handler()
+}
+
+// writeTestTokens writes a tokens file for toks, whose secrets are
+// "secret-" and their names, and returns its path.
+func writeTestTokens(t *testing.T, toks ...apiToken) string {
+	t.Helper()
+	for i, tok := range toks {
+		sum := sha256.Sum256([]byte("secret-" + tok.Name))
+		toks[i].SHA256 = hex.EncodeToString(sum[:])
+	}
+	b, err := yaml.Marshal(tokensFile{Tokens: toks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), "tokens.yaml")
+	if err := os.WriteFile(p, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPaging(t *testing.T) {
+	h := newTestServer(t, 3)
+	cases := []struct {
+		query string
+		code  int
+		names int
+		next  bool
+	}{
+		{"", http.StatusOK, 3, false},
+		{"limit=2", http.StatusOK, 2, true},
+		{"limit=2&offset=2", http.StatusOK, 1, false},
+		{"offset=3", http.StatusOK, 0, false},
+		{"offset=9223372036854775800", http.StatusOK, 0, false},
+		{"limit=1000&offset=9223372036854775807", http.StatusOK, 0, false},
+		{"offset=9223372036854775808", http.StatusBadRequest, 0, false},
+		{"offset=-1", http.StatusBadRequest, 0, false},
+		{"offset=x", http.StatusBadRequest, 0, false},
+		{"limit=0", http.StatusBadRequest, 0, false},
+		{"limit=1001", http.StatusBadRequest, 0, false},
+	}
+	for _, tc := range cases {
+		for _, ep := range []struct{ path, list string }{
+			{"/v1/blueprints?", "blueprints"},
+			{"/v1/search?q=a&", "results"},
+		} {
+			t.Run(ep.path+tc.query, func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, httptest.NewRequest("GET", ep.path+tc.query, nil))
+				if rec.Code != tc.code {
+					t.Fatalf("status %d, want %d: %s", rec.Code, tc.code, rec.Body)
+				}
+				if tc.code != http.StatusOK {
+					return
+				}
+				var body map[string]json.RawMessage
+				var list []json.RawMessage
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				if err := json.Unmarshal(body[ep.list], &list); err != nil {
+					t.Fatal(err)
+				}
+				if len(list) != tc.names {
+					t.Errorf("got %d entries, want %d", len(list), tc.names)
+				}
+				if next := rec.Header().Get("Link") != ""; next != tc.next {
+					t.Errorf("next link %v, want %v", next, tc.next)
+				}
+			})
+		}
+	}
+}
+
+func TestWithTimeouts(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /fast", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, http.StatusOK, map[string]bool{"ok": true})
+	})
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	l := serveLimits{Timeout: time.Hour, Timeouts: map[string]time.Duration{"GET /slow": 10 * time.Millisecond}}
+	h := withTimeouts(mux, l)
+	for _, tc := range []struct {
+		path string
+		code int
+	}{
+		{"/fast", http.StatusOK},
+		{"/slow", http.StatusServiceUnavailable},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tc.path, nil))
+		if rec.Code != tc.code {
+			t.Fatalf("%s: status %d, want %d", tc.path, rec.Code, tc.code)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: body is not JSON: %q", tc.path, rec.Body)
+		}
+		if tc.code != http.StatusOK && body["error"] == nil {
+			t.Errorf("%s: no error in %v", tc.path, body)
+		}
+	}
+}
+
+func TestMiddlewareRoute(t *testing.T) {
+	// spans and metrics are only kept with telemetry on
+	tel.mu.Lock()
+	on, spans := tel.on, tel.spans
+	tel.on, tel.spans = true, nil
+	tel.mu.Unlock()
+	t.Cleanup(func() {
+		tel.mu.Lock()
+		tel.on, tel.spans = on, spans
+		tel.mu.Unlock()
+	})
+
+	// the API nested under a root mux, as runServe does with a site
+	root := http.NewServeMux()
+	root.Handle("/v1/", newTestServer(t, 1))
+	root.Handle("/", http.NotFoundHandler())
+	h := middleware(root, serveLimits{})
+	for _, tc := range []struct {
+		path, route string
+	}{
+		{"/v1/blueprints/a-0", "GET /v1/blueprints/{ref...}"},
+		{"/v1/search?q=a", "GET /v1/search"},
+		{"/index.html", "/"},
+	} {
+		tel.mu.Lock()
+		tel.spans = nil
+		tel.mu.Unlock()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tc.path, nil))
+		tel.mu.Lock()
+		got := tel.spans
+		tel.mu.Unlock()
+		if len(got) != 1 {
+			t.Fatalf("%s: %d spans, want 1", tc.path, len(got))
+		}
+		if route := got[0].attrs["http.route"]; route != tc.route {
+			t.Errorf("%s: http.route %v, want %q", tc.path, route, tc.route)
+		}
+		metricServerDuration.mu.Lock()
+		var recorded bool
+		for _, p := range metricServerDuration.points {
+			recorded = recorded || p.attrs["http.route"] == tc.route
+		}
+		metricServerDuration.mu.Unlock()
+		if !recorded {
+			t.Errorf("%s: no duration recorded for %q", tc.path, tc.route)
+		}
+	}
+}
+
+func TestServeLimitsTimeout(t *testing.T) {
+	l := serveLimits{Timeouts: map[string]time.Duration{"GET /v1/search": time.Minute}}
+	for route, want := range map[string]time.Duration{
+		"GET /v1/search":     time.Minute,
+		"GET /v1/blueprints": 15 * time.Second,
+		"":                   15 * time.Second,
+	} {
+		if got := l.timeout(route); got != want {
+			t.Errorf("timeout(%q) = %v, want %v", route, got, want)
+		}
+	}
+}
+
+func TestBlueprintETag(t *testing.T) {
+	dir := t.TempDir()
+	var cfg config
+	cfg.Serve.Tokens = writeTestTokens(t, apiToken{Name: "admin", Admin: true})
+	cfg.Serve.Owners = filepath.Join(dir, "owners.yaml")
+	h := serveTestRegistry(t, cfg, testEntries(1))
+
+	do := func(method, header, value, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/v1/blueprints/a-0", strings.NewReader(body))
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		if method == http.MethodPatch {
+			r.Header.Set("Authorization", "Bearer secret-admin")
+			r.Header.Set("Content-Type", "application/merge-patch+json")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(http.MethodGet, "", "", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("GET: %d with ETag %q, want 200 with a weak ETag", w.Code, etag)
+	}
+	if w := do(http.MethodGet, "If-None-Match", etag, ""); w.Code != http.StatusNotModified {
+		t.Errorf("GET If-None-Match: %d, want 304", w.Code)
+	}
+
+	cases := []struct {
+		name    string
+		ifMatch string
+		want    int
+	}{
+		{"missing", "", http.StatusPreconditionRequired},
+		{"current", etag, http.StatusOK},
+		{"stale", etag, http.StatusPreconditionFailed},
+	}
+	for _, c := range cases {
+		w := do(http.MethodPatch, "If-Match", c.ifMatch, `{"description": "patched entry"}`)
+		if w.Code != c.want {
+			t.Errorf("PATCH with %s If-Match: %d, want %d: %s", c.name, w.Code, c.want, w.Body)
+		}
+	}
+
+	w = do(http.MethodGet, "If-None-Match", etag, "")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("GET after PATCH: %d with ETag %q, want 200 with a new ETag", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestVisibilityPerRequest(t *testing.T) {
+	db := testEntries(1)
+	db.Blueprints = append(db.Blueprints,
+		registry.Blueprint{Name: "inside", Namespace: "acme", Version: "1.0.0", Description: "test entry", Visibility: visibilityInternal},
+		registry.Blueprint{Name: "secret", Namespace: "acme", Version: "1.0.0", Description: "test entry", Visibility: visibilityPrivate},
+	)
+	var cfg config
+	cfg.Serve.Tokens = writeTestTokens(t,
+		apiToken{Name: "acme", Namespaces: []string{"acme"}},
+		apiToken{Name: "other"},
+	)
+	h := serveTestRegistry(t, cfg, db)
+
+	cases := []struct {
+		name  string
+		token string
+		want  []string
+	}{
+		{"anonymous", "", []string{"getdragon/a-0"}},
+		{"unknown token", "secret-nobody", []string{"getdragon/a-0"}},
+		{"other namespace", "secret-other", []string{"getdragon/a-0", "acme/inside"}},
+		{"granted namespace", "secret-acme", []string{"getdragon/a-0", "acme/inside", "acme/secret"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			get := func(target string) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(http.MethodGet, target, nil)
+				if c.token != "" {
+					r.Header.Set("Authorization", "Bearer "+c.token)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				return w
+			}
+
+			var list struct {
+				Blueprints []registry.Blueprint `json:"blueprints"`
+			}
+			if err := json.NewDecoder(get("/v1/blueprints").Body).Decode(&list); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, bp := range list.Blueprints {
+				names = append(names, bp.FullName())
+			}
+			if !slices.Equal(names, c.want) {
+				t.Errorf("list: %v, want %v", names, c.want)
+			}
+
+			var found struct {
+				Total int `json:"total"`
+			}
+			if err := json.NewDecoder(get("/v1/search?q=secret").Body).Decode(&found); err != nil {
+				t.Fatal(err)
+			}
+			want := slices.Contains(c.want, "acme/secret")
+			if (found.Total > 0) != want {
+				t.Errorf("search found %d for secret, want it found: %t", found.Total, want)
+			}
+
+			w := get("/v1/blueprints/acme/secret")
+			if code := map[bool]int{true: http.StatusOK, false: http.StatusNotFound}[want]; w.Code != code {
+				t.Errorf("get acme/secret: %d, want %d", w.Code, code)
+			}
+			if cc := w.Header().Get("Cache-Control"); c.token != "" && want && !strings.HasPrefix(cc, "private") {
+				t.Errorf("get acme/secret: Cache-Control %q, want private", cc)
+			}
+		})
+	}
+}
+
+func TestCollectionProfileVisibility(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	col := "collections:\n  starters:\n    title: Starters\n    blueprints: [acme/secret]\n"
+	if err := os.WriteFile("collections.yaml", []byte(col), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prof := "profiles:\n  stable:\n    pins:\n      acme/secret: 1.0.0\n"
+	if err := os.WriteFile("profiles.yaml", []byte(prof), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db := registry.Database{Blueprints: []registry.Blueprint{
+		{Name: "secret", Namespace: "acme", Version: "1.0.0", Description: "test entry", Visibility: visibilityPrivate},
+	}}
+	var cfg config
+	cfg.Serve.Tokens = writeTestTokens(t, apiToken{Name: "acme", Namespaces: []string{"acme"}})
+	h := serveTestRegistry(t, cfg, db)
+
+	for _, target := range []string{"/v1/collections/starters", "/v1/profiles/stable"} {
+		for _, c := range []struct {
+			token string
+			code  int
+		}{
+			{"", http.StatusNotFound},
+			{"secret-acme", http.StatusOK},
+		} {
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			if c.token != "" {
+				r.Header.Set("Authorization", "Bearer "+c.token)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != c.code {
+				t.Errorf("%s with %q: %d, want %d", target, c.token, w.Code, c.code)
+			}
+			if v := w.Header().Get("Vary"); !strings.Contains(v, "Authorization") {
+				t.Errorf("%s with %q: Vary %q, want Authorization", target, c.token, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// signatureConfig says how cosign signatures on release assets are
+// verified. Assets are signed with `cosign sign-blob`, which publishes a
+// <asset>.sig and, when signing keyless, a <asset>.pem certificate.
+type signatureConfig struct {
+	// Key is a cosign public key file. Without one, signatures are
+	// verified keyless against the certificate.
+	Key string `yaml:"key"`
+	// Identity is a regexp the certificate identity must match; by default
+	// a workflow of the source repo.
+	Identity string `yaml:"identity"`
+	// Issuer is the OIDC issuer of the certificate, GitHub Actions by
+	// default.
+	Issuer string `yaml:"issuer"`
+	// Cosign is the cosign binary, found on $PATH by default.
+	Cosign string `yaml:"cosign"`
+}
+
+const githubActionsIssuer = "https://token.actions.githubusercontent.com"
+
+// cosignTimeout bounds a verification, which may contact the transparency
+// log.
+const cosignTimeout = 2 * time.Minute
+
+func (s signatureConfig) validate() error {
+	if s.Identity != "" {
+		if _, err := regexp.Compile(s.Identity); err != nil {
+			return fmt.Errorf("signatures.identity: %w", err)
+		}
+	}
+	if s.Key != "" && (s.Identity != "" || s.Issuer != "") {
+		return errors.New("signatures: identity and issuer only apply without a key")
+	}
+	return nil
+}
+
+var errUnsigned = errors.New("no signature")
+
+// signatureAssets finds the companion assets cosign published for name.
+func signatureAssets(assets []registry.GitHubAsset, name string) (sig, cert *registry.GitHubAsset) {
+	for i := range assets {
+		switch assets[i].Name {
+		case name + ".sig":
+			sig = &assets[i]
+		case name + ".pem", name + ".crt":
+			cert = &assets[i]
+		}
+	}
+	return sig, cert
+}
+
+// verifySignature checks the cosign signature of a release asset with
+// the given digest. It returns errUnsigned when the release has no
+// signature for the asset.
+func verifySignature(ctx context.Context, sc signatureConfig, repo string, assets []registry.GitHubAsset, a registry.GitHubAsset, digest string) error {
+	sigAsset, certAsset := signatureAssets(assets, a.Name)
+	if sigAsset == nil {
+		return errUnsigned
+	}
+	if sc.Key == "" && certAsset == nil {
+		return fmt.Errorf("%s has no certificate and signatures.key is not set", sigAsset.Name)
+	}
+	cosign := orDefault(sc.Cosign, "cosign")
+	if _, err := exec.LookPath(cosign); err != nil {
+		return fmt.Errorf("cosign is needed to verify signatures: %w", err)
+	}
+
+	f, err := downloadVerified(ctx, a, digest)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	dir, err := os.MkdirTemp("", "dragon-sig-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	fetch := func(c *registry.GitHubAsset) (string, error) {
+		b, err := defaultClient.getLimit(ctx, c.DownloadURL(), 64<<10)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", c.Name, err)
+		}
+		p := dir + string(os.PathSeparator) + c.Name
+		return p, os.WriteFile(p, b, 0o600)
+	}
+	sigPath, err := fetch(sigAsset)
+	if err != nil {
+		return err
+	}
+	args := []string{"verify-blob", "--signature", sigPath}
+	if sc.Key != "" {
+		args = append(args, "--key", sc.Key)
+	} else {
+		certPath, err := fetch(certAsset)
+		if err != nil {
+			return err
+		}
+		identity := orDefault(sc.Identity, "^https://github.com/"+regexp.QuoteMeta(repo)+"/")
+		args = append(args, "--certificate", certPath,
+			"--certificate-identity-regexp", identity,
+			"--certificate-oidc-issuer", orDefault(sc.Issuer, githubActionsIssuer))
+	}
+	args = append(args, f.Name())
+	_, err = runCosign(ctx, cosign, args...)
+	return err
+}
+
+// downloadVerified downloads the asset again and checks it still hashes to
+// digest, so what is verified is what gets recorded. The caller removes
+// the file.
+func downloadVerified(ctx context.Context, a registry.GitHubAsset, digest string) (*os.File, error) {
+	f, _, err := downloadArchive(ctx, a.DownloadURL())
+	if err != nil {
+		return nil, err
+	}
+	fail := func(err error) (*os.File, error) {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return fail(err)
+	}
+	if sum, err := fileSHA256(f); err != nil {
+		return fail(err)
+	} else if digest != "" && sum != digest {
+		return fail(fmt.Errorf("archive changed while indexing: hashes to %s, not %s", sum, digest))
+	}
+	return f, nil
+}
+
+// runCosign runs cosign and returns its stdout.
+func runCosign(ctx context.Context, cosign string, args ...string) ([]byte, error) {
+	return runTool(ctx, "cosign", cosign, args...)
+}
+
+// runTool runs a verification tool and returns its stdout. Failures carry
+// the last line the tool printed, where it puts its error.
+func runTool(ctx context.Context, name, bin string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, cosignTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, bin, args...)
+	var out, errb bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errb
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(orDefault(errb.String(), out.String())); msg != "" {
+			return nil, fmt.Errorf("%s: %s", name, lastLine(msg))
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return out.Bytes(), nil
+}
+
+// lastLine returns the last line of s.
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
@@ -0,0 +1,282 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// snapshotConfig publishes the registry as releases of the registry repo
+// itself, so consumers have stable, versioned URLs for the index.
+type snapshotConfig struct {
+	// Repo is the owner/repo the releases are created in. Empty disables
+	// snapshots.
+	Repo string `yaml:"repo"`
+	// Every publishes a snapshot once updates have changed this many
+	// entries since the last one. 0 publishes only on demand, with the
+	// snapshot command.
+	Every int `yaml:"every"`
+	// State keeps the changes since the last snapshot, which make up its
+	// changelog. Defaults to snapshots.json.
+	State string `yaml:"state"`
+}
+
+func (s snapshotConfig) validate() error {
+	if s.Repo != "" && strings.Count(s.Repo, "/") != 1 {
+		return fmt.Errorf("snapshots.repo: want owner/repo, got %q", s.Repo)
+	}
+	if s.Every < 0 {
+		return errors.New("snapshots.every must not be negative")
+	}
+	if s.Every > 0 && s.Repo == "" {
+		return errors.New("snapshots.every needs snapshots.repo")
+	}
+	return nil
+}
+
+func (s snapshotConfig) state() string { return orDefault(s.State, "snapshots.json") }
+
+// snapshotState is what happened since the last snapshot.
+type snapshotState struct {
+	// Last is the tag of the latest snapshot release.
+	Last    string           `json:"last,omitempty"`
+	Changes []snapshotChange `json:"changes"`
+}
+
+// snapshotChange is the outcome of one update.
+type snapshotChange struct {
+	Repo    string         `json:"repo"`
+	Tag     string         `json:"tag"`
+	At      time.Time      `json:"at"`
+	Added   []changedEntry `json:"added,omitempty"`
+	Updated []changedEntry `json:"updated,omitempty"`
+	Removed []changedEntry `json:"removed,omitempty"`
+}
+
+// entries counts the entries changed since the last snapshot.
+func (st snapshotState) entries() int {
+	n := 0
+	for _, c := range st.Changes {
+		n += len(c.Added) + len(c.Updated) + len(c.Removed)
+	}
+	return n
+}
+
+func loadSnapshotState(p string) (snapshotState, error) {
+	var st snapshotState
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	if err := json.Unmarshal(b, &st); err != nil {
+		return st, fmt.Errorf("%s: %w", p, err)
+	}
+	return st, nil
+}
+
+func saveSnapshotState(p string, st snapshotState) error {
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(p); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return registry.WriteFileAtomic(p, append(b, '\n'))
+}
+
+// recordSnapshotChanges adds what an update changed to the state and
+// reports whether a snapshot is due.
+func recordSnapshotChanges(sc snapshotConfig, cs changeSet, now time.Time) (bool, error) {
+	st, err := loadSnapshotState(sc.state())
+	if err != nil {
+		return false, err
+	}
+	if len(cs.Added)+len(cs.Updated)+len(cs.Removed) > 0 {
+		// the field diffs are for reviewers, not the changelog
+		updated := make([]changedEntry, len(cs.Updated))
+		for i, e := range cs.Updated {
+			e.Fields = nil
+			updated[i] = e
+		}
+		st.Changes = append(st.Changes, snapshotChange{
+			Repo: cs.Repo, Tag: cs.Tag, At: now.UTC(),
+			Added: cs.Added, Updated: updated, Removed: cs.Removed,
+		})
+		if err := saveSnapshotState(sc.state(), st); err != nil {
+			return false, err
+		}
+	}
+	return sc.Every > 0 && st.entries() >= sc.Every, nil
+}
+
+// changelog renders the changes since the last snapshot as Markdown.
+func (st snapshotState) changelog() string {
+	var b strings.Builder
+	if st.Last != "" {
+		fmt.Fprintf(&b, "Changes since %s.\n", st.Last)
+	}
+	if len(st.Changes) == 0 {
+		b.WriteString("\nNo entries changed.\n")
+	}
+	for _, c := range st.Changes {
+		fmt.Fprintf(&b, "\n### %s %s (%s)\n\n", c.Repo, c.Tag, c.At.Format(time.DateOnly))
+		for _, e := range c.Added {
+			fmt.Fprintf(&b, "- added %s %s\n", e.Name, e.Version)
+		}
+		for _, e := range c.Updated {
+			if e.From != "" && e.From != e.Version {
+				fmt.Fprintf(&b, "- updated %s %s → %s\n", e.Name, e.From, e.Version)
+			} else {
+				fmt.Fprintf(&b, "- updated %s %s\n", e.Name, e.Version)
+			}
+		}
+		for _, e := range c.Removed {
+			if e.Reason != "" {
+				fmt.Fprintf(&b, "- removed %s %s: %s\n", e.Name, e.Version, e.Reason)
+			} else {
+				fmt.Fprintf(&b, "- removed %s %s\n", e.Name, e.Version)
+			}
+		}
+	}
+	return b.String()
+}
+
+// snapshotFiles lists the registry files at p and the signatures saveDB
+// wrote next to them.
+func snapshotFiles(p string, out outputConfig) ([]string, error) {
+	var files []string
+	for _, f := range registryFiles(p, out) {
+		if _, err := os.Stat(f); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		for _, ext := range []string{".sig", ".pem", ".pub"} {
+			if _, err := os.Stat(f + ext); err == nil {
+				files = append(files, f+ext)
+			}
+		}
+	}
+	return files, nil
+}
+
+// publishSnapshot creates a release of the registry at p with its files,
+// their checksums and the changelog attached, and starts a new changelog.
+// It returns the release's URL.
+func publishSnapshot(ctx context.Context, cfg config, p string, now time.Time) (string, error) {
+	if p == "-" || strings.HasPrefix(p, embeddedPrefix) {
+		return "", errors.New("snapshots need a registry file")
+	}
+	sc := cfg.Snapshots
+	st, err := loadSnapshotState(sc.state())
+	if err != nil {
+		return "", err
+	}
+	files, err := snapshotFiles(p, cfg.Output)
+	if err != nil {
+		return "", err
+	}
+	var sums bytes.Buffer
+	for _, f := range files {
+		fh, err := os.Open(f)
+		if err != nil {
+			return "", err
+		}
+		sum, err := fileSHA256(fh)
+		fh.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sums, "%s  %s\n", sum, filepath.Base(f))
+	}
+	changelog := st.changelog()
+
+	tag := "snapshot-" + now.UTC().Format("20060102T150405Z")
+	payload, _ := json.Marshal(map[string]any{
+		"tag_name": tag,
+		"name":     "Registry snapshot " + now.UTC().Format(time.DateOnly),
+		"body":     changelog,
+	})
+	var rel struct {
+		ghReleaseRef
+		HTMLURL string `json:"html_url"`
+	}
+	if err := defaultClient.githubJSON(ctx, "POST", defaultClient.apiURL("/repos/%s/releases", sc.Repo), bytes.NewReader(payload), "application/json", &rel); err != nil {
+		return "", fmt.Errorf("create release: %w", err)
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+		if _, err := uploadAsset(ctx, rel.UploadURL, filepath.Base(f), io.NewSectionReader(bytes.NewReader(b), 0, int64(len(b))), "application/octet-stream"); err != nil {
+			return "", fmt.Errorf("upload %s: %w", filepath.Base(f), err)
+		}
+	}
+	for name, b := range map[string][]byte{"SHA256SUMS": sums.Bytes(), "CHANGELOG.md": []byte(changelog)} {
+		if _, err := uploadAsset(ctx, rel.UploadURL, name, io.NewSectionReader(bytes.NewReader(b), 0, int64(len(b))), "text/plain"); err != nil {
+			return "", fmt.Errorf("upload %s: %w", name, err)
+		}
+	}
+	if err := saveSnapshotState(sc.state(), snapshotState{Last: tag}); err != nil {
+		return "", err
+	}
+	return rel.HTMLURL, nil
+}
+
+// runSnapshot publishes a snapshot release now, or with --if-due only
+// once snapshots.every entries have changed.
+func runSnapshot(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	ifDue := flags.Bool("if-due", false, "only publish once snapshots.every entries have changed")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if cfg.Snapshots.Repo == "" {
+		return errors.New("snapshots.repo is not configured")
+	}
+	if *ifDue {
+		st, err := loadSnapshotState(cfg.Snapshots.state())
+		if err != nil {
+			return err
+		}
+		if cfg.Snapshots.Every == 0 || st.entries() < cfg.Snapshots.Every {
+			fmt.Fprintf(os.Stderr, "%d entries changed since the last snapshot; not due\n", st.entries())
+			return nil
+		}
+	}
+	u, err := publishSnapshot(ctx, cfg, registryPath(), time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Println(u)
+	return nil
+}
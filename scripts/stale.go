@@ -0,0 +1,230 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// freshnessConfig flags entries whose source stopped releasing.
+type freshnessConfig struct {
+	// StaleAfterDays is how long a source repo may go without a release
+	// before its entries are flagged stale. 0 disables the check.
+	StaleAfterDays int `yaml:"stale_after_days"`
+	// Notify opens an issue in the source repo when its entries go
+	// stale, mentioning their maintainers.
+	Notify bool `yaml:"notify"`
+}
+
+func (f freshnessConfig) validate() error {
+	if f.StaleAfterDays < 0 {
+		return errors.New("freshness.stale_after_days must not be negative")
+	}
+	return nil
+}
+
+// localLastRelease is the newest release time the registry recorded for
+// any entry of repo.
+func localLastRelease(db registry.Database, repo string) time.Time {
+	var last time.Time
+	for _, bp := range db.Blueprints {
+		if !strings.EqualFold(bp.Repo, repo) {
+			continue
+		}
+		for _, rel := range bp.Versions() {
+			if rel.ReleasedAt.After(last) {
+				last = rel.ReleasedAt
+			}
+		}
+	}
+	return last
+}
+
+// lastReleases finds when each source repo last released: from GitHub,
+// falling back to what the registry recorded when offline or GitHub
+// doesn't know.
+func lastReleases(ctx context.Context, db registry.Database, offline bool) map[string]time.Time {
+	out := map[string]time.Time{}
+	for _, bp := range db.Blueprints {
+		if _, ok := out[bp.Repo]; ok {
+			continue
+		}
+		last := localLastRelease(db, bp.Repo)
+		if repo, err := parseRepo(bp.Repo); err == nil && !offline {
+			rel, err := latestRelease(ctx, repo)
+			switch {
+			case err != nil:
+				fmt.Fprintf(os.Stderr, "%s: %v; using the releases the registry recorded\n", repo, err)
+			case rel.PublishedAt.After(last):
+				last = rel.PublishedAt
+			}
+		}
+		out[bp.Repo] = last
+	}
+	return out
+}
+
+// staleChange is an entry whose flag changed.
+type staleChange struct {
+	Name        string
+	Repo        string
+	Stale       bool
+	LastRelease time.Time
+	Maintainers []string
+}
+
+// markStale flags the entries whose repo last released before the window
+// and clears the flag of the others. Entries with no known release time
+// are left as they are.
+func markStale(db *registry.Database, last map[string]time.Time, window time.Duration, now time.Time) []staleChange {
+	var changes []staleChange
+	for i := range db.Blueprints {
+		bp := &db.Blueprints[i]
+		when, ok := last[bp.Repo]
+		if !ok || when.IsZero() {
+			continue
+		}
+		stale := now.Sub(when) > window
+		switch {
+		case stale && bp.Stale == nil:
+			bp.Stale = &registry.Staleness{Since: now, LastRelease: when}
+		case stale:
+			// still stale; keep when it was first noticed
+			bp.Stale.LastRelease = when
+			continue
+		case bp.Stale != nil:
+			bp.Stale = nil
+		default:
+			continue
+		}
+		changes = append(changes, staleChange{Name: bp.FullName(), Repo: bp.Repo, Stale: stale, LastRelease: when, Maintainers: bp.Maintainers})
+	}
+	return changes
+}
+
+// runStale flags entries whose source repo hasn't released within
+// freshness.stale_after_days, and clears the flag once it releases again.
+// It is meant to run on a schedule.
+func runStale(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("stale", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "list the changes without writing")
+	offline := flags.Bool("offline", false, "use only the release times the registry recorded")
+	days := flags.Int("days", 0, "stale after this many days without a release; default freshness.stale_after_days")
+	notify := flags.Bool("notify", false, "open an issue in the source repo of entries that go stale; default freshness.notify")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	window := orDefault(*days, cfg.Freshness.StaleAfterDays)
+	if window <= 0 {
+		return errors.New("freshness.stale_after_days is not set")
+	}
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	last := lastReleases(ctx, *st.snapshot(), *offline)
+	now := time.Now().UTC()
+	dur := time.Duration(window) * 24 * time.Hour
+
+	probe := st.snapshot().Clone()
+	changes := markStale(&probe, last, dur, now)
+	if !*dryRun && len(changes) > 0 {
+		err := st.update(func(db *registry.Database) error {
+			changes = markStale(db, last, dur, now)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	var stale []staleChange
+	for _, c := range changes {
+		if c.Stale {
+			fmt.Printf("stale\t%s\tlast release %s\n", c.Name, c.LastRelease.Format(time.DateOnly))
+			stale = append(stale, c)
+		} else {
+			fmt.Printf("fresh\t%s\treleased %s\n", c.Name, c.LastRelease.Format(time.DateOnly))
+		}
+	}
+	n := 0
+	for _, bp := range probe.Blueprints {
+		if bp.Stale != nil {
+			n++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d of %d entries stale (no release in %d days), %d changed\n", n, len(probe.Blueprints), window, len(changes))
+
+	if *dryRun || !(*notify || cfg.Freshness.Notify) {
+		return nil
+	}
+	var errs []error
+	for repo, cs := range groupByRepo(stale) {
+		if err := openStaleIssue(ctx, repo, cs, window); err != nil {
+			errs = append(errs, fmt.Errorf("notify %s: %w", repo, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// groupByRepo groups changes by the owner/repo of their source; sources
+// that aren't GitHub repos can't be notified and are left out.
+func groupByRepo(cs []staleChange) map[string][]staleChange {
+	out := map[string][]staleChange{}
+	for _, c := range cs {
+		if repo, err := parseRepo(c.Repo); err == nil {
+			out[repo] = append(out[repo], c)
+		}
+	}
+	return out
+}
+
+// openStaleIssue tells the maintainers of repo that its entries were
+// flagged stale.
+func openStaleIssue(ctx context.Context, repo string, cs []staleChange, days int) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "This repo hasn't published a release since %s, more than %d days ago, so the dragon registry now flags its blueprints as stale:\n\n",
+		cs[0].LastRelease.Format(time.DateOnly), days)
+	var mentions []string
+	for _, c := range cs {
+		fmt.Fprintf(&body, "- %s\n", c.Name)
+		for _, m := range c.Maintainers {
+			if h := strings.TrimPrefix(m, "@"); repoOwnerRe.MatchString(h) && !slices.Contains(mentions, "@"+h) {
+				mentions = append(mentions, "@"+h)
+			}
+		}
+	}
+	body.WriteString("\nUsers searching the registry are told these may be unmaintained. The flag is cleared by the next release.\n")
+	if len(mentions) > 0 {
+		fmt.Fprintf(&body, "\ncc %s\n", strings.Join(mentions, " "))
+	}
+	payload, err := json.Marshal(map[string]any{
+		"title": "Blueprints flagged stale in the dragon registry",
+		"body":  body.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return defaultClient.githubJSON(ctx, "POST", defaultClient.apiURL("/repos/%s/issues", repo), bytes.NewReader(payload), "application/json", nil)
+}
@@ -0,0 +1,207 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// statsConfig configures the run history.
+type statsConfig struct {
+	// History is the JSON-lines file each update appends a statsPoint to.
+	// Empty disables history.
+	History string `yaml:"history"`
+	// Downloads is the JSON file `stats ingest` rolls access logs up
+	// into. Empty disables ingestion.
+	Downloads string `yaml:"downloads"`
+	// Installs is the JSON file install pings are counted in. Empty
+	// turns the install endpoint off.
+	Installs string `yaml:"installs"`
+}
+
+// statsPoint is one sample of registry statistics.
+type statsPoint struct {
+	Time       time.Time      `json:"time"`
+	Repo       string         `json:"repo,omitempty"`
+	Tag        string         `json:"tag,omitempty"`
+	Entries    int            `json:"entries"`
+	Namespaces map[string]int `json:"namespaces"`
+	Tags       map[string]int `json:"tags"`
+	// Downloads is the total download count GitHub reported for the
+	// release's blueprint assets.
+	Downloads int64 `json:"downloads"`
+}
+
+// snapshotStats counts the entries of db.
+func snapshotStats(db registry.Database) statsPoint {
+	sp := statsPoint{
+		Time:       time.Now().UTC(),
+		Entries:    len(db.Blueprints),
+		Namespaces: map[string]int{},
+		Tags:       map[string]int{},
+	}
+	for _, bp := range db.Blueprints {
+		sp.Namespaces[bp.Namespace]++
+		for _, t := range bp.Tags {
+			sp.Tags[t]++
+		}
+	}
+	return sp
+}
+
+// appendHistory adds a point to the history file, creating it if needed.
+func appendHistory(p string, sp statsPoint) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(sp)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readHistory returns the points in the history file, oldest first.
+func readHistory(p string) ([]statsPoint, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var points []statsPoint
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 4<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var sp statsPoint
+		if err := json.Unmarshal(sc.Bytes(), &sp); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", p, line, err)
+		}
+		points = append(points, sp)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	return points, nil
+}
+
+func runStats(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "ingest":
+			return runStatsIngest(args[1:])
+		case "downloads":
+			return runStatsDownloads(args[1:])
+		case "installs":
+			return runStatsInstalls(args[1:])
+		case "usage":
+			return runStatsUsage(args[1:])
+		}
+	}
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	history := flags.Bool("history", false, "show the recorded history instead of the current registry")
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if *history {
+		if cfg.Stats.History == "" {
+			return errors.New("no stats.history file configured")
+		}
+		points, err := readHistory(cfg.Stats.History)
+		if err != nil {
+			return fmt.Errorf("read history: %w", err)
+		}
+		if *asJSON {
+			return printJSON(points)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tRELEASE\tENTRIES\tNAMESPACES\tTAGS\tDOWNLOADS")
+		for _, sp := range points {
+			release := "-"
+			if sp.Repo != "" {
+				release = sp.Repo + "@" + sp.Tag
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\n", sp.Time.Format(time.DateTime), release,
+				sp.Entries, len(sp.Namespaces), len(sp.Tags), sp.Downloads)
+		}
+		return w.Flush()
+	}
+
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	sp := snapshotStats(db)
+	if *asJSON {
+		return printJSON(sp)
+	}
+	fmt.Printf("entries: %d\n", sp.Entries)
+	printCounts("namespaces", sp.Namespaces)
+	printCounts("tags", sp.Tags)
+	return nil
+}
+
+// printCounts prints counts largest first.
+func printCounts(title string, counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Printf("%s:\n", title)
+	for _, k := range keys {
+		fmt.Printf("  %-24s %d\n", k, counts[k])
+	}
+}
+
+func printJSON(v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
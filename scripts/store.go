@@ -0,0 +1,192 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// store holds the registry for concurrent use. Readers get immutable
+// snapshots; writers stage changes in a transaction that commit applies to
+// the latest registry and persists as a whole, so concurrent writers never
+// lose each other's changes and nobody observes a partial update. Commits
+// hold the registry's file lock and start from what is on disk, so this
+// holds across processes too.
+type store struct {
+	path string
+	out  outputConfig
+	// onCommit is told about every committed change, after it is
+	// persisted; nil when nothing listens.
+	onCommit func(before, after registry.Database) error
+
+	mu  sync.Mutex // serializes commits
+	cur atomic.Pointer[registry.Database]
+}
+
+// openStore loads the registry at path, to be written as cfg says.
+func openStore(path string, cfg config) (*store, error) {
+	db, err := loadDB(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &store{path: path, out: cfg.Output}
+	if cfg.Webhooks.Subscribers != "" && !strings.HasPrefix(path, embeddedPrefix) {
+		s.onCommit = cfg.Webhooks.enqueue
+	}
+	s.cur.Store(&db)
+	return s, nil
+}
+
+// reload replaces the current registry with what is on disk, for
+// readers of a file another process writes.
+func (s *store) reload() error {
+	db, err := loadDB(s.path)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur.Store(&db)
+	return nil
+}
+
+// snapshot returns the current registry. It is shared and must not be
+// modified; use a transaction to make changes.
+func (s *store) snapshot() *registry.Database {
+	return s.cur.Load()
+}
+
+// txn is a set of staged changes to a store.
+type txn struct {
+	s   *store
+	ops []func(*registry.Database) error
+}
+
+var errTxnDone = errors.New("transaction already committed")
+
+// lockWait is how long a commit waits for another process to release the
+// registry's file lock.
+var lockWait = time.Minute
+
+// onDisk reports whether the registry is a file other processes may write,
+// rather than stdin or the embedded snapshot.
+func (s *store) onDisk() bool {
+	return s.path != "-" && !strings.HasPrefix(s.path, embeddedPrefix)
+}
+
+// begin starts a transaction.
+func (s *store) begin() *txn {
+	return &txn{s: s}
+}
+
+// stage queues a change. Changes run in order at commit time, against
+// whatever the registry is then, so they should look entries up rather
+// than rely on what an earlier snapshot contained.
+func (t *txn) stage(op func(*registry.Database) error) {
+	t.ops = append(t.ops, op)
+}
+
+// pending reports how many changes are staged.
+func (t *txn) pending() int {
+	return len(t.ops)
+}
+
+// commit applies the staged changes to a copy of the latest registry,
+// persists it and publishes it. If any change fails, or the write does,
+// the store is left untouched. A failing commit hook is only reported:
+// the change is already persisted.
+func (t *txn) commit() error {
+	_, _, err := t.commitChanges()
+	return err
+}
+
+// commitChanges is commit, returning the registry the changes were
+// applied to and the result, so that diffing them shows what this
+// transaction changed and not what another writer did meanwhile. Both
+// are the current registry when nothing was staged.
+func (t *txn) commitChanges() (before, after registry.Database, err error) {
+	if t.s == nil {
+		return before, after, errTxnDone
+	}
+	s := t.s
+	t.s = nil
+	if len(t.ops) == 0 {
+		cur := *s.snapshot()
+		return cur, cur, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.cur.Load()
+	if s.onDisk() {
+		unlock, err := registry.Lock(s.path, lockWait)
+		if err != nil {
+			return before, after, err
+		}
+		defer unlock()
+		// another process may have saved since we last loaded
+		disk, err := loadDB(s.path)
+		if err != nil {
+			return before, after, err
+		}
+		prev = &disk
+	}
+	next := prev.Clone()
+	for _, op := range t.ops {
+		if err := op(&next); err != nil {
+			return before, after, err
+		}
+	}
+	if err := saveDB(s.path, next, s.out); err != nil {
+		return before, after, err
+	}
+	s.cur.Store(&next)
+	if s.onCommit != nil {
+		if err := s.onCommit(*prev, next); err != nil {
+			fmt.Fprintf(os.Stderr, "webhooks: %v\n", err)
+		}
+	}
+	return *prev, next, nil
+}
+
+// preview applies the staged changes to a copy of the latest snapshot
+// and returns it along with that snapshot, persisting and publishing
+// nothing. The transaction is done afterwards, as after a commit.
+func (t *txn) preview() (before, after registry.Database, err error) {
+	if t.s == nil {
+		return before, after, errTxnDone
+	}
+	s := t.s
+	t.s = nil
+	before = *s.snapshot()
+	after = before.Clone()
+	for _, op := range t.ops {
+		if err := op(&after); err != nil {
+			return registry.Database{}, registry.Database{}, err
+		}
+	}
+	return before, after, nil
+}
+
+// update runs a single-step transaction.
+func (s *store) update(op func(*registry.Database) error) error {
+	t := s.begin()
+	t.stage(op)
+	return t.commit()
+}
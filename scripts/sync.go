@@ -0,0 +1,187 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// syncFilter selects which entries a registry takes in, whether indexed
+// from a release or mirrored from an upstream registry. Empty fields
+// don't filter.
+type syncFilter struct {
+	// Include and Exclude are path.Match patterns on "namespace/name",
+	// e.g. "getdragon/*" or "*/k8s-*".
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+	// Tags keeps only entries carrying at least one of them.
+	Tags []string `yaml:"tags"`
+	// ExcludeTags drops entries carrying any of them.
+	ExcludeTags []string `yaml:"exclude_tags"`
+}
+
+func (f syncFilter) validate() error {
+	for _, p := range slices.Concat(f.Include, f.Exclude) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("sync: bad pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// match reports whether bp passes the filter, and if not, why.
+func (f syncFilter) match(bp registry.Blueprint) (bool, string) {
+	name := bp.FullName()
+	matchAny := func(patterns []string) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if len(f.Include) > 0 && !matchAny(f.Include) {
+		return false, "not included by name"
+	}
+	if matchAny(f.Exclude) {
+		return false, "excluded by name"
+	}
+	if len(f.Tags) > 0 && !slices.ContainsFunc(bp.Tags, func(t string) bool { return slices.Contains(f.Tags, t) }) {
+		return false, "has none of the required tags"
+	}
+	for _, t := range bp.Tags {
+		if slices.Contains(f.ExcludeTags, t) {
+			return false, "tagged " + t
+		}
+	}
+	return true, ""
+}
+
+// loadUpstream reads a registry from a URL or a local file.
+func loadUpstream(ctx context.Context, src string) (registry.Database, error) {
+	if !strings.HasPrefix(src, "https://") && !strings.HasPrefix(src, "http://") {
+		return loadDB(src)
+	}
+	var db registry.Database
+	b, err := defaultClient.get(ctx, src)
+	if err != nil {
+		return db, err
+	}
+	if err := json.Unmarshal(b, &db); err != nil {
+		return db, err
+	}
+	return db, registry.Migrate(&db)
+}
+
+// runSync mirrors the entries of an upstream registry that pass the sync
+// filter into registry.json.
+func runSync(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	from := flags.String("from", "", "upstream registry URL or file")
+	dryRun := flags.Bool("dry-run", false, "only report what would be mirrored")
+	flags.Parse(args)
+	if *from == "" {
+		return errors.New("usage: sync --from <registry URL or file>")
+	}
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	upstream, err := loadUpstream(ctx, *from)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", *from, err)
+	}
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	tx := st.begin()
+
+	var mirrored, pending int
+	for _, bp := range upstream.Blueprints {
+		if ok, why := cfg.Sync.match(bp); !ok {
+			fmt.Fprintf(os.Stderr, "%s: skipped: %s\n", bp.FullName(), why)
+			continue
+		}
+		if bp.Repo != "" {
+			repo, err := parseRepo(bp.Repo)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: skipped: %v\n", bp.FullName(), err)
+				continue
+			}
+			bp.Repo = repoID(repo)
+		}
+		if sanitizeEntry(&bp, cfg.Text) {
+			fmt.Fprintf(os.Stderr, "%s: description sanitized\n", bp.FullName())
+		}
+		problems := checkEntry(bp, cfg.Validation)
+		for _, f := range problems {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", bp.FullName(), f)
+		}
+		if hasErrors(problems) {
+			fmt.Fprintf(os.Stderr, "%s: skipped: fails validation\n", bp.FullName())
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("would mirror %s %s\n", bp.FullName(), bp.Version)
+			continue
+		}
+		if cfg.Review.Required {
+			err := writePending(cfg.Review.dir(), pendingEntry{
+				Repo:        *from,
+				SubmittedAt: time.Now().UTC(),
+				Entry:       bp,
+			})
+			if err != nil {
+				return fmt.Errorf("write pending: %w", err)
+			}
+			pending++
+			continue
+		}
+		tx.stage(func(db *registry.Database) error {
+			return upsertResolved(db, bp, cfg.Conflicts)
+		})
+		mirrored++
+	}
+	if *dryRun {
+		return nil
+	}
+	var pruned []prunedRelease
+	if tx.pending() > 0 {
+		if err := stageRetention(tx, cfg.Retention, &pruned); err != nil {
+			return err
+		}
+	}
+	if err := tx.commit(); err != nil {
+		return fmt.Errorf("update registry: %w", err)
+	}
+	for _, p := range pruned {
+		fmt.Fprintf(os.Stderr, "%s: dropped version %s (retention)\n", p.Name, p.Version)
+	}
+	fmt.Printf("mirrored %d of %d entries from %s", mirrored, len(upstream.Blueprints), *from)
+	if pending > 0 {
+		fmt.Printf(", %d await review in %s", pending, cfg.Review.dir())
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,443 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// A small OpenTelemetry-compatible tracer and meter. Spans and metrics are
+// exported as OTLP/HTTP JSON to the collector configured through the
+// standard OTEL_EXPORTER_OTLP_* variables; without an endpoint everything
+// here is a no-op. We keep this in-tree rather than pulling in the OTel SDK,
+// which would outweigh the module's other dependencies (yaml.v3 and toml)
+// many times over. Outgoing requests carry a W3C traceparent header, so a
+// traced service they reach joins the same trace.
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const instrumentationScope = "github.com/getDragon-dev/dragon-registry"
+
+// attrs are span and metric attributes. Values may be string, bool, int,
+// int64 or float64.
+type attrs map[string]any
+
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+)
+
+type span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+	attrs    attrs
+	err      error
+}
+
+type spanKey struct{}
+
+// startSpan starts a span as a child of the span in ctx, if any. The
+// returned span must be finished with finish.
+func startSpan(ctx context.Context, name string, kind int, a attrs) (context.Context, *span) {
+	if !tel.enabled() {
+		return ctx, nil
+	}
+	s := &span{name: name, kind: kind, start: time.Now(), attrs: attrs{}}
+	for k, v := range a {
+		s.attrs[k] = v
+	}
+	if parent := spanFrom(ctx); parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else if id, err := hex.DecodeString(correlationID(ctx)); err == nil && len(id) == len(s.traceID) {
+		// the first span of a run is traced under the run's ID
+		copy(s.traceID[:], id)
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// traceparent is the W3C Trace Context header of a request sent from
+// within s, naming s as its parent; "" without a span.
+func (s *span) traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return "00-" + hex.EncodeToString(s.traceID[:]) + "-" + hex.EncodeToString(s.spanID[:]) + "-01"
+}
+
+// spanFrom returns the span in ctx, or nil.
+func spanFrom(ctx context.Context) *span {
+	s, _ := ctx.Value(spanKey{}).(*span)
+	return s
+}
+
+func (s *span) set(k string, v any) {
+	if s != nil {
+		s.attrs[k] = v
+	}
+}
+
+// finish ends the span, marking it failed when err is non-nil.
+func (s *span) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	tel.mu.Lock()
+	tel.spans = append(tel.spans, s)
+	flush := len(tel.spans) >= 512
+	tel.mu.Unlock()
+	if flush {
+		go tel.flushSpans(context.Background())
+	}
+}
+
+type point struct {
+	attrs  attrs
+	count  int64
+	sum    float64
+	bucket []int64
+}
+
+type instrument struct {
+	name   string
+	desc   string
+	unit   string
+	bounds []float64 // nil for counters
+	mu     sync.Mutex
+	points map[string]*point
+}
+
+func (in *instrument) point(a attrs) *point {
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var id strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&id, "%s=%v;", k, a[k])
+	}
+	p, ok := in.points[id.String()]
+	if !ok {
+		p = &point{attrs: a, bucket: make([]int64, len(in.bounds)+1)}
+		in.points[id.String()] = p
+	}
+	return p
+}
+
+// add increments a counter.
+func (in *instrument) add(n int64, a attrs) {
+	if !tel.enabled() {
+		return
+	}
+	in.mu.Lock()
+	in.point(a).count += n
+	in.mu.Unlock()
+}
+
+// record adds an observation to a histogram.
+func (in *instrument) record(v float64, a attrs) {
+	if !tel.enabled() {
+		return
+	}
+	in.mu.Lock()
+	p := in.point(a)
+	p.count++
+	p.sum += v
+	i := sort.SearchFloat64s(in.bounds, v)
+	p.bucket[i]++
+	in.mu.Unlock()
+}
+
+var secondsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+func newCounter(name, unit, desc string) *instrument {
+	in := &instrument{name: name, unit: unit, desc: desc, points: map[string]*point{}}
+	tel.instruments = append(tel.instruments, in)
+	return in
+}
+
+func newHistogram(name, unit, desc string, bounds []float64) *instrument {
+	in := newCounter(name, unit, desc)
+	in.bounds = bounds
+	return in
+}
+
+var (
+	metricUpdateRuns     = newCounter("registry.update.runs", "{run}", "Release updates processed, by outcome.")
+	metricUpdateDuration = newHistogram("registry.update.duration", "s", "Duration of release updates.", secondsBuckets)
+	metricAssetsIndexed  = newCounter("registry.assets.indexed", "{asset}", "Release assets indexed into the registry.")
+	metricHTTPDuration   = newHistogram("http.client.request.duration", "s", "Duration of outbound HTTP requests.", secondsBuckets)
+)
+
+type telemetry struct {
+	mu          sync.Mutex
+	on          bool
+	service     string
+	tracesURL   string
+	metricsURL  string
+	headers     map[string]string
+	started     time.Time
+	spans       []*span
+	instruments []*instrument
+}
+
+var tel = &telemetry{}
+
+func (t *telemetry) enabled() bool { return t != nil && t.on }
+
+// setupTelemetry enables export when an OTLP endpoint is configured. The
+// returned function flushes pending data and must be called before exit.
+func setupTelemetry() func(context.Context) {
+	base := strings.TrimRight(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "/")
+	tel.tracesURL = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	tel.metricsURL = os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT")
+	if base != "" {
+		if tel.tracesURL == "" {
+			tel.tracesURL = base + "/v1/traces"
+		}
+		if tel.metricsURL == "" {
+			tel.metricsURL = base + "/v1/metrics"
+		}
+	}
+	if os.Getenv("OTEL_SDK_DISABLED") == "true" || (tel.tracesURL == "" && tel.metricsURL == "") {
+		return func(context.Context) {}
+	}
+
+	tel.service = os.Getenv("OTEL_SERVICE_NAME")
+	if tel.service == "" {
+		tel.service = "dragon-registry"
+	}
+	tel.headers = map[string]string{}
+	for _, kv := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			tel.headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	tel.started = time.Now()
+	tel.on = true
+
+	// long-running commands (worker) export metrics periodically
+	interval := 60 * time.Second
+	if ms, err := strconv.Atoi(os.Getenv("OTEL_METRIC_EXPORT_INTERVAL")); err == nil && ms > 0 {
+		interval = time.Duration(ms) * time.Millisecond
+	}
+	stop := make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				tel.flushSpans(context.Background())
+				tel.flushMetrics(context.Background())
+			}
+		}
+	}()
+
+	return func(ctx context.Context) {
+		close(stop)
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		tel.flushSpans(ctx)
+		tel.flushMetrics(ctx)
+	}
+}
+
+// OTLP JSON encoding; see opentelemetry-proto's JSON mapping.
+
+type otlpKV struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func otlpAttrs(a attrs) []otlpKV {
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]otlpKV, 0, len(a))
+	for _, k := range keys {
+		var v map[string]any
+		switch x := a[k].(type) {
+		case string:
+			v = map[string]any{"stringValue": x}
+		case bool:
+			v = map[string]any{"boolValue": x}
+		case int:
+			v = map[string]any{"intValue": strconv.Itoa(x)}
+		case int64:
+			v = map[string]any{"intValue": strconv.FormatInt(x, 10)}
+		case float64:
+			v = map[string]any{"doubleValue": x}
+		default:
+			v = map[string]any{"stringValue": fmt.Sprint(x)}
+		}
+		out = append(out, otlpKV{k, v})
+	}
+	return out
+}
+
+func nanos(t time.Time) string { return strconv.FormatInt(t.UnixNano(), 10) }
+
+func (t *telemetry) resource() map[string]any {
+	return map[string]any{"attributes": otlpAttrs(attrs{"service.name": t.service})}
+}
+
+func (t *telemetry) flushSpans(ctx context.Context) {
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(spans) == 0 || t.tracesURL == "" {
+		return
+	}
+
+	out := make([]map[string]any, 0, len(spans))
+	for _, s := range spans {
+		js := map[string]any{
+			"traceId":           hex.EncodeToString(s.traceID[:]),
+			"spanId":            hex.EncodeToString(s.spanID[:]),
+			"name":              s.name,
+			"kind":              s.kind,
+			"startTimeUnixNano": nanos(s.start),
+			"endTimeUnixNano":   nanos(s.end),
+			"attributes":        otlpAttrs(s.attrs),
+		}
+		if s.parentID != [8]byte{} {
+			js["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != nil {
+			js["status"] = map[string]any{"code": 2, "message": s.err.Error()}
+		}
+		out = append(out, js)
+	}
+	t.post(ctx, t.tracesURL, map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": t.resource(),
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": instrumentationScope},
+				"spans": out,
+			}},
+		}},
+	})
+}
+
+func (t *telemetry) flushMetrics(ctx context.Context) {
+	if t.metricsURL == "" {
+		return
+	}
+	now := nanos(time.Now())
+	start := nanos(t.started)
+	var metrics []any
+	for _, in := range t.instruments {
+		in.mu.Lock()
+		var dps []any
+		for _, p := range in.points {
+			dp := map[string]any{
+				"attributes":        otlpAttrs(p.attrs),
+				"startTimeUnixNano": start,
+				"timeUnixNano":      now,
+			}
+			if in.bounds == nil {
+				dp["asInt"] = strconv.FormatInt(p.count, 10)
+			} else {
+				buckets := make([]string, len(p.bucket))
+				for i, c := range p.bucket {
+					buckets[i] = strconv.FormatInt(c, 10)
+				}
+				dp["count"] = strconv.FormatInt(p.count, 10)
+				dp["sum"] = p.sum
+				dp["bucketCounts"] = buckets
+				dp["explicitBounds"] = in.bounds
+			}
+			dps = append(dps, dp)
+		}
+		in.mu.Unlock()
+		if len(dps) == 0 {
+			continue
+		}
+		m := map[string]any{"name": in.name, "unit": in.unit, "description": in.desc}
+		// cumulative temporality
+		if in.bounds == nil {
+			m["sum"] = map[string]any{"aggregationTemporality": 2, "isMonotonic": true, "dataPoints": dps}
+		} else {
+			m["histogram"] = map[string]any{"aggregationTemporality": 2, "dataPoints": dps}
+		}
+		metrics = append(metrics, m)
+	}
+	if len(metrics) == 0 {
+		return
+	}
+	t.post(ctx, t.metricsURL, map[string]any{
+		"resourceMetrics": []any{map[string]any{
+			"resource": t.resource(),
+			"scopeMetrics": []any{map[string]any{
+				"scope":   map[string]any{"name": instrumentationScope},
+				"metrics": metrics,
+			}},
+		}},
+	})
+}
+
+// post sends an export request. Telemetry must never fail the update, so
+// errors are only reported on stderr.
+func (t *telemetry) post(ctx context.Context, url string, payload any) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "otlp export:", err)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "otlp export:", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "otlp export:", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "otlp export: POST %s: %d: %s\n", url, resp.StatusCode, string(body))
+	}
+}
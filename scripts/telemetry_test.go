@@ -0,0 +1,56 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTraceparent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+	c := newHTTPClient(httpConfig{}, srv.Client())
+
+	sp := &span{}
+	copy(sp.traceID[:], "0123456789abcdef")
+	copy(sp.spanID[:], "01234567")
+	cases := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"in a span", context.WithValue(context.Background(), spanKey{}, sp), "00-30313233343536373839616263646566-3031323334353637-01"},
+		{"untraced", context.Background(), ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got = "unset"
+			req, err := http.NewRequestWithContext(tc.ctx, "GET", srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.doOnce(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got != tc.want {
+				t.Errorf("traceparent %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
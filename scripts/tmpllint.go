@@ -0,0 +1,184 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// Lint levels for templates.lint.
+const (
+	lintOff   = "off"
+	lintWarn  = "warn"
+	lintError = "error"
+)
+
+// templateRules configure linting of blueprint templates against the
+// manifest's declared parameters.
+type templateRules struct {
+	// Lint is off, warn or error. With error, entries with findings are
+	// not published.
+	Lint string `yaml:"lint"`
+	// Extensions of files treated as Go templates.
+	Extensions []string `yaml:"extensions"`
+	// Builtins are variables the dragon CLI always provides, which need no
+	// declaration.
+	Builtins []string `yaml:"builtins"`
+}
+
+func (r templateRules) validate() error {
+	switch r.Lint {
+	case lintOff, lintWarn, lintError:
+		return nil
+	}
+	return fmt.Errorf("templates.lint: want off, warn or error, got %q", r.Lint)
+}
+
+func (r templateRules) isTemplate(name string) bool {
+	for _, ext := range r.Extensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+const maxTemplateBytes = 1 << 20
+
+// templateRefs parses a template and returns the top-level variables it
+// references. Functions are not resolved, so templates using the CLI's
+// helper functions parse fine.
+func templateRefs(name, text string) ([]string, error) {
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(text, "", "", map[string]*parse.Tree{}); err != nil {
+		return nil, err
+	}
+	refs := map[string]bool{}
+	walkTemplate(tree.Root, true, refs)
+	out := make([]string, 0, len(refs))
+	for r := range refs {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// walkTemplate collects field references. rootDot is false inside range and
+// with bodies, where "." no longer is the template data.
+func walkTemplate(n parse.Node, rootDot bool, refs map[string]bool) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkTemplate(c, rootDot, refs)
+		}
+	case *parse.ActionNode:
+		walkTemplate(n.Pipe, rootDot, refs)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			walkTemplate(c, rootDot, refs)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			walkTemplate(a, rootDot, refs)
+		}
+	case *parse.ChainNode:
+		walkTemplate(n.Node, rootDot, refs)
+	case *parse.FieldNode:
+		if rootDot && len(n.Ident) > 0 {
+			refs[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		// $ is always the template data
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			refs[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		walkTemplate(n.Pipe, rootDot, refs)
+		walkTemplate(n.List, rootDot, refs)
+		walkTemplate(n.ElseList, rootDot, refs)
+	case *parse.RangeNode:
+		walkTemplate(n.Pipe, rootDot, refs)
+		walkTemplate(n.List, false, refs)
+		walkTemplate(n.ElseList, rootDot, refs)
+	case *parse.WithNode:
+		walkTemplate(n.Pipe, rootDot, refs)
+		walkTemplate(n.List, false, refs)
+		walkTemplate(n.ElseList, rootDot, refs)
+	case *parse.TemplateNode:
+		walkTemplate(n.Pipe, rootDot, refs)
+	}
+}
+
+// scanTemplates collects variable references from every template in the
+// archive, keyed by variable with the files that use it.
+func scanTemplates(info *archiveInfo, rules templateRules) (refs map[string][]string, errs []string) {
+	refs = map[string][]string{}
+	for _, f := range info.Files {
+		if !rules.isTemplate(f) {
+			continue
+		}
+		b, err := info.readFile(f, maxTemplateBytes)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", f, err))
+			continue
+		}
+		vars, err := templateRefs(path.Base(f), string(b))
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		for _, v := range vars {
+			refs[v] = append(refs[v], f)
+		}
+	}
+	return refs, errs
+}
+
+// lintTemplates cross-checks template references against the manifest's
+// parameters and returns human-readable findings.
+func lintTemplates(man registry.Manifest, scan *assetScan, rules templateRules) []string {
+	findings := slices.Clone(scan.TemplateErrors)
+	declared := map[string]bool{}
+	for _, p := range man.Parameters {
+		declared[p.Name] = true
+	}
+
+	var vars []string
+	for v := range scan.TemplateRefs {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	for _, v := range vars {
+		if !declared[v] && !slices.Contains(rules.Builtins, v) {
+			findings = append(findings, fmt.Sprintf("undeclared variable %q used in %s", v, strings.Join(scan.TemplateRefs[v], ", ")))
+		}
+	}
+	for _, p := range man.Parameters {
+		if _, used := scan.TemplateRefs[p.Name]; !used {
+			findings = append(findings, fmt.Sprintf("parameter %q is declared but never used", p.Name))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// runRemove deletes entries from the registry, recording why.
+func runRemove(args []string) error {
+	flags := flag.NewFlagSet("remove", flag.ExitOnError)
+	reason := flags.String("reason", "", "why the entries are removed (required)")
+	list := flags.Bool("list", false, "list the tombstones instead")
+	flags.Parse(args)
+
+	if *list {
+		db, err := loadDB(registryPath())
+		if err != nil {
+			return fmt.Errorf("load registry: %w", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tVERSION\tREMOVED\tREASON")
+		for _, t := range db.Metadata.Tombstones {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.Name, t.Version, t.RemovedAt.Format(time.DateOnly), t.Reason)
+		}
+		return w.Flush()
+	}
+	if flags.NArg() == 0 || *reason == "" {
+		return errors.New("usage: remove --reason <text> <namespace/name | name>...")
+	}
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	tx := st.begin()
+	now := time.Now().UTC()
+	for _, ref := range flags.Args() {
+		bp, err := registry.Find(*st.snapshot(), ref)
+		if err != nil {
+			return err
+		}
+		name := bp.FullName()
+		tx.stage(func(db *registry.Database) error {
+			t, err := registry.Remove(db, name, *reason, now)
+			if err == nil {
+				fmt.Fprintf(os.Stderr, "removed %s %s\n", t.Name, t.Version)
+			}
+			return err
+		})
+	}
+	if err := tx.commit(); err != nil {
+		return fmt.Errorf("update registry: %w", err)
+	}
+	return nil
+}
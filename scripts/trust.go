@@ -0,0 +1,139 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+)
+
+// Trust levels, most trusted first.
+const (
+	trustOfficial  = "official"
+	trustPartner   = "partner"
+	trustCommunity = "community"
+)
+
+var trustLevels = []string{trustOfficial, trustPartner, trustCommunity}
+
+// trustConfig assigns a trust level to each source repo; the level picks
+// the policy entries from that repo are held to.
+type trustConfig struct {
+	// Default applies to repos no source rule matches. Empty disables
+	// trust levels altogether.
+	Default string `yaml:"default"`
+	// Sources are checked in order; the first match wins.
+	Sources []trustSource `yaml:"sources"`
+	// Policies override the built-in policy of a level.
+	Policies map[string]trustPolicy `yaml:"policies"`
+}
+
+type trustSource struct {
+	// Match is an owner/repo, or a path.Match pattern like "acme/*".
+	Match string `yaml:"match"`
+	Level string `yaml:"level"`
+}
+
+// trustPolicy lists the checks that are mandatory for a level.
+type trustPolicy struct {
+	// Review holds entries for approval even if review.required is off.
+	Review bool `yaml:"review"`
+	// Scan requires the archive to be downloaded and pass inspection.
+	Scan bool `yaml:"scan"`
+	// License requires a detected or declared license.
+	License bool `yaml:"license"`
+	// TemplateLint raises templates.lint to at least this level.
+	TemplateLint string `yaml:"template_lint"`
+	// Signature requires assets to carry a valid cosign signature.
+	Signature bool `yaml:"signature"`
+}
+
+// defaultTrustPolicies are the policies levels get unless configured.
+var defaultTrustPolicies = map[string]trustPolicy{
+	trustOfficial:  {},
+	trustPartner:   {Scan: true, License: true},
+	trustCommunity: {Review: true, Scan: true, License: true, TemplateLint: lintWarn},
+}
+
+func validateTrustLevel(level string) error {
+	if !slices.Contains(trustLevels, level) {
+		return fmt.Errorf("unknown trust level %q: want official, partner or community", level)
+	}
+	return nil
+}
+
+func (t trustConfig) validate() error {
+	if t.Default == "" {
+		if len(t.Sources) > 0 || len(t.Policies) > 0 {
+			return fmt.Errorf("trust: sources and policies need trust.default")
+		}
+		return nil
+	}
+	if err := validateTrustLevel(t.Default); err != nil {
+		return fmt.Errorf("trust.default: %w", err)
+	}
+	for _, s := range t.Sources {
+		if _, err := path.Match(s.Match, ""); err != nil {
+			return fmt.Errorf("trust.sources: bad pattern %q: %w", s.Match, err)
+		}
+		if err := validateTrustLevel(s.Level); err != nil {
+			return fmt.Errorf("trust.sources %q: %w", s.Match, err)
+		}
+	}
+	for level, p := range t.Policies {
+		if err := validateTrustLevel(level); err != nil {
+			return fmt.Errorf("trust.policies: %w", err)
+		}
+		if p.TemplateLint != "" {
+			if err := (templateRules{Lint: p.TemplateLint}).validate(); err != nil {
+				return fmt.Errorf("trust.policies.%s: %w", level, err)
+			}
+		}
+	}
+	return nil
+}
+
+// levelOf returns the trust level of repo, or "" when trust levels are
+// not configured.
+func (t trustConfig) levelOf(repo string) string {
+	for _, s := range t.Sources {
+		if ok, _ := path.Match(s.Match, repo); ok {
+			return s.Level
+		}
+	}
+	return t.Default
+}
+
+func (t trustConfig) policy(level string) trustPolicy {
+	if p, ok := t.Policies[level]; ok {
+		return p
+	}
+	return defaultTrustPolicies[level]
+}
+
+// stricterLint returns the stricter of two lint levels.
+func stricterLint(a, b string) string {
+	order := []string{"", lintOff, lintWarn, lintError}
+	if slices.Index(order, b) > slices.Index(order, a) {
+		return b
+	}
+	return a
+}
+
+// trustAtLeast reports whether level is min or more trusted. Entries
+// without a level count as community.
+func trustAtLeast(level, min string) bool {
+	if level == "" {
+		level = trustCommunity
+	}
+	return slices.Index(trustLevels, level) <= slices.Index(trustLevels, min)
+}
@@ -0,0 +1,436 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// tufConfig makes every registry write also publish TUF metadata (root,
+// targets, snapshot and timestamp roles), so clients get rollback and
+// freshness protection on top of the index signature.
+type tufConfig struct {
+	// Dir receives the metadata, e.g. next to registry.json on the site.
+	Dir string `yaml:"dir"`
+	// Keys holds the roles' ed25519 keys as <role>.key; $TUF_<ROLE>_KEY
+	// (a PEM key) takes precedence, for CI secrets. Never publish it.
+	Keys string `yaml:"keys"`
+	// Expires overrides how long each role's metadata is valid.
+	Expires map[string]time.Duration `yaml:"expires"`
+}
+
+// tufSpecVersion is the TUF specification version the metadata follows.
+const tufSpecVersion = "1.0.31"
+
+var tufRoles = []string{"root", "targets", "snapshot", "timestamp"}
+
+// defaultTUFExpiry is how long metadata stays valid. The timestamp is the
+// shortest: re-signing it with `tuf refresh` is what proves freshness.
+var defaultTUFExpiry = map[string]time.Duration{
+	"root":      365 * 24 * time.Hour,
+	"targets":   90 * 24 * time.Hour,
+	"snapshot":  7 * 24 * time.Hour,
+	"timestamp": 24 * time.Hour,
+}
+
+func (t tufConfig) enabled() bool { return t.Dir != "" }
+
+func (t tufConfig) validate() error {
+	if !t.enabled() {
+		if t.Keys != "" || len(t.Expires) > 0 {
+			return errors.New("output.tuf: keys and expires need dir")
+		}
+		return nil
+	}
+	if t.Keys == "" {
+		return errors.New("output.tuf: keys is required")
+	}
+	for role, d := range t.Expires {
+		if !slices.Contains(tufRoles, role) {
+			return fmt.Errorf("output.tuf.expires: unknown role %q", role)
+		}
+		if d <= 0 {
+			return fmt.Errorf("output.tuf.expires.%s: must be positive", role)
+		}
+	}
+	return nil
+}
+
+func (t tufConfig) expires(role string, now time.Time) time.Time {
+	d, ok := t.Expires[role]
+	if !ok {
+		d = defaultTUFExpiry[role]
+	}
+	return now.Add(d).UTC().Truncate(time.Second)
+}
+
+// tufKey is a public key as TUF metadata lists it.
+type tufKey struct {
+	KeyType string            `json:"keytype"`
+	Scheme  string            `json:"scheme"`
+	KeyVal  map[string]string `json:"keyval"`
+}
+
+func newTUFKey(pub ed25519.PublicKey) tufKey {
+	return tufKey{KeyType: "ed25519", Scheme: "ed25519", KeyVal: map[string]string{"public": hex.EncodeToString(pub)}}
+}
+
+// id is the key ID: the SHA-256 of the key's canonical JSON.
+func (k tufKey) id() (string, error) {
+	b, err := registry.CanonicalJSON(k)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// roleKey returns the private key of role, e.g. $TUF_SNAPSHOT_KEY or
+// snapshot.key.
+func (t tufConfig) roleKey(role string) (ed25519.PrivateKey, error) {
+	b := []byte(os.Getenv("TUF_" + strings.ToUpper(strings.ReplaceAll(role, ".", "_")) + "_KEY"))
+	if len(b) == 0 {
+		var err error
+		if b, err = os.ReadFile(filepath.Join(t.Keys, role+".key")); err != nil {
+			return nil, fmt.Errorf("%s key: %w", role, err)
+		}
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s key: no PEM block", role)
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s key: %w", role, err)
+	}
+	priv, ok := k.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s key: want ed25519, got %T", role, k)
+	}
+	return priv, nil
+}
+
+type tufSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// tufEnvelope is a signed metadata file.
+type tufEnvelope struct {
+	Signed     map[string]any `json:"signed"`
+	Signatures []tufSignature `json:"signatures"`
+}
+
+// signTUF signs the canonical form of signed with each key.
+func signTUF(signed map[string]any, keys ...ed25519.PrivateKey) (tufEnvelope, error) {
+	b, err := registry.CanonicalJSON(signed)
+	if err != nil {
+		return tufEnvelope{}, err
+	}
+	env := tufEnvelope{Signed: signed, Signatures: []tufSignature{}}
+	for _, k := range keys {
+		id, err := newTUFKey(k.Public().(ed25519.PublicKey)).id()
+		if err != nil {
+			return tufEnvelope{}, err
+		}
+		env.Signatures = append(env.Signatures, tufSignature{KeyID: id, Sig: hex.EncodeToString(ed25519.Sign(k, b))})
+	}
+	return env, nil
+}
+
+// readTUF reads a role's current metadata; a missing file reads as none.
+func (t tufConfig) readTUF(role string) (map[string]any, error) {
+	b, err := os.ReadFile(filepath.Join(t.Dir, role+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var env tufEnvelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		return nil, fmt.Errorf("%s.json: %w", role, err)
+	}
+	return env.Signed, nil
+}
+
+// writeTUF signs and writes a role's metadata, returning the bytes written.
+func (t tufConfig) writeTUF(name string, signed map[string]any, keys ...ed25519.PrivateKey) ([]byte, error) {
+	env, err := signTUF(signed, keys...)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, '\n')
+	return b, registry.WriteFileAtomic(filepath.Join(t.Dir, name), b)
+}
+
+func tufVersion(signed map[string]any) int {
+	v, _ := signed["version"].(float64)
+	return int(v)
+}
+
+func tufHashes(b []byte) map[string]any {
+	sum := sha256.Sum256(b)
+	return map[string]any{"sha256": hex.EncodeToString(sum[:])}
+}
+
+// stale reports whether a role's metadata must be re-signed: it is
+// missing, or past half of its validity.
+func (t tufConfig) stale(signed map[string]any, role string, now time.Time) bool {
+	if signed == nil {
+		return true
+	}
+	s, _ := signed["expires"].(string)
+	exp, err := time.Parse(time.RFC3339, s)
+	d, ok := t.Expires[role]
+	if !ok {
+		d = defaultTUFExpiry[role]
+	}
+	return err != nil || exp.Sub(now) < d/2
+}
+
+// publish updates the metadata for the registry files at paths. Each role
+// gets a new version when what it describes changed or it nears expiry;
+// the timestamp is renewed every time. Keys are only read for the roles
+// that are signed, so a refresh needs just the timestamp key.
+func (t tufConfig) publish(paths []string, now time.Time) error {
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return err
+	}
+	keys := map[string]ed25519.PrivateKey{}
+	key := func(role string) (ed25519.PrivateKey, error) {
+		if k, ok := keys[role]; ok {
+			return k, nil
+		}
+		k, err := t.roleKey(role)
+		if err == nil {
+			keys[role] = k
+		}
+		return k, err
+	}
+
+	if err := t.publishRoot(key, paths != nil, now); err != nil {
+		return err
+	}
+
+	// targets lists the registry files with their hashes
+	targets, err := t.readTUF("targets")
+	if err != nil {
+		return err
+	}
+	files := map[string]any{}
+	if old, ok := targets["targets"].(map[string]any); ok {
+		maps.Copy(files, old)
+	}
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.Base(p)] = map[string]any{"length": len(b), "hashes": tufHashes(b)}
+	}
+	targetsVersion := tufVersion(targets)
+	if t.stale(targets, "targets", now) || !sameJSON(targets["targets"], files) {
+		k, err := key("targets")
+		if err != nil {
+			return err
+		}
+		targetsVersion++
+		_, err = t.writeTUF("targets.json", map[string]any{
+			"_type": "targets", "spec_version": tufSpecVersion, "version": targetsVersion,
+			"expires": t.expires("targets", now), "targets": files,
+		}, k)
+		if err != nil {
+			return err
+		}
+	}
+
+	// snapshot pins the targets version, so older targets can't be replayed
+	snapshot, err := t.readTUF("snapshot")
+	if err != nil {
+		return err
+	}
+	snapshotVersion := tufVersion(snapshot)
+	pinned := map[string]any{"targets.json": map[string]any{"version": targetsVersion}}
+	var snapshotBytes []byte
+	if t.stale(snapshot, "snapshot", now) || !sameJSON(snapshot["meta"], pinned) {
+		k, err := key("snapshot")
+		if err != nil {
+			return err
+		}
+		snapshotVersion++
+		if snapshotBytes, err = t.writeTUF("snapshot.json", map[string]any{
+			"_type": "snapshot", "spec_version": tufSpecVersion, "version": snapshotVersion,
+			"expires": t.expires("snapshot", now), "meta": pinned,
+		}, k); err != nil {
+			return err
+		}
+	} else if snapshotBytes, err = os.ReadFile(filepath.Join(t.Dir, "snapshot.json")); err != nil {
+		return err
+	}
+
+	// timestamp points at the snapshot; renewing it proves freshness
+	k, err := key("timestamp")
+	if err != nil {
+		return err
+	}
+	ts, err := t.readTUF("timestamp")
+	if err != nil {
+		return err
+	}
+	_, err = t.writeTUF("timestamp.json", map[string]any{
+		"_type": "timestamp", "spec_version": tufSpecVersion, "version": tufVersion(ts) + 1,
+		"expires": t.expires("timestamp", now),
+		"meta": map[string]any{"snapshot.json": map[string]any{
+			"version": snapshotVersion, "length": len(snapshotBytes), "hashes": tufHashes(snapshotBytes),
+		}},
+	}, k)
+	return err
+}
+
+// publishRoot writes a new root when it nears expiry or, if keys is set,
+// when the role keys changed. A root whose own key changed must also be signed by the
+// previous root key ($TUF_ROOT_PREVIOUS_KEY or root.previous.key), so
+// clients can follow the rotation.
+func (t tufConfig) publishRoot(key func(string) (ed25519.PrivateKey, error), keys bool, now time.Time) error {
+	root, err := t.readTUF("root")
+	if err != nil {
+		return err
+	}
+	if !keys && !t.stale(root, "root", now) {
+		return nil
+	}
+	roleKeys := map[string]any{}
+	roles := map[string]any{}
+	ids := map[string]string{}
+	for _, role := range tufRoles {
+		k, err := key(role)
+		if err != nil {
+			return err
+		}
+		pub := newTUFKey(k.Public().(ed25519.PublicKey))
+		id, err := pub.id()
+		if err != nil {
+			return err
+		}
+		ids[role] = id
+		roleKeys[id] = pub
+		roles[role] = map[string]any{"keyids": []string{id}, "threshold": 1}
+	}
+	if !t.stale(root, "root", now) && sameJSON(root["roles"], roles) {
+		return nil
+	}
+	rootKey, _ := key("root")
+	signers := []ed25519.PrivateKey{rootKey}
+	oldRoles, _ := root["roles"].(map[string]any)
+	if old, ok := oldRoles["root"].(map[string]any); ok {
+		oldIDs, _ := old["keyids"].([]any)
+		if !slices.Contains(oldIDs, any(ids["root"])) {
+			prev, err := t.roleKey("root.previous")
+			if err != nil {
+				return fmt.Errorf("the root key changed: %w", err)
+			}
+			id, _ := newTUFKey(prev.Public().(ed25519.PublicKey)).id()
+			if !slices.Contains(oldIDs, any(id)) {
+				return errors.New("the root key changed and the previous root key isn't the one root.json lists")
+			}
+			signers = append(signers, prev)
+		}
+	}
+	version := tufVersion(root) + 1
+	b, err := t.writeTUF("root.json", map[string]any{
+		"_type": "root", "spec_version": tufSpecVersion, "version": version,
+		"expires": t.expires("root", now), "consistent_snapshot": false,
+		"keys": roleKeys, "roles": roles,
+	}, signers...)
+	if err != nil {
+		return err
+	}
+	// clients walk N.root.json to follow key rotations
+	return registry.WriteFileAtomic(filepath.Join(t.Dir, fmt.Sprintf("%d.root.json", version)), b)
+}
+
+// sameJSON compares two values by their canonical JSON, so values read back
+// from a file compare equal to freshly built ones.
+func sameJSON(a, b any) bool {
+	ab, err1 := registry.CanonicalJSON(a)
+	bb, err2 := registry.CanonicalJSON(b)
+	return err1 == nil && err2 == nil && string(ab) == string(bb)
+}
+
+// runTUF manages the TUF metadata: init generates the role keys, refresh
+// renews the timestamp, which must happen more often than it expires.
+func runTUF(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: tuf init | refresh")
+	}
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	t := cfg.Output.TUF
+	if !t.enabled() {
+		return errors.New("output.tuf is not configured")
+	}
+	switch args[0] {
+	case "init":
+		if err := os.MkdirAll(t.Keys, 0o700); err != nil {
+			return err
+		}
+		for _, role := range tufRoles {
+			p := filepath.Join(t.Keys, role+".key")
+			if _, err := os.Stat(p); err == nil {
+				fmt.Fprintf(os.Stderr, "%s exists; keeping it\n", p)
+				continue
+			}
+			_, priv, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				return err
+			}
+			der, err := x509.MarshalPKCS8PrivateKey(priv)
+			if err != nil {
+				return err
+			}
+			b := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+			if err := os.WriteFile(p, b, 0o600); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "generated %s\n", p)
+		}
+		if registryPath() == "-" {
+			return nil
+		}
+		return t.publish(registryFiles(registryPath(), cfg.Output), time.Now())
+	case "refresh":
+		return t.publish(nil, time.Now())
+	}
+	return fmt.Errorf("unknown tuf command %q", args[0])
+}
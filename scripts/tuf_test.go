@@ -0,0 +1,333 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// newTestTUF returns a config with fresh keys for every role and a
+// registry file to publish.
+func newTestTUF(t *testing.T) (tufConfig, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	tc := tufConfig{Dir: filepath.Join(dir, "tuf"), Keys: filepath.Join(dir, "keys")}
+	if err := os.MkdirAll(tc.Keys, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for _, role := range tufRoles {
+		t.Setenv("TUF_"+strings.ToUpper(role)+"_KEY", "")
+		writeTestKey(t, tc, role)
+	}
+	t.Setenv("TUF_ROOT_PREVIOUS_KEY", "")
+	p := filepath.Join(dir, "registry.json")
+	if err := os.WriteFile(p, []byte(`{"schema_version":1,"blueprints":[]}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return tc, []string{p}
+}
+
+func writeTestKey(t *testing.T, tc tufConfig, role string) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(tc.Keys, role+".key"), b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readEnvelope(dir, name string) (tufEnvelope, []byte, error) {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return tufEnvelope{}, nil, err
+	}
+	var env tufEnvelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		return tufEnvelope{}, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return env, b, nil
+}
+
+// verifyRole checks that env carries a threshold of valid signatures
+// from the keys root assigns to role.
+func verifyRole(root map[string]any, role string, env tufEnvelope) error {
+	msg, err := registry.CanonicalJSON(env.Signed)
+	if err != nil {
+		return err
+	}
+	keys, _ := root["keys"].(map[string]any)
+	r, _ := root["roles"].(map[string]any)[role].(map[string]any)
+	ids, _ := r["keyids"].([]any)
+	threshold, _ := r["threshold"].(float64)
+	valid := map[string]bool{}
+	for _, s := range env.Signatures {
+		if valid[s.KeyID] || !slices.Contains(ids, any(s.KeyID)) {
+			continue
+		}
+		k, _ := keys[s.KeyID].(map[string]any)
+		kv, _ := k["keyval"].(map[string]any)
+		pub, err1 := hex.DecodeString(fmt.Sprint(kv["public"]))
+		sig, err2 := hex.DecodeString(s.Sig)
+		if err1 == nil && err2 == nil && len(pub) == ed25519.PublicKeySize && ed25519.Verify(pub, msg, sig) {
+			valid[s.KeyID] = true
+		}
+	}
+	if threshold < 1 || len(valid) < int(threshold) {
+		return fmt.Errorf("%s: %d valid signatures, threshold %v", role, len(valid), threshold)
+	}
+	return nil
+}
+
+func checkExpiry(env tufEnvelope, role string, now time.Time) error {
+	exp, err := time.Parse(time.RFC3339, fmt.Sprint(env.Signed["expires"]))
+	if err != nil {
+		return fmt.Errorf("%s: %w", role, err)
+	}
+	if !now.Before(exp) {
+		return fmt.Errorf("%s: expired at %s", role, exp)
+	}
+	return nil
+}
+
+// verifyTUF checks dir the way a client would (TUF spec §5): follow the
+// root chain from trusted, then verify timestamp, snapshot and targets
+// against the last root and each other.
+func verifyTUF(dir string, trusted map[string]any, now time.Time) error {
+	root := trusted
+	for v := tufVersion(root) + 1; ; v++ {
+		next, _, err := readEnvelope(dir, fmt.Sprintf("%d.root.json", v))
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		// a new root is signed by the keys of the old one and its own
+		if err := verifyRole(root, "root", next); err != nil {
+			return fmt.Errorf("%d.root.json by the old root: %w", v, err)
+		}
+		if err := verifyRole(next.Signed, "root", next); err != nil {
+			return fmt.Errorf("%d.root.json by itself: %w", v, err)
+		}
+		if tufVersion(next.Signed) != v {
+			return fmt.Errorf("%d.root.json has version %d", v, tufVersion(next.Signed))
+		}
+		root = next.Signed
+	}
+	if err := checkExpiry(tufEnvelope{Signed: root}, "root", now); err != nil {
+		return err
+	}
+
+	ts, _, err := readEnvelope(dir, "timestamp.json")
+	if err != nil {
+		return err
+	}
+	if err := verifyRole(root, "timestamp", ts); err != nil {
+		return err
+	}
+	if err := checkExpiry(ts, "timestamp", now); err != nil {
+		return err
+	}
+	snap, snapBytes, err := readEnvelope(dir, "snapshot.json")
+	if err != nil {
+		return err
+	}
+	pin, _ := ts.Signed["meta"].(map[string]any)["snapshot.json"].(map[string]any)
+	if !sameJSON(pin["hashes"], tufHashes(snapBytes)) || pin["length"] != float64(len(snapBytes)) {
+		return errors.New("snapshot.json doesn't match the timestamp")
+	}
+	if err := verifyRole(root, "snapshot", snap); err != nil {
+		return err
+	}
+	if err := checkExpiry(snap, "snapshot", now); err != nil {
+		return err
+	}
+	targets, _, err := readEnvelope(dir, "targets.json")
+	if err != nil {
+		return err
+	}
+	tpin, _ := snap.Signed["meta"].(map[string]any)["targets.json"].(map[string]any)
+	if tpin["version"] != float64(tufVersion(targets.Signed)) {
+		return errors.New("targets.json doesn't match the snapshot")
+	}
+	if err := verifyRole(root, "targets", targets); err != nil {
+		return err
+	}
+	return checkExpiry(targets, "targets", now)
+}
+
+// trustRoot reads version 1 of the root, as a client ships with it.
+func trustRoot(t *testing.T, tc tufConfig) map[string]any {
+	t.Helper()
+	env, _, err := readEnvelope(tc.Dir, "1.root.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env.Signed
+}
+
+func TestTUFChain(t *testing.T) {
+	now := time.Now()
+	tc, paths := newTestTUF(t)
+	if err := tc.publish(paths, now); err != nil {
+		t.Fatal(err)
+	}
+	trusted := trustRoot(t, tc)
+	if err := verifyTUF(tc.Dir, trusted, now); err != nil {
+		t.Fatalf("fresh metadata: %v", err)
+	}
+	// a refresh renews the timestamp alone and the chain still holds
+	if err := tc.publish(nil, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTUF(tc.Dir, trusted, now.Add(time.Hour)); err != nil {
+		t.Fatalf("after a refresh: %v", err)
+	}
+}
+
+func TestTUFBelowThreshold(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name   string
+		tamper func(t *testing.T, tc tufConfig, env *tufEnvelope)
+	}{
+		{"wrong key", func(t *testing.T, tc tufConfig, env *tufEnvelope) {
+			k, err := tc.roleKey("snapshot")
+			if err != nil {
+				t.Fatal(err)
+			}
+			signed, err := signTUF(env.Signed, k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			*env = signed
+		}},
+		{"no signatures", func(t *testing.T, tc tufConfig, env *tufEnvelope) {
+			env.Signatures = nil
+		}},
+		{"altered after signing", func(t *testing.T, tc tufConfig, env *tufEnvelope) {
+			env.Signed["targets"] = map[string]any{}
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tc, paths := newTestTUF(t)
+			if err := tc.publish(paths, now); err != nil {
+				t.Fatal(err)
+			}
+			env, _, err := readEnvelope(tc.Dir, "targets.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.tamper(t, tc, &env)
+			b, err := json.Marshal(env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(tc.Dir, "targets.json"), b, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err = verifyTUF(tc.Dir, trustRoot(t, tc), now)
+			if err == nil || !strings.Contains(err.Error(), "targets: 0 valid signatures") {
+				t.Errorf("verify: %v, want targets below threshold", err)
+			}
+		})
+	}
+}
+
+func TestTUFExpired(t *testing.T) {
+	now := time.Now()
+	tc, paths := newTestTUF(t)
+	// published two days ago and never refreshed
+	if err := tc.publish(paths, now.Add(-48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	trusted := trustRoot(t, tc)
+	err := verifyTUF(tc.Dir, trusted, now)
+	if err == nil || !strings.Contains(err.Error(), "timestamp: expired") {
+		t.Fatalf("verify: %v, want an expired timestamp", err)
+	}
+	// refreshing renews the timestamp, and only it
+	if err := tc.publish(nil, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTUF(tc.Dir, trusted, now); err != nil {
+		t.Fatalf("after a refresh: %v", err)
+	}
+}
+
+func TestTUFRootRotation(t *testing.T) {
+	now := time.Now()
+	tc, paths := newTestTUF(t)
+	if err := tc.publish(paths, now); err != nil {
+		t.Fatal(err)
+	}
+	trusted := trustRoot(t, tc)
+
+	// a new root key without the old one can't be published
+	old := filepath.Join(t.TempDir(), "root.key")
+	if err := os.Rename(filepath.Join(tc.Keys, "root.key"), old); err != nil {
+		t.Fatal(err)
+	}
+	writeTestKey(t, tc, "root")
+	if err := tc.publish(paths, now); err == nil || !strings.Contains(err.Error(), "root key changed") {
+		t.Fatalf("publish without root.previous.key: %v", err)
+	}
+	if err := os.Rename(old, filepath.Join(tc.Keys, "root.previous.key")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tc.publish(paths, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTUF(tc.Dir, trusted, now); err != nil {
+		t.Fatalf("rotated root: %v", err)
+	}
+
+	// the same root signed by the new key alone breaks the chain
+	env, _, err := readEnvelope(tc.Dir, "2.root.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := tc.roleKey("root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.writeTUF("2.root.json", env.Signed, k); err != nil {
+		t.Fatal(err)
+	}
+	err = verifyTUF(tc.Dir, trusted, now)
+	if err == nil || !strings.Contains(err.Error(), "by the old root") {
+		t.Errorf("verify: %v, want the chain broken", err)
+	}
+}
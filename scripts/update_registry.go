@@ -11,185 +11,898 @@
 package main
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path"
+	"slices"
 	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 
-    "gopkg.in/yaml.v3"
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
 )
 
-type Blueprint struct {
-	Name        string   `json:"name"`
-	Version     string   `json:"version"`
-	Repo        string   `json:"repo"`
-	Path        string   `json:"path"`
-	DownloadURL string   `json:"download_url"`
-	Description string   `json:"description"`
-	Tags        []string `json:"tags"`
-}
+var (
+	stdinOnce     sync.Once
+	stdinRegistry []byte
+	stdinErr      error
+)
 
-type Database struct {
-	Blueprints []Blueprint `json:"blueprints"`
+// readRegistry reads the registry file p, the embedded snapshot for an
+// "embedded:" path, or stdin for "-". Stdin is read once and shared by
+// every load.
+func readRegistry(p string) ([]byte, error) {
+	if name, ok := strings.CutPrefix(p, embeddedPrefix); ok && embedded != nil {
+		return fs.ReadFile(embedded, name)
+	}
+	if p != "-" {
+		return os.ReadFile(p)
+	}
+	stdinOnce.Do(func() {
+		stdinRegistry, stdinErr = io.ReadAll(os.Stdin)
+	})
+	if stdinErr == nil && len(bytes.TrimSpace(stdinRegistry)) == 0 {
+		return nil, os.ErrNotExist
+	}
+	return stdinRegistry, stdinErr
 }
 
-func loadDB(p string) (Database, error) {
-	var db Database
-	b, err := os.ReadFile(p)
+func loadDB(p string) (registry.Database, error) {
+	var db registry.Database
+	b, err := readRegistry(p)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return Database{Blueprints: []Blueprint{}}, nil
+			return registry.Database{SchemaVersion: registry.CurrentSchema, Blueprints: []registry.Blueprint{}}, nil
 		}
 		return db, err
 	}
-	if err := json.Unmarshal(b, &db); err != nil {
-		return db, err
+	return registry.Decode(b, registry.FormatOf(p))
+}
+
+// registryStdout receives registries saved to "-". main points it at the
+// real stdout when it moves progress output to stderr.
+var registryStdout io.Writer = os.Stdout
+
+// saveDB writes db to p, plus a copy next to it in each extra format. A p
+// of "-" writes JSON to stdout, without the copies.
+func saveDB(p string, db registry.Database, out outputConfig) error {
+	if p == "-" {
+		b, err := registry.Encode(registry.Normalize(db), registry.FormatJSON, out.Canonical)
+		if err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(b, []byte("\n")) {
+			b = append(b, '\n')
+		}
+		_, err = registryStdout.Write(b)
+		return err
 	}
-	// ensure non-nil slice to avoid "null"
-	if db.Blueprints == nil {
-		db.Blueprints = []Blueprint{}
+	if strings.HasPrefix(p, embeddedPrefix) && embedded != nil {
+		return errors.New("the embedded registry is read-only; use --registry-path")
+	}
+	if err := registry.Save(p, db, registry.SaveOptions{Canonical: out.Canonical, Formats: out.Formats}); err != nil {
+		return err
 	}
-	return db, nil
+	if out.Sign.enabled() {
+		for _, f := range registryFiles(p, out) {
+			if err := out.Sign.sign(context.Background(), f); err != nil {
+				return err
+			}
+		}
+	}
+	if out.TUF.enabled() {
+		if err := out.TUF.publish(registryFiles(p, out), time.Now()); err != nil {
+			return fmt.Errorf("tuf: %w", err)
+		}
+	}
+	return nil
 }
 
-func saveDB(p string, db Database) error {
-	if db.Blueprints == nil {
-		db.Blueprints = []Blueprint{}
+func main() {
+	ctx := withCorrelationID(context.Background(), runCorrelationID())
+	shutdown := setupTelemetry()
+
+	// Global flags come before the command
+	global := flag.NewFlagSet("dragon-registry", flag.ExitOnError)
+	global.StringVar(&registryFlag, "registry-path", "", "registry to read and write (.json, .yaml, .pb or .cbor; - for stdin/stdout); default $REGISTRY_PATH or registry.json")
+	global.Usage = usage(global)
+	global.Parse(os.Args[1:])
+
+	// Without a subcommand we keep the original behaviour of updating the
+	// registry from TAG/BLUEPRINTS_REPO, which is what the workflow runs.
+	cmd, args := "update", global.Args()
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	// A broken config is reported by the command itself
+	if cfg, err := loadConfig(configPath()); err == nil {
+		defaultClient = newHTTPClient(cfg.HTTP, http.DefaultClient)
+	}
+	// Commands that write the registry to stdout report on stderr
+	switch cmd {
+	case "update", "add", "worker", "sync", "approve", "prune", "remove", "deprecate", "matrix-test", "backfill-versions", "stale", "images":
+		if registryPath() == "-" {
+			registryStdout, os.Stdout = os.Stdout, os.Stderr
+		}
+	}
+
+	var err error
+	switch cmd {
+	case "update", "add":
+		err = runUpdate(ctx, args)
+	case "digest":
+		err = runDigest(ctx, args)
+	case "enqueue":
+		err = runEnqueue(ctx, args)
+	case "worker":
+		err = runWorker(ctx, args)
+	case "resolve":
+		err = runResolve(args)
+	case "remove":
+		err = runRemove(args)
+	case "deprecate":
+		err = runDeprecate(args)
+	case "versions":
+		err = runVersions(args)
+	case "tuf":
+		err = runTUF(args)
+	case "sign":
+		err = runSign(ctx, args)
+	case "loadtest":
+		err = runLoadtest(ctx, args)
+	case "export":
+		err = runExport(args)
+	case "owners":
+		err = runOwners(args)
+	case "stats":
+		err = runStats(args)
+	case "healthcheck":
+		err = runHealthcheck(ctx, args)
+	case "mirrors":
+		err = runMirrors(args)
+	case "search":
+		err = runSearch(args)
+	case "details":
+		err = runDetails(ctx, args)
+	case "prune":
+		err = runPrune(ctx, args)
+	case "collections":
+		err = runCollections(args)
+	case "login":
+		err = runLogin(ctx, args)
+	case "logout":
+		err = runLogout(args)
+	case "canonical":
+		err = runCanonical(args)
+	case "convert":
+		err = runConvert(args)
+	case "features":
+		err = runFeatures(args)
+	case "pending":
+		err = runPending(args)
+	case "approve":
+		err = runApprove(args)
+	case "reject":
+		err = runReject(args)
+	case "sync":
+		err = runSync(ctx, args)
+	case "deps":
+		err = runDeps(args)
+	case "lock":
+		err = runLock(ctx, args)
+	case "profile":
+		err = runProfile(args)
+	case "validate":
+		err = runValidate(args)
+	case "validate-history":
+		err = runValidateHistory(args)
+	case "backfill-versions":
+		err = runBackfillVersions(args)
+	case "manifest":
+		err = runManifest(args)
+	case "lint-manifest":
+		err = runLintManifest(args)
+	case "list":
+		err = runList(args)
+	case "snapshot":
+		err = runSnapshot(ctx, args)
+	case "serve":
+		err = runServe(ctx, args)
+	case "verify":
+		err = runVerify(ctx, args)
+	case "discover":
+		err = runDiscover(ctx, args)
+	case "stale":
+		err = runStale(ctx, args)
+	case "images":
+		err = runImages(ctx, args)
+	case "matrix-test":
+		err = runMatrixTest(ctx, args)
+	case "webhooks":
+		err = runWebhooks(ctx, args)
+	case "help":
+		global.Usage()
+	default:
+		fmt.Fprintln(os.Stderr, cliCatalog().sprintf("unknown command %q", cmd))
+		global.Usage()
+		os.Exit(2)
 	}
-    for i := range db.Blueprints {
-        if db.Blueprints[i].Tags == nil {
-            db.Blueprints[i].Tags = []string{}
-        }
-    }
-	b, err := json.MarshalIndent(db, "", "  ")
+	shutdown(ctx)
 	if err != nil {
-		return err
+		cli := cliCatalog()
+		fmt.Fprintf(os.Stderr, "%s\n%s\n", cli.translate(err.Error()), cli.sprintf("run %s", correlationID(ctx)))
+		os.Exit(1)
 	}
-	return os.WriteFile(p, b, 0o644)
 }
 
-type ghRelease struct {
-	TagName string `json:"tag_name"`
-	Assets  []struct {
-		Name               string `json:"name"`
-		BrowserDownloadURL string `json:"browser_download_url"`
-	} `json:"assets"`
+// commands is what help lists, in the order a maintainer meets them.
+var commands = []struct{ name, summary string }{
+	{"update", "index a release; the default command"},
+	{"add", "index a release: add <repo> <tag>"},
+	{"remove", "remove an entry, leaving a tombstone"},
+	{"deprecate", "mark an entry deprecated"},
+	{"list", "list the entries"},
+	{"search", "search the entries"},
+	{"resolve", "print the entry a reference resolves to"},
+	{"versions", "list the releases of an entry"},
+	{"verify", "check entries' archives against their digests"},
+	{"matrix-test", "test entries against dragon CLI versions"},
+	{"validate", "check the registry against the rules"},
+	{"validate-history", "check every revision of the registry"},
+	{"backfill-versions", "restore previous releases from the registry's history"},
+	{"sync", "merge entries from an upstream registry"},
+	{"manifest", "write a new manifest: manifest init"},
+	{"lint-manifest", "check manifests before publishing them"},
+	{"discover", "find blueprint repos in the org"},
+	{"enqueue", "queue a release to index"},
+	{"worker", "index queued releases"},
+	{"pending", "list candidates awaiting review"},
+	{"approve", "admit a reviewed candidate"},
+	{"reject", "drop a reviewed candidate"},
+	{"prune", "apply the retention rules"},
+	{"stale", "flag entries whose source stopped releasing"},
+	{"images", "re-host and optimize the entries' icons and screenshots"},
+	{"deps", "print the dependency closure of an entry"},
+	{"lock", "write a dragon-lock.json"},
+	{"profile", "list or resolve profiles"},
+	{"collections", "list collections or their entries"},
+	{"features", "count or filter by features"},
+	{"details", "rewrite the detail documents"},
+	{"owners", "print the owners of entries"},
+	{"export", "write the registry as an audience sees it"},
+	{"convert", "rewrite the registry in another encoding"},
+	{"canonical", "print the canonical JSON of the registry"},
+	{"sign", "sign the registry files"},
+	{"snapshot", "publish the registry as a release"},
+	{"tuf", "manage the TUF metadata"},
+	{"digest", "render a digest of new and updated entries"},
+	{"webhooks", "manage subscribers and deliver events"},
+	{"stats", "print download statistics"},
+	{"serve", "serve the registry over HTTP"},
+	{"healthcheck", "probe download URLs and mirrors"},
+	{"mirrors", "print mirror scores"},
+	{"loadtest", "load test a registry endpoint"},
+	{"login", "store a token for maintainer commands"},
+	{"logout", "forget the stored token"},
 }
 
-type bpManifest struct {
-	Name        string   `yaml:"name"`
-	Version     string   `yaml:"version"`
-	Description string   `yaml:"description"`
-	Tags        []string `yaml:"tags"`
+func usage(global *flag.FlagSet) func() {
+	return func() {
+		out := global.Output()
+		cli := cliCatalog()
+		fmt.Fprintf(out, "%s\n\n%s\n", cli.sprintf("usage: %s [--registry-path path] <command> [flags]", global.Name()), cli.translate("commands:"))
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		for _, c := range commands {
+			fmt.Fprintf(tw, "  %s\t%s\n", c.name, cli.translate(c.summary))
+		}
+		tw.Flush()
+		fmt.Fprintf(out, "\n%s\n", cli.translate("flags:"))
+		global.PrintDefaults()
+		fmt.Fprintf(out, "\n%s\n", cli.sprintf("run %s <command> -h for the flags of a command", global.Name()))
+	}
 }
 
-func httpGet(ctx context.Context, url string) ([]byte, error) {
-	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if tok := os.Getenv("GITHUB_TOKEN"); tok != "" {
-		req.Header.Set("Authorization", "Bearer "+tok)
+func runUpdate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("update", flag.ExitOnError)
+	changes := flags.String("changes", os.Getenv("CHANGESET"), "write the change set as JSON to this file, - for stdout")
+	// e.g. getDragon-dev/dragon-blueprints or its clone URL
+	repo := flags.String("repo", os.Getenv("BLUEPRINTS_REPO"), "source repo; default $BLUEPRINTS_REPO")
+	tag := flags.String("tag", os.Getenv("TAG"), "release tag; default $TAG")
+	strict := flags.Bool("strict", false, "fail manifests with unknown fields; default manifests.strict")
+	dryRun := flags.Bool("dry-run", false, "index the release but print a diff instead of writing anything")
+	allReleases := flags.Bool("all-releases", false, "index every release of the repo, oldest first, instead of one tag")
+	prFlag := flags.String("pr", os.Getenv("REGISTRY_PR"), "post the diff as a comment on this pull request (owner/repo#123 or its URL), kept up to date; default $REGISTRY_PR")
+	sources := flags.Bool("sources", false, "index the latest release of every repo in the sources file, or every release with --all-releases")
+	flags.Parse(args)
+
+	const usage = "usage: update|add [--changes file] [--strict] [--dry-run] [--repo repo --tag tag | <repo> <tag> | --all-releases [<repo>] | --sources [--all-releases]]"
+	// add takes them as arguments: add <repo> <tag>
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		if !*allReleases {
+			return errors.New(usage)
+		}
+		*repo = flags.Arg(0)
+	case 2:
+		*repo, *tag = flags.Arg(0), flags.Arg(1)
+	default:
+		return errors.New(usage)
+	}
+	explicit := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	switch {
+	case *sources && (flags.NArg() > 0 || explicit["repo"] || explicit["tag"]):
+		return errors.New("--sources takes no repo or tag")
+	case *sources:
+	case *allReleases && *repo == "":
+		return errors.New("missing --repo (or BLUEPRINTS_REPO env)")
+	case *allReleases && flags.NArg() == 2:
+		return errors.New("--all-releases takes no tag")
+	case !*allReleases && (*tag == "" || *repo == ""):
+		return errors.New("missing --repo and --tag (or BLUEPRINTS_REPO and TAG env)")
+	}
+	var pr prRef
+	if *prFlag != "" {
+		var err error
+		if pr, err = parsePR(*prFlag); err != nil {
+			return fmt.Errorf("--pr: %w", err)
+		}
+	}
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	cfg.Manifests.Strict = cfg.Manifests.Strict || *strict
+	if *changes == "-" {
+		if registryPath() == "-" {
+			return errors.New("the registry and the change set can't both go to stdout")
+		}
+		// keep stdout for the change set
+		defer func(stdout *os.File) { os.Stdout = stdout }(os.Stdout)
+		os.Stdout = os.Stderr
+	}
+	var cs changeSet
+	switch {
+	case *sources:
+		cs, err = updateSources(ctx, cfg, *allReleases, *dryRun)
+	case *allReleases:
+		cs, err = updateAllReleases(ctx, cfg, *repo, *dryRun)
+	default:
+		cs, err = updateRegistry(ctx, cfg, *repo, *tag, *dryRun)
+	}
+	if pr.Number > 0 {
+		// the author hears about a failed run too
+		if cerr := commentOnPR(ctx, pr, cs, err); cerr != nil {
+			fmt.Fprintf(os.Stderr, "comment on %s: %v\n", pr, cerr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		if err := writeDiff(os.Stdout, cs); err != nil {
+			return err
+		}
+	}
+	if *changes == "" {
+		return nil
 	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	resp, err := http.DefaultClient.Do(req)
+	if *changes == "-" {
+		return writeChangeSet(registryStdout, cs)
+	}
+	f, err := os.Create(*changes)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode/100 != 2 {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("GET %s: %d: %s", url, resp.StatusCode, string(b))
+	if err := writeChangeSet(f, cs); err != nil {
+		f.Close()
+		return err
 	}
-	return io.ReadAll(resp.Body)
+	return f.Close()
 }
 
-func main() {
-	ctx := context.Background()
-	tag := os.Getenv("TAG")
-	repo := os.Getenv("BLUEPRINTS_REPO") // e.g. getDragon-dev/dragon-blueprints
-	if tag == "" || repo == "" {
-		fmt.Fprintln(os.Stderr, "missing TAG or BLUEPRINTS_REPO env")
-		os.Exit(1)
+// updateRegistry indexes the blueprints released under tag in repo and
+// reports what changed. A dry run goes through the same steps but leaves
+// the registry, the review queue and everything derived from them alone,
+// and uploads nothing.
+func updateRegistry(ctx context.Context, cfg config, repo, tag string, dryRun bool) (cs changeSet, err error) {
+	ctx, sp := startSpan(ctx, "update release", spanKindInternal, attrs{"repo": repo, "tag": tag})
+	start := time.Now()
+	defer func() {
+		outcome := "ok"
+		if err != nil {
+			outcome = "error"
+		}
+		sp.finish(err)
+		metricUpdateRuns.add(1, attrs{"repo": repo, "outcome": outcome})
+		metricUpdateDuration.record(time.Since(start).Seconds(), attrs{"repo": repo})
+	}()
+
+	cs = newChangeSet(repo, tag)
+	cs.RunID = correlationID(ctx)
+	cs.DryRun = dryRun
+	sp.set("run.id", cs.RunID)
+	fmt.Fprintf(os.Stderr, "update %s@%s: run %s\n", repo, tag, cs.RunID)
+	repo, private, err := verifyRepo(ctx, repo)
+	if err != nil {
+		return cs, err
+	}
+	cs.Repo = repo
+	// a listed source may lay out its blueprints its own way
+	sources, err := loadSources(cfg.Discovery.sources())
+	if err != nil {
+		return cs, fmt.Errorf("sources: %w", err)
+	}
+	src := sources.source(repo)
+	if src.Assets != nil {
+		cfg.Assets = *src.Assets
+	}
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return cs, fmt.Errorf("load registry: %w", err)
 	}
+	before := *st.snapshot()
+	tx := st.begin()
 
-	db, err := loadDB("registry.json")
+	// Fetch release metadata for this tag. A private repo's files and
+	// assets are only served through the API, with credentials.
+	gh := github()
+	gh.Private = private
+	rel, err := gh.Release(ctx, repo, tag)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "load registry:", err)
-		os.Exit(1)
+		return cs, fmt.Errorf("release: %w", err)
+	}
+	published := rel.PublishedAt
+	if published.IsZero() {
+		published = time.Now().UTC()
+	}
+	namespace := os.Getenv("REGISTRY_NAMESPACE")
+	if namespace == "" {
+		namespace = registry.DefaultNamespace
+	}
+	if src.Namespace != "" {
+		namespace = src.Namespace
+	}
+	plugins, err := discoverPlugins(pluginDir())
+	if err != nil {
+		return cs, fmt.Errorf("plugins: %w", err)
+	}
+	vocab, err := loadFeatureVocab(cfg.Features.Vocabulary)
+	if err != nil {
+		return cs, fmt.Errorf("features: %w", err)
 	}
+	// The source's trust level decides which checks are mandatory
+	trust := cfg.Trust.levelOf(repo)
+	policy := cfg.Trust.policy(trust)
+	cfg.Templates.Lint = stricterLint(cfg.Templates.Lint, policy.TemplateLint)
+	cfg.Review.Required = cfg.Review.Required || policy.Review
+	sp.set("trust.level", trust)
 
-	// Fetch release metadata for this tag
-	relURL := fmt.Sprintf("https://api.github.com/repos/%s/releases/tags/%s", repo, tag)
-	rb, err := httpGet(ctx, relURL)
+	// the repo license is shared by all its blueprints; look it up once
+	var repoLicense string
+	var repoLicenseFetched bool
+	readmes := map[string]*readme{}
+	// indexed are the entries staged, to tell the unchanged ones apart
+	var indexed []changedEntry
+	digests := indexDigests(before)
+	slugs := cfg.IDs.strategy()
+	// digests published with the release spare downloading assets to
+	// hash them
+	sums, err := releaseChecksums(ctx, rel.Assets)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "release:", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "%s: checksums: %v\n", repo, err)
 	}
-	var rel ghRelease
-	if err := json.Unmarshal(rb, &rel); err != nil {
-		fmt.Fprintln(os.Stderr, "decode release:", err)
-		os.Exit(1)
+	// what the repo's blueprints share; each manifest is layered over it
+	defaults, err := gh.Defaults(ctx, repo, tag)
+	if err != nil {
+		return cs, fmt.Errorf("%s: %w", registry.DefaultsFile, err)
 	}
 
-	// Iterate assets like "<name>.zip"
-	for _, a := range rel.Assets {
-		if !strings.HasSuffix(a.Name, ".zip") {
+	// Iterate the assets the config identifies as blueprints
+	var downloads int64
+	for _, sel := range cfg.Assets.selectAssets(rel.Assets) {
+		a, name := sel.Asset, sel.Name
+		downloads += a.DownloadCount
+		actx, asp := startSpan(ctx, "index asset", spanKindInternal, attrs{"asset.name": a.Name})
+		// Fetch the manifest from the repo at this tag, falling back to
+		// the one packaged in the asset
+		man, err := fetchManifest(actx, repo, tag, path.Join(src.dir(), name), private, cfg.Manifests.Strict)
+		// Look inside the archive when the manifest is missing, the
+		// templates need linting or tags are derived from the contents
+		var scan *assetScan
+		if errors.Is(err, registry.ErrNoManifest) || cfg.Templates.Lint != lintOff || cfg.Tags.Auto || policy.Scan || cfg.Rehost.Repo != "" {
+			var serr error
+			scan, serr = scanAsset(actx, a.DownloadURL(), cfg.Templates, cfg.Manifests.Strict, cfg.Digests.extra())
+			switch {
+			case serr != nil && policy.Scan:
+				err := fmt.Errorf("inspect archive: %w", serr)
+				cs.skip(name, err.Error())
+				asp.finish(err)
+				continue
+			case serr != nil:
+				fmt.Fprintf(os.Stderr, "%s: inspect archive: %v\n", name, serr)
+			case errors.Is(err, registry.ErrNoManifest):
+				man, err = scan.Manifest, scan.ManifestErr
+			}
+		}
+		if err != nil && !errors.Is(err, registry.ErrNoManifest) {
+			// a newer format would be misread, not just lose fields
+			if cfg.Manifests.Strict || errors.Is(err, registry.ErrUnknownAPIVersion) {
+				err := fmt.Errorf("manifest: %w", err)
+				cs.skip(name, err.Error())
+				asp.finish(err)
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "%s: manifest: %v\n", name, err)
+			man = registry.Manifest{}
+		}
+		for _, w := range man.Warnings {
+			fmt.Fprintf(os.Stderr, "%s: manifest: %s\n", name, w)
+		}
+		// Hold the asset name to the naming convention
+		if check := cfg.Assets.Naming.check(); check != lintOff {
+			problems := cfg.Assets.Naming.crossCheck(sel, man.Version, tag)
+			if sel.Nonconforming {
+				problems = append(problems, fmt.Sprintf("asset name doesn't follow %q", cfg.Assets.Naming.Pattern))
+			}
+			for _, p := range problems {
+				fmt.Fprintf(os.Stderr, "%s: %s: %s\n", a.Name, check, p)
+			}
+			if len(problems) > 0 && check == lintError {
+				err := errors.New("asset naming")
+				cs.skip(a.Name, err.Error())
+				asp.finish(err)
+				continue
+			}
+		}
+		if man.Version == "" && sel.Version != "" {
+			man.Version = strings.TrimPrefix(sel.Version, "v")
+		}
+		if scan != nil && cfg.Templates.Lint != lintOff {
+			findings := lintTemplates(man, scan, cfg.Templates)
+			for _, f := range findings {
+				fmt.Fprintf(os.Stderr, "%s: template lint: %s\n", name, f)
+			}
+			if len(findings) > 0 && cfg.Templates.Lint == lintError {
+				err := fmt.Errorf("%d template lint findings", len(findings))
+				cs.skip(name, err.Error())
+				asp.finish(err)
+				continue
+			}
+		}
+		// Fill in what the manifest leaves out, if it exists at all
+		man.Inherit(defaults)
+		described := man.Description != ""
+		err = cfg.Fallback.fill(&man, fallbackData{
+			Repo:         repo,
+			Tag:          tag,
+			Asset:        name,
+			AssetFile:    a.Name,
+			AssetVersion: strings.TrimPrefix(sel.Version, "v"),
+			Namespace:    namespace,
+		})
+		if err != nil {
+			cs.skip(name, err.Error())
+			asp.finish(err)
 			continue
 		}
-		name := strings.TrimSuffix(a.Name, ".zip")
-		// Fetch manifest.yaml from the repo at this tag
-		manifestURL := fmt.Sprintf(
-            "https://raw.githubusercontent.com/%s/%s/%s",
-            repo, tag, path.Join("blueprints", name, "manifest.yaml"),
-        )
-		mb, err := httpGet(ctx, manifestURL)
-		var man bpManifest
-		if err == nil {
-			_ = yaml.Unmarshal(mb, &man)
+		// A manifest may name its namespace explicitly ("acme/svc")
+		ns, bpName := registry.SplitRef(man.Name)
+		if ns == "" {
+			ns = namespace
 		}
-		// Fallbacks if manifest missing
-		if man.Name == "" {
-			man.Name = name
+		var title string
+		if slug := slugs.slug(slugSource{Repo: repoID(repo), Path: path.Join(src.dir(), name), Name: bpName}); slug != bpName {
+			title, bpName = bpName, slug
 		}
-		if man.Version == "" {
-			man.Version = strings.TrimPrefix(tag, "v")
+		license, licenseSource := man.License, licenseFromManifest
+		switch {
+		case license != "":
+		case scan != nil && scan.License != "":
+			license, licenseSource = scan.License, licenseFromArchive
+		default:
+			if !repoLicenseFetched {
+				repoLicense, err = fetchRepoLicense(actx, repo, tag)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%s: license: %v\n", repo, err)
+				}
+				repoLicenseFetched = true
+			}
+			license, licenseSource = repoLicense, licenseFromRepo
 		}
-		if man.Description == "" {
-			man.Description = fmt.Sprintf("%s blueprint", name)
+		if license == "" {
+			licenseSource = ""
+			if policy.License {
+				err := fmt.Errorf("no license, which %s sources require", trust)
+				cs.skip(name, err.Error())
+				asp.finish(err)
+				continue
+			}
+		}
+		features, findings := checkFeatures(man.Features, vocab)
+		for _, f := range findings {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, f)
+		}
+		deps, findings := checkDependencies(man.Dependencies, ns+"/"+bpName)
+		for _, f := range findings {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, f)
+		}
+		// Every entry records the digest of its archive so clients can
+		// verify downloads
+		digest, err := assetDigest(actx, a, sums, scan, before)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: digest: %v\n", name, err)
+			cs.skip(name, err.Error())
+			asp.finish(err)
+			continue
+		}
+		extra, err := assetExtraDigests(actx, cfg.Digests, a, digest, scan, before)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: digests: %v\n", name, err)
+			cs.skip(name, err.Error())
+			asp.finish(err)
+			continue
+		}
+		// A signature that is published must verify; unsigned assets are
+		// rejected where the trust policy requires signing
+		signed := false
+		switch err := verifySignature(actx, cfg.Signatures, repo, rel.Assets, a, digest); {
+		case errors.Is(err, errUnsigned) && policy.Signature:
+			err := fmt.Errorf("unsigned, which %s sources require", trust)
+			cs.skip(name, err.Error())
+			asp.finish(err)
+			continue
+		case errors.Is(err, errUnsigned):
+			fmt.Fprintf(os.Stderr, "%s: not signed\n", name)
+		case err != nil:
+			err = fmt.Errorf("signature: %w", err)
+			cs.skip(name, err.Error())
+			asp.finish(err)
+			continue
+		default:
+			signed = true
+		}
+		// Provenance, when published, must verify too
+		prov, err := verifyProvenance(actx, cfg.Provenance, repo, tag, rel.Assets, a, digest)
+		switch {
+		case errors.Is(err, errNoProvenance):
+		case err != nil:
+			err = fmt.Errorf("provenance: %w", err)
+			cs.skip(name, err.Error())
+			asp.finish(err)
+			continue
+		}
+		tags := man.Tags
+		if cfg.Tags.Auto && scan != nil {
+			tags = mergeAutoTags(tags, deriveTags(scan.Files, cfg.Templates))
 		}
 
-		entry := Blueprint{
-			Name:        man.Name,
-			Version:     man.Version,
-			Repo:        "github.com/" + repo,
-			Path:        path.Join("blueprints", name),
-			DownloadURL: a.BrowserDownloadURL,
-			Description: man.Description,
-			Tags:        man.Tags,
+		entry := registry.Blueprint{
+			Namespace:     ns,
+			Name:          bpName,
+			Title:         title,
+			Version:       man.Version,
+			Repo:          repoID(repo),
+			Path:          path.Join(src.dir(), name),
+			DownloadURL:   a.DownloadURL(),
+			SHA256:        digest,
+			Digests:       extra,
+			Asset:         &registry.AssetRef{ID: a.ID, Size: a.Size},
+			Signed:        signed,
+			Provenance:    prov,
+			Description:   man.Description,
+			Tags:          tags,
+			Features:      features,
+			Dependencies:  deps,
+			Visibility:    man.Visibility,
+			License:       license,
+			LicenseSource: licenseSource,
+			Maintainers:   man.Maintainers,
+			Category:      man.Category,
+			Icon:          man.Icon,
+			Screenshots:   man.Screenshots,
+			Deprecation:   qualifyDeprecation(man.Deprecated),
+			Trust:         trust,
+			CreatedAt:     published,
+			UpdatedAt:     published,
 		}
 
-		// Upsert into db
-		found := false
-		for i := range db.Blueprints {
-			if db.Blueprints[i].Name == entry.Name {
-				db.Blueprints[i] = entry
-				found = true
-				break
+		// Let plugins enrich or reject the candidate
+		entry, ok, err := applyPlugins(actx, plugins, pluginRequest{
+			Protocol: pluginProtocol,
+			Hook:     "enrich",
+			Repo:     repo,
+			Tag:      tag,
+			Asset:    a.Name,
+			Entry:    entry,
+		})
+		if err != nil {
+			asp.finish(err)
+			return cs, err
+		}
+		if !ok {
+			cs.skip(a.Name, "rejected by a plugin")
+			asp.set("plugin.rejected", true)
+			asp.finish(nil)
+			continue
+		}
+		if sanitizeEntry(&entry, cfg.Text) {
+			fmt.Fprintf(os.Stderr, "%s: description sanitized\n", a.Name)
+		}
+		problems := checkEntry(entry, cfg.Validation)
+		for _, f := range problems {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", a.Name, f)
+		}
+		if hasErrors(problems) {
+			err := errors.New("fails validation")
+			cs.skip(a.Name, err.Error())
+			asp.finish(err)
+			continue
+		}
+		if ok, why := cfg.Sync.match(entry); !ok {
+			cs.skip(entry.FullName(), why)
+			asp.set("sync.filtered", true)
+			asp.finish(nil)
+			continue
+		}
+		rd, err := indexReadme(actx, cfg.Details, repo, tag, entry.Path, private, scan)
+		if err != nil && !errors.Is(err, errNoReadme) {
+			fmt.Fprintf(os.Stderr, "%s: readme: %v\n", entry.FullName(), err)
+		}
+		entry.Quality = rateQuality(qualityInput{Entry: entry, Described: described, Readme: rd != nil, Params: man.Parameters})
+		if len(entry.Quality.Missing) > 0 {
+			fmt.Fprintf(os.Stderr, "%s: quality %d, missing %s\n", entry.FullName(), entry.Quality.Score, strings.Join(entry.Quality.Missing, ", "))
+		}
+		// The same archive may already be indexed from another tag or repo
+		if ref, ok := digests[entry.SHA256]; ok && entry.SHA256 != "" {
+			switch {
+			case ref.republished(entry, entry.DownloadURL):
+				src := entry.DownloadURL
+				tx.stage(func(db *registry.Database) error {
+					return addSource(db, ref.Entry, src)
+				})
+				cs.skip(a.Name, fmt.Sprintf("same archive as %s %s; recorded as another source", ref.Entry, ref.Version))
+				asp.finish(nil)
+				continue
+			case cfg.Rehost.isMirror(ref.DownloadURL):
+				// reference the stored copy instead of uploading another
+				entry.SourceURL, entry.DownloadURL = entry.DownloadURL, ref.DownloadURL
+				fmt.Fprintf(os.Stderr, "%s: same archive as %s %s; reusing its mirror copy\n", entry.FullName(), ref.Entry, ref.Version)
+			}
+		}
+		if cfg.Rehost.Repo != "" && !cfg.Rehost.isMirror(entry.DownloadURL) {
+			// Serve the verified archive from our own releases, keeping
+			// the original as provenance
+			var u string
+			var err error
+			if dryRun {
+				err = errors.New("dry run")
+			} else {
+				u, err = rehostAsset(actx, cfg.Rehost, repo, tag, entry)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: rehost: %v; keeping the original URL\n", entry.FullName(), err)
+			} else {
+				entry.SourceURL, entry.DownloadURL = entry.DownloadURL, u
 			}
 		}
-		if !found {
-			db.Blueprints = append(db.Blueprints, entry)
+		if !dryRun {
+			for _, w := range hostImages(actx, cfg.Images, &entry) {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", entry.FullName(), w)
+			}
 		}
+
+		if cfg.Review.Required {
+			// Hold the candidate for a maintainer to approve
+			if !dryRun {
+				err := writePending(cfg.Review.dir(), pendingEntry{
+					Repo:        repo,
+					Tag:         tag,
+					SubmittedAt: time.Now().UTC(),
+					Entry:       entry,
+				})
+				if err != nil {
+					asp.finish(err)
+					return cs, fmt.Errorf("write pending: %w", err)
+				}
+			}
+			cs.Pending = append(cs.Pending, changedEntry{Name: entry.FullName(), Version: entry.Version})
+		} else {
+			// names are unique per namespace
+			tx.stage(func(db *registry.Database) error {
+				return upsertResolved(db, entry, cfg.Conflicts)
+			})
+			indexed = append(indexed, changedEntry{Name: entry.FullName(), Version: entry.Version})
+			if entry.SHA256 != "" {
+				digests[entry.SHA256] = digestRef{Entry: entry.FullName(), Version: entry.Version, DownloadURL: entry.DownloadURL, Current: true, SourceURL: entry.SourceURL}
+			}
+			if cfg.Details.Dir != "" {
+				readmes[entry.FullName()] = rd
+			}
+		}
+		asp.set("blueprint.name", entry.FullName())
+		asp.set("blueprint.version", entry.Version)
+		asp.finish(nil)
+		metricAssetsIndexed.add(1, attrs{"repo": repo})
 	}
 
-	if err := saveDB("registry.json", db); err != nil {
-		fmt.Fprintln(os.Stderr, "save registry:", err)
-		os.Exit(1)
+	// Old versions are pruned only along with a change
+	if tx.pending() > 0 {
+		if err := stageRetention(tx, cfg.Retention, &cs.Pruned); err != nil {
+			return cs, err
+		}
+	}
+	// Clients learn which digests to insist on from the registry
+	if !slices.Equal(before.Metadata.RequiredDigests, cfg.Digests.Require) {
+		tx.stage(func(db *registry.Database) error {
+			db.Metadata.RequiredDigests = slices.Clone(cfg.Digests.Require)
+			return nil
+		})
+	}
+	if dryRun {
+		base, after, err := tx.preview()
+		if err != nil {
+			return cs, fmt.Errorf("update registry: %w", err)
+		}
+		cs.diffDB(base, after)
+		cs.unchanged(indexed)
+		return cs, nil
+	}
+	// diffed against the registry the commit started from, which another
+	// writer may have changed since it was loaded above
+	base, db, err := tx.commitChanges()
+	if err != nil {
+		return cs, fmt.Errorf("update registry: %w", err)
+	}
+	for _, p := range cs.Pruned {
+		fmt.Fprintf(os.Stderr, "%s: dropped version %s (retention)\n", p.Name, p.Version)
+	}
+	cs.diffDB(base, db)
+	cs.unchanged(indexed)
+	if cfg.Details.Dir != "" {
+		if err := writeDetails(cfg.Details, db, readmes); err != nil {
+			return cs, fmt.Errorf("details: %w", err)
+		}
+	}
+	if cfg.Images.Dir != "" {
+		if err := pruneImages(cfg.Images, db); err != nil {
+			return cs, fmt.Errorf("images: %w", err)
+		}
+	}
+	if cfg.Stats.History != "" {
+		sp := snapshotStats(db)
+		sp.Repo, sp.Tag, sp.Downloads = repo, tag, downloads
+		if err := appendHistory(cfg.Stats.History, sp); err != nil {
+			return cs, fmt.Errorf("stats history: %w", err)
+		}
+	}
+	if cfg.Snapshots.Repo != "" {
+		due, err := recordSnapshotChanges(cfg.Snapshots, cs, time.Now())
+		if err != nil {
+			return cs, fmt.Errorf("snapshots: %w", err)
+		}
+		if due && registryPath() != "-" {
+			// the registry is written; a failed release is retried next time
+			if u, err := publishSnapshot(ctx, cfg, registryPath(), time.Now()); err != nil {
+				fmt.Fprintf(os.Stderr, "snapshot: %v\n", err)
+			} else {
+				fmt.Printf("published snapshot %s\n", u)
+			}
+		}
 	}
 
 	fmt.Printf("registry updated for %s at %s with %d entries\n", tag, time.Now().Format(time.RFC3339), len(db.Blueprints))
-}
\ No newline at end of file
+	if len(cs.Pending) > 0 {
+		fmt.Printf("%d entries await review in %s\n", len(cs.Pending), cfg.Review.dir())
+	}
+	return cs, nil
+}
@@ -0,0 +1,480 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// usageDays is how many days of daily counts are kept per token.
+const usageDays = 90
+
+// usageConfig meters what each API token uses of a shared registry.
+type usageConfig struct {
+	// File is the JSON file the counts are kept in. Empty meters nothing.
+	File string `yaml:"file"`
+	// Window is the period quotas are counted over, aligned to UTC.
+	// Defaults to 24h.
+	Window time.Duration `yaml:"window"`
+	// Quota applies to the tokens that don't set their own.
+	Quota tokenQuota `yaml:"quota"`
+}
+
+// tokenQuota caps what a token may use per window; zero is unlimited.
+type tokenQuota struct {
+	Requests  int64 `yaml:"requests" json:"requests,omitempty"`
+	Downloads int64 `yaml:"downloads" json:"downloads,omitempty"`
+	Publishes int64 `yaml:"publishes" json:"publishes,omitempty"`
+}
+
+func (u usageConfig) validate() error {
+	if u.Window < 0 {
+		return errors.New("serve.usage.window must not be negative")
+	}
+	return u.Quota.validate()
+}
+
+func (u usageConfig) window() time.Duration { return orDefault(u.Window, 24*time.Hour) }
+
+func (q tokenQuota) validate() error {
+	if q.Requests < 0 || q.Downloads < 0 || q.Publishes < 0 {
+		return errors.New("quotas must not be negative")
+	}
+	return nil
+}
+
+// usageKind is what a token is charged for.
+type usageKind int
+
+const (
+	// usageRequest is any API request.
+	usageRequest usageKind = iota
+	// usageDownload is a download of the whole registry.
+	usageDownload
+	// usagePublish is an edit accepted through the API.
+	usagePublish
+)
+
+func (k usageKind) String() string {
+	return [...]string{"request", "download", "publish"}[k]
+}
+
+func (q tokenQuota) limit(k usageKind) int64 {
+	return [...]int64{q.Requests, q.Downloads, q.Publishes}[k]
+}
+
+type usageCounts struct {
+	Requests  int64 `json:"requests"`
+	Downloads int64 `json:"downloads"`
+	Publishes int64 `json:"publishes"`
+}
+
+func (c *usageCounts) count(k usageKind) *int64 {
+	return [...]*int64{&c.Requests, &c.Downloads, &c.Publishes}[k]
+}
+
+func (c *usageCounts) add(o usageCounts) {
+	c.Requests += o.Requests
+	c.Downloads += o.Downloads
+	c.Publishes += o.Publishes
+}
+
+// tokenUsage is what one token used: in all, per UTC day and in the
+// current quota window.
+type tokenUsage struct {
+	Total       usageCounts            `json:"total"`
+	Daily       map[string]usageCounts `json:"daily"`
+	WindowStart time.Time              `json:"window_start"`
+	Window      usageCounts            `json:"window"`
+	LastSeen    time.Time              `json:"last_seen"`
+}
+
+// usageStats is the usage file: usage per token name.
+type usageStats struct {
+	Tokens map[string]*tokenUsage `json:"tokens"`
+}
+
+func readUsageStats(p string) (usageStats, error) {
+	s := usageStats{}
+	b, err := os.ReadFile(p)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return s, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return s, fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	if s.Tokens == nil {
+		s.Tokens = map[string]*tokenUsage{}
+	}
+	return s, nil
+}
+
+// quotaError is a charge turned away because the token used its quota.
+type quotaError struct {
+	Kind  usageKind
+	Limit int64
+	Reset time.Time
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("%s quota of %d exceeded until %s", e.Kind, e.Limit, e.Reset.UTC().Format(time.RFC3339))
+}
+
+// usageMeter counts usage in memory and enforces the quotas; flush
+// persists the counts.
+type usageMeter struct {
+	cfg  usageConfig
+	path string
+
+	mu    sync.Mutex
+	stats usageStats
+	dirty bool
+}
+
+func openUsageMeter(cfg usageConfig) (*usageMeter, error) {
+	s, err := readUsageStats(cfg.File)
+	if err != nil {
+		return nil, err
+	}
+	return &usageMeter{cfg: cfg, path: cfg.File, stats: s}, nil
+}
+
+// quota is what tok may use per window.
+func (m *usageMeter) quota(tok apiToken) tokenQuota {
+	if tok.Quota != nil {
+		return *tok.Quota
+	}
+	return m.cfg.Quota
+}
+
+// current returns tok's usage with its window moved to the one now is in.
+// The caller holds mu.
+func (m *usageMeter) current(tok apiToken, now time.Time) *tokenUsage {
+	u := m.stats.Tokens[tok.Name]
+	if u == nil {
+		u = &tokenUsage{Daily: map[string]usageCounts{}}
+		m.stats.Tokens[tok.Name] = u
+	}
+	if start := now.UTC().Truncate(m.cfg.window()); !u.WindowStart.Equal(start) {
+		u.WindowStart, u.Window = start, usageCounts{}
+	}
+	return u
+}
+
+// check reports whether tok may use one more of each kind.
+func (m *usageMeter) check(tok apiToken, now time.Time, kinds ...usageKind) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checkLocked(tok, m.current(tok, now), kinds)
+}
+
+func (m *usageMeter) checkLocked(tok apiToken, u *tokenUsage, kinds []usageKind) error {
+	q := m.quota(tok)
+	for _, k := range kinds {
+		if l := q.limit(k); l > 0 && *u.Window.count(k) >= l {
+			return &quotaError{Kind: k, Limit: l, Reset: u.WindowStart.Add(m.cfg.window())}
+		}
+	}
+	return nil
+}
+
+// charge counts one of each kind against tok, unless that would exceed
+// its quota. It returns the usage after the charge.
+func (m *usageMeter) charge(tok apiToken, now time.Time, kinds ...usageKind) (tokenUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.current(tok, now)
+	if err := m.checkLocked(tok, u, kinds); err != nil {
+		return *u, err
+	}
+	day := now.UTC().Format(time.DateOnly)
+	d, ok := u.Daily[day]
+	if !ok {
+		cutoff := now.UTC().AddDate(0, 0, -usageDays).Format(time.DateOnly)
+		for k := range u.Daily {
+			if k <= cutoff {
+				delete(u.Daily, k)
+			}
+		}
+	}
+	for _, k := range kinds {
+		*u.Total.count(k)++
+		*u.Window.count(k)++
+		*d.count(k)++
+	}
+	u.Daily[day] = d
+	u.LastSeen = now.UTC()
+	m.dirty = true
+	return *u, nil
+}
+
+// report is the usage of the tokens named, or of all of them, over the
+// last days days.
+func (m *usageMeter) report(tokens []apiToken, days int, now time.Time) []usageRow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	quotas := map[string]tokenQuota{}
+	for _, t := range tokens {
+		quotas[t.Name] = m.quota(t)
+	}
+	return usageReport(m.stats, quotas, m.cfg.window(), days, now)
+}
+
+// flush writes the counts if they changed since the last flush.
+func (m *usageMeter) flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(m.stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := registry.WriteFileAtomic(m.path, append(b, '\n')); err != nil {
+		return err
+	}
+	m.dirty = false
+	return nil
+}
+
+// usageRow is one token's line of a usage report.
+type usageRow struct {
+	Token string `json:"token"`
+	// Days is what it used over the days reported.
+	Days     usageCounts `json:"days"`
+	Total    usageCounts `json:"total"`
+	LastSeen time.Time   `json:"last_seen,omitzero"`
+	// Window is what counts against Quota, until Reset.
+	Window usageCounts `json:"window"`
+	Reset  time.Time   `json:"reset,omitzero"`
+	Quota  *tokenQuota `json:"quota,omitempty"`
+}
+
+// usageReport lists the tokens in quotas, or all of them if it is nil,
+// heaviest users over the last days days first.
+func usageReport(s usageStats, quotas map[string]tokenQuota, window time.Duration, days int, now time.Time) []usageRow {
+	since := now.UTC().AddDate(0, 0, -days).Format(time.DateOnly)
+	start := now.UTC().Truncate(window)
+	var rows []usageRow
+	add := func(name string, u *tokenUsage) {
+		row := usageRow{Token: name, Reset: start.Add(window)}
+		if u != nil {
+			row.Total, row.LastSeen = u.Total, u.LastSeen
+			if u.WindowStart.Equal(start) {
+				row.Window = u.Window
+			}
+			for day, c := range u.Daily {
+				if day > since {
+					row.Days.add(c)
+				}
+			}
+		}
+		if q, ok := quotas[name]; ok && q != (tokenQuota{}) {
+			row.Quota = &q
+		}
+		rows = append(rows, row)
+	}
+	if quotas == nil {
+		for name, u := range s.Tokens {
+			add(name, u)
+		}
+	} else {
+		for name := range quotas {
+			add(name, s.Tokens[name])
+		}
+	}
+	slices.SortFunc(rows, func(a, b usageRow) int {
+		return cmp.Or(cmp.Compare(b.Days.Requests, a.Days.Requests), cmp.Compare(a.Token, b.Token))
+	})
+	return rows
+}
+
+// meter counts what requests bearing a token use and turns away those
+// over quota. Requests without a known token aren't metered, and a token
+// can always ask for its own usage.
+func (s *server) meter(next http.Handler) http.Handler {
+	downloads := map[string]bool{}
+	for format := range registryTypes {
+		downloads["/"+registry.SiblingPath("registry.json", format)] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tok, ok := s.writers.authenticate(r)
+		if !ok || r.URL.Path == "/v1/me/usage" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		kinds := []usageKind{usageRequest}
+		if downloads[r.URL.Path] && r.Method == http.MethodGet {
+			kinds = append(kinds, usageDownload)
+		}
+		u, err := s.usage.charge(tok, time.Now(), kinds...)
+		if q := s.usage.quota(tok); q.Requests > 0 {
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.FormatInt(q.Requests, 10))
+			h.Set("X-RateLimit-Remaining", strconv.FormatInt(max(q.Requests-u.Window.Requests, 0), 10))
+			h.Set("X-RateLimit-Reset", strconv.FormatInt(u.WindowStart.Add(s.usage.cfg.window()).Unix(), 10))
+		}
+		if writeQuotaError(w, r, err) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeQuotaError answers 429 if err is a quota error.
+func writeQuotaError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var qe *quotaError
+	if !errors.As(err, &qe) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(time.Until(qe.Reset).Seconds()+1), 1)))
+	writeError(w, r, http.StatusTooManyRequests, qe.Error())
+	return true
+}
+
+// getMyUsage serves GET /v1/me/usage: what the bearer token used, with
+// its quota.
+func (s *server) getMyUsage(w http.ResponseWriter, r *http.Request) {
+	tok, ok := s.writers.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="dragon-registry"`)
+		writeError(w, r, http.StatusUnauthorized, "a bearer token is required")
+		return
+	}
+	days, ok := usageDaysParam(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, r, http.StatusOK, s.usage.report([]apiToken{tok}, days, time.Now())[0])
+}
+
+// getUsage serves GET /v1/usage, the usage of every token, to admins.
+func (s *server) getUsage(w http.ResponseWriter, r *http.Request) {
+	tok, ok := s.writers.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="dragon-registry"`)
+		writeError(w, r, http.StatusUnauthorized, "a bearer token is required")
+		return
+	}
+	if !tok.Admin {
+		writeError(w, r, http.StatusForbidden, "only admins may see the usage of all tokens")
+		return
+	}
+	days, ok := usageDaysParam(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"days":   days,
+		"tokens": orEmpty(s.usage.report(s.writers.tokens, days, time.Now())),
+	})
+}
+
+// usageDaysParam reads the days a usage report covers, 30 by default.
+func usageDaysParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	q := r.URL.Query().Get("days")
+	if q == "" {
+		return 30, true
+	}
+	days, err := strconv.Atoi(q)
+	if err != nil || days < 1 || days > usageDays {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("days must be 1 to %d", usageDays))
+		return 0, false
+	}
+	return days, true
+}
+
+// runStatsUsage prints what each API token used, heaviest first.
+func runStatsUsage(args []string) error {
+	flags := flag.NewFlagSet("stats usage", flag.ExitOnError)
+	days := flags.Int("days", 30, fmt.Sprintf("report the last days, at most %d", usageDays))
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+	if *days < 1 || *days > usageDays {
+		return fmt.Errorf("--days must be 1 to %d", usageDays)
+	}
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	u := cfg.Serve.Usage
+	if u.File == "" {
+		return errors.New("no serve.usage.file configured")
+	}
+	s, err := readUsageStats(u.File)
+	if err != nil {
+		return fmt.Errorf("read usage: %w", err)
+	}
+	var quotas map[string]tokenQuota
+	if cfg.Serve.Tokens != "" {
+		tf, err := loadTokens(cfg.Serve.Tokens)
+		if err != nil {
+			return fmt.Errorf("tokens: %w", err)
+		}
+		m := &usageMeter{cfg: u}
+		quotas = map[string]tokenQuota{}
+		for _, t := range tf.Tokens {
+			quotas[t.Name] = m.quota(t)
+		}
+		// tokens since revoked still show up in the report
+		for name := range s.Tokens {
+			if _, ok := quotas[name]; !ok {
+				quotas[name] = tokenQuota{}
+			}
+		}
+	}
+	rows := usageReport(s, quotas, u.window(), *days, time.Now())
+	if *asJSON {
+		return printJSON(rows)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TOKEN\tREQUESTS\tDOWNLOADS\tPUBLISHES\tWINDOW\tQUOTA\tLAST SEEN")
+	for _, row := range rows {
+		quota, seen := "-", "-"
+		if row.Quota != nil {
+			quota = fmt.Sprintf("%s/%s/%s", quotaString(row.Quota.Requests), quotaString(row.Quota.Downloads), quotaString(row.Quota.Publishes))
+		}
+		if !row.LastSeen.IsZero() {
+			seen = row.LastSeen.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d/%d/%d\t%s\t%s\n", row.Token, row.Days.Requests, row.Days.Downloads, row.Days.Publishes,
+			row.Window.Requests, row.Window.Downloads, row.Window.Publishes, quota, seen)
+	}
+	return w.Flush()
+}
+
+func quotaString(n int64) string {
+	if n == 0 {
+		return "∞"
+	}
+	return strconv.FormatInt(n, 10)
+}
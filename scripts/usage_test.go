@@ -0,0 +1,194 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestMeter(t *testing.T, cfg usageConfig) *usageMeter {
+	t.Helper()
+	cfg.File = filepath.Join(t.TempDir(), "usage.json")
+	m, err := openUsageMeter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestUsageWindowRollover(t *testing.T) {
+	m := newTestMeter(t, usageConfig{Window: time.Hour, Quota: tokenQuota{Requests: 2}})
+	tok := apiToken{Name: "ci"}
+	start := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	for _, at := range []time.Duration{0, 30 * time.Minute} {
+		if _, err := m.charge(tok, start.Add(at), usageRequest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := m.charge(tok, start.Add(59*time.Minute), usageRequest)
+	var qe *quotaError
+	if !errors.As(err, &qe) {
+		t.Fatalf("third request in the window: %v, want a quota error", err)
+	}
+	if want := start.Add(time.Hour); !qe.Reset.Equal(want) || qe.Limit != 2 || qe.Kind != usageRequest {
+		t.Errorf("quota error %+v, want a reset at %s", qe, want)
+	}
+
+	// the next window starts from nothing; the totals carry on
+	u, err := m.charge(tok, start.Add(time.Hour), usageRequest)
+	if err != nil {
+		t.Fatalf("first request of the next window: %v", err)
+	}
+	if !u.WindowStart.Equal(start.Add(time.Hour)) || u.Window.Requests != 1 || u.Total.Requests != 3 {
+		t.Errorf("after the rollover: window from %s with %d requests, %d in all", u.WindowStart, u.Window.Requests, u.Total.Requests)
+	}
+
+	// a token with an empty quota of its own is exempt
+	free := apiToken{Name: "free", Quota: &tokenQuota{}}
+	for range 5 {
+		if _, err := m.charge(free, start, usageRequest); err != nil {
+			t.Fatalf("exempt token: %v", err)
+		}
+	}
+}
+
+func TestUsageDailyReset(t *testing.T) {
+	m := newTestMeter(t, usageConfig{})
+	tok := apiToken{Name: "ci"}
+	day := time.Date(2025, 6, 1, 23, 59, 0, 0, time.UTC)
+	charges := []struct {
+		at    time.Time
+		kinds []usageKind
+	}{
+		{day.AddDate(0, 0, -usageDays), []usageKind{usageRequest}},
+		{day, []usageKind{usageRequest, usageDownload}},
+		{day, []usageKind{usageRequest}},
+		{day.Add(2 * time.Minute), []usageKind{usageRequest}},
+	}
+	for _, c := range charges {
+		if _, err := m.charge(tok, c.at, c.kinds...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u := m.stats.Tokens["ci"]
+	want := map[string]usageCounts{
+		"2025-06-01": {Requests: 2, Downloads: 1},
+		"2025-06-02": {Requests: 1},
+	}
+	if len(u.Daily) != len(want) {
+		t.Errorf("days kept: %v, want %v", u.Daily, want)
+	}
+	for d, c := range want {
+		if u.Daily[d] != c {
+			t.Errorf("%s: %+v, want %+v", d, u.Daily[d], c)
+		}
+	}
+	if u.Total.Requests != 4 {
+		t.Errorf("total requests %d, want 4", u.Total.Requests)
+	}
+	// the default window is the UTC day, so it reset at midnight too
+	if u.Window.Requests != 1 || !u.WindowStart.Equal(day.Add(time.Minute)) {
+		t.Errorf("window from %s with %d requests", u.WindowStart, u.Window.Requests)
+	}
+
+	rows := m.report([]apiToken{tok}, 1, day.Add(2*time.Minute))
+	if len(rows) != 1 || rows[0].Days != want["2025-06-02"] {
+		t.Errorf("report of the last day: %+v", rows)
+	}
+
+	// what was counted survives a restart
+	if err := m.flush(); err != nil {
+		t.Fatal(err)
+	}
+	again, err := openUsageMeter(m.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := again.stats.Tokens["ci"].Daily; len(got) != len(want) || got["2025-06-01"] != want["2025-06-01"] {
+		t.Errorf("reopened: %v", got)
+	}
+}
+
+func TestMeterTooManyRequests(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "registry.json")
+	b, err := json.Marshal(testEntries(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var cfg config
+	cfg.Serve.Tokens = writeTestTokens(t, apiToken{Name: "ci"})
+	cfg.Serve.Usage = usageConfig{Window: time.Hour, Quota: tokenQuota{Requests: 2}}
+	s, err := newServer(cfg, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.writers, err = loadWriteAccess(cfg); err != nil {
+		t.Fatal(err)
+	}
+	s.usage = newTestMeter(t, cfg.Serve.Usage)
+	h := s.handler()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer secret-ci")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	for i, want := range []string{"1", "0"} {
+		rec := get("/v1/blueprints")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("X-RateLimit-Remaining"); got != want {
+			t.Errorf("request %d: %s remaining, want %s", i+1, got, want)
+		}
+	}
+
+	rec := get("/v1/blueprints")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over quota: status %d, want 429", rec.Code)
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+		t.Errorf("429 body %q: %v", rec.Body, err)
+	}
+	reset, err := strconv.ParseInt(rec.Header().Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil {
+		t.Fatalf("Retry-After %q: %v", rec.Header().Get("Retry-After"), err)
+	}
+	// Retry-After is the wait until the window resets, rounded up
+	if until := time.Until(time.Unix(reset, 0)); retry < 1 || retry > int(time.Hour/time.Second) || time.Duration(retry)*time.Second < until {
+		t.Errorf("Retry-After %ds, window resets in %s", retry, until)
+	}
+
+	// a token can always see its own usage
+	if rec := get("/v1/me/usage"); rec.Code != http.StatusOK {
+		t.Errorf("own usage over quota: status %d", rec.Code)
+	}
+}
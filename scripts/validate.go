@@ -0,0 +1,287 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// validateDB checks a migrated registry against the rules the updater
+// enforces when writing it. Problems start with their severity.
+func validateDB(db registry.Database, v validationConfig) []string {
+	var problems []string
+	if mv := db.Metadata.MinClientVersion; mv != "" {
+		if _, err := registry.ParseSemver(mv); err != nil {
+			problems = append(problems, fmt.Sprintf("error: metadata: min_client_version %q: %v", mv, err))
+		}
+	}
+	for _, alg := range db.Metadata.RequiredDigests {
+		if !registry.SupportedDigest(alg) {
+			problems = append(problems, fmt.Sprintf("error: metadata: required digest %q is not a supported algorithm", alg))
+		}
+	}
+	seen := map[string]bool{}
+	for i, bp := range db.Blueprints {
+		where := bp.FullName()
+		if bp.Name == "" {
+			where = fmt.Sprintf("entry %d", i)
+		}
+		if seen[bp.FullName()] {
+			problems = append(problems, "error: "+where+": duplicate entry")
+		}
+		seen[bp.FullName()] = true
+		if err := bp.AllDigests().Verify(nil, db.Metadata.RequiredDigests); err != nil {
+			problems = append(problems, fmt.Sprintf("error: %s: %v, which the registry requires", where, err))
+		}
+		for _, f := range checkEntry(bp, v) {
+			problems = append(problems, fmt.Sprintf("%s: %s: %s [%s]", f.Severity, where, f.Message, f.Rule))
+		}
+	}
+	return problems
+}
+
+// jsonFields returns the JSON names of t's fields.
+func jsonFields(t reflect.Type) map[string]bool {
+	out := map[string]bool{}
+	for f := range t.Fields() {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			out[name] = true
+		}
+	}
+	return out
+}
+
+// unknownFields reports keys the current schema doesn't know, which a
+// decode would silently drop.
+func unknownFields(b []byte) []string {
+	var top map[string]json.RawMessage
+	var raw struct {
+		Blueprints []map[string]json.RawMessage `json:"blueprints"`
+	}
+	if json.Unmarshal(b, &top) != nil || json.Unmarshal(b, &raw) != nil {
+		return nil // reported as a decode error
+	}
+	var problems []string
+	known := jsonFields(reflect.TypeFor[registry.Database]())
+	for k := range top {
+		if !known[k] {
+			problems = append(problems, fmt.Sprintf("error: unknown top-level field %q", k))
+		}
+	}
+	fields := jsonFields(reflect.TypeFor[registry.Blueprint]())
+	unknown := map[string]int{}
+	for _, bp := range raw.Blueprints {
+		for k := range bp {
+			if !fields[k] {
+				unknown[k]++
+			}
+		}
+	}
+	for k, n := range unknown {
+		problems = append(problems, fmt.Sprintf("error: unknown entry field %q (%d entries)", k, n))
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// registryJSON reads the registry at p as JSON, converting the other
+// encodings. YAML keeps fields the schema doesn't know, so they are still
+// reported; the binary forms can't carry them.
+func registryJSON(p string) ([]byte, error) {
+	b, err := readRegistry(p)
+	if err != nil {
+		return nil, err
+	}
+	var db registry.Database
+	switch registry.FormatOf(p) {
+	case registry.FormatYAML:
+		var doc any
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+		return json.Marshal(doc)
+	case registry.FormatProto, registry.FormatCBOR:
+		db, err = registry.Unmarshal(b, registry.FormatOf(p))
+	default:
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(db)
+}
+
+// blueprintPathRe matches schema error paths within an entry.
+var blueprintPathRe = regexp.MustCompile(`^/blueprints/\d+(/|$)`)
+
+// checkRegistry decodes, migrates and validates one registry revision.
+func checkRegistry(b []byte, v validationConfig) []string {
+	var db registry.Database
+	decodeErr := json.Unmarshal(b, &db)
+	var problems []string
+	schemaErrs, _ := registry.ValidateSchema(b)
+	for _, e := range schemaErrs {
+		// entries are held to the schema by its rule, at its severity,
+		// unless the registry doesn't decode far enough to apply rules
+		if decodeErr != nil || !blueprintPathRe.MatchString(e.Path) {
+			problems = append(problems, "error: "+e.Error()+" [schema]")
+		}
+	}
+	if decodeErr != nil {
+		return append(problems, "error: decode: "+decodeErr.Error())
+	}
+	problems = append(problems, unknownFields(b)...)
+	if err := registry.Migrate(&db); err != nil {
+		return append(problems, "error: migrate: "+err.Error())
+	}
+	return append(problems, validateDB(db, v)...)
+}
+
+// revision is one historical version of the registry.
+type revision struct {
+	ID   string // commit or file name
+	When string
+	What string // commit subject
+	Data []byte
+}
+
+// gitRevisions lists the commits that touched file, oldest first.
+func gitRevisions(file string) ([]revision, error) {
+	out, err := exec.Command("git", "log", "--reverse", "--format=%H%x09%cs%x09%s", "--", file).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+	var revs []revision
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		parts := strings.SplitN(sc.Text(), "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		data, err := exec.Command("git", "show", parts[0]+":"+filepath.ToSlash(file)).Output()
+		if err != nil {
+			// deleted in this commit
+			continue
+		}
+		revs = append(revs, revision{ID: parts[0][:12], When: parts[1], What: parts[2], Data: data})
+	}
+	return revs, sc.Err()
+}
+
+// dirRevisions reads the JSON snapshots in dir, in name order.
+func dirRevisions(dir string) ([]revision, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var revs []revision
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		revs = append(revs, revision{ID: filepath.Base(p), When: fi.ModTime().Format("2006-01-02"), Data: data})
+	}
+	return revs, nil
+}
+
+// runValidateHistory checks every revision of registry.json, or every
+// snapshot in a directory, against the current schema and reports the
+// revision that introduced each problem.
+func runValidateHistory(args []string) error {
+	flags := flag.NewFlagSet("validate-history", flag.ExitOnError)
+	file := flags.String("file", registryPath(), "registry file whose git history to walk")
+	dir := flags.String("dir", "", "validate the *.json snapshots in this directory instead")
+	verbose := flags.Bool("v", false, "list every problem of every revision")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	var revs []revision
+	if *dir != "" {
+		revs, err = dirRevisions(*dir)
+	} else {
+		revs, err = gitRevisions(*file)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := printRuleset(os.Stdout, cfg.Validation); err != nil {
+		return err
+	}
+	fmt.Println()
+	var failing int
+	prev := map[string]bool{}
+	for _, rev := range revs {
+		problems := checkRegistry(rev.Data, cfg.Validation)
+		cur := map[string]bool{}
+		var errs int
+		for _, p := range problems {
+			cur[p] = true
+			if strings.HasPrefix(p, lintError+": ") {
+				errs++
+			}
+		}
+		status := "ok"
+		switch {
+		case errs > 0:
+			status = fmt.Sprintf("%d errors", errs)
+			failing++
+		case len(problems) > 0:
+			status = fmt.Sprintf("%d warnings", len(problems))
+		}
+		fmt.Printf("%s  %s  %-12s %s\n", rev.ID, rev.When, status, rev.What)
+		for _, p := range problems {
+			switch {
+			case !prev[p]:
+				fmt.Printf("    introduced: %s\n", p)
+			case *verbose:
+				fmt.Printf("    %s\n", p)
+			}
+		}
+		var fixed []string
+		for p := range prev {
+			if !cur[p] {
+				fixed = append(fixed, p)
+			}
+		}
+		sort.Strings(fixed)
+		for _, p := range fixed {
+			fmt.Printf("    fixed: %s\n", p)
+		}
+		prev = cur
+	}
+	fmt.Printf("%d of %d revisions fail validation\n", failing, len(revs))
+	return nil
+}
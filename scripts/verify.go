@@ -0,0 +1,181 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// runVerify downloads the archives of the given entries, or of all of
+// them, and checks they still hash to the recorded digests. With --links
+// it only checks that their download URLs answer. With --record it adds
+// the digests digests.algorithms configures to the entries that lack
+// them, once the archive has passed the others.
+func runVerify(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	links := flags.Bool("links", false, "only probe the download URLs and mirrors, without downloading")
+	fail := flags.Bool("fail", false, "with --links, exit non-zero if any link is broken")
+	concurrency := flags.Int("concurrency", 8, "parallel probes, with --links")
+	timeout := flags.Duration("timeout", 15*time.Second, "per-link timeout, with --links")
+	record := flags.Bool("record", false, "record the configured digests entries lack")
+	flags.Parse(args)
+
+	var algs []string
+	if *record {
+		cfg, err := loadConfig(configPath())
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		if algs = cfg.Digests.extra(); len(algs) == 0 {
+			return errors.New("digests.algorithms is not set")
+		}
+	}
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	bps := db.Blueprints
+	if flags.NArg() > 0 {
+		bps = nil
+		for _, ref := range flags.Args() {
+			bp, err := registry.Find(db, ref)
+			if err != nil {
+				return err
+			}
+			bps = append(bps, bp)
+		}
+	}
+	if *links {
+		return verifyLinks(ctx, bps, *concurrency, *timeout, *fail)
+	}
+	var failed int
+	added := map[string]registry.Digests{}
+	for _, bp := range bps {
+		got, err := verifyEntry(ctx, bp, algs)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "FAIL %s@%s: %v\n", bp.FullName(), bp.Version, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "ok   %s@%s\n", bp.FullName(), bp.Version)
+		for _, alg := range algs {
+			if bp.Digests[alg] == "" {
+				if added[bp.FullName()] == nil {
+					added[bp.FullName()] = registry.Digests{}
+				}
+				added[bp.FullName()][alg] = got[alg]
+			}
+		}
+	}
+	if len(added) > 0 {
+		if err := recordDigests(added, bps); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "recorded digests for %d entries\n", len(added))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d entries failed verification", failed, len(bps))
+	}
+	return nil
+}
+
+// verifyLinks probes the entries' download URLs and mirrors and reports
+// the broken ones with the status they answered.
+func verifyLinks(ctx context.Context, bps []registry.Blueprint, concurrency int, timeout time.Duration, fail bool) error {
+	// nothing counts as slow here
+	rep := checkLinks(ctx, registry.Database{Blueprints: bps}, concurrency, timeout, timeout)
+	for _, r := range rep.Results {
+		if r.State != linkDead {
+			continue
+		}
+		status := "-"
+		if r.Status != 0 {
+			status = strconv.Itoa(r.Status)
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n", status, r.Entry, r.Kind, r.URL)
+		if r.Status == 0 {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", r.URL, r.Error)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "checked %d links: %d broken\n", rep.Total, rep.Dead)
+	if fail && rep.Dead > 0 {
+		return fmt.Errorf("%d of %d links are broken", rep.Dead, rep.Total)
+	}
+	return nil
+}
+
+// verifyEntry checks the entry's archive against every digest recorded
+// for it that can be computed, and returns those and the digests in
+// algs.
+func verifyEntry(ctx context.Context, bp registry.Blueprint, algs []string) (registry.Digests, error) {
+	if bp.SHA256 == "" {
+		return nil, errors.New("no sha256 recorded")
+	}
+	f, _, err := downloadArchive(ctx, bp.DownloadURL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	want := bp.AllDigests()
+	got, err := fileDigests(f, append(want.Computable(), algs...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := want.Verify(got, nil); err != nil {
+		return nil, fmt.Errorf("archive %w", err)
+	}
+	return got, nil
+}
+
+// recordDigests adds digests to the entries they were computed for,
+// unless an entry changed release since it was verified.
+func recordDigests(added map[string]registry.Digests, verified []registry.Blueprint) error {
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	st, err := openStore(registryPath(), cfg)
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	sums := map[string]string{}
+	for _, bp := range verified {
+		sums[bp.FullName()] = bp.SHA256
+	}
+	return st.update(func(db *registry.Database) error {
+		for i, bp := range db.Blueprints {
+			d := added[bp.FullName()]
+			if d == nil || bp.SHA256 != sums[bp.FullName()] {
+				continue
+			}
+			if bp.Digests == nil {
+				db.Blueprints[i].Digests = registry.Digests{}
+			}
+			maps.Copy(db.Blueprints[i].Digests, d)
+		}
+		return nil
+	})
+}
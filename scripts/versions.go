@@ -0,0 +1,61 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// runVersions lists the releases of an entry that can be pinned.
+func runVersions(args []string) error {
+	flags := flag.NewFlagSet("versions", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print JSON")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: versions [--json] <namespace/name | name>")
+	}
+
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	bp, err := registry.Find(db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(bp.Versions())
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tRELEASED\tSHA256\tURL")
+	for i, r := range bp.Versions() {
+		version := r.Version
+		if i == 0 {
+			version += " (current)"
+		}
+		released, digest := "-", "-"
+		if !r.ReleasedAt.IsZero() {
+			released = r.ReleasedAt.Format(time.DateOnly)
+		}
+		if r.SHA256 != "" {
+			digest = r.SHA256[:min(12, len(r.SHA256))]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", version, released, digest, r.DownloadURL)
+	}
+	return w.Flush()
+}
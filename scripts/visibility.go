@@ -0,0 +1,153 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// Entry visibility. An empty value is treated as public.
+const (
+	visibilityPublic   = "public"
+	visibilityInternal = "internal"
+	visibilityPrivate  = "private"
+)
+
+func validateVisibility(v string) error {
+	switch v {
+	case "", visibilityPublic, visibilityInternal, visibilityPrivate:
+		return nil
+	}
+	return fmt.Errorf("invalid visibility %q: want public, internal or private", v)
+}
+
+// principal is the client a registry view is produced for.
+type principal struct {
+	// Authenticated clients see internal entries.
+	Authenticated bool
+	// Namespaces the client may read private entries of.
+	Namespaces []string
+	// Teams the client belongs to, matched against owners.yaml.
+	Teams []string
+	// Admin sees everything.
+	Admin bool
+}
+
+var anonymous = principal{}
+
+// union is a principal that may see what either p or q may.
+func (p principal) union(q principal) principal {
+	return principal{
+		Authenticated: p.Authenticated || q.Authenticated,
+		Namespaces:    slices.Concat(p.Namespaces, q.Namespaces),
+		Teams:         slices.Concat(p.Teams, q.Teams),
+		Admin:         p.Admin || q.Admin,
+	}
+}
+
+// visibleTo reports whether p may see bp.
+func visibleTo(bp registry.Blueprint, p principal) bool {
+	switch bp.Visibility {
+	case "", visibilityPublic:
+		return true
+	case visibilityInternal:
+		return p.Authenticated
+	case visibilityPrivate:
+		return p.Admin || (p.Authenticated && slices.Contains(p.Namespaces, bp.Namespace))
+	}
+	// unknown values fail closed
+	return p.Admin
+}
+
+// filterVisible returns the part of db that p may see.
+func filterVisible(db registry.Database, p principal) registry.Database {
+	out := db
+	out.Blueprints = []registry.Blueprint{}
+	for _, bp := range db.Blueprints {
+		if visibleTo(bp, p) {
+			out.Blueprints = append(out.Blueprints, bp)
+		}
+	}
+	out.Metadata.Tombstones = nil
+	for _, t := range db.Metadata.Tombstones {
+		ns, name := registry.SplitRef(t.Name)
+		if visibleTo(registry.Blueprint{Namespace: ns, Name: name, Visibility: t.Visibility}, p) {
+			out.Metadata.Tombstones = append(out.Metadata.Tombstones, t)
+		}
+	}
+	return out
+}
+
+// audiencePrincipal returns the principal of an audience: public,
+// internal or all.
+func audiencePrincipal(audience string) (principal, error) {
+	switch audience {
+	case "", "public":
+		return anonymous, nil
+	case "internal":
+		return principal{Authenticated: true}, nil
+	case "all":
+		return principal{Authenticated: true, Admin: true}, nil
+	}
+	return principal{}, fmt.Errorf("unknown audience %q: want public, internal or all", audience)
+}
+
+// runExport writes the registry as seen by an audience, e.g. the public
+// copy synced to the website.
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	audience := flags.String("audience", "public", "public, internal or all")
+	namespaces := flags.String("namespaces", "", "comma-separated namespaces whose private entries to include")
+	out := flags.String("o", "registry.public.json", "output file")
+	minTrust := flags.String("min-trust", "", "only include entries at least this trusted (official, partner, community)")
+	flags.Parse(args)
+
+	p, err := audiencePrincipal(*audience)
+	if err != nil {
+		return err
+	}
+	if *namespaces != "" {
+		if *audience == "public" {
+			return fmt.Errorf("--namespaces needs an authenticated audience")
+		}
+		p.Namespaces = strings.Split(*namespaces, ",")
+	}
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	db, err := loadDB(registryPath())
+	if err != nil {
+		return fmt.Errorf("load registry: %w", err)
+	}
+	view := filterVisible(db, p)
+	if *minTrust != "" {
+		if err := validateTrustLevel(*minTrust); err != nil {
+			return err
+		}
+		view.Blueprints = slices.DeleteFunc(view.Blueprints, func(bp registry.Blueprint) bool {
+			return !trustAtLeast(bp.Trust, *minTrust)
+		})
+	}
+	// Not saveDB: an export is a derived copy, so it isn't signed or
+	// added to the TUF targets.
+	if err := registry.Save(*out, view, registry.SaveOptions{Canonical: cfg.Output.Canonical, Formats: cfg.Output.Formats}); err != nil {
+		return fmt.Errorf("save %s: %w", *out, err)
+	}
+	fmt.Printf("exported %d of %d entries to %s\n", len(view.Blueprints), len(db.Blueprints), *out)
+	return nil
+}
@@ -0,0 +1,573 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// Event types sent to webhook subscribers.
+const (
+	eventAdded   = "entry.added"
+	eventUpdated = "entry.updated"
+	eventYanked  = "entry.yanked"
+)
+
+var eventTypes = []string{eventAdded, eventUpdated, eventYanked}
+
+// Headers on webhook deliveries.
+const (
+	eventHeader     = "X-Dragon-Event"
+	deliveryHeader  = "X-Dragon-Delivery"
+	signatureHeader = "X-Dragon-Signature-256"
+)
+
+// webhookConfig sends registry changes to subscriber URLs.
+type webhookConfig struct {
+	// Subscribers is the subscriber list. Empty disables webhooks.
+	Subscribers string `yaml:"subscribers"`
+	// Dir holds the outbox and the delivery log. Defaults to
+	// .dragon-webhooks.
+	Dir string `yaml:"dir"`
+	// MaxAttempts is how often a delivery is tried before it is parked
+	// under failed/. Defaults to 5.
+	MaxAttempts int `yaml:"max_attempts"`
+	// Timeout bounds one delivery. Defaults to 10s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (w webhookConfig) validate() error {
+	if w.MaxAttempts < 0 {
+		return errors.New("webhooks.max_attempts must not be negative")
+	}
+	if w.Timeout < 0 {
+		return errors.New("webhooks.timeout must not be negative")
+	}
+	return nil
+}
+
+func (w webhookConfig) dir() string { return orDefault(w.Dir, ".dragon-webhooks") }
+
+func (w webhookConfig) maxAttempts() int { return orDefault(w.MaxAttempts, 5) }
+
+func (w webhookConfig) timeout() time.Duration { return orDefault(w.Timeout, 10*time.Second) }
+
+// subscribersFile lists the webhook subscribers.
+type subscribersFile struct {
+	Subscribers []subscriber `yaml:"subscribers"`
+}
+
+type subscriber struct {
+	URL string `yaml:"url"`
+	// SecretEnv names the environment variable holding the HMAC secret
+	// deliveries are signed with; the secret itself is never stored.
+	SecretEnv string `yaml:"secret_env"`
+	// Events are the event types sent; empty means all of them.
+	Events []string `yaml:"events,omitempty"`
+}
+
+func (s subscriber) validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("subscriber %q: not an http(s) URL", s.URL)
+	}
+	if s.SecretEnv == "" {
+		return fmt.Errorf("subscriber %s: secret_env is required", s.URL)
+	}
+	for _, e := range s.Events {
+		if !slices.Contains(eventTypes, e) {
+			return fmt.Errorf("subscriber %s: unknown event %q (want one of %s)", s.URL, e, strings.Join(eventTypes, ", "))
+		}
+	}
+	return nil
+}
+
+func (s subscriber) wants(event string) bool {
+	return len(s.Events) == 0 || slices.Contains(s.Events, event)
+}
+
+// loadSubscribers reads a subscribers file. A missing file lists no
+// subscribers.
+func loadSubscribers(p string) (subscribersFile, error) {
+	var sf subscribersFile
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return sf, nil
+		}
+		return sf, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&sf); err != nil && !errors.Is(err, io.EOF) {
+		return sf, fmt.Errorf("%s: %w", p, err)
+	}
+	for _, s := range sf.Subscribers {
+		if err := s.validate(); err != nil {
+			return sf, fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return sf, nil
+}
+
+func (sf subscribersFile) find(u string) (subscriber, bool) {
+	i := slices.IndexFunc(sf.Subscribers, func(s subscriber) bool { return s.URL == u })
+	if i < 0 {
+		return subscriber{}, false
+	}
+	return sf.Subscribers[i], true
+}
+
+// webhookEvent is the JSON body of a delivery.
+type webhookEvent struct {
+	ID    string       `json:"id"`
+	Type  string       `json:"type"`
+	Time  time.Time    `json:"time"`
+	Entry changedEntry `json:"entry"`
+}
+
+// registryEvents lists the events for the changes between two registries.
+// Removed entries are reported as yanked.
+func registryEvents(before, after registry.Database, now time.Time) []webhookEvent {
+	var cs changeSet
+	cs.diffDB(before, after)
+	var events []webhookEvent
+	add := func(typ string, entries []changedEntry) {
+		for _, e := range entries {
+			events = append(events, webhookEvent{ID: newCorrelationID(), Type: typ, Time: now, Entry: e})
+		}
+	}
+	add(eventAdded, cs.Added)
+	add(eventUpdated, cs.Updated)
+	add(eventYanked, cs.Removed)
+	return events
+}
+
+// delivery is one event on its way to one subscriber, kept as a file in
+// the outbox until it is delivered or gives up.
+type delivery struct {
+	Event       webhookEvent `json:"event"`
+	URL         string       `json:"url"`
+	Attempts    int          `json:"attempts"`
+	NextAttempt time.Time    `json:"next_attempt"`
+	LastError   string       `json:"last_error,omitempty"`
+}
+
+func (d delivery) key() string {
+	sum := sha256.Sum256([]byte(d.URL))
+	return d.Event.ID + "-" + hex.EncodeToString(sum[:4])
+}
+
+// deliveryRecord is one line of the delivery log.
+type deliveryRecord struct {
+	Time       time.Time `json:"time"`
+	EventID    string    `json:"event_id"`
+	Type       string    `json:"type"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	Status     int       `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// outbox holds pending deliveries as one JSON file each. A sender moves
+// a delivery from pending/ to sending/ by rename, so two senders never
+// post the same one; failed/ keeps the deliveries that gave up.
+type outbox struct {
+	cfg webhookConfig
+}
+
+func openOutbox(cfg webhookConfig) (*outbox, error) {
+	for _, sub := range []string{"pending", "sending", "failed"} {
+		if err := os.MkdirAll(filepath.Join(cfg.dir(), sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &outbox{cfg: cfg}, nil
+}
+
+func (o *outbox) path(state, key string) string {
+	return filepath.Join(o.cfg.dir(), state, key+".json")
+}
+
+func (o *outbox) put(state string, d delivery) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return registry.WriteFileAtomic(o.path(state, d.key()), b)
+}
+
+// enqueue queues the events of a registry change for every subscriber
+// that wants them. It is the store's commit hook.
+func (w webhookConfig) enqueue(before, after registry.Database) error {
+	events := registryEvents(before, after, time.Now().UTC())
+	if len(events) == 0 {
+		return nil
+	}
+	sf, err := loadSubscribers(w.Subscribers)
+	if err != nil {
+		return err
+	}
+	if len(sf.Subscribers) == 0 {
+		return nil
+	}
+	o, err := openOutbox(w)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, ev := range events {
+		for _, s := range sf.Subscribers {
+			if !s.wants(ev.Type) {
+				continue
+			}
+			if err := o.put("pending", delivery{Event: ev, URL: s.URL, NextAttempt: ev.Time}); err != nil {
+				return err
+			}
+			n++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "webhooks: queued %d deliveries for %d events\n", n, len(events))
+	return nil
+}
+
+// retryDelay is how long a delivery waits after its nth failed attempt:
+// a minute, doubling each time, at most six hours.
+func retryDelay(attempts int) time.Duration {
+	d := time.Minute << min(attempts-1, 9)
+	return min(d, 6*time.Hour)
+}
+
+// deliverAll sends the deliveries that are due, oldest event first. It
+// reports how many were sent and how many failed.
+func (o *outbox) deliverAll(ctx context.Context, sf subscribersFile) (sent, failed int, err error) {
+	o.recover()
+	entries, err := os.ReadDir(filepath.Join(o.cfg.dir(), "pending"))
+	if err != nil {
+		return 0, 0, err
+	}
+	var due []delivery
+	now := time.Now()
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(o.cfg.dir(), "pending", e.Name()))
+		if err != nil {
+			continue
+		}
+		var d delivery
+		if err := json.Unmarshal(b, &d); err != nil {
+			// an unreadable delivery can never succeed; park it
+			key := strings.TrimSuffix(e.Name(), ".json")
+			_ = registry.ReplaceFile(o.path("pending", key), o.path("failed", key))
+			continue
+		}
+		if !d.NextAttempt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Event.Time.Before(due[j].Event.Time) })
+
+	for _, d := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		key := d.key()
+		if err := renameNoReplace(o.path("pending", key), o.path("sending", key)); err != nil {
+			// another sender has it
+			continue
+		}
+		s, ok := sf.find(d.URL)
+		if !ok {
+			// unsubscribed since the event was queued
+			os.Remove(o.path("sending", key))
+			continue
+		}
+		if o.send(ctx, s, &d) {
+			sent++
+			os.Remove(o.path("sending", key))
+			continue
+		}
+		failed++
+		state := "pending"
+		if d.Attempts >= o.cfg.maxAttempts() {
+			state = "failed"
+			fmt.Fprintf(os.Stderr, "webhooks: %s to %s gave up after %d attempts: %s\n", d.Event.ID, d.URL, d.Attempts, d.LastError)
+		}
+		if err := o.put(state, d); err != nil {
+			return sent, failed, err
+		}
+		os.Remove(o.path("sending", key))
+	}
+	return sent, failed, nil
+}
+
+// recover returns deliveries a sender held far longer than one attempt
+// takes, which means it died mid-delivery, to pending.
+func (o *outbox) recover() {
+	entries, err := os.ReadDir(filepath.Join(o.cfg.dir(), "sending"))
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < 2*o.cfg.timeout()+time.Minute {
+			continue
+		}
+		key := strings.TrimSuffix(e.Name(), ".json")
+		if err := renameNoReplace(o.path("sending", key), o.path("pending", key)); errors.Is(err, fs.ErrExist) {
+			os.Remove(o.path("sending", key))
+		}
+	}
+}
+
+// send posts one delivery, signed with the subscriber's secret, and logs
+// the attempt. On failure it schedules the next attempt.
+func (o *outbox) send(ctx context.Context, s subscriber, d *delivery) bool {
+	d.Attempts++
+	start := time.Now()
+	status, err := postEvent(ctx, s, d.Event, o.cfg.timeout())
+	rec := deliveryRecord{
+		Time:       start.UTC(),
+		EventID:    d.Event.ID,
+		Type:       d.Event.Type,
+		URL:        d.URL,
+		Attempt:    d.Attempts,
+		Status:     status,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+		d.LastError = err.Error()
+		d.NextAttempt = time.Now().UTC().Add(retryDelay(d.Attempts))
+	}
+	if lerr := o.log(rec); lerr != nil {
+		fmt.Fprintf(os.Stderr, "webhooks: delivery log: %v\n", lerr)
+	}
+	return err == nil
+}
+
+func (o *outbox) log(rec deliveryRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(o.cfg.dir(), "deliveries.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// signEvent returns the signature header value for body:
+// sha256=<hex HMAC-SHA256 of the body>.
+func signEvent(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postEvent sends ev to s. Any 2xx response counts as delivered.
+func postEvent(ctx context.Context, s subscriber, ev webhookEvent, timeout time.Duration) (int, error) {
+	secret := os.Getenv(s.SecretEnv)
+	if secret == "" {
+		return 0, fmt.Errorf("$%s is not set", s.SecretEnv)
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(eventHeader, ev.Type)
+	req.Header.Set(deliveryHeader, ev.ID)
+	req.Header.Set(signatureHeader, signEvent(secret, body))
+	// the outbox retries on its own schedule
+	resp, err := defaultClient.doOnce(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("POST %s: %s", s.URL, resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// deliverWebhooks sends what is due in the outbox, if webhooks are on.
+func deliverWebhooks(ctx context.Context, cfg webhookConfig) error {
+	if cfg.Subscribers == "" {
+		return nil
+	}
+	sf, err := loadSubscribers(cfg.Subscribers)
+	if err != nil {
+		return err
+	}
+	o, err := openOutbox(cfg)
+	if err != nil {
+		return err
+	}
+	sent, failed, err := o.deliverAll(ctx, sf)
+	if sent+failed > 0 {
+		fmt.Fprintf(os.Stderr, "webhooks: %d delivered, %d failed\n", sent, failed)
+	}
+	return err
+}
+
+func runWebhooks(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: webhooks list | add --url URL --secret-env NAME [--events e,...] | remove URL | deliver | log [-n N]")
+	}
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	w := cfg.Webhooks
+	if w.Subscribers == "" {
+		return errors.New("webhooks.subscribers is not set")
+	}
+	sf, err := loadSubscribers(w.Subscribers)
+	if err != nil {
+		return fmt.Errorf("subscribers: %w", err)
+	}
+	save := func() error {
+		b, err := yaml.Marshal(sf)
+		if err != nil {
+			return err
+		}
+		return registry.WriteFileAtomic(w.Subscribers, b)
+	}
+
+	switch args[0] {
+	case "list":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, s := range sf.Subscribers {
+			events := "all"
+			if len(s.Events) > 0 {
+				events = strings.Join(s.Events, ",")
+			}
+			fmt.Fprintf(tw, "%s\t$%s\t%s\n", s.URL, s.SecretEnv, events)
+		}
+		return tw.Flush()
+	case "add":
+		flags := flag.NewFlagSet("webhooks add", flag.ExitOnError)
+		u := flags.String("url", "", "URL to post events to")
+		secretEnv := flags.String("secret-env", "", "environment variable holding the signing secret")
+		events := flags.String("events", "", "comma-separated event types to send; default all")
+		flags.Parse(args[1:])
+		s := subscriber{URL: *u, SecretEnv: *secretEnv, Events: splitList(*events)}
+		if err := s.validate(); err != nil {
+			return err
+		}
+		if _, ok := sf.find(s.URL); ok {
+			return fmt.Errorf("%s is already subscribed", s.URL)
+		}
+		sf.Subscribers = append(sf.Subscribers, s)
+		if err := save(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "subscribed %s\n", s.URL)
+		return nil
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("usage: webhooks remove URL")
+		}
+		if _, ok := sf.find(args[1]); !ok {
+			return fmt.Errorf("%s is not subscribed", args[1])
+		}
+		sf.Subscribers = slices.DeleteFunc(sf.Subscribers, func(s subscriber) bool { return s.URL == args[1] })
+		if err := save(); err != nil {
+			return err
+		}
+		// its pending deliveries are dropped when next due
+		fmt.Fprintf(os.Stderr, "unsubscribed %s\n", args[1])
+		return nil
+	case "deliver":
+		return deliverWebhooks(ctx, w)
+	case "log":
+		flags := flag.NewFlagSet("webhooks log", flag.ExitOnError)
+		n := flags.Int("n", 20, "number of attempts to print, most recent last")
+		flags.Parse(args[1:])
+		return printDeliveryLog(filepath.Join(w.dir(), "deliveries.jsonl"), *n)
+	}
+	return fmt.Errorf("unknown webhooks command %q", args[0])
+}
+
+// printDeliveryLog prints the last n attempts of the delivery log.
+func printDeliveryLog(p string, n int) error {
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var recs []deliveryRecord
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var rec deliveryRecord
+		if json.Unmarshal(sc.Bytes(), &rec) != nil {
+			continue
+		}
+		recs = append(recs, rec)
+		if len(recs) > n {
+			recs = recs[1:]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, r := range recs {
+		outcome := fmt.Sprint(r.Status)
+		if r.Error != "" {
+			outcome = r.Error
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t#%d\t%dms\t%s\n", r.Time.Format(time.RFC3339), r.EventID, r.Type, r.URL, r.Attempt, r.DurationMS, outcome)
+	}
+	return tw.Flush()
+}
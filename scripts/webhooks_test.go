@@ -0,0 +1,258 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// webhookTest queues one entry.added event for a subscriber at url,
+// signed with $WEBHOOK_TEST_SECRET, and returns the outbox and the
+// subscribers.
+func webhookTest(t *testing.T, url string, maxAttempts int) (*outbox, subscribersFile) {
+	t.Helper()
+	t.Setenv("WEBHOOK_TEST_SECRET", "s3cret")
+	dir := t.TempDir()
+	sf := subscribersFile{Subscribers: []subscriber{{URL: url, SecretEnv: "WEBHOOK_TEST_SECRET"}}}
+	b, err := yaml.Marshal(sf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := webhookConfig{
+		Subscribers: filepath.Join(dir, "subscribers.yaml"),
+		Dir:         filepath.Join(dir, "outbox"),
+		MaxAttempts: maxAttempts,
+		Timeout:     5 * time.Second,
+	}
+	if err := os.WriteFile(cfg.Subscribers, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	after := testEntries(1)
+	if err := cfg.enqueue(registry.Database{}, after); err != nil {
+		t.Fatal(err)
+	}
+	o, err := openOutbox(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o, sf
+}
+
+// deliveries lists the deliveries in an outbox state.
+func deliveries(t *testing.T, o *outbox, state string) []delivery {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(o.cfg.dir(), state, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []delivery
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d delivery
+		if err := json.Unmarshal(b, &d); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// makeDue moves every pending delivery's next attempt to now, as if its
+// backoff had passed.
+func makeDue(t *testing.T, o *outbox) {
+	t.Helper()
+	for _, d := range deliveries(t, o, "pending") {
+		d.NextAttempt = time.Now().Add(-time.Second)
+		if err := o.put("pending", d); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		got []*http.Request
+		bod [][]byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, r)
+		bod = append(bod, b)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	o, sf := webhookTest(t, srv.URL+"/hook", 0)
+	sent, failed, err := o.deliverAll(context.Background(), sf)
+	if err != nil || sent != 1 || failed != 0 {
+		t.Fatalf("deliverAll = %d sent, %d failed, %v", sent, failed, err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("%d requests, want 1", len(got))
+	}
+	r, body := got[0], bod[0]
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get(signatureHeader) != want {
+		t.Errorf("%s = %q, want %q", signatureHeader, r.Header.Get(signatureHeader), want)
+	}
+	if !hmac.Equal([]byte(signEvent("s3cret", body)), []byte(r.Header.Get(signatureHeader))) {
+		t.Error("signEvent disagrees with the header sent")
+	}
+	if signEvent("other", body) == r.Header.Get(signatureHeader) {
+		t.Error("signature doesn't depend on the secret")
+	}
+	var ev webhookEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if r.Header.Get(eventHeader) != eventAdded || ev.Type != eventAdded {
+		t.Errorf("event %q, body type %q, want %s", r.Header.Get(eventHeader), ev.Type, eventAdded)
+	}
+	if r.Header.Get(deliveryHeader) != ev.ID {
+		t.Errorf("%s = %q, want the event ID %q", deliveryHeader, r.Header.Get(deliveryHeader), ev.ID)
+	}
+	if n := len(deliveries(t, o, "pending")); n != 0 {
+		t.Errorf("%d deliveries still pending", n)
+	}
+}
+
+func TestWebhookRetryOn5xx(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	o, sf := webhookTest(t, srv.URL, 0)
+	ctx := context.Background()
+	for attempt := 1; attempt <= 2; attempt++ {
+		start := time.Now()
+		sent, failed, err := o.deliverAll(ctx, sf)
+		if err != nil || sent != 0 || failed != 1 {
+			t.Fatalf("attempt %d: %d sent, %d failed, %v", attempt, sent, failed, err)
+		}
+		pending := deliveries(t, o, "pending")
+		if len(pending) != 1 {
+			t.Fatalf("attempt %d: %d pending, want 1", attempt, len(pending))
+		}
+		d := pending[0]
+		if d.Attempts != attempt || !strings.Contains(d.LastError, "503") {
+			t.Errorf("attempt %d: attempts %d, last error %q", attempt, d.Attempts, d.LastError)
+		}
+		// backs off a minute, then two
+		wait := d.NextAttempt.Sub(start)
+		if want := retryDelay(attempt); wait < want || wait > want+time.Minute {
+			t.Errorf("attempt %d: next attempt in %s, want %s", attempt, wait, want)
+		}
+		// not due yet: nothing is sent
+		if sent, failed, _ := o.deliverAll(ctx, sf); sent+failed != 0 {
+			t.Errorf("attempt %d: retried before its backoff", attempt)
+		}
+		makeDue(t, o)
+	}
+	sent, failed, err := o.deliverAll(ctx, sf)
+	if err != nil || sent != 1 || failed != 0 {
+		t.Fatalf("third attempt: %d sent, %d failed, %v", sent, failed, err)
+	}
+	if calls != 3 {
+		t.Errorf("%d calls, want 3", calls)
+	}
+	if n := len(deliveries(t, o, "pending")) + len(deliveries(t, o, "failed")); n != 0 {
+		t.Errorf("%d deliveries left after success", n)
+	}
+
+	b, err := os.ReadFile(filepath.Join(o.cfg.dir(), "deliveries.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []int
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var rec deliveryRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, rec.Status)
+	}
+	if want := []int{503, 503, 200}; !slices.Equal(statuses, want) {
+		t.Errorf("logged statuses %v, want %v", statuses, want)
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	o, sf := webhookTest(t, srv.URL, 2)
+	for range 2 {
+		if _, _, err := o.deliverAll(context.Background(), sf); err != nil {
+			t.Fatal(err)
+		}
+		makeDue(t, o)
+	}
+	if n := len(deliveries(t, o, "pending")); n != 0 {
+		t.Errorf("%d still pending after max_attempts", n)
+	}
+	failed := deliveries(t, o, "failed")
+	if len(failed) != 1 || failed[0].Attempts != 2 {
+		t.Fatalf("failed = %+v, want one delivery after 2 attempts", failed)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for _, c := range []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{9, 256 * time.Minute},
+		{10, 6 * time.Hour},
+		{50, 6 * time.Hour},
+	} {
+		if got := retryDelay(c.attempts); got != c.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", c.attempts, got, c.want)
+		}
+	}
+}
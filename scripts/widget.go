@@ -0,0 +1,188 @@
+// Copyright 2025 getDragon-dev
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/getDragon-dev/dragon-registry/pkg/registry"
+)
+
+// embedCard is the compact view of an entry that authors embed in their
+// own sites: enough to render a card or badges without the full entry.
+type embedCard struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	// Downloads is the total counted by stats ingest; absent when
+	// downloads aren't counted.
+	Downloads *int64 `json:"downloads,omitempty"`
+	// Quality is the entry's score; absent for unrated entries.
+	Quality    *int         `json:"quality,omitempty"`
+	Deprecated bool         `json:"deprecated,omitempty"`
+	UpdatedAt  time.Time    `json:"updated_at,omitzero"`
+	Badges     []embedBadge `json:"badges"`
+}
+
+// embedBadge is one shields-style badge: a label, a message and the
+// color of the message side.
+type embedBadge struct {
+	Label   string `json:"label"`
+	Message string `json:"message"`
+	Color   string `json:"color"`
+}
+
+// Badge colors, as hex so they work without a badge service.
+const (
+	badgeGreen  = "#4c1"
+	badgeYellow = "#dfb317"
+	badgeOrange = "#fe7d37"
+	badgeRed    = "#e05d44"
+	badgeBlue   = "#007ec6"
+)
+
+func newEmbedCard(bp registry.Blueprint, downloads map[string]int64) embedCard {
+	c := embedCard{
+		Name:        bp.FullName(),
+		Version:     bp.Version,
+		Description: bp.Description,
+		Deprecated:  bp.Deprecation != nil,
+		UpdatedAt:   bp.UpdatedAt,
+	}
+	versionColor := badgeBlue
+	if c.Deprecated {
+		versionColor = badgeRed
+	}
+	c.Badges = append(c.Badges, embedBadge{"version", bp.Version, versionColor})
+	if bp.Quality != nil {
+		score := bp.Quality.Score
+		c.Quality = &score
+		c.Badges = append(c.Badges, embedBadge{"quality", fmt.Sprint(score), qualityColor(score)})
+	}
+	if downloads != nil {
+		n := downloads[bp.FullName()]
+		c.Downloads = &n
+		c.Badges = append(c.Badges, embedBadge{"downloads", shortCount(n), badgeBlue})
+	}
+	if c.Deprecated {
+		c.Badges = append(c.Badges, embedBadge{"status", "deprecated", badgeRed})
+	}
+	return c
+}
+
+func qualityColor(score int) string {
+	switch {
+	case score >= 80:
+		return badgeGreen
+	case score >= 60:
+		return badgeYellow
+	case score >= 40:
+		return badgeOrange
+	}
+	return badgeRed
+}
+
+// shortCount abbreviates a count the way badges do: 950, 1.2k, 3.4M.
+func shortCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1e6), ".0") + "M"
+	case n >= 1_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1e3), ".0") + "k"
+	}
+	return fmt.Sprint(n)
+}
+
+// callbackRe bounds JSONP callback names to plain (dotted) identifiers,
+// so a callback can't inject script.
+var callbackRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+const maxCallbackLen = 64
+
+const embedCardHTML = `<!doctype html>
+<html><head><meta charset="utf-8"><title>{{.Name}}</title>
+<style>
+body{margin:0;font:14px/1.4 system-ui,sans-serif;color:#24292f}
+.card{border:1px solid #d0d7de;border-radius:6px;padding:12px 16px}
+.name{font-weight:600;font-size:16px}
+.desc{margin:6px 0 10px;color:#57606a}
+.badge{display:inline-flex;font-size:11px;margin-right:4px;border-radius:3px;overflow:hidden}
+.badge span{padding:2px 6px;color:#fff}
+.badge .l{background:#555}
+</style></head>
+<body><div class="card">
+<div class="name">{{.Name}}</div>
+<div class="desc">{{.Description}}</div>
+<div>{{range .Badges}}<span class="badge"><span class="l">{{.Label}}</span><span style="background:{{.Color}}">{{.Message}}</span></span>{{end}}</div>
+</div></body></html>
+`
+
+var embedCardTmpl = template.Must(template.New("card").Parse(embedCardHTML))
+
+// getEmbed serves the card of an entry at /v1/embed/{ref}.json, wrapped
+// in ?callback= for JSONP, or as a self-contained page at
+// /v1/embed/{ref}.html for iframes. Any site may embed it.
+func (s *server) getEmbed(w http.ResponseWriter, r *http.Request) {
+	ref := r.PathValue("ref")
+	var format string
+	for _, ext := range []string{".json", ".html"} {
+		if name, ok := strings.CutSuffix(ref, ext); ok {
+			ref, format = name, ext
+		}
+	}
+	if format == "" {
+		writeError(w, r, http.StatusNotFound, "embed paths end in .json or .html")
+		return
+	}
+	cur := s.cur.Load()
+	bp, err := registry.Find(cur.db, ref)
+	if err != nil {
+		writeLookupError(w, r, err)
+		return
+	}
+	card := newEmbedCard(bp, cur.downloads)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if format == ".html" {
+		var b bytes.Buffer
+		if err := embedCardTmpl.Execute(&b, card); err != nil {
+			writeError(w, r, http.StatusInternalServerError, "render card")
+			return
+		}
+		// meant to be framed anywhere, and to run nothing
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors *")
+		writeBody(w, r, http.StatusOK, "text/html; charset=utf-8", b.Bytes())
+		return
+	}
+	cb := r.URL.Query().Get("callback")
+	if cb == "" {
+		writeJSON(w, r, http.StatusOK, card)
+		return
+	}
+	if len(cb) > maxCallbackLen || !callbackRe.MatchString(cb) {
+		writeError(w, r, http.StatusBadRequest, "invalid callback")
+		return
+	}
+	b, err := json.Marshal(card)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, "encode response")
+		return
+	}
+	writeBody(w, r, http.StatusOK, "text/javascript; charset=utf-8", []byte("/**/"+cb+"("+string(b)+");\n"))
+}